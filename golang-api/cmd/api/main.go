@@ -1,18 +1,47 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"io"
 	"log"
 	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/centroidsol/todo-api/internal/broker"
 	"github.com/centroidsol/todo-api/internal/config"
 	"github.com/centroidsol/todo-api/internal/database"
-	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/demo"
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/exports"
+	"github.com/centroidsol/todo-api/internal/googletasks"
+	"github.com/centroidsol/todo-api/internal/jobs"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/notify"
+	"github.com/centroidsol/todo-api/internal/outbox"
+	"github.com/centroidsol/todo-api/internal/reporting"
+	"github.com/centroidsol/todo-api/internal/repository"
 	"github.com/centroidsol/todo-api/internal/routes"
+	"github.com/centroidsol/todo-api/internal/scheduler"
+	"github.com/centroidsol/todo-api/internal/services"
 
 	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=..."
+var (
+	version   string
+	commit    string
+	buildTime string
 )
 
 // @title Todo API
@@ -36,13 +65,33 @@ import (
 
 // @tag.name health
 // @tag.description Health check endpoints
+
+// @tag.name admin
+// @tag.description Administrative endpoints (require X-Admin-Token)
+
+// @tag.name auth
+// @tag.description User registration and authentication
+
+// @tag.name keys
+// @tag.description Personal API key management
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the access token
 func main() {
+	migrateOnly := parseFlags(os.Args[1:])
+
 	// Load configuration
 	cfg := config.Load()
+	applyBuildInfo(cfg)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Setup logger
-	logger := setupLogger(cfg)
-	logger.Info("Starting Todo API", "version", cfg.App.Version, "environment", cfg.App.Environment)
+	logger, logLevel := setupLogger(cfg)
+	logger.Info("Starting Todo API", "version", cfg.App.Version, "commit", cfg.App.Commit, "environment", cfg.App.Environment)
 
 	// Initialize database
 	db, err := database.New(cfg)
@@ -50,32 +99,162 @@ func main() {
 		logger.Error("Failed to initialize database", "error", err)
 		log.Fatal(err)
 	}
-	defer func() {
+
+	// Init containers run the migrations and exit before the app starts
+	if migrateOnly {
 		if err := db.Close(); err != nil {
-			logger.Error("Failed to close database", "error", err)
+			log.Fatal(err)
 		}
-	}()
+		logger.Info("Database migrated", "path", cfg.Database.Path, "schema_version", database.SchemaVersion())
+		return
+	}
+
+	// Domain event bus, fed from the transactional outbox
+	bus := events.NewBus(logger)
+
+	var publisher broker.Publisher
+	if cfg.Broker.Type != "" {
+		publisher, err = broker.New(cfg.Broker)
+		if err != nil {
+			logger.Error("Failed to initialize event broker", "type", cfg.Broker.Type, "error", err)
+			log.Fatal(err)
+		}
+		bus.Subscribe("broker", broker.Forwarder(publisher, cfg.Broker))
+	}
+
+	if cfg.Slack.WebhookURL != "" {
+		slack, err := notify.NewSlackNotifier(cfg.Slack)
+		if err != nil {
+			logger.Error("Failed to initialize Slack notifications", "error", err)
+			log.Fatal(err)
+		}
+		bus.Subscribe("slack", slack.Handle, slack.Events()...)
+	}
+
+	notifications := services.NewNotificationService(repository.NewNotificationRepository(db.DB()), repository.NewUserRepository(db.DB()), logger)
+	bus.Subscribe("notifications", notifications.HandleEvent, services.NotificationEvents...)
+	// A public demo must not post to URLs its visitors choose
+	if !cfg.Demo.Enabled {
+		bus.Subscribe("hooks", services.NewHookService(repository.NewHookRepository(db.DB()), nil, cfg.Hooks.AllowPrivateTargets, logger).HandleEvent)
+	}
+
+	// With prefork every child process runs main too; background work runs
+	// once, in the parent, and children only serve requests. A standby
+	// leaves it to the primary.
+	background := !fiber.IsChild() && !cfg.Database.ReadOnly
+
+	relay := outbox.NewRelay(repository.NewOutboxRepository(db.DB()), bus, cfg.Outbox, logger)
+	if background {
+		relay.Start()
+	}
+
+	reporter, err := reporting.New(cfg.Reporting, cfg.App, logger)
+	if err != nil {
+		logger.Error("Failed to initialize error reporting", "error", err)
+		log.Fatal(err)
+	}
 
 	// Create Fiber app
-	app := fiber.New(fiber.Config{
-		AppName:      cfg.App.Name,
-		ErrorHandler: middleware.ErrorHandler(logger),
-		Prefork:      false, // Set to true for production if needed
-		ServerHeader: "Todo-API/" + cfg.App.Version,
-		BodyLimit:    1 * 1024 * 1024, // 1MB
-	})
+	app := routes.NewApp(cfg, logger, reporter)
 
 	// Setup routes
-	routes.Setup(app, db, cfg, logger)
+	var draining atomic.Bool
+	var accessLog io.WriteCloser
+	if cfg.Logging.AccessLogFile != "" {
+		f, err := logging.NewRotatingFile(cfg.Logging.AccessLogFile, int64(cfg.Logging.AccessLogMaxSizeMB)<<20, cfg.Logging.AccessLogMaxBackups)
+		if err != nil {
+			logger.Error("Failed to open access log", "error", err)
+			log.Fatal(err)
+		}
+		accessLog = f
+	}
+
+	store := config.NewStore(cfg)
+	// The todo jobs share the routes' todo service, so their writes drop
+	// its cached counts. Workers only start below, once handlers exist.
+	jobManager := jobs.NewManager(repository.NewJobRepository(db.DB()), cfg.Jobs, logger)
+	sched := scheduler.New(logger)
+	todoService := routes.Setup(app, db, store, logger, accessLog, logLevel, reporter, jobManager, &draining, sched, relay)
+
+	if cfg.Demo.Enabled {
+		if err := demo.Seed(context.Background(), todoService, cfg.Demo.Todos); err != nil {
+			logger.Error("Failed to seed demo data", "error", err)
+			log.Fatal(err)
+		}
+		logger.Info("Demo mode: seeded in-memory database", "todos", cfg.Demo.Todos, "reset_interval", cfg.Demo.ResetInterval.String())
+	}
 
-	// Graceful shutdown
+	// Background jobs
+	audit := services.NewAuditService(repository.NewAuditRepository(db.DB()), logger)
+	jobManager.Register(jobs.TypePurgeCompletedTodos, jobs.PurgeCompletedTodos(todoService, audit, cfg.Purge.RetentionDays))
+	jobManager.Register(jobs.TypePurgeTrash, jobs.PurgeTrash(todoService, audit, cfg.Trash.RetentionDays))
+	jobManager.Register(jobs.TypeDatabaseBackup, jobs.DatabaseBackup(db, cfg.Backup))
+	jobManager.Register(jobs.TypeImportTodos, jobs.ImportTodos(todoService))
+	jobManager.Register(jobs.TypeBulkTags, jobs.BulkTags(todoService))
+	jobManager.RegisterSerial(jobs.TypeWriteTodo, jobs.WriteTodo(todoService))
+	jobManager.Register(jobs.TypeAnnounceDueSoon, jobs.AnnounceDueSoon(todoService, cfg.Notify.DueSoon))
+	jobManager.Register(jobs.TypeScheduledExport, jobs.ScheduledExport(todoService, exports.NewStore(cfg.Export), cfg.Export))
+	jobManager.Register(jobs.TypeAccountExport, jobs.AccountExport(services.NewAccountService(services.AccountRepositories{
+		Users:         repository.NewUserRepository(db.DB()),
+		APIKeys:       repository.NewAPIKeyRepository(db.DB()),
+		Hooks:         repository.NewHookRepository(db.DB()),
+		Notifications: repository.NewNotificationRepository(db.DB()),
+		Google:        repository.NewGoogleTasksRepository(db.DB()),
+		Audit:         repository.NewAuditRepository(db.DB()),
+		Usage:         repository.NewUsageRepository(db.DB()),
+	}, repository.NewUnitOfWork(db.DB()), audit, logger)))
+	if cfg.Google.Enabled() {
+		googleTasks := services.NewGoogleTasksService(repository.NewGoogleTasksRepository(db.DB()), todoService, googletasks.NewClient(cfg.Google), logger)
+		jobManager.Register(jobs.TypeSyncGoogleTasks, jobs.SyncGoogleTasks(googleTasks))
+	}
+	if background {
+		jobManager.Start()
+	}
+
+	if cfg.Purge.Enabled {
+		sched.Every("purge-completed-todos", cfg.Purge.Interval, scheduler.EnqueueJob(jobManager, jobs.TypePurgeCompletedTodos, nil))
+	}
+	if cfg.Trash.RetentionDays > 0 {
+		sched.Every("purge-trash", cfg.Trash.PurgeInterval, scheduler.EnqueueJob(jobManager, jobs.TypePurgeTrash, nil))
+	}
+	if cfg.Backup.Enabled {
+		sched.Every("database-backup", cfg.Backup.Interval, scheduler.EnqueueJob(jobManager, jobs.TypeDatabaseBackup, nil))
+	}
+	if cfg.Notify.DueSoon > 0 {
+		sched.Every("announce-due-soon", cfg.Notify.DueSoonInterval, scheduler.EnqueueJob(jobManager, jobs.TypeAnnounceDueSoon, nil))
+	}
+	if cfg.Google.Enabled() {
+		sched.Every("sync-google-tasks", cfg.Google.SyncInterval, scheduler.EnqueueJob(jobManager, jobs.TypeSyncGoogleTasks, nil))
+	}
+	if cfg.Export.Enabled {
+		sched.Every("scheduled-export", cfg.Export.Interval, scheduler.EnqueueJob(jobManager, jobs.TypeScheduledExport, nil))
+	}
+	if cfg.Demo.Enabled && cfg.Demo.ResetInterval > 0 {
+		sched.Every("reset-demo", cfg.Demo.ResetInterval, demo.Reset(db, todoService, cfg.Demo.Todos))
+	}
+	if background {
+		sched.Start()
+	}
+
+	// SIGHUP reloads the settings that are safe to change while serving
+	go reloadOnSignal(store, logLevel, logger)
+
+	// Graceful shutdown: report not ready for the drain period so load
+	// balancers stop routing new traffic here, then stop accepting
+	// connections and wait for in-flight requests
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 
-		logger.Info("Shutting down server...")
-		if err := app.Shutdown(); err != nil {
+		logger.Info("Shutting down server...", "drain_period", cfg.Server.DrainPeriod.String(), "timeout", cfg.Server.ShutdownTimeout.String())
+		draining.Store(true)
+		time.Sleep(cfg.Server.DrainPeriod)
+
+		if err := app.ShutdownWithTimeout(cfg.Server.ShutdownTimeout); err != nil {
 			logger.Error("Server shutdown error", "error", err)
 		}
 	}()
@@ -83,30 +262,166 @@ func main() {
 	// Start server
 	address := cfg.Server.Host + ":" + cfg.Server.Port
 	logger.Info("Server starting", "address", address)
-	
-	if cfg.IsDevelopment() {
+
+	if cfg.Docs.Enabled {
 		logger.Info("Swagger documentation available", "url", "http://"+address+"/swagger/index.html")
 	}
 
-	if err := app.Listen(address); err != nil {
+	if cfg.TLS.Enabled() {
+		err = listenAutocert(app, address, cfg.TLS, logger)
+	} else {
+		err = app.Listen(address)
+	}
+	if err != nil {
 		logger.Error("Server startup error", "error", err)
 		log.Fatal(err)
 	}
+
+	// Listen returns as soon as the listener closes; wait for in-flight
+	// requests before tearing down what they depend on
+	<-shutdownDone
+
+	// Stop producers before consumers: no new jobs are scheduled, running
+	// jobs finish, pending outbox messages stop being relayed, subscribers
+	// drain, and only then are the broker and database closed
+	sched.Stop()
+	jobManager.Stop()
+	relay.Stop()
+	bus.Close()
+
+	if publisher != nil {
+		if err := publisher.Close(); err != nil {
+			logger.Error("Failed to close event broker", "error", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close database", "error", err)
+	}
+
+	reporter.Close()
+
+	if accessLog != nil {
+		if err := accessLog.Close(); err != nil {
+			logger.Error("Failed to close access log", "error", err)
+		}
+	}
+
+	logger.Info("Server stopped")
 }
 
-func setupLogger(cfg *config.Config) *slog.Logger {
-	var handler slog.Handler
+// applyBuildInfo records the injected build information in the config.
+// Without -ldflags the commit falls back to the VCS revision Go embeds in
+// the binary.
+func applyBuildInfo(cfg *config.Config) {
+	if version != "" {
+		cfg.App.Version = version
+	}
+
+	cfg.App.Commit = commit
+	cfg.App.BuildTime = buildTime
 
-	opts := &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && cfg.App.Commit == "":
+				cfg.App.Commit = setting.Value
+			case setting.Key == "vcs.time" && cfg.App.BuildTime == "":
+				cfg.App.BuildTime = setting.Value
+			}
+		}
 	}
 
-	if cfg.IsDevelopment() {
-		opts.Level = slog.LevelDebug
-		handler = slog.NewTextHandler(os.Stdout, opts)
-	} else {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+	if cfg.App.Commit == "" {
+		cfg.App.Commit = "unknown"
+	}
+	if cfg.App.BuildTime == "" {
+		cfg.App.BuildTime = "unknown"
+	}
+}
+
+// parseFlags applies command-line overrides by setting the matching
+// environment variables before the configuration is loaded, so flags win
+// over both the environment and the config file. It reports whether
+// --migrate-only was given.
+func parseFlags(args []string) bool {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	overrides := map[string]*string{
+		"PORT":          flags.String("port", "", "Port to listen on (overrides PORT)"),
+		"HOST":          flags.String("host", "", "Host to bind to (overrides HOST)"),
+		"DATABASE_PATH": flags.String("db", "", "SQLite database path (overrides DATABASE_PATH)"),
+		"ENVIRONMENT":   flags.String("env", "", "Environment: development, test, staging or production (overrides ENVIRONMENT)"),
+	}
+	migrateOnly := flags.Bool("migrate-only", false, "Apply database migrations and exit")
+	flags.Parse(args)
+
+	for key, value := range overrides {
+		if *value != "" {
+			os.Setenv(key, *value)
+		}
+	}
+
+	return *migrateOnly
+}
+
+// listenAutocert serves HTTPS with certificates from Let's Encrypt (or the
+// configured ACME directory), answering TLS-ALPN-01 challenges on the same
+// listener, so the server must be reachable on port 443 for the domains
+func listenAutocert(app *fiber.App, address string, cfg config.TLSConfig, logger *slog.Logger) error {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+	}
+	if cfg.DirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
 	}
 
-	return slog.New(handler)
-}
\ No newline at end of file
+	logger.Info("Serving HTTPS with automatic certificates", "domains", cfg.AutocertDomains, "cache_dir", cfg.CacheDir)
+	return app.Listener(tls.NewListener(ln, manager.TLSConfig()))
+}
+
+// reloadOnSignal applies the config file on every SIGHUP. An invalid file
+// is logged and the running configuration is kept.
+func reloadOnSignal(store *config.Store, logLevel *slog.LevelVar, logger *slog.Logger) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	for range sigChan {
+		changed, err := store.Reload()
+		if err != nil {
+			logger.Error("Failed to reload configuration, keeping the current one", "error", err)
+			continue
+		}
+
+		for _, setting := range changed {
+			if setting == "LOG_LEVEL" {
+				level, _ := logging.ParseLevel(store.Get().Logging.Level)
+				logLevel.Set(level)
+			}
+		}
+		logger.Info("Configuration reloaded", "changed", changed)
+	}
+}
+
+func setupLogger(cfg *config.Config) (*slog.Logger, *slog.LevelVar) {
+	level := new(slog.LevelVar)
+	parsed, err := logging.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		log.Fatal(err)
+	}
+	level.Set(parsed)
+
+	logger, err := logging.New(os.Stdout, cfg.Logging.Format, level)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return logger, level
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearOverrides restores the variables parseFlags sets once the test ends
+func clearOverrides(t *testing.T) {
+	for _, key := range []string{"PORT", "HOST", "DATABASE_PATH", "ENVIRONMENT"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	clearOverrides(t)
+	t.Setenv("HOST", "0.0.0.0")
+	t.Setenv("PORT", "3001")
+
+	migrateOnly := parseFlags([]string{"--port", "8080", "--db", "./flags.db", "--env", "test"})
+	assert.False(t, migrateOnly)
+
+	// Flags win over the environment, which keeps what no flag overrides
+	cfg := config.Load()
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, "./flags.db", cfg.Database.Path)
+	assert.Equal(t, "test", cfg.App.Environment)
+
+	assert.True(t, parseFlags([]string{"--migrate-only"}))
+}
+
+func TestMigrateOnly(t *testing.T) {
+	clearOverrides(t)
+	path := t.TempDir() + "/todos.db"
+
+	args := os.Args
+	defer func() { os.Args = args }()
+	// The test environment always uses an in-memory database
+	os.Args = []string{"api", "--migrate-only", "--db", path, "--env", "development"}
+
+	// main returns once the database is migrated instead of serving
+	main()
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var version int
+	require.NoError(t, db.QueryRow("PRAGMA user_version").Scan(&version))
+	assert.Equal(t, database.SchemaVersion(), version)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/auth"
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// todoPage is a page of todos as returned by both backends
+type todoPage struct {
+	Data       []models.Todo `json:"data"`
+	Total      int           `json:"total"`
+	Page       int           `json:"page"`
+	PerPage    int           `json:"per_page"`
+	TotalPages int           `json:"total_pages"`
+}
+
+// backend is the set of operations the CLI performs, implemented against
+// the database layer and against the HTTP API
+type backend interface {
+	ListTodos(params models.QueryParams) (*todoPage, error)
+	// EachTodo calls fn for every todo, oldest first
+	EachTodo(fn func(models.Todo) error) error
+	CreateTodo(req models.CreateTodoRequest) (*models.Todo, error)
+	PurgeCompleted(olderThan time.Duration) (int64, error)
+	CreateUser(req models.RegisterRequest) (*models.User, error)
+	Close() error
+}
+
+// directBackend uses the services on the local database. Domain events
+// are written to the outbox and delivered by the next running server.
+type directBackend struct {
+	db    *database.Database
+	todos services.TodoService
+	users services.UserService
+}
+
+func newDirectBackend(cfg *config.Config, logger *slog.Logger) (*directBackend, error) {
+	db, err := database.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the created account is used, so issued tokens are discarded
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		db.Close()
+		return nil, err
+	}
+	tokens := auth.NewTokenManager(hex.EncodeToString(secret), cfg.Auth.TokenTTL, cfg.App.Name)
+	guard := services.NewLoginGuard(repository.NewLoginFailureRepository(db.DB()), services.NewAuditService(repository.NewAuditRepository(db.DB()), logger), cfg.Auth, logger)
+
+	return &directBackend{
+		db:    db,
+		todos: services.NewTodoService(repository.NewTodoRepository(db.DB()), repository.NewUnitOfWork(db.DB()), logger),
+		users: services.NewUserService(repository.NewUserRepository(db.DB()), guard, tokens, cfg.Auth.PasswordCost, logger),
+	}, nil
+}
+
+func (b *directBackend) ListTodos(params models.QueryParams) (*todoPage, error) {
+	response, err := b.todos.GetTodos(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, _ := response.Data.([]models.Todo)
+	return &todoPage{
+		Data:       todos,
+		Total:      response.Total,
+		Page:       response.Page,
+		PerPage:    response.PerPage,
+		TotalPages: response.TotalPages,
+	}, nil
+}
+
+func (b *directBackend) EachTodo(fn func(models.Todo) error) error {
+	params := models.QueryParams{Sort: "id", Order: "asc"}
+	return b.todos.StreamTodos(context.Background(), params, fn)
+}
+
+func (b *directBackend) CreateTodo(req models.CreateTodoRequest) (*models.Todo, error) {
+	todo, _, err := b.todos.CreateTodo(context.Background(), req)
+	return todo, err
+}
+
+func (b *directBackend) PurgeCompleted(olderThan time.Duration) (int64, error) {
+	return b.todos.PurgeCompletedTodos(context.Background(), olderThan)
+}
+
+func (b *directBackend) CreateUser(req models.RegisterRequest) (*models.User, error) {
+	response, err := b.users.Register(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return response.User, nil
+}
+
+func (b *directBackend) Close() error {
+	return b.db.Close()
+}
+
+// httpBackend talks to a running server
+type httpBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newHTTPBackend(baseURL, token string) *httpBackend {
+	return &httpBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (b *httpBackend) ListTodos(params models.QueryParams) (*todoPage, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(params.Page))
+	query.Set("per_page", strconv.Itoa(params.PerPage))
+	query.Set("sort", params.Sort)
+	query.Set("order", params.Order)
+	if params.Search != "" {
+		query.Set("search", params.Search)
+	}
+	if params.Completed != nil {
+		query.Set("completed", strconv.FormatBool(*params.Completed))
+	}
+
+	var page todoPage
+	if err := b.do(http.MethodGet, "/api/todos?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// EachTodo pages through the list endpoint
+func (b *httpBackend) EachTodo(fn func(models.Todo) error) error {
+	params := models.DefaultQueryParams()
+	params.PerPage = 100
+	params.Sort = "id"
+	params.Order = "asc"
+
+	for {
+		page, err := b.ListTodos(params)
+		if err != nil {
+			return err
+		}
+		for _, todo := range page.Data {
+			if err := fn(todo); err != nil {
+				return err
+			}
+		}
+		if params.Page >= page.TotalPages {
+			return nil
+		}
+		params.Page++
+	}
+}
+
+func (b *httpBackend) CreateTodo(req models.CreateTodoRequest) (*models.Todo, error) {
+	var todo models.Todo
+	if err := b.do(http.MethodPost, "/api/todos", req, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (b *httpBackend) PurgeCompleted(olderThan time.Duration) (int64, error) {
+	return 0, errors.New("purge needs direct database access; drop -api")
+}
+
+func (b *httpBackend) CreateUser(req models.RegisterRequest) (*models.User, error) {
+	var response models.AuthResponse
+	if err := b.do(http.MethodPost, "/api/auth/register", req, &response); err != nil {
+		return nil, err
+	}
+	return response.User, nil
+}
+
+func (b *httpBackend) Close() error {
+	return nil
+}
+
+func (b *httpBackend) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,409 @@
+// Command todocli administers a Todo API installation, either directly
+// through the database layer or remotely through the HTTP API.
+//
+// Usage:
+//
+//	todocli [-db path | -api url [-token token]] <command> [flags]
+//
+// Commands: list, create, purge, export, migrate, encrypt, create-user
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, global globalOptions, args []string) error
+}
+
+var commands = []command{
+	{"list", "List todos", runList},
+	{"create", "Create a todo", runCreate},
+	{"purge", "Delete completed todos older than the retention period", runPurge},
+	{"export", "Export all todos as JSON or CSV", runExport},
+	{"migrate", "Create or upgrade the database schema", runMigrate},
+	{"encrypt", "Encrypt a plaintext database with DATABASE_ENCRYPTION_KEY", runEncrypt},
+	{"create-user", "Create a user account", runCreateUser},
+}
+
+type globalOptions struct {
+	dbPath  string
+	apiURL  string
+	token   string
+	verbose bool
+}
+
+func main() {
+	cfg := config.Load()
+
+	var global globalOptions
+	flags := flag.NewFlagSet("todocli", flag.ExitOnError)
+	flags.StringVar(&global.dbPath, "db", cfg.Database.Path, "SQLite database path (direct mode)")
+	flags.StringVar(&global.apiURL, "api", os.Getenv("TODO_API_URL"), "Base URL of a running API, e.g. http://localhost:3001 (HTTP mode)")
+	flags.StringVar(&global.token, "token", os.Getenv("TODO_API_TOKEN"), "Access token or API key for HTTP mode")
+	flags.BoolVar(&global.verbose, "v", false, "Log service activity to stderr")
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage(flags)
+		os.Exit(2)
+	}
+
+	name, args := flags.Arg(0), flags.Args()[1:]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(cfg, global, args)
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+		case errors.Is(err, errUsage):
+			os.Exit(2)
+		default:
+			fmt.Fprintf(os.Stderr, "todocli %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "todocli: unknown command %q\n\n", name)
+	usage(flags)
+	os.Exit(2)
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintln(out, "Usage: todocli [global flags] <command> [flags]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nGlobal flags:")
+	flags.PrintDefaults()
+}
+
+// errUsage is returned for flag errors, which the flag package already
+// reported along with the command's usage
+var errUsage = errors.New("invalid usage")
+
+// parseFlags parses the arguments of a command. Command flag sets continue
+// on error, so that a command returns instead of exiting the process.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return err
+}
+
+// open returns the HTTP client when an API URL is given and the database
+// backend otherwise
+func open(cfg *config.Config, global globalOptions) (backend, error) {
+	if global.apiURL != "" {
+		return newHTTPBackend(global.apiURL, global.token), nil
+	}
+
+	level := slog.LevelWarn
+	if global.verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg.Database.Path = global.dbPath
+	return newDirectBackend(cfg, logger)
+}
+
+func runList(cfg *config.Config, global globalOptions, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	params := models.DefaultQueryParams()
+	flags.IntVar(&params.Page, "page", params.Page, "Page number")
+	flags.IntVar(&params.PerPage, "per-page", params.PerPage, "Todos per page (max 100)")
+	flags.StringVar(&params.Sort, "sort", params.Sort, "Sort field")
+	flags.StringVar(&params.Order, "order", params.Order, "Sort order (asc or desc)")
+	flags.StringVar(&params.Search, "search", "", "Search in title and description")
+	completed := flags.String("completed", "", "Filter by completion (true or false)")
+	asJSON := flags.Bool("json", false, "Print JSON instead of a table")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *completed != "" {
+		value, err := strconv.ParseBool(*completed)
+		if err != nil {
+			return fmt.Errorf("invalid -completed value %q", *completed)
+		}
+		params.Completed = &value
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	page, err := b.ListTodos(params)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, page)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDONE\tTITLE\tUPDATED")
+	for _, todo := range page.Data {
+		done := " "
+		if todo.Completed {
+			done = "x"
+		}
+		fmt.Fprintf(w, "%d\t[%s]\t%s\t%s\n", todo.ID, done, todo.Title, todo.UpdatedAt.Format(time.RFC3339))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nPage %d of %d (%d todos)\n", page.Page, page.TotalPages, page.Total)
+	return nil
+}
+
+func runCreate(cfg *config.Config, global globalOptions, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	description := flags.String("description", "", "Todo description")
+	completed := flags.Bool("completed", false, "Create the todo as completed")
+	due := flags.String("due", "", "Due date (RFC3339 or YYYY-MM-DD)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: todocli create [flags] <title>")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("exactly one title is required")
+	}
+
+	req := models.CreateTodoRequest{Title: flags.Arg(0), Completed: *completed}
+	if *description != "" {
+		req.Description = description
+	}
+	if *due != "" {
+		req.DueDate = due
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	todo, err := b.CreateTodo(req)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(os.Stdout, todo)
+}
+
+func runPurge(cfg *config.Config, global globalOptions, args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	days := flags.Int("days", cfg.Purge.RetentionDays, "Retention period in days")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *days < 1 {
+		return fmt.Errorf("-days must be at least 1")
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	purged, err := b.PurgeCompleted(time.Duration(*days) * 24 * time.Hour)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Purged %d completed todos older than %d days\n", purged, *days)
+	return nil
+}
+
+func runExport(cfg *config.Config, global globalOptions, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "json", "Output format (json or csv)")
+	output := flags.String("o", "", "Output file (default stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "csv" {
+		return writeCSV(out, b.EachTodo)
+	}
+	return writeJSONArray(out, b.EachTodo)
+}
+
+func runMigrate(cfg *config.Config, global globalOptions, args []string) error {
+	if global.apiURL != "" {
+		return errors.New("migrate needs direct database access; drop -api")
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	// Opening the database applies the schema
+	fmt.Printf("Database %s is up to date\n", global.dbPath)
+	return nil
+}
+
+func runEncrypt(cfg *config.Config, global globalOptions, args []string) error {
+	if global.apiURL != "" {
+		return errors.New("encrypt needs direct database access; drop -api")
+	}
+	if cfg.Database.EncryptionKey == "" {
+		return errors.New("DATABASE_ENCRYPTION_KEY or DATABASE_ENCRYPTION_KEY_FILE must be set")
+	}
+
+	// The server must be stopped: the file is replaced underneath it
+	if err := database.Encrypt(context.Background(), global.dbPath, cfg.Database.EncryptionKey); err != nil {
+		return err
+	}
+
+	fmt.Printf("Database %s is now encrypted\n", global.dbPath)
+	return nil
+}
+
+func runCreateUser(cfg *config.Config, global globalOptions, args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	email := flags.String("email", "", "Email address (required)")
+	password := flags.String("password", os.Getenv("TODO_USER_PASSWORD"), "Password, at least 8 characters (or TODO_USER_PASSWORD)")
+	name := flags.String("name", "", "Display name")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	req := models.RegisterRequest{Email: *email, Password: *password}
+	if *name != "" {
+		req.Name = name
+	}
+
+	b, err := open(cfg, global)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	user, err := b.CreateUser(req)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(os.Stdout, user)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeJSONArray writes the todos produced by each as an indented JSON
+// array, formatted like writeJSON, one todo at a time
+func writeJSONArray(w io.Writer, each func(func(models.Todo) error) error) error {
+	buf := bufio.NewWriter(w)
+	first := true
+	err := each(func(todo models.Todo) error {
+		encoded, err := json.MarshalIndent(todo, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		if first {
+			buf.WriteString("[\n  ")
+			first = false
+		} else {
+			buf.WriteString(",\n  ")
+		}
+		_, err = buf.Write(encoded)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if first {
+		buf.WriteString("[]\n")
+	} else {
+		buf.WriteString("\n]\n")
+	}
+	return buf.Flush()
+}
+
+func writeCSV(w io.Writer, each func(func(models.Todo) error) error) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "title", "description", "completed", "created_at", "updated_at"})
+	err := each(func(todo models.Todo) error {
+		description := ""
+		if todo.Description != nil {
+			description = *todo.Description
+		}
+		return writer.Write([]string{
+			strconv.Itoa(todo.ID),
+			todo.Title,
+			description,
+			strconv.FormatBool(todo.Completed),
+			todo.CreatedAt.Format(time.RFC3339),
+			todo.UpdatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memory selects the direct backend on an in-memory database, which every
+// command opens empty and drops when it returns
+var memory = globalOptions{dbPath: ":memory:"}
+
+func testConfig() *config.Config {
+	cfg := config.Load()
+	cfg.Auth.PasswordCost = bcrypt.MinCost
+	return cfg
+}
+
+// run runs the named command and returns what it printed to stdout. Flag
+// errors are printed to stderr, which is discarded.
+func run(t *testing.T, cfg *config.Config, global globalOptions, name string, args ...string) (string, error) {
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	require.NoError(t, err)
+	defer out.Close()
+	discard, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer discard.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, discard
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err = cmd.run(cfg, global, args)
+			printed, readErr := os.ReadFile(out.Name())
+			require.NoError(t, readErr)
+			return string(printed), err
+		}
+	}
+	t.Fatalf("unknown command %q", name)
+	return "", nil
+}
+
+func TestCommands(t *testing.T) {
+	cfg := testConfig()
+
+	out, err := run(t, cfg, memory, "create", "-description", "From the CLI", "-due", "2030-01-02", "-completed", "Write the report")
+	require.NoError(t, err)
+	var todo models.Todo
+	require.NoError(t, json.Unmarshal([]byte(out), &todo))
+	assert.NotZero(t, todo.ID)
+	assert.Equal(t, "Write the report", todo.Title)
+	assert.Equal(t, "From the CLI", *todo.Description)
+	assert.True(t, todo.Completed)
+	require.NotNil(t, todo.DueDate)
+
+	out, err = run(t, cfg, memory, "create-user", "-email", "cli@example.com", "-password", "correct-horse", "-name", "CLI")
+	require.NoError(t, err)
+	var user models.User
+	require.NoError(t, json.Unmarshal([]byte(out), &user))
+	assert.Equal(t, "cli@example.com", user.Email)
+	assert.NotContains(t, out, "correct-horse")
+
+	out, err = run(t, cfg, memory, "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "ID  DONE  TITLE  UPDATED")
+	assert.Contains(t, out, "Page 1 of 0 (0 todos)")
+
+	out, err = run(t, cfg, memory, "list", "-json", "-completed", "true")
+	require.NoError(t, err)
+	var page todoPage
+	require.NoError(t, json.Unmarshal([]byte(out), &page))
+	assert.Empty(t, page.Data)
+
+	out, err = run(t, cfg, memory, "export")
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", out)
+
+	out, err = run(t, cfg, memory, "export", "-format", "csv")
+	require.NoError(t, err)
+	assert.Equal(t, "id,title,description,completed,created_at,updated_at\n", out)
+
+	out, err = run(t, cfg, memory, "purge", "-days", "30")
+	require.NoError(t, err)
+	assert.Equal(t, "Purged 0 completed todos older than 30 days\n", out)
+
+	out, err = run(t, cfg, memory, "migrate")
+	require.NoError(t, err)
+	assert.Equal(t, "Database :memory: is up to date\n", out)
+}
+
+func TestDirectBackend(t *testing.T) {
+	cfg := testConfig()
+	cfg.Database.Path = ":memory:"
+	b, err := newDirectBackend(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	defer b.Close()
+
+	for _, title := range []string{"First", "Second", "Third"} {
+		_, err := b.CreateTodo(models.CreateTodoRequest{Title: title, Completed: title == "Second"})
+		require.NoError(t, err)
+	}
+
+	completed := true
+	params := models.DefaultQueryParams()
+	params.Completed = &completed
+	page, err := b.ListTodos(params)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, "Second", page.Data[0].Title)
+	}
+
+	var out strings.Builder
+	require.NoError(t, writeJSONArray(&out, b.EachTodo))
+	var exported []models.Todo
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &exported))
+	if assert.Len(t, exported, 3) {
+		assert.Equal(t, "First", exported[0].Title)
+		assert.Equal(t, "Third", exported[2].Title)
+	}
+
+	// The todo was completed just now, so it is kept until it is backdated
+	purged, err := b.PurgeCompleted(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	_, err = b.db.DB().Exec("UPDATE todos SET completed_at = ? WHERE completed = 1", time.Now().AddDate(0, 0, -2))
+	require.NoError(t, err)
+	purged, err = b.PurgeCompleted(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = b.CreateUser(models.RegisterRequest{Email: "twice@example.com", Password: "correct-horse"})
+	require.NoError(t, err)
+	_, err = b.CreateUser(models.RegisterRequest{Email: "twice@example.com", Password: "correct-horse"})
+	assert.Error(t, err)
+}
+
+func TestCommandErrors(t *testing.T) {
+	cfg := testConfig()
+	cfg.Database.EncryptionKey = ""
+	remote := globalOptions{apiURL: "http://localhost:3001"}
+
+	tests := []struct {
+		name    string
+		global  globalOptions
+		command string
+		args    []string
+		usage   bool
+		message string
+	}{
+		{"unknown flag", memory, "list", []string{"-limit", "5"}, true, "flag provided but not defined: -limit"},
+		{"invalid flag value", memory, "list", []string{"-page", "first"}, true, `invalid value "first" for flag -page`},
+		{"invalid completion filter", memory, "list", []string{"-completed", "maybe"}, false, `invalid -completed value "maybe"`},
+		{"missing title", memory, "create", nil, false, "exactly one title is required"},
+		{"several titles", memory, "create", []string{"Buy", "milk"}, false, "exactly one title is required"},
+		{"flags after the title", memory, "create", []string{"Buy milk", "-completed"}, false, "exactly one title is required"},
+		{"invalid due date", memory, "create", []string{"-due", "soon", "Buy milk"}, false, "due"},
+		{"retention too short", memory, "purge", []string{"-days", "0"}, false, "-days must be at least 1"},
+		{"unsupported export format", memory, "export", []string{"-format", "xml"}, false, `unsupported format "xml"`},
+		{"migrate over HTTP", remote, "migrate", nil, false, "migrate needs direct database access; drop -api"},
+		{"encrypt over HTTP", remote, "encrypt", nil, false, "encrypt needs direct database access; drop -api"},
+		{"encrypt without key", memory, "encrypt", nil, false, "DATABASE_ENCRYPTION_KEY or DATABASE_ENCRYPTION_KEY_FILE must be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, cfg, tt.global, tt.command, tt.args...)
+			require.Error(t, err)
+			assert.Equal(t, tt.usage, errors.Is(err, errUsage))
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestHelpFlag(t *testing.T) {
+	_, err := run(t, testConfig(), memory, "export", "-h")
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.NotErrorIs(t, err, errUsage)
+}
+
+func TestWriteCSV(t *testing.T) {
+	description := "Line one, with \"quotes\""
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	todos := []models.Todo{{ID: 1, Title: "Plain", CreatedAt: created, UpdatedAt: created}, {ID: 2, Title: "Quoted", Description: &description, Completed: true, CreatedAt: created, UpdatedAt: created}}
+
+	var out strings.Builder
+	err := writeCSV(&out, func(fn func(models.Todo) error) error {
+		for _, todo := range todos {
+			if err := fn(todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "id,title,description,completed,created_at,updated_at\n"+
+		"1,Plain,,false,2024-05-06T07:08:09Z,2024-05-06T07:08:09Z\n"+
+		"2,Quoted,\"Line one, with \"\"quotes\"\"\",true,2024-05-06T07:08:09Z,2024-05-06T07:08:09Z\n", out.String())
+
+	// Errors from the source stop the export
+	err = writeCSV(io.Discard, func(fn func(models.Todo) error) error { return errors.New("database is locked") })
+	assert.EqualError(t, err, "database is locked")
+}
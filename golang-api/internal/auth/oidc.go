@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// Identity is the subset of the identity provider's claims used to map an
+// external account onto a local user
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type userInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// OIDCProvider implements the OpenID Connect authorization code flow
+// against a provider discovered from its issuer URL (Google, Keycloak, ...)
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	oauth     *oauth2.Config
+}
+
+func NewOIDCProvider(cfg config.OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the provider URL the user is redirected to. verifier
+// is a PKCE code verifier from oauth2.GenerateVerifier; only its challenge
+// is sent, and the same verifier must be passed to Exchange.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, verifier string) (string, error) {
+	oauth, _, err := p.config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange trades the authorization code for an access token, proving with
+// the PKCE verifier that the caller started the login, and loads the
+// caller's identity from the userinfo endpoint
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier string) (*Identity, error) {
+	oauth, discovery, err := p.config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	resp, err := oauth.Client(ctx, token).Get(discovery.UserinfoEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo endpoint returned status %d", resp.StatusCode)
+	}
+
+	var info userInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode userinfo: %w", err)
+	}
+
+	if info.Subject == "" {
+		return nil, errors.New("userinfo response has no subject")
+	}
+
+	identity := &Identity{
+		Issuer:  discovery.Issuer,
+		Subject: info.Subject,
+		Email:   strings.ToLower(info.Email),
+		Name:    info.Name,
+	}
+	if info.EmailVerified != nil {
+		identity.EmailVerified = *info.EmailVerified
+	}
+
+	return identity, nil
+}
+
+// config lazily fetches the discovery document so that a provider outage
+// does not prevent the API from starting
+func (p *OIDCProvider) config(ctx context.Context) (*oauth2.Config, *discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.discovery, nil
+	}
+
+	url := strings.TrimSuffix(p.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("OIDC discovery returned status %d", resp.StatusCode)
+	}
+
+	var discovery discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+
+	// Identities are keyed by issuer, so a document claiming another issuer
+	// must not be trusted
+	if discovery.Issuer != p.cfg.IssuerURL {
+		return nil, nil, fmt.Errorf("OIDC discovery document issuer %q does not match %q", discovery.Issuer, p.cfg.IssuerURL)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, nil, errors.New("OIDC discovery document is missing required endpoints")
+	}
+
+	p.discovery = &discovery
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}
+
+	return p.oauth, p.discovery, nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for malformed, expired or tampered tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims are the JWT claims issued to authenticated users
+type Claims struct {
+	Email string `json:"email"`
+	// Scope limits the token to space-separated scopes. Tokens without
+	// one carry the user's full permissions.
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Scopes returns the scopes the token is limited to, or nil when it is not
+// limited
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// UserID returns the numeric user ID stored in the subject claim
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// TokenManager issues and verifies HS256 signed access tokens
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+	issuer string
+}
+
+func NewTokenManager(secret string, ttl time.Duration, issuer string) *TokenManager {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	return &TokenManager{
+		secret: []byte(secret),
+		ttl:    ttl,
+		issuer: issuer,
+	}
+}
+
+// Issue creates a signed token for the user, limited to the scopes when
+// any are given
+func (m *TokenManager) Issue(userID int, email string, scopes ...string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	claims := Claims{
+		Email: email,
+		Scope: strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Issuer:    m.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// Parse verifies a token and returns its claims
+func (m *TokenManager) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(m.issuer))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if _, err := claims.UserID(); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,33 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/config"
+)
+
+// Supported broker types
+const (
+	TypeNATS  = "nats"
+	TypeKafka = "kafka"
+)
+
+// Publisher sends a serialized message to an external broker
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, data []byte) error
+	Close() error
+}
+
+// New creates the publisher selected by cfg.Type
+func New(cfg config.BrokerConfig) (Publisher, error) {
+	switch strings.ToLower(cfg.Type) {
+	case TypeNATS:
+		return NewNATSPublisher(cfg.URL)
+	case TypeKafka:
+		return NewKafkaPublisher(strings.Split(cfg.URL, ","))
+	default:
+		return nil, fmt.Errorf("unsupported event broker type: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,150 @@
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/broker"
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	topic string
+	key   string
+	data  []byte
+}
+
+// fakePublisher records what it is asked to publish
+type fakePublisher struct {
+	messages []message
+	err      error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, topic, key string, data []byte) error {
+	p.messages = append(p.messages, message{topic: topic, key: key, data: data})
+	return p.err
+}
+
+func (p *fakePublisher) Close() error {
+	return nil
+}
+
+func testEvent() events.Event {
+	return events.Event{
+		ID:         7,
+		Type:       events.TodoCompleted,
+		TodoID:     42,
+		Todo:       &models.Todo{ID: 42, Title: "Ship it", Completed: true},
+		OccurredAt: time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC),
+	}
+}
+
+func TestSerialize_JSON(t *testing.T) {
+	evt := testEvent()
+
+	for _, format := range []string{"", broker.FormatJSON, "JSON"} {
+		data, err := broker.Serialize(evt, format)
+		require.NoError(t, err)
+
+		var decoded events.Event
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, evt.ID, decoded.ID)
+		assert.Equal(t, evt.Type, decoded.Type)
+		assert.Equal(t, evt.TodoID, decoded.TodoID)
+		assert.Equal(t, "Ship it", decoded.Todo.Title)
+		assert.True(t, evt.OccurredAt.Equal(decoded.OccurredAt))
+	}
+}
+
+func TestSerialize_CloudEvents(t *testing.T) {
+	data, err := broker.Serialize(testEvent(), broker.FormatCloudEvents)
+	require.NoError(t, err)
+
+	var ce map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &ce))
+	assert.Equal(t, "1.0", ce["specversion"])
+	assert.Equal(t, "7", ce["id"])
+	assert.Equal(t, "todo-api", ce["source"])
+	assert.Equal(t, events.TodoCompleted, ce["type"])
+	assert.Equal(t, "todos/42", ce["subject"])
+	assert.Equal(t, "2024-03-01T12:30:00.0000005Z", ce["time"])
+	assert.Equal(t, "application/json", ce["datacontenttype"])
+
+	// The event itself is the data
+	payload, ok := ce["data"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, events.TodoCompleted, payload["type"])
+	assert.Equal(t, float64(42), payload["todo_id"])
+
+	// Events about no particular todo have no subject
+	evt := testEvent()
+	evt.TodoID = 0
+	data, err = broker.Serialize(evt, broker.FormatCloudEvents)
+	require.NoError(t, err)
+	ce = nil
+	require.NoError(t, json.Unmarshal(data, &ce))
+	assert.NotContains(t, ce, "subject")
+}
+
+func TestSerialize_UnknownFormat(t *testing.T) {
+	_, err := broker.Serialize(testEvent(), "avro")
+	assert.ErrorContains(t, err, "unsupported event serialization format: avro")
+}
+
+func TestForwarder(t *testing.T) {
+	publisher := &fakePublisher{}
+	forward := broker.Forwarder(publisher, config.BrokerConfig{Topic: "todos.{type}", Format: broker.FormatJSON})
+
+	require.NoError(t, forward(context.Background(), testEvent()))
+
+	// Events without a todo are published without a key
+	require.NoError(t, forward(context.Background(), events.Event{Type: events.TodosPurged, OccurredAt: time.Now()}))
+
+	require.Len(t, publisher.messages, 2)
+	assert.Equal(t, "todos.todo.completed", publisher.messages[0].topic)
+	assert.Equal(t, "42", publisher.messages[0].key)
+	expected, err := broker.Serialize(testEvent(), broker.FormatJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(publisher.messages[0].data))
+
+	assert.Equal(t, "todos."+events.TodosPurged, publisher.messages[1].topic)
+	assert.Empty(t, publisher.messages[1].key)
+}
+
+func TestForwarder_FixedTopic(t *testing.T) {
+	publisher := &fakePublisher{}
+	forward := broker.Forwarder(publisher, config.BrokerConfig{Topic: "todo-events", Format: broker.FormatCloudEvents})
+
+	require.NoError(t, forward(context.Background(), testEvent()))
+	require.Len(t, publisher.messages, 1)
+	assert.Equal(t, "todo-events", publisher.messages[0].topic)
+	assert.Contains(t, string(publisher.messages[0].data), `"specversion":"1.0"`)
+}
+
+func TestForwarder_Errors(t *testing.T) {
+	// Publish failures are returned to the bus
+	publisher := &fakePublisher{err: errors.New("broker down")}
+	forward := broker.Forwarder(publisher, config.BrokerConfig{Topic: "todos"})
+	assert.EqualError(t, forward(context.Background(), testEvent()), "broker down")
+
+	// Nothing is published in an unknown format
+	publisher = &fakePublisher{}
+	forward = broker.Forwarder(publisher, config.BrokerConfig{Topic: "todos", Format: "avro"})
+	assert.Error(t, forward(context.Background(), testEvent()))
+	assert.Empty(t, publisher.messages)
+}
+
+func TestNew_UnknownType(t *testing.T) {
+	for _, brokerType := range []string{"", "rabbitmq"} {
+		publisher, err := broker.New(config.BrokerConfig{Type: brokerType, URL: "amqp://localhost"})
+		assert.Nil(t, publisher)
+		assert.EqualError(t, err, "unsupported event broker type: "+brokerType)
+	}
+}
@@ -0,0 +1,76 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/events"
+)
+
+// Serialization formats
+const (
+	FormatJSON        = "json"
+	FormatCloudEvents = "cloudevents"
+)
+
+// cloudEvent is the structured-mode JSON representation of a CloudEvents
+// 1.0 event
+type cloudEvent struct {
+	SpecVersion     string       `json:"specversion"`
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject,omitempty"`
+	Time            string       `json:"time"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            events.Event `json:"data"`
+}
+
+// Forwarder returns an event handler that publishes every event to the
+// broker. The topic may contain a "{type}" placeholder that is replaced by
+// the event type, e.g. "todos.{type}".
+func Forwarder(publisher Publisher, cfg config.BrokerConfig) events.Handler {
+	return func(ctx context.Context, evt events.Event) error {
+		data, err := Serialize(evt, cfg.Format)
+		if err != nil {
+			return err
+		}
+
+		topic := strings.ReplaceAll(cfg.Topic, "{type}", evt.Type)
+
+		var key string
+		if evt.TodoID != 0 {
+			key = strconv.Itoa(evt.TodoID)
+		}
+
+		return publisher.Publish(ctx, topic, key, data)
+	}
+}
+
+// Serialize encodes an event in the given format
+func Serialize(evt events.Event, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return json.Marshal(evt)
+	case FormatCloudEvents:
+		ce := cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              strconv.Itoa(evt.ID),
+			Source:          "todo-api",
+			Type:            evt.Type,
+			Time:            evt.OccurredAt.Format("2006-01-02T15:04:05.999999999Z07:00"),
+			DataContentType: "application/json",
+			Data:            evt,
+		}
+		if evt.TodoID != 0 {
+			ce.Subject = "todos/" + strconv.Itoa(evt.TodoID)
+		}
+		return json.Marshal(ce)
+	default:
+		return nil, fmt.Errorf("unsupported event serialization format: %s", format)
+	}
+}
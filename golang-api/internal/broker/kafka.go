@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a writer for the given brokers. Messages with
+// the same key (the todo ID) go to the same partition, preserving order
+// per todo.
+func NewKafkaPublisher(brokers []string) (Publisher, error) {
+	if len(brokers) == 0 || brokers[0] == "" {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+
+	return &kafkaPublisher{writer: writer}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, topic, key string, data []byte) error {
+	msg := kafka.Message{
+		Topic: topic,
+		Value: data,
+	}
+	if key != "" {
+		msg.Key = []byte(key)
+	}
+
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish to Kafka: %w", err)
+	}
+
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+type natsPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the NATS server at url. The connection
+// reconnects automatically if the server goes away.
+func NewNATSPublisher(url string) (Publisher, error) {
+	conn, err := nats.Connect(url, nats.Name("todo-api"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	return &natsPublisher{conn: conn}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, subject, key string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if key != "" {
+		msg.Header.Set("Todo-Key", key)
+	}
+
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+
+	// Flush so that a successful return means the server received it
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush NATS connection: %w", err)
+	}
+
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,341 @@
+package caldav
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// ErrInvalidCalendarData is returned for bodies that are not an
+// iCalendar object with a VTODO
+var ErrInvalidCalendarData = errors.New("invalid calendar data")
+
+// VTODO status values
+const (
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusInProcess   = "IN-PROCESS"
+	StatusCompleted   = "COMPLETED"
+	StatusCancelled   = "CANCELLED"
+)
+
+const (
+	utcFormat      = "20060102T150405Z"
+	floatingFormat = "20060102T150405"
+	dateFormat     = "20060102"
+)
+
+// Task is the part of a VTODO that maps onto a todo. Priority follows
+// RFC 5545: 0 is undefined, 1 the highest and 9 the lowest.
+type Task struct {
+	UID         string
+	Summary     string
+	Description string
+	Status      string
+	Completed   *time.Time
+	Due         *time.Time
+	Priority    int
+	Categories  []string
+}
+
+// Done reports whether the task is finished. Cancelled tasks count as
+// done, since todos have no cancelled state.
+func (t *Task) Done() bool {
+	return t.Status == StatusCompleted || t.Status == StatusCancelled || (t.Status == "" && t.Completed != nil)
+}
+
+// TodoPriority maps the task's priority onto a todo priority, or "" when
+// it has none
+func (t *Task) TodoPriority() string {
+	switch {
+	case t.Priority == 1:
+		return models.PriorityUrgent
+	case t.Priority >= 2 && t.Priority <= 4:
+		return models.PriorityHigh
+	case t.Priority == 5:
+		return models.PriorityMedium
+	case t.Priority >= 6 && t.Priority <= 9:
+		return models.PriorityLow
+	}
+	return ""
+}
+
+// Encode renders the todo as an iCalendar object holding one VTODO
+func Encode(todo *models.Todo, uid string) []byte {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Todo API//CalDAV//EN")
+	line("BEGIN", "VTODO")
+	line("UID", escapeText(uid))
+	line("DTSTAMP", todo.UpdatedAt.UTC().Format(utcFormat))
+	line("CREATED", todo.CreatedAt.UTC().Format(utcFormat))
+	line("LAST-MODIFIED", todo.UpdatedAt.UTC().Format(utcFormat))
+	line("SUMMARY", escapeText(todo.Title))
+	if todo.Description != nil && *todo.Description != "" {
+		line("DESCRIPTION", escapeText(*todo.Description))
+	}
+
+	switch {
+	case todo.Completed:
+		line("STATUS", StatusCompleted)
+		line("PERCENT-COMPLETE", "100")
+		if todo.CompletedAt != nil {
+			line("COMPLETED", todo.CompletedAt.UTC().Format(utcFormat))
+		}
+	case todo.Status == models.StatusInProgress:
+		line("STATUS", StatusInProcess)
+	default:
+		line("STATUS", StatusNeedsAction)
+	}
+
+	if todo.DueDate != nil {
+		due := todo.DueDate.UTC()
+		// Dates given without a time are stored as midnight UTC
+		if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+			writeFolded(&b, "DUE;VALUE=DATE:"+due.Format(dateFormat))
+		} else {
+			line("DUE", due.Format(utcFormat))
+		}
+	}
+
+	if todo.Priority != nil {
+		switch *todo.Priority {
+		case models.PriorityUrgent:
+			line("PRIORITY", "1")
+		case models.PriorityHigh:
+			line("PRIORITY", "3")
+		case models.PriorityMedium:
+			line("PRIORITY", "5")
+		case models.PriorityLow:
+			line("PRIORITY", "9")
+		}
+	}
+
+	if len(todo.Tags) > 0 {
+		tags := make([]string, len(todo.Tags))
+		for i, tag := range todo.Tags {
+			tags[i] = escapeText(tag)
+		}
+		line("CATEGORIES", strings.Join(tags, ","))
+	}
+
+	line("END", "VTODO")
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// Decode reads the first VTODO of an iCalendar object. Properties the
+// API has no place for, and nested components such as alarms, are
+// ignored.
+func Decode(data []byte) (*Task, error) {
+	var task *Task
+	depth := 0
+	inCalendar := false
+
+	for _, raw := range unfold(string(data)) {
+		name, params, value, ok := splitProperty(raw)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			inCalendar = true
+			continue
+		case name == "BEGIN":
+			if task == nil && depth == 0 && inCalendar && strings.EqualFold(value, "VTODO") {
+				task = &Task{}
+			}
+			depth++
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO") && depth == 1 && task != nil:
+			return finishTask(task)
+		case name == "END":
+			depth--
+			continue
+		}
+
+		// Only properties of the VTODO itself, not of its alarms
+		if task == nil || depth != 1 {
+			continue
+		}
+
+		var err error
+		switch name {
+		case "UID":
+			task.UID = unescapeText(value)
+		case "SUMMARY":
+			task.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			task.Description = unescapeText(value)
+		case "STATUS":
+			task.Status = strings.ToUpper(value)
+		case "COMPLETED":
+			task.Completed, err = parseTime(value, params)
+		case "DUE":
+			task.Due, err = parseTime(value, params)
+		case "PRIORITY":
+			task.Priority, err = strconv.Atoi(value)
+			if err == nil && (task.Priority < 0 || task.Priority > 9) {
+				err = fmt.Errorf("must be between 0 and 9")
+			}
+		case "CATEGORIES":
+			for _, category := range splitList(value) {
+				if category = strings.TrimSpace(unescapeText(category)); category != "" {
+					task.Categories = append(task.Categories, category)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCalendarData, name, err)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no VTODO component", ErrInvalidCalendarData)
+}
+
+func finishTask(task *Task) (*Task, error) {
+	if task.UID == "" {
+		return nil, fmt.Errorf("%w: UID is required", ErrInvalidCalendarData)
+	}
+	return task, nil
+}
+
+// unfold splits the content into logical lines, joining continuation
+// lines that start with a space or tab
+func unfold(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitProperty splits a content line into its uppercase name, its
+// parameters and its value. Parameter values may be quoted and contain
+// colons.
+func splitProperty(line string) (name string, params map[string]string, value string, ok bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params = make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// parseTime reads a DATE or DATE-TIME value. Times with a TZID are
+// converted from that zone; floating times are taken as UTC.
+func parseTime(value string, params map[string]string) (*time.Time, error) {
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	var t time.Time
+	var err error
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse(utcFormat, value)
+	case len(value) == len(dateFormat):
+		t, err = time.Parse(dateFormat, value)
+	default:
+		t, err = time.ParseInLocation(floatingFormat, value, loc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", value)
+	}
+
+	t = t.UTC()
+	return &t, nil
+}
+
+// splitList splits a multi-valued property on commas that are not
+// escaped
+func splitList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// writeFolded writes a content line, folded at 75 octets without
+// splitting UTF-8 sequences. Continuation lines start with a space.
+func writeFolded(b *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
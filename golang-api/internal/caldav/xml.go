@@ -0,0 +1,217 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// XML namespaces of WebDAV, CalDAV and the CalendarServer extensions
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+var prefixes = map[string]string{
+	NamespaceDAV:            "d",
+	NamespaceCalDAV:         "c",
+	NamespaceCalendarServer: "cs",
+}
+
+// Property names served by the handler
+var (
+	PropResourceType                  = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	PropDisplayName                   = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	PropCurrentUserPrincipal          = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	PropPrincipalURL                  = xml.Name{Space: NamespaceDAV, Local: "principal-URL"}
+	PropGetETag                       = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	PropGetContentType                = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	PropCalendarHomeSet               = xml.Name{Space: NamespaceCalDAV, Local: "calendar-home-set"}
+	PropCalendarData                  = xml.Name{Space: NamespaceCalDAV, Local: "calendar-data"}
+	PropSupportedCalendarComponentSet = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	PropGetCTag                       = xml.Name{Space: NamespaceCalendarServer, Local: "getctag"}
+)
+
+// element is any XML element, used to walk request bodies
+type element struct {
+	XMLName  xml.Name
+	Children []element `xml:",any"`
+	Text     string    `xml:",chardata"`
+}
+
+func (e *element) child(name xml.Name) *element {
+	for i := range e.Children {
+		if e.Children[i].XMLName == name {
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+func (e *element) propNames() []xml.Name {
+	prop := e.child(xml.Name{Space: NamespaceDAV, Local: "prop"})
+	if prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, len(prop.Children))
+	for i, child := range prop.Children {
+		names[i] = child.XMLName
+	}
+	return names
+}
+
+// ParsePropfind returns the properties a PROPFIND asks for, or nil when
+// it asks for all of them (an empty body or allprop)
+func ParsePropfind(body []byte) ([]xml.Name, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var root element
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("invalid PROPFIND body: %w", err)
+	}
+	if root.XMLName != (xml.Name{Space: NamespaceDAV, Local: "propfind"}) {
+		return nil, fmt.Errorf("invalid PROPFIND body: unexpected %s element", root.XMLName.Local)
+	}
+	return root.propNames(), nil
+}
+
+// Report is a calendar-query or calendar-multiget REPORT. Hrefs is only
+// set for multiget; query filters are not evaluated since the collection
+// holds nothing but VTODOs.
+type Report struct {
+	Multiget bool
+	Props    []xml.Name
+	Hrefs    []string
+}
+
+// ParseReport reads a calendar-query or calendar-multiget REPORT body
+func ParseReport(body []byte) (*Report, error) {
+	var root element
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("invalid REPORT body: %w", err)
+	}
+
+	report := &Report{Props: root.propNames()}
+	switch root.XMLName {
+	case xml.Name{Space: NamespaceCalDAV, Local: "calendar-query"}:
+	case xml.Name{Space: NamespaceCalDAV, Local: "calendar-multiget"}:
+		report.Multiget = true
+		for _, child := range root.Children {
+			if child.XMLName == (xml.Name{Space: NamespaceDAV, Local: "href"}) {
+				report.Hrefs = append(report.Hrefs, strings.TrimSpace(child.Text))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported report %s", root.XMLName.Local)
+	}
+	return report, nil
+}
+
+// Response is one resource of a multistatus response. Props maps the
+// properties the resource has to their inner XML; a resource without
+// Props is reported as missing.
+type Response struct {
+	Href  string
+	Props map[xml.Name]string
+}
+
+// Multistatus renders the responses, each with the requested properties,
+// or with all of their properties when requested is nil
+func Multistatus(responses []Response, requested []xml.Name) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<d:multistatus xmlns:d="%s" xmlns:c="%s" xmlns:cs="%s">`, NamespaceDAV, NamespaceCalDAV, NamespaceCalendarServer)
+
+	for _, resp := range responses {
+		b.WriteString("<d:response>")
+		b.WriteString(Href(resp.Href))
+
+		if resp.Props == nil {
+			writeStatus(&b, http.StatusNotFound)
+			b.WriteString("</d:response>")
+			continue
+		}
+
+		names := requested
+		if names == nil {
+			for name := range resp.Props {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool {
+				return names[i].Space+names[i].Local < names[j].Space+names[j].Local
+			})
+		}
+
+		var found, missing []xml.Name
+		for _, name := range names {
+			if _, ok := resp.Props[name]; ok {
+				found = append(found, name)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range found {
+				writeElement(&b, name, resp.Props[name])
+			}
+			b.WriteString("</d:prop>")
+			writeStatus(&b, http.StatusOK)
+			b.WriteString("</d:propstat>")
+		}
+		if len(missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range missing {
+				writeElement(&b, name, "")
+			}
+			b.WriteString("</d:prop>")
+			writeStatus(&b, http.StatusNotFound)
+			b.WriteString("</d:propstat>")
+		}
+
+		b.WriteString("</d:response>")
+	}
+
+	b.WriteString("</d:multistatus>")
+	return b.Bytes()
+}
+
+// Href renders a DAV:href element
+func Href(path string) string {
+	return "<d:href>" + Text(path) + "</d:href>"
+}
+
+// Text escapes character data
+func Text(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func writeElement(b *bytes.Buffer, name xml.Name, inner string) {
+	tag := name.Local
+	declare := ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		declare = ` xmlns:x="` + Text(name.Space) + `"`
+	}
+
+	if inner == "" {
+		fmt.Fprintf(b, "<%s%s/>", tag, declare)
+		return
+	}
+	fmt.Fprintf(b, "<%s%s>%s</%s>", tag, declare, inner, tag)
+}
+
+func writeStatus(b *bytes.Buffer, code int) {
+	fmt.Fprintf(b, "<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
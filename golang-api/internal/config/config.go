@@ -1,54 +1,639 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	App      AppConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	App       AppConfig
+	Purge     PurgeConfig
+	Trash     TrashConfig
+	Jobs      JobsConfig
+	Outbox    OutboxConfig
+	Broker    BrokerConfig
+	Slack     SlackConfig
+	Hooks     HooksConfig
+	Notify    NotificationsConfig
+	Admin     AdminConfig
+	Auth      AuthConfig
+	OIDC      OIDCConfig
+	Google    GoogleTasksConfig
+	RateLimit RateLimitConfig
+	Backup    BackupConfig
+	Export    ExportConfig
+	Account   AccountConfig
+	Plans     PlansConfig
+	Logging   LoggingConfig
+	Reporting ErrorReportingConfig
+	CORS      CORSConfig
+	TLS       TLSConfig
+	Metrics   MetricsConfig
+	Status    StatusConfig
+	Docs      DocsConfig
+	Demo      DemoConfig
+	Todos     TodoConfig
+
+	// loadErrs holds *_FILE secrets that could not be read, reported by
+	// Validate
+	loadErrs []error
 }
 
 type ServerConfig struct {
 	Port string
 	Host string
+	// DrainPeriod is how long /ready reports not ready after a shutdown
+	// signal before the listener closes
+	DrainPeriod time.Duration
+	// ShutdownTimeout bounds the wait for in-flight requests
+	ShutdownTimeout time.Duration
+	// TrustedProxies lists the load balancer IPs or CIDR ranges whose
+	// ProxyHeader is believed for the client address
+	TrustedProxies []string
+	ProxyHeader    string
+	// BodyLimit is the maximum request body size in bytes
+	BodyLimit int
+	// Prefork runs one process per CPU sharing the port (SO_REUSEPORT).
+	// State kept in memory, such as rate limits, is per process.
+	Prefork bool
 }
 
 type DatabaseConfig struct {
 	Path string
+	// EncryptionKey opens the database with SQLCipher. 64 hex digits are
+	// used as the raw key, anything else as a passphrase. Empty leaves the
+	// database unencrypted.
+	EncryptionKey string
+	// BusyRetries retries todo reads that fail because another connection
+	// holds a lock this many times; zero disables it
+	BusyRetries int
+	// CountCacheTTL caches todo list totals until the todos change, and
+	// for at most this long; zero counts them on every request
+	CountCacheTTL time.Duration
+	// ConnectTimeout is how long startup keeps retrying a database that
+	// cannot be opened yet; zero tries once
+	ConnectTimeout time.Duration
+	// MinFreeDiskPercent makes the deep health check warn when less of
+	// the database's file system is free; zero disables it
+	MinFreeDiskPercent float64
+	// Replication is litestream or litefs when that tool replicates the
+	// database file, switching it to WAL with settings that let it keep
+	// up; empty leaves SQLite's defaults
+	Replication string
+	// ReadOnly runs a standby on a replicated copy: writes are refused,
+	// migrations and background jobs do not run
+	ReadOnly bool
+	// QueryTimeout interrupts a todo repository call that runs longer;
+	// zero lets calls run as long as their request does
+	QueryTimeout time.Duration
+	// BusyTimeout is how long a statement waits for another connection's
+	// lock before failing as busy
+	BusyTimeout time.Duration
+}
+
+// PurgeConfig controls the background job that deletes old completed todos
+type PurgeConfig struct {
+	Enabled       bool
+	Interval      time.Duration
+	RetentionDays int
+}
+
+// TrashConfig controls how long deleted todos stay in the trash. A
+// RetentionDays of 0 keeps them until they are purged by hand.
+type TrashConfig struct {
+	RetentionDays int
+	PurgeInterval time.Duration
+}
+
+// Retention returns how long the trash keeps a todo, or 0 for forever
+func (t TrashConfig) Retention() time.Duration {
+	return time.Duration(t.RetentionDays) * 24 * time.Hour
+}
+
+// JobsConfig controls the background job worker pool
+type JobsConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	RetryBackoff time.Duration
+}
+
+// OutboxConfig controls delivery of persisted domain events
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+}
+
+// BrokerConfig selects an optional external broker (NATS or Kafka) that
+// receives every domain event
+type BrokerConfig struct {
+	Type   string
+	URL    string
+	Topic  string
+	Format string
+}
+
+// SlackConfig configures notifications posted to a Slack incoming webhook.
+// Templates maps event types to text/template strings overriding the
+// defaults, e.g. SLACK_TEMPLATE_TODO_COMPLETED for todo.completed.
+type SlackConfig struct {
+	WebhookURL string
+	Events     []string
+	Templates  map[string]string
+}
+
+// HooksConfig configures REST hooks. AllowPrivateTargets lets hooks post
+// to loopback and private network addresses, for installs whose hook
+// targets run beside the API; otherwise only public addresses are used.
+type HooksConfig struct {
+	AllowPrivateTargets bool
+}
+
+// NotificationsConfig controls in-app notifications. Open todos coming due
+// within DueSoon are announced once per due date, checked every
+// DueSoonInterval; 0 disables due soon notifications.
+type NotificationsConfig struct {
+	DueSoon         time.Duration
+	DueSoonInterval time.Duration
+}
+
+// AuthConfig configures user authentication tokens and protection of
+// password logins against guessing
+type AuthConfig struct {
+	JWTSecret string
+	TokenTTL  time.Duration
+	// PasswordCost is the bcrypt cost new passwords are hashed at
+	PasswordCost int
+	// Required rejects anonymous requests to the todo data. Otherwise
+	// only credentials are limited by their scopes.
+	Required bool
+	// LoginDelay is how long an account or client IP must wait after a
+	// failed login, doubled by every further consecutive failure up to a
+	// minute; 0 disables delays
+	LoginDelay time.Duration
+	// LoginMaxFailures locks an account after this many consecutive
+	// failed logins, LoginMaxFailuresPerIP a client IP; 0 disables the
+	// lock
+	LoginMaxFailures      int
+	LoginMaxFailuresPerIP int
+	// LoginLockout is how long a lock lasts, and how long failures are
+	// remembered
+	LoginLockout time.Duration
+}
+
+// OIDCConfig configures login through an external OpenID Connect provider.
+// OIDC login is enabled when IssuerURL and ClientID are set.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Enabled reports whether an identity provider is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
+
+// GoogleTasksConfig configures two-way sync with Google Tasks. Users can
+// link a Google account when ClientID is set; linked accounts are synced
+// every SyncInterval. AuthURL, TokenURL and APIURL default to Google's
+// endpoints when empty.
+type GoogleTasksConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	SyncInterval time.Duration
+	AuthURL      string
+	TokenURL     string
+	APIURL       string
+}
+
+// Enabled reports whether the Google Tasks integration is configured
+func (c GoogleTasksConfig) Enabled() bool {
+	return c.ClientID != ""
+}
+
+// RateLimitConfig sets how many requests each kind of caller may make per
+// window. Anonymous callers are limited per IP, authenticated users per
+// account and API keys per key. A limit of 0 disables limiting for that
+// tier.
+type RateLimitConfig struct {
+	Enabled   bool
+	Window    time.Duration
+	Anonymous int
+	User      int
+	APIKey    int
+}
+
+// LoggingConfig configures request logging
+type LoggingConfig struct {
+	// Level is debug, info, warn or error. Defaults to debug in development
+	// and info elsewhere.
+	Level string
+	// Format is text or json. Defaults to text in development and json
+	// elsewhere.
+	Format string
+	// RequestIDFormat is uuidv7 (default), uuidv4 or hex
+	RequestIDFormat string
+	// SlowRequestThreshold logs a detailed warning for requests that take
+	// longer; zero disables it
+	SlowRequestThreshold time.Duration
+	// SlowQueryThreshold logs a warning for todo repository calls that
+	// take longer; zero disables it
+	SlowQueryThreshold time.Duration
+	// SuccessSampleRate is the fraction (0-1) of fast 2xx/3xx requests
+	// that get an access log line. Errors and slow requests are always
+	// logged.
+	SuccessSampleRate float64
+	// AccessLogFile receives every request in Apache combined format,
+	// separately from the application log. Empty disables it.
+	AccessLogFile string
+	// AccessLogMaxSizeMB rotates the access log once it reaches this size,
+	// keeping AccessLogMaxBackups old files
+	AccessLogMaxSizeMB  int
+	AccessLogMaxBackups int
+}
+
+// TLSConfig enables HTTPS with certificates obtained and renewed
+// automatically from an ACME CA (Let's Encrypt by default) for the listed
+// domains
+type TLSConfig struct {
+	AutocertDomains []string
+	// CacheDir stores issued certificates and the account key across
+	// restarts
+	CacheDir string
+	// Email is given to the CA for expiry and problem notices
+	Email string
+	// DirectoryURL overrides the ACME directory, e.g. for Let's Encrypt
+	// staging
+	DirectoryURL string
+}
+
+// Enabled reports whether automatic certificates are configured
+func (c TLSConfig) Enabled() bool {
+	return len(c.AutocertDomains) > 0
+}
+
+// DocsConfig controls the Swagger UI and OpenAPI document under /swagger.
+// Outside development they require authentication.
+type DocsConfig struct {
+	Enabled bool
+}
+
+// DemoConfig runs the API as a public playground: an in-memory database
+// seeded with generated todos and reset every ResetInterval
+type DemoConfig struct {
+	Enabled bool
+	// Todos is the number of todos seeded at startup and on every reset
+	Todos int
+	// ResetInterval is how often everything, users included, is wiped
+	// and seeded again; 0 disables resets
+	ResetInterval time.Duration
+}
+
+// MetricsConfig controls the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	Enabled bool
+}
+
+// StatusConfig controls the /status page
+type StatusConfig struct {
+	// CheckInterval is how old the last deep health check may be before
+	// /status runs another; zero checks on every request
+	CheckInterval time.Duration
+	// History is how long resolved incidents stay listed
+	History time.Duration
+}
+
+// TodoConfig holds rules applied to todos
+type TodoConfig struct {
+	// UniqueActiveTitles rejects a title, ignoring case, that another
+	// uncompleted todo already has
+	UniqueActiveTitles bool
+	// FetchLinkTitles fetches the page title of links added without one
+	FetchLinkTitles bool
+	// WriteBehind queues todo creates, updates and deletes as jobs and
+	// answers 202 with the job instead of applying them in the request
+	WriteBehind bool
+}
+
+// CORSConfig lists the browser origins allowed to call the API. In
+// development every origin is allowed.
+type CORSConfig struct {
+	AllowedOrigins []string
+}
+
+// ErrorReportingConfig configures where panics and 5xx errors are sent
+type ErrorReportingConfig struct {
+	// DSN is a Sentry DSN, or webhook+https://... for a generic JSON hook.
+	// Empty disables reporting.
+	DSN string
+}
+
+// BackupConfig configures scheduled database snapshots
+type BackupConfig struct {
+	Enabled  bool
+	Dir      string
+	Interval time.Duration
+	Retain   int
+}
+
+// AccountConfig controls what users can do with their own account data
+type AccountConfig struct {
+	// ExportSyncLimit is the number of records up to which an account
+	// export is returned directly; larger ones run as a background job
+	ExportSyncLimit int
+}
+
+// PlansConfig defines the plans users can be on, by name. Users that were
+// not moved to a plan are on Default. With no plans every user has every
+// feature and no limits.
+type PlansConfig struct {
+	Default string
+	Plans   map[string]PlanConfig
+}
+
+// PlanConfig is what a plan includes: the features users on it can use,
+// and how many hooks and API keys they can have. Zero limits are
+// unlimited.
+type PlanConfig struct {
+	Features   []string
+	MaxHooks   int
+	MaxAPIKeys int
+}
+
+// ExportConfig configures scheduled exports of all todos. They are written
+// to S3 when a bucket is set and to Dir otherwise.
+type ExportConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Formats lists the files written by every export: json, csv or both
+	Formats []string
+	// Retain is the number of exports of each format that are kept
+	Retain int
+	Dir    string
+	S3     S3Config
+}
+
+// S3Config locates an S3 bucket, or a bucket in an S3-compatible store
+// when Endpoint is set
+type S3Config struct {
+	Bucket string
+	// Prefix is prepended to the object keys, e.g. "todo-api/"
+	Prefix string
+	Region string
+	// Endpoint is the base URL of an S3-compatible store such as MinIO,
+	// addressed path-style. Empty uses AWS.
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AdminConfig protects the /api/admin endpoints
+type AdminConfig struct {
+	Token string
 }
 
 type AppConfig struct {
 	Environment string
 	Name        string
 	Version     string
+	// Commit and BuildTime are injected at build time, see cmd/api
+	Commit    string
+	BuildTime string
+	// ConfigFile is the dotenv file read at startup and on reload
+	ConfigFile string
+	// ResponseEnvelope wraps every JSON response in {"data", "meta",
+	// "error"}; clients can also opt in or out with ?envelope=
+	ResponseEnvelope bool
 }
 
 func Load() *Config {
-	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	cfg := load()
+
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+		if cfg.IsDevelopment() {
+			cfg.Logging.Level = "debug"
+		}
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "json"
+		if cfg.IsDevelopment() {
+			cfg.Logging.Format = "text"
+		}
+	}
+	if cfg.Demo.Enabled {
+		cfg.Database.Path = ":memory:"
+	}
+
+	return cfg
+}
+
+func load() *Config {
+	secretFiles.Lock()
+	defer secretFiles.Unlock()
+	secretFiles.errs = nil
+
+	// Load the config file if it exists
+	configFile := getEnv("CONFIG_FILE", ".env")
+	if err := godotenv.Load(configFile); err != nil {
+		log.Printf("No %s file found", configFile)
 	}
 
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "3001"),
 			Host: getEnv("HOST", "0.0.0.0"),
+
+			DrainPeriod:     getEnvAsDuration("SHUTDOWN_DRAIN_PERIOD", 0),
+			ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+			TrustedProxies:  getEnvAsSlice("TRUSTED_PROXIES", nil),
+			ProxyHeader:     getEnv("PROXY_HEADER", "X-Forwarded-For"),
+			BodyLimit:       getEnvAsInt("BODY_LIMIT", 1024*1024),
+			Prefork:         getEnvAsBool("PREFORK", false),
 		},
 		Database: DatabaseConfig{
-			Path: getEnv("DATABASE_PATH", "./todos.db"),
+			Path:               getEnv("DATABASE_PATH", "./todos.db"),
+			EncryptionKey:      getEnv("DATABASE_ENCRYPTION_KEY", ""),
+			BusyRetries:        getEnvAsInt("DATABASE_BUSY_RETRIES", 0),
+			CountCacheTTL:      getEnvAsDuration("DATABASE_COUNT_CACHE_TTL", 0),
+			ConnectTimeout:     getEnvAsDuration("DATABASE_CONNECT_TIMEOUT", 30*time.Second),
+			MinFreeDiskPercent: getEnvAsFloat("DATABASE_MIN_FREE_DISK_PERCENT", 10),
+			Replication:        getEnv("DATABASE_REPLICATION", ""),
+			ReadOnly:           getEnvAsBool("DATABASE_READ_ONLY", false),
+			QueryTimeout:       getEnvAsDuration("DATABASE_QUERY_TIMEOUT", 30*time.Second),
+			BusyTimeout:        getEnvAsDuration("DATABASE_BUSY_TIMEOUT", 5*time.Second),
 		},
 		App: AppConfig{
 			Environment: getEnv("ENVIRONMENT", "development"),
+			ConfigFile:  configFile,
 			Name:        getEnv("APP_NAME", "Todo API"),
 			Version:     getEnv("APP_VERSION", "1.0.0"),
+
+			ResponseEnvelope: getEnvAsBool("RESPONSE_ENVELOPE", false),
+		},
+		Purge: PurgeConfig{
+			Enabled:       getEnvAsBool("PURGE_ENABLED", false),
+			Interval:      getEnvAsDuration("PURGE_INTERVAL", 24*time.Hour),
+			RetentionDays: getEnvAsInt("PURGE_RETENTION_DAYS", 90),
+		},
+		Trash: TrashConfig{
+			RetentionDays: getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+			PurgeInterval: getEnvAsDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvAsInt("JOBS_WORKERS", 2),
+			PollInterval: getEnvAsDuration("JOBS_POLL_INTERVAL", time.Second),
+			MaxAttempts:  getEnvAsInt("JOBS_MAX_ATTEMPTS", 3),
+			RetryBackoff: getEnvAsDuration("JOBS_RETRY_BACKOFF", 30*time.Second),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: getEnvAsDuration("OUTBOX_POLL_INTERVAL", 250*time.Millisecond),
+			BatchSize:    getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+			MaxAttempts:  getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 10),
+			RetryBackoff: getEnvAsDuration("OUTBOX_RETRY_BACKOFF", 5*time.Second),
+		},
+		Broker: BrokerConfig{
+			Type:   getEnv("EVENT_BROKER", ""),
+			URL:    getEnv("EVENT_BROKER_URL", ""),
+			Topic:  getEnv("EVENT_BROKER_TOPIC", "todo.events"),
+			Format: getEnv("EVENT_BROKER_FORMAT", "json"),
+		},
+		Slack: SlackConfig{
+			WebhookURL: getEnv("SLACK_WEBHOOK_URL", ""),
+			Events:     getEnvAsSlice("SLACK_EVENTS", []string{"todo.created", "todo.completed"}),
+			Templates:  getSlackTemplates(),
+		},
+		Hooks: HooksConfig{
+			AllowPrivateTargets: getEnvAsBool("HOOKS_ALLOW_PRIVATE_TARGETS", false),
+		},
+		Notify: NotificationsConfig{
+			DueSoon:         getEnvAsDuration("NOTIFY_DUE_SOON", 24*time.Hour),
+			DueSoonInterval: getEnvAsDuration("NOTIFY_DUE_SOON_INTERVAL", 15*time.Minute),
+		},
+		Admin: AdminConfig{
+			Token: getEnv("ADMIN_TOKEN", ""),
+		},
+		Auth: AuthConfig{
+			JWTSecret: getEnv("JWT_SECRET", ""),
+			TokenTTL:  getEnvAsDuration("JWT_TTL", 24*time.Hour),
+
+			PasswordCost: getEnvAsInt("PASSWORD_HASH_COST", 10),
+			Required:     getEnvAsBool("AUTH_REQUIRED", false),
+
+			LoginDelay:            getEnvAsDuration("LOGIN_DELAY", time.Second),
+			LoginMaxFailures:      getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+			LoginMaxFailuresPerIP: getEnvAsInt("LOGIN_MAX_FAILURES_PER_IP", 50),
+			LoginLockout:          getEnvAsDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:3001/api/auth/oidc/callback"),
+			Scopes:       getEnvAsSlice("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		},
+		Google: GoogleTasksConfig{
+			ClientID:     getEnv("GOOGLE_TASKS_CLIENT_ID", ""),
+			ClientSecret: getEnv("GOOGLE_TASKS_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GOOGLE_TASKS_REDIRECT_URL", "http://localhost:3001/api/integrations/google/callback"),
+			SyncInterval: getEnvAsDuration("GOOGLE_TASKS_SYNC_INTERVAL", 15*time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:   getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Window:    getEnvAsDuration("RATE_LIMIT_WINDOW", time.Minute),
+			Anonymous: getEnvAsInt("RATE_LIMIT_ANONYMOUS", 60),
+			User:      getEnvAsInt("RATE_LIMIT_USER", 300),
+			APIKey:    getEnvAsInt("RATE_LIMIT_API_KEY", 600),
+		},
+		Logging: LoggingConfig{
+			Level:                getEnv("LOG_LEVEL", ""),
+			Format:               getEnv("LOG_FORMAT", ""),
+			RequestIDFormat:      getEnv("REQUEST_ID_FORMAT", "uuidv7"),
+			SlowRequestThreshold: getEnvAsDuration("SLOW_REQUEST_THRESHOLD", time.Second),
+			SlowQueryThreshold:   getEnvAsDuration("SLOW_QUERY_THRESHOLD", 250*time.Millisecond),
+			SuccessSampleRate:    getEnvAsFloat("LOG_SAMPLE_RATE", 1),
+			AccessLogFile:        getEnv("ACCESS_LOG_FILE", ""),
+			AccessLogMaxSizeMB:   getEnvAsInt("ACCESS_LOG_MAX_SIZE_MB", 100),
+			AccessLogMaxBackups:  getEnvAsInt("ACCESS_LOG_MAX_BACKUPS", 5),
 		},
+		TLS: TLSConfig{
+			AutocertDomains: getEnvAsSlice("TLS_AUTOCERT_DOMAINS", nil),
+			CacheDir:        getEnv("TLS_AUTOCERT_CACHE_DIR", "./certs"),
+			Email:           getEnv("TLS_AUTOCERT_EMAIL", ""),
+			DirectoryURL:    getEnv("TLS_AUTOCERT_DIRECTORY_URL", ""),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", true),
+		},
+		Status: StatusConfig{
+			CheckInterval: getEnvAsDuration("STATUS_CHECK_INTERVAL", 30*time.Second),
+			History:       getEnvAsDuration("STATUS_HISTORY", 24*time.Hour),
+		},
+		Demo: DemoConfig{
+			Enabled:       getEnvAsBool("DEMO_MODE", false),
+			Todos:         getEnvAsInt("DEMO_TODOS", 50),
+			ResetInterval: getEnvAsDuration("DEMO_RESET_INTERVAL", time.Hour),
+		},
+		Docs: DocsConfig{
+			Enabled: getEnvAsBool("DOCS_ENABLED", getEnv("ENVIRONMENT", "development") == "development"),
+		},
+		Todos: TodoConfig{
+			UniqueActiveTitles: getEnvAsBool("TODO_UNIQUE_ACTIVE_TITLES", false),
+			FetchLinkTitles:    getEnvAsBool("TODO_FETCH_LINK_TITLES", false),
+			WriteBehind:        getEnvAsBool("TODO_WRITE_BEHIND", false),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"https://yourdomain.com"}),
+		},
+		Reporting: ErrorReportingConfig{
+			DSN: getEnv("ERROR_REPORTING_DSN", getEnv("SENTRY_DSN", "")),
+		},
+		Backup: BackupConfig{
+			Enabled:  getEnvAsBool("BACKUP_ENABLED", false),
+			Dir:      getEnv("BACKUP_DIR", "./backups"),
+			Interval: getEnvAsDuration("BACKUP_INTERVAL", 24*time.Hour),
+			Retain:   getEnvAsInt("BACKUP_RETAIN", 7),
+		},
+		Export: ExportConfig{
+			Enabled:  getEnvAsBool("EXPORT_ENABLED", false),
+			Interval: getEnvAsDuration("EXPORT_INTERVAL", 24*time.Hour),
+			Formats:  getEnvAsSlice("EXPORT_FORMATS", []string{"json"}),
+			Retain:   getEnvAsInt("EXPORT_RETAIN", 7),
+			Dir:      getEnv("EXPORT_DIR", "./exports"),
+			S3: S3Config{
+				Bucket:          getEnv("EXPORT_S3_BUCKET", ""),
+				Prefix:          getEnv("EXPORT_S3_PREFIX", ""),
+				Region:          getEnv("EXPORT_S3_REGION", getEnv("AWS_REGION", "us-east-1")),
+				Endpoint:        getEnv("EXPORT_S3_ENDPOINT", ""),
+				AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+				SessionToken:    getEnv("AWS_SESSION_TOKEN", ""),
+			},
+		},
+		Account: AccountConfig{
+			ExportSyncLimit: getEnvAsInt("ACCOUNT_EXPORT_SYNC_LIMIT", 1000),
+		},
+		Plans: getPlans(),
 	}
+
+	cfg.loadErrs = secretFiles.errs
+	return cfg
 }
 
 func (c *Config) IsDevelopment() bool {
@@ -63,15 +648,42 @@ func (c *Config) IsTest() bool {
 	return c.App.Environment == "test"
 }
 
-func getEnv(key, defaultValue string) string {
+// secretFiles collects read errors for *_FILE variables during load
+var secretFiles struct {
+	sync.Mutex
+	errs []error
+}
+
+// lookupEnv returns the value of key or, when it is unset, the contents of
+// the file named by key_FILE (Docker and Kubernetes secrets), without the
+// trailing newline
+func lookupEnv(key string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		secretFiles.errs = append(secretFiles.errs, fmt.Errorf("%s_FILE: %w", key, err))
+		return ""
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := lookupEnv(key); value != "" {
+		return value
+	}
 	return defaultValue
 }
 
 func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
 			return intValue
 		}
@@ -79,11 +691,88 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := lookupEnv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
 			return boolValue
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := lookupEnv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := lookupEnv(key); value != "" {
+		parts := strings.Split(value, ",")
+		result := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if trimmed := strings.TrimSpace(part); trimmed != "" {
+				result = append(result, trimmed)
+			}
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// getSlackTemplates collects SLACK_TEMPLATE_<EVENT> overrides, where the
+// event type is upper-cased with dots replaced by underscores
+func getSlackTemplates() map[string]string {
+	templates := make(map[string]string)
+	for _, eventType := range []string{"todo.created", "todo.updated", "todo.completed", "todo.reopened", "todo.status_changed", "todo.deleted", "todo.restored", "todo.purged", "todos.purged", "trash.purged"} {
+		key := "SLACK_TEMPLATE_" + strings.ToUpper(strings.ReplaceAll(eventType, ".", "_"))
+		if value := lookupEnv(key); value != "" {
+			templates[eventType] = value
+		}
+	}
+	return templates
+}
+
+// planFeatures are the features a plan can include, see models.Features
+var planFeatures = []string{"api_keys", "hooks", "google_tasks"}
+
+// getPlans reads the plans named in PLANS and their PLAN_<NAME>_*
+// settings, where the name is upper-cased with dashes replaced by
+// underscores. A plan includes every feature unless PLAN_<NAME>_FEATURES
+// lists them; "none" includes none.
+func getPlans() PlansConfig {
+	names := getEnvAsSlice("PLANS", nil)
+	if len(names) == 0 {
+		return PlansConfig{}
+	}
+
+	plans := PlansConfig{
+		Default: getEnv("PLAN_DEFAULT", names[0]),
+		Plans:   make(map[string]PlanConfig, len(names)),
+	}
+	for _, name := range names {
+		prefix := "PLAN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		features := getEnvAsSlice(prefix+"FEATURES", planFeatures)
+		if len(features) == 1 && features[0] == "none" {
+			features = []string{}
+		}
+		plans.Plans[name] = PlanConfig{
+			Features:   features,
+			MaxHooks:   getEnvAsInt(prefix+"MAX_HOOKS", 0),
+			MaxAPIKeys: getEnvAsInt(prefix+"MAX_API_KEYS", 0),
+		}
+	}
+	return plans
+}
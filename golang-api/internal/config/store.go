@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/joho/godotenv"
+)
+
+// Store holds the running configuration. Most settings are fixed at
+// startup, but Reload can apply the ones that are safe to change while
+// serving. It is safe for concurrent use.
+type Store struct {
+	current atomic.Pointer[Config]
+	mu      sync.Mutex
+}
+
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get returns the current configuration. Callers must treat it as
+// read-only; it is replaced, never modified, on reload.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload re-reads the config file (CONFIG_FILE, default .env) over the
+// process environment, validates the result and applies the log level,
+// access log, rate limit, CORS and plan settings. Everything else keeps
+// its startup value. It returns the names of the settings that changed.
+func (s *Store) Reload() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.Get()
+
+	values, err := godotenv.Read(prev.App.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", prev.App.ConfigFile, err)
+	}
+	for key, value := range values {
+		os.Setenv(key, value)
+	}
+
+	loaded := Load()
+	if err := loaded.Validate(); err != nil {
+		return nil, err
+	}
+
+	next := *prev
+	next.Logging.Level = loaded.Logging.Level
+	next.Logging.SlowRequestThreshold = loaded.Logging.SlowRequestThreshold
+	next.Logging.SuccessSampleRate = loaded.Logging.SuccessSampleRate
+	next.RateLimit = loaded.RateLimit
+	next.CORS = loaded.CORS
+	next.Plans = loaded.Plans
+
+	var changed []string
+	if next.Logging.Level != prev.Logging.Level {
+		changed = append(changed, "LOG_LEVEL")
+	}
+	if next.Logging.SlowRequestThreshold != prev.Logging.SlowRequestThreshold {
+		changed = append(changed, "SLOW_REQUEST_THRESHOLD")
+	}
+	if next.Logging.SuccessSampleRate != prev.Logging.SuccessSampleRate {
+		changed = append(changed, "LOG_SAMPLE_RATE")
+	}
+	if next.RateLimit != prev.RateLimit {
+		changed = append(changed, "RATE_LIMIT_*")
+	}
+	if !reflect.DeepEqual(next.CORS, prev.CORS) {
+		changed = append(changed, "CORS_ALLOWED_ORIGINS")
+	}
+	if !reflect.DeepEqual(next.Plans, prev.Plans) {
+		changed = append(changed, "PLAN*")
+	}
+
+	s.current.Store(&next)
+	return changed, nil
+}
@@ -0,0 +1,366 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for values that would otherwise only
+// fail at the first request (or never fail loudly at all). Every problem is
+// reported, not just the first.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.loadErrs...)
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("PORT must be between 1 and 65535, got %q", c.Server.Port)
+	}
+	if c.Server.DrainPeriod < 0 {
+		add("SHUTDOWN_DRAIN_PERIOD must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	if c.Server.BodyLimit < 1 {
+		add("BODY_LIMIT must be positive")
+	}
+	if c.Server.Prefork {
+		if c.TLS.Enabled() {
+			add("PREFORK cannot be combined with TLS_AUTOCERT_DOMAINS")
+		}
+		if c.IsTest() || c.Database.Path == ":memory:" {
+			add("PREFORK needs a file database; every process would get its own in-memory one")
+		}
+		if c.Database.CountCacheTTL > 0 {
+			add("PREFORK cannot be combined with DATABASE_COUNT_CACHE_TTL; a process would not see the writes of the others")
+		}
+		if c.Auth.JWTSecret == "" {
+			add("PREFORK needs JWT_SECRET; every process would sign tokens with its own random secret")
+		}
+	}
+
+	if c.Demo.Enabled {
+		if c.Demo.Todos < 0 {
+			add("DEMO_TODOS must not be negative")
+		}
+		if c.Demo.ResetInterval < 0 {
+			add("DEMO_RESET_INTERVAL must not be negative")
+		}
+	}
+
+	if c.Status.CheckInterval < 0 {
+		add("STATUS_CHECK_INTERVAL must not be negative")
+	}
+	if c.Status.History <= 0 {
+		add("STATUS_HISTORY must be positive")
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("TRUSTED_PROXIES must list IP addresses or CIDR ranges, got %q", proxy)
+			}
+		}
+	}
+	if len(c.Server.TrustedProxies) > 0 && c.Server.ProxyHeader == "" {
+		add("PROXY_HEADER is required when TRUSTED_PROXIES is set")
+	}
+
+	if c.Database.EncryptionKey != "" && (c.IsTest() || c.Database.Path == ":memory:") {
+		add("DATABASE_ENCRYPTION_KEY needs a file database")
+	}
+	if c.Database.BusyRetries < 0 {
+		add("DATABASE_BUSY_RETRIES must not be negative")
+	}
+	if c.Database.CountCacheTTL < 0 {
+		add("DATABASE_COUNT_CACHE_TTL must not be negative")
+	}
+	if c.Database.ConnectTimeout < 0 {
+		add("DATABASE_CONNECT_TIMEOUT must not be negative")
+	}
+	if c.Database.QueryTimeout < 0 {
+		add("DATABASE_QUERY_TIMEOUT must not be negative")
+	}
+	if c.Database.BusyTimeout < 0 {
+		add("DATABASE_BUSY_TIMEOUT must not be negative")
+	}
+	if c.Database.MinFreeDiskPercent < 0 || c.Database.MinFreeDiskPercent > 100 {
+		add("DATABASE_MIN_FREE_DISK_PERCENT must be between 0 and 100")
+	}
+	switch c.Database.Replication {
+	case "", "litestream", "litefs":
+	default:
+		add("DATABASE_REPLICATION must be litestream, litefs or empty, got %q", c.Database.Replication)
+	}
+	if c.Database.Replication != "" && (c.IsTest() || c.Database.Path == ":memory:") {
+		add("DATABASE_REPLICATION needs a file database")
+	}
+	if c.Database.ReadOnly && c.Demo.Enabled {
+		add("DATABASE_READ_ONLY cannot be used with DEMO_MODE")
+	}
+
+	// A standby's database belongs to the replication tool
+	if !c.IsTest() && !c.Database.ReadOnly {
+		if err := checkWritable(c.Database.Path); err != nil {
+			add("DATABASE_PATH %q is not writable: %w", c.Database.Path, err)
+		}
+	}
+
+	switch c.App.Environment {
+	case "development", "test", "staging", "production":
+	default:
+		add("ENVIRONMENT must be development, test, staging or production, got %q", c.App.Environment)
+	}
+
+	if c.IsProduction() {
+		if c.Auth.JWTSecret == "" {
+			add("JWT_SECRET is required in production")
+		} else if len(c.Auth.JWTSecret) < 32 {
+			add("JWT_SECRET must be at least 32 characters in production")
+		}
+	}
+	if c.Auth.TokenTTL <= 0 {
+		add("JWT_TTL must be positive")
+	}
+	if c.Auth.PasswordCost < 4 || c.Auth.PasswordCost > 31 {
+		add("PASSWORD_HASH_COST must be between 4 and 31")
+	}
+	if c.Auth.LoginDelay < 0 {
+		add("LOGIN_DELAY must not be negative")
+	}
+	if c.Auth.LoginMaxFailures < 0 || c.Auth.LoginMaxFailuresPerIP < 0 {
+		add("LOGIN_MAX_FAILURES and LOGIN_MAX_FAILURES_PER_IP must not be negative")
+	}
+	if (c.Auth.LoginDelay > 0 || c.Auth.LoginMaxFailures > 0 || c.Auth.LoginMaxFailuresPerIP > 0) && c.Auth.LoginLockout <= 0 {
+		add("LOGIN_LOCKOUT must be positive when login delays or lockouts are enabled")
+	}
+
+	if (c.OIDC.IssuerURL == "") != (c.OIDC.ClientID == "") {
+		add("OIDC_ISSUER_URL and OIDC_CLIENT_ID must be set together")
+	}
+	if c.OIDC.Enabled() && c.OIDC.ClientSecret == "" {
+		add("OIDC_CLIENT_SECRET is required when OIDC login is enabled")
+	}
+
+	if c.Google.Enabled() {
+		if c.Google.ClientSecret == "" {
+			add("GOOGLE_TASKS_CLIENT_SECRET is required when GOOGLE_TASKS_CLIENT_ID is set")
+		}
+		if c.Google.SyncInterval <= 0 {
+			add("GOOGLE_TASKS_SYNC_INTERVAL must be positive when Google Tasks sync is enabled")
+		}
+	}
+
+	switch strings.ToLower(c.Broker.Type) {
+	case "":
+	case "nats", "kafka":
+		if c.Broker.URL == "" {
+			add("EVENT_BROKER_URL is required when EVENT_BROKER is set")
+		}
+	default:
+		add("EVENT_BROKER must be nats or kafka, got %q", c.Broker.Type)
+	}
+
+	if c.Purge.Enabled && c.Purge.Interval <= 0 {
+		add("PURGE_INTERVAL must be positive when purging is enabled")
+	}
+	if c.Trash.RetentionDays < 0 {
+		add("TRASH_RETENTION_DAYS must not be negative")
+	}
+	if c.Trash.RetentionDays > 0 && c.Trash.PurgeInterval <= 0 {
+		add("TRASH_PURGE_INTERVAL must be positive when TRASH_RETENTION_DAYS is set")
+	}
+	if c.Notify.DueSoon < 0 {
+		add("NOTIFY_DUE_SOON must not be negative")
+	}
+	if c.Notify.DueSoon > 0 && c.Notify.DueSoonInterval <= 0 {
+		add("NOTIFY_DUE_SOON_INTERVAL must be positive when NOTIFY_DUE_SOON is set")
+	}
+	if c.Jobs.Workers < 1 {
+		add("JOBS_WORKERS must be at least 1")
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Window <= 0 {
+			add("RATE_LIMIT_WINDOW must be positive when rate limiting is enabled")
+		}
+		if c.RateLimit.Anonymous < 0 || c.RateLimit.User < 0 || c.RateLimit.APIKey < 0 {
+			add("rate limits must not be negative")
+		}
+	}
+
+	if c.Backup.Enabled {
+		if c.Backup.Interval <= 0 {
+			add("BACKUP_INTERVAL must be positive when backups are enabled")
+		}
+		if c.Backup.Retain < 1 {
+			add("BACKUP_RETAIN must be at least 1 when backups are enabled")
+		}
+		if err := checkWritableDir(c.Backup.Dir); err != nil {
+			add("BACKUP_DIR %q is not writable: %w", c.Backup.Dir, err)
+		}
+	}
+
+	if c.Export.Enabled {
+		if c.Export.Interval <= 0 {
+			add("EXPORT_INTERVAL must be positive when exports are enabled")
+		}
+		if c.Export.Retain < 1 {
+			add("EXPORT_RETAIN must be at least 1 when exports are enabled")
+		}
+		if len(c.Export.Formats) == 0 {
+			add("EXPORT_FORMATS must list json, csv or both")
+		}
+		for _, format := range c.Export.Formats {
+			if format != "json" && format != "csv" {
+				add("EXPORT_FORMATS must list json, csv or both, got %q", format)
+			}
+		}
+		if c.Export.S3.Bucket == "" {
+			if err := checkWritableDir(c.Export.Dir); err != nil {
+				add("EXPORT_DIR %q is not writable: %w", c.Export.Dir, err)
+			}
+		} else if c.Export.S3.AccessKeyID == "" || c.Export.S3.SecretAccessKey == "" {
+			add("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when EXPORT_S3_BUCKET is set")
+		}
+		if endpoint := c.Export.S3.Endpoint; endpoint != "" {
+			if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("EXPORT_S3_ENDPOINT must be an http or https URL, got %q", endpoint)
+			}
+		}
+	}
+
+	if c.Account.ExportSyncLimit < 0 {
+		add("ACCOUNT_EXPORT_SYNC_LIMIT must not be negative")
+	}
+
+	if len(c.Plans.Plans) > 0 {
+		if _, ok := c.Plans.Plans[c.Plans.Default]; !ok {
+			add("PLAN_DEFAULT must be one of PLANS, got %q", c.Plans.Default)
+		}
+		for name, plan := range c.Plans.Plans {
+			for _, feature := range plan.Features {
+				if !slices.Contains(planFeatures, feature) {
+					add("plan %q includes unknown feature %q, must be one of %s or none", name, feature, strings.Join(planFeatures, ", "))
+				}
+			}
+			if plan.MaxHooks < 0 || plan.MaxAPIKeys < 0 {
+				add("plan %q limits must not be negative", name)
+			}
+		}
+	}
+
+	if c.TLS.Enabled() {
+		if err := checkWritableDir(c.TLS.CacheDir); err != nil {
+			add("TLS_AUTOCERT_CACHE_DIR %q is not writable: %w", c.TLS.CacheDir, err)
+		}
+		for _, domain := range c.TLS.AutocertDomains {
+			if strings.ContainsAny(domain, "/:*") {
+				add("TLS_AUTOCERT_DOMAINS must list plain host names, got %q", domain)
+			}
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "text", "json":
+	default:
+		add("LOG_FORMAT must be text or json, got %q", c.Logging.Format)
+	}
+	switch c.Logging.RequestIDFormat {
+	case "uuidv7", "uuidv4", "hex":
+	default:
+		add("REQUEST_ID_FORMAT must be uuidv7, uuidv4 or hex, got %q", c.Logging.RequestIDFormat)
+	}
+	if c.Logging.SuccessSampleRate < 0 || c.Logging.SuccessSampleRate > 1 {
+		add("LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+	if c.Logging.AccessLogFile != "" {
+		if c.Logging.AccessLogMaxSizeMB < 1 {
+			add("ACCESS_LOG_MAX_SIZE_MB must be at least 1")
+		}
+		if c.Logging.AccessLogMaxBackups < 0 {
+			add("ACCESS_LOG_MAX_BACKUPS must not be negative")
+		}
+		if err := checkWritableDir(filepath.Dir(c.Logging.AccessLogFile)); err != nil {
+			add("ACCESS_LOG_FILE %q is not writable: %w", c.Logging.AccessLogFile, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkWritable verifies that the database file, or the directory it will
+// be created in, can be written
+func checkWritable(path string) error {
+	if path == "" {
+		return errors.New("path is empty")
+	}
+	if path == ":memory:" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return errors.New("path is a directory")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	// SQLite creates the file but not its directory
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	return checkWritableDir(dir)
+}
+
+// checkWritableDir verifies that files can be created in dir. A missing
+// directory is accepted when its parent is writable, since it is created
+// on first use.
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		return checkWritableDir(parent)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup writes a consistent snapshot of the database to path using
+// VACUUM INTO. The database stays available for reads and writes while
+// the snapshot is taken. path must not exist.
+func (d *Database) Backup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup target %s already exists", path)
+	}
+
+	if _, err := d.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of the database with the backup at path
+// and applies any migrations the backup is missing. The backup is
+// validated first and copied with SQLite's online backup API, so the
+// replacement is atomic for other connections. The backup is opened
+// read-write because SQLite's integrity check of full-text indexes needs
+// write access, so path should be a copy. Backups of an encrypted
+// database are encrypted with the same key.
+func (d *Database) Restore(ctx context.Context, path string) error {
+	src, err := open("file:"+path, d.key)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer src.Close()
+
+	if err := validateBackup(ctx, src); err != nil {
+		return err
+	}
+
+	if err := d.copyFrom(ctx, src); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	if err := d.migrate(); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+
+	// The backup carries the index as it was configured when it was taken
+	if err := d.SetUniqueActiveTitles(d.uniqueActiveTitles); err != nil {
+		log.Printf("Restored database: %v", err)
+	}
+
+	return nil
+}
+
+// copyFrom overwrites the database with the contents of src. The
+// connections are released before returning, as an in-memory database
+// only has one.
+func (d *Database) copyFrom(ctx context.Context, src *sql.DB) error {
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	dstConn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// ErrInvalidBackup is returned when a restore is attempted with a file
+// that is not a usable backup
+var ErrInvalidBackup = errors.New("invalid backup")
+
+func validateBackup(ctx context.Context, db *sql.DB) error {
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%w: not a SQLite database", ErrInvalidBackup)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("%w: failed to read schema version", ErrInvalidBackup)
+	}
+	if version > SchemaVersion() {
+		return fmt.Errorf("%w: schema version %d is newer than supported version %d", ErrInvalidBackup, version, SchemaVersion())
+	}
+
+	var todos int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'todos'").Scan(&todos); err != nil || todos == 0 {
+		return fmt.Errorf("%w: todos table is missing", ErrInvalidBackup)
+	}
+
+	return nil
+}
@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/centroidsol/todo-api/internal/config"
 	_ "github.com/mattn/go-sqlite3"
@@ -11,6 +14,20 @@ import (
 
 type Database struct {
 	db *sql.DB
+	// path is the database file, or ":memory:"
+	path string
+	// key unlocks the database and its backups when it is encrypted
+	key string
+	// replication is the tool replicating the database file, if any, and
+	// readOnly is set on a standby, whose replica only that tool writes
+	replication string
+	readOnly    bool
+
+	uniqueActiveTitles bool
+
+	// stmts are the statements prepared by Prepare, by query
+	stmtsMu sync.Mutex
+	stmts   map[string]*sql.Stmt
 }
 
 func New(cfg *config.Config) (*Database, error) {
@@ -21,32 +38,158 @@ func New(cfg *config.Config) (*Database, error) {
 		dbPath = cfg.Database.Path
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	key := cfg.Database.EncryptionKey
+	pragmas := connectionPragmas(dbPath, cfg.Database.Replication, cfg.Database.ReadOnly, cfg.Database.BusyTimeout)
+	db, err := connect(dbPath, key, pragmas, cfg.Database.ConnectTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
+	if key != "" {
+		if err := unlock(context.Background(), db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
-	database := &Database{db: db}
+	// Configure connection pool. Every connection to ":memory:" opens its
+	// own empty database, so in-memory databases must use a single one.
+	if dbPath == ":memory:" {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+	} else {
+		db.SetMaxOpenConns(25)
+		db.SetMaxIdleConns(25)
+	}
+
+	database := &Database{
+		db:          db,
+		path:        dbPath,
+		key:         key,
+		replication: cfg.Database.Replication,
+		readOnly:    cfg.Database.ReadOnly,
+		stmts:       make(map[string]*sql.Stmt),
+	}
 
-	if err := database.migrate(); err != nil {
+	if database.readOnly {
+		if err := database.checkSchema(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	} else if err := database.migrate(); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := database.SetUniqueActiveTitles(cfg.Todos.UniqueActiveTitles); err != nil {
+		return nil, err
+	}
+
 	log.Printf("Database connected successfully: %s", dbPath)
 	return database, nil
 }
 
+// The wait between connection attempts starts at initialConnectBackoff
+// and doubles after every failure up to maxConnectBackoff
+const (
+	initialConnectBackoff = 100 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
+)
+
+// connect opens the database and pings it, retrying with exponential
+// backoff until it answers or timeout has passed, so a database that
+// starts after the API, or a volume mounted late, does not fail the boot.
+// A zero timeout tries once.
+func connect(dsn, key string, pragmas []string, timeout time.Duration) (*sql.DB, error) {
+	deadline := time.Now().Add(timeout)
+	wait := initialConnectBackoff
+	for attempt := 1; ; attempt++ {
+		db, err := open(dsn, key, pragmas...)
+		if err == nil {
+			if err = db.Ping(); err == nil {
+				return db, nil
+			}
+			db.Close()
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			if attempt > 1 {
+				return nil, fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return nil, err
+		}
+		log.Printf("Database not ready (attempt %d), retrying in %s: %v", attempt, wait, err)
+		time.Sleep(wait)
+		wait = min(2*wait, maxConnectBackoff)
+	}
+}
+
+// connectionPragmas are the settings every connection starts with. A
+// statement waits busyTimeout for other connections' locks, and foreign
+// keys are enforced, so rows cannot be added for a deleted user or todo
+// and their ON DELETE CASCADE clauses apply. Replication
+// tools follow the write-ahead log, so a replicated database uses WAL,
+// which a standby inherits with the file and must not change, and
+// synchronous=NORMAL, which is durable in WAL mode and spares a sync per
+// commit. A standby's connections cannot write.
+func connectionPragmas(path, replication string, readOnly bool, busyTimeout time.Duration) []string {
+	// PRAGMA does not accept bound parameters
+	pragmas := []string{
+		fmt.Sprintf("PRAGMA busy_timeout = %d", busyTimeout.Milliseconds()),
+		"PRAGMA foreign_keys = ON",
+	}
+	if path != ":memory:" && replication != "" {
+		if !readOnly {
+			pragmas = append(pragmas, "PRAGMA journal_mode = WAL")
+		}
+		pragmas = append(pragmas, "PRAGMA synchronous = NORMAL")
+	}
+	if readOnly {
+		pragmas = append(pragmas, "PRAGMA query_only = ON")
+	}
+	return pragmas
+}
+
+// Close closes the prepared statements and then the database
 func (d *Database) Close() error {
+	d.stmtsMu.Lock()
+	for query, stmt := range d.stmts {
+		stmt.Close()
+		delete(d.stmts, query)
+	}
+	d.stmtsMu.Unlock()
+
 	if d.db != nil {
 		return d.db.Close()
 	}
 	return nil
 }
 
+// Prepared returns the statement Prepare prepared for query, or nil
+func (d *Database) Prepared(query string) *sql.Stmt {
+	d.stmtsMu.Lock()
+	defer d.stmtsMu.Unlock()
+	return d.stmts[query]
+}
+
+// Prepare returns query prepared as a statement, preparing it on the first
+// call and reusing it afterwards. The statements stay open until Close.
+// Only queries run often with the same text belong here.
+func (d *Database) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	d.stmtsMu.Lock()
+	defer d.stmtsMu.Unlock()
+
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := d.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	d.stmts[query] = stmt
+	return stmt, nil
+}
+
 func (d *Database) DB() *sql.DB {
 	return d.db
 }
@@ -55,51 +198,93 @@ func (d *Database) Ping() error {
 	return d.db.Ping()
 }
 
-func (d *Database) migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS todos (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		description TEXT,
-		completed BOOLEAN DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(created_at);
-	CREATE INDEX IF NOT EXISTS idx_todos_completed ON todos(completed);
-	CREATE INDEX IF NOT EXISTS idx_todos_title ON todos(title);
-
-	-- Trigger to update updated_at timestamp
-	CREATE TRIGGER IF NOT EXISTS update_todos_updated_at
-	AFTER UPDATE ON todos
-	FOR EACH ROW
-	BEGIN
-		UPDATE todos SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-	END;
-	`
+// SetUniqueActiveTitles adds or removes the partial unique index that
+// keeps titles of uncompleted todos outside the trash unique, ignoring
+// case. It lives
+// outside the migrations because it follows configuration. Enabling it
+// fails while duplicates exist. A standby leaves the index to the
+// primary.
+func (d *Database) SetUniqueActiveTitles(enabled bool) error {
+	d.uniqueActiveTitles = enabled
+	if d.readOnly {
+		return nil
+	}
+
+	query := "DROP INDEX IF EXISTS idx_todos_unique_active_title"
+	if enabled {
+		query = "CREATE UNIQUE INDEX IF NOT EXISTS idx_todos_unique_active_title ON todos(title COLLATE NOCASE) WHERE completed = 0 AND deleted_at IS NULL"
+	}
 
 	if _, err := d.db.Exec(query); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+		return fmt.Errorf("failed to enforce unique active titles: %w", err)
+	}
+	return nil
+}
+
+// migrate applies the migrations newer than the schema version recorded
+// in the database, each in its own transaction
+func (d *Database) migrate() error {
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if version > SchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, SchemaVersion())
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to execute migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
 	}
 
 	return nil
 }
 
 func (d *Database) Clear() error {
-	_, err := d.db.Exec("DELETE FROM todos")
-	return err
+	for _, table := range []string{"todos", "todo_revisions", "todo_tags", "todo_notes", "todo_links", "saved_searches", "jobs", "outbox", "notifications", "caldav_objects", "google_task_links", "google_accounts", "hooks", "audit_log", "login_failures", "deleted_users", "api_usage", "user_identities", "api_keys", "users"} {
+		if _, err := d.db.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d *Database) Stats() (map[string]interface{}, error) {
 	stats := d.db.Stats()
 	
+	d.stmtsMu.Lock()
+	prepared := len(d.stmts)
+	d.stmtsMu.Unlock()
+
 	var todoCount int
 	err := d.db.QueryRow("SELECT COUNT(*) FROM todos").Scan(&todoCount)
 	if err != nil {
 		return nil, err
 	}
 
+	replication, err := d.Replication()
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
 		"open_connections":      stats.OpenConnections,
 		"in_use":               stats.InUse,
@@ -110,5 +295,7 @@ func (d *Database) Stats() (map[string]interface{}, error) {
 		"max_idle_time_closed": stats.MaxIdleTimeClosed,
 		"max_lifetime_closed":  stats.MaxLifetimeClosed,
 		"todo_count":           todoCount,
+		"prepared_statements":  prepared,
+		"replication":          replication,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package database
+
+import "syscall"
+
+// diskSpace returns the bytes available to the process and the size of
+// the file system holding dir
+func diskSpace(dir string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrEncryptionUnsupported is returned when an encryption key is set but
+// the binary's SQLite is not SQLCipher. The bundled SQLite has no
+// encryption; build with -tags libsqlite3 against libsqlcipher instead.
+var ErrEncryptionUnsupported = errors.New("database encryption needs SQLCipher; build with -tags libsqlite3 linked against libsqlcipher")
+
+// open opens the SQLite database at dsn. Every connection runs pragmas
+// before use, after unlocking it with key when there is one.
+func open(dsn, key string, pragmas ...string) (*sql.DB, error) {
+	if key == "" && len(pragmas) == 0 {
+		return sql.Open("sqlite3", dsn)
+	}
+	if key != "" {
+		pragmas = append([]string{"PRAGMA key = " + quoteKey(key)}, pragmas...)
+	}
+	return sql.OpenDB(newPragmaConnector(dsn, pragmas)), nil
+}
+
+// pragmaConnector opens SQLite connections that run pragmas first. PRAGMA
+// key has to come before anything else touches the database, as SQLCipher
+// requires, which rules out the driver's own DSN parameters.
+type pragmaConnector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func newPragmaConnector(dsn string, pragmas []string) *pragmaConnector {
+	return &pragmaConnector{
+		driver: &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				for _, pragma := range pragmas {
+					if _, err := conn.Exec(pragma, nil); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		dsn: dsn,
+	}
+}
+
+func (c *pragmaConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *pragmaConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// keyLiteral returns key the way SQLCipher reads it: 64 hex digits as a
+// raw 256-bit key, anything else as a passphrase to derive one from
+func keyLiteral(key string) string {
+	if isRawKey(key) {
+		return "x'" + key + "'"
+	}
+	return key
+}
+
+// quoteKey returns key as a string literal for PRAGMA key, which does not
+// accept bound parameters
+func quoteKey(key string) string {
+	return "'" + strings.ReplaceAll(keyLiteral(key), "'", "''") + "'"
+}
+
+func isRawKey(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+	for _, r := range key {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// requireCipher returns ErrEncryptionUnsupported unless db runs on
+// SQLCipher. Plain SQLite ignores the unknown pragma and returns no row.
+func requireCipher(ctx context.Context, db *sql.DB) error {
+	var version string
+	err := db.QueryRowContext(ctx, "PRAGMA cipher_version").Scan(&version)
+	if err == sql.ErrNoRows || version == "" {
+		return ErrEncryptionUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for SQLCipher: %w", err)
+	}
+	return nil
+}
+
+// unlock checks that a keyed database opened: SQLCipher only reports a
+// wrong key, or a database that was never encrypted, on the first read
+func unlock(ctx context.Context, db *sql.DB) error {
+	if err := requireCipher(ctx, db); err != nil {
+		return err
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to unlock database, the key is wrong or the database is not encrypted (see todocli encrypt): %w", err)
+	}
+	return nil
+}
+
+// Encrypt converts the plaintext database at path into one encrypted
+// with key, in place. The encrypted copy is written next to it with
+// sqlcipher_export, checked, and then renamed over the original, so a
+// failure leaves the plaintext database as it was. Nothing else may have
+// the database open.
+func Encrypt(ctx context.Context, path, key string) error {
+	if key == "" {
+		return errors.New("an encryption key is required")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	tmp := path + ".encrypting"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	version, err := exportEncrypted(ctx, path, tmp, key)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := checkEncrypted(ctx, tmp, key, version); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	return nil
+}
+
+// exportEncrypted copies the plaintext database at path to an encrypted
+// one at dst and returns the schema version it carried over
+func exportEncrypted(ctx context.Context, path, dst, key string) (int, error) {
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=rw")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer src.Close()
+
+	// ATTACH only lasts for the connection it ran on
+	src.SetMaxOpenConns(1)
+
+	if err := requireCipher(ctx, src); err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := src.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read database, it may already be encrypted: %w", err)
+	}
+
+	if _, err := src.ExecContext(ctx, "ATTACH DATABASE ? AS encrypted KEY ?", dst, keyLiteral(key)); err != nil {
+		return 0, fmt.Errorf("failed to create encrypted database: %w", err)
+	}
+
+	if _, err := src.ExecContext(ctx, "SELECT sqlcipher_export('encrypted')"); err != nil {
+		src.ExecContext(ctx, "DETACH DATABASE encrypted")
+		return 0, fmt.Errorf("failed to export to encrypted database: %w", err)
+	}
+
+	// sqlcipher_export copies the schema and data but not user_version,
+	// which the migrations rely on
+	if _, err := src.ExecContext(ctx, fmt.Sprintf("PRAGMA encrypted.user_version = %d", version)); err != nil {
+		src.ExecContext(ctx, "DETACH DATABASE encrypted")
+		return 0, fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	if _, err := src.ExecContext(ctx, "DETACH DATABASE encrypted"); err != nil {
+		return 0, fmt.Errorf("failed to close encrypted database: %w", err)
+	}
+
+	return version, nil
+}
+
+// checkEncrypted opens the encrypted copy with key and checks it is
+// intact before it replaces the original
+func checkEncrypted(ctx context.Context, path, key string, version int) error {
+	db, err := open("file:"+path, key)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := unlock(ctx, db); err != nil {
+		return err
+	}
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check encrypted database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("encrypted database failed its integrity check: %s", result)
+	}
+
+	var got int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&got); err != nil || got != version {
+		return fmt.Errorf("encrypted database has schema version %d, want %d", got, version)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// errDiskUnsupported is returned by diskSpace where it cannot be measured
+var errDiskUnsupported = errors.New("disk space cannot be measured on this platform")
+
+// DeepCheck pings the database, writes a row to health_checks, reads it
+// back and deletes it, timing each step, and measures the disk space left
+// for the database file. Steps that take slow or longer warn, as does
+// less free disk than minFreePercent; zero disables either warning. A
+// read-only database skips the write, read and delete.
+func (d *Database) DeepCheck(ctx context.Context, slow time.Duration, minFreePercent float64) map[string]models.HealthCheck {
+	checks := make(map[string]models.HealthCheck, 5)
+	timed := func(name string, fn func() error) bool {
+		start := time.Now()
+		err := fn()
+		check := models.HealthCheck{Status: models.HealthOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+		switch {
+		case err != nil:
+			check.Status, check.Error = models.HealthFailed, err.Error()
+		case slow > 0 && time.Since(start) >= slow:
+			check.Status = models.HealthWarn
+		}
+		checks[name] = check
+		return err == nil
+	}
+
+	timed("ping", func() error { return d.db.PingContext(ctx) })
+
+	// A standby cannot write the probe
+	if d.readOnly {
+		for _, name := range []string{"write", "read", "delete"} {
+			checks[name] = models.HealthCheck{Status: models.HealthSkipped}
+		}
+		return d.withDisk(checks, minFreePercent)
+	}
+
+	token, err := probeToken()
+	if err != nil {
+		checks["write"] = models.HealthCheck{Status: models.HealthFailed, Error: err.Error()}
+		return d.withDisk(checks, minFreePercent)
+	}
+
+	var id int64
+	wrote := timed("write", func() error {
+		result, err := d.db.ExecContext(ctx, "INSERT INTO health_checks (token) VALUES (?)", token)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	})
+	if !wrote {
+		checks["read"] = models.HealthCheck{Status: models.HealthSkipped}
+		checks["delete"] = models.HealthCheck{Status: models.HealthSkipped}
+		return d.withDisk(checks, minFreePercent)
+	}
+
+	timed("read", func() error {
+		var read string
+		if err := d.db.QueryRowContext(ctx, "SELECT token FROM health_checks WHERE id = ?", id).Scan(&read); err != nil {
+			return err
+		}
+		if read != token {
+			return fmt.Errorf("read back %q, wrote %q", read, token)
+		}
+		return nil
+	})
+	timed("delete", func() error {
+		_, err := d.db.ExecContext(ctx, "DELETE FROM health_checks WHERE id = ?", id)
+		return err
+	})
+
+	return d.withDisk(checks, minFreePercent)
+}
+
+// withDisk adds the disk check to checks. An in-memory database has no
+// file to run out of space for.
+func (d *Database) withDisk(checks map[string]models.HealthCheck, minFreePercent float64) map[string]models.HealthCheck {
+	if d.path == ":memory:" {
+		checks["disk"] = models.HealthCheck{Status: models.HealthSkipped}
+		return checks
+	}
+
+	free, total, err := diskSpace(filepath.Dir(d.path))
+	if errors.Is(err, errDiskUnsupported) {
+		checks["disk"] = models.HealthCheck{Status: models.HealthSkipped, Error: err.Error()}
+		return checks
+	}
+	if err != nil {
+		checks["disk"] = models.HealthCheck{Status: models.HealthFailed, Error: err.Error()}
+		return checks
+	}
+
+	check := models.HealthCheck{Status: models.HealthOK, FreeBytes: free, TotalBytes: total}
+	if total > 0 {
+		check.FreePercent = float64(free) * 100 / float64(total)
+	}
+	if minFreePercent > 0 && check.FreePercent < minFreePercent {
+		check.Status = models.HealthWarn
+	}
+	checks["disk"] = check
+	return checks
+}
+
+func probeToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,450 @@
+package database
+
+// migrations are applied in order and never edited once released; add a
+// new entry to change the schema. The schema version stored in SQLite's
+// user_version is the number of migrations applied.
+//
+// The first migration uses IF NOT EXISTS so databases created before
+// versioning was introduced upgrade cleanly.
+var migrations = []string{
+	`
+	CREATE TABLE IF NOT EXISTS todos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT,
+		completed BOOLEAN DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(created_at);
+	CREATE INDEX IF NOT EXISTS idx_todos_completed ON todos(completed);
+	CREATE INDEX IF NOT EXISTS idx_todos_title ON todos(title);
+
+	-- Trigger to update updated_at timestamp
+	CREATE TRIGGER IF NOT EXISTS update_todos_updated_at
+	AFTER UPDATE ON todos
+	FOR EACH ROW
+	BEGIN
+		UPDATE todos SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+	END;
+
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		payload TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		max_attempts INTEGER NOT NULL DEFAULT 3,
+		last_error TEXT,
+		result TEXT,
+		run_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs(status, run_at);
+	CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs(type);
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		delivered_to TEXT,
+		last_error TEXT,
+		available_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_outbox_status_available_at ON outbox(status, available_at);
+
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		name TEXT,
+		password_hash TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(issuer, subject)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+	`,
+	// Full-text index over todo titles and descriptions. FTS4 is used
+	// because go-sqlite3 only builds FTS5 with the sqlite_fts5 tag. The index
+	// keeps its own copy of the text so it stays consistent when the
+	// updated_at trigger rewrites a row.
+	`
+	CREATE VIRTUAL TABLE todos_fts USING fts4(title, description);
+
+	INSERT INTO todos_fts(docid, title, description) SELECT id, title, description FROM todos;
+
+	CREATE TRIGGER todos_fts_insert AFTER INSERT ON todos BEGIN
+		INSERT INTO todos_fts(docid, title, description) VALUES (NEW.id, NEW.title, NEW.description);
+	END;
+	CREATE TRIGGER todos_fts_update AFTER UPDATE OF title, description ON todos BEGIN
+		UPDATE todos_fts SET title = NEW.title, description = NEW.description WHERE docid = NEW.id;
+	END;
+	CREATE TRIGGER todos_fts_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM todos_fts WHERE docid = OLD.id;
+	END;
+	`,
+	// Immutable edit history. Every insert, and every update that changes
+	// a user-visible field, stores the resulting state as the next
+	// revision. Foreign keys are not enforced, so a trigger removes the
+	// history of deleted todos.
+	`
+	CREATE TABLE todo_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		revision INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		description TEXT,
+		completed BOOLEAN NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(todo_id, revision)
+	);
+
+	INSERT INTO todo_revisions (todo_id, revision, title, description, completed, created_at)
+	SELECT id, 1, title, description, completed, updated_at FROM todos;
+
+	CREATE TRIGGER todo_revisions_insert AFTER INSERT ON todos BEGIN
+		INSERT INTO todo_revisions (todo_id, revision, title, description, completed)
+		VALUES (NEW.id, 1, NEW.title, NEW.description, NEW.completed);
+	END;
+	CREATE TRIGGER todo_revisions_update AFTER UPDATE OF title, description, completed ON todos
+	WHEN OLD.title IS NOT NEW.title OR OLD.description IS NOT NEW.description OR OLD.completed IS NOT NEW.completed
+	BEGIN
+		INSERT INTO todo_revisions (todo_id, revision, title, description, completed)
+		SELECT NEW.id, COALESCE(MAX(revision), 0) + 1, NEW.title, NEW.description, NEW.completed
+		FROM todo_revisions WHERE todo_id = NEW.id;
+	END;
+	CREATE TRIGGER todo_revisions_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM todo_revisions WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Optimistic concurrency: version is bumped by every update and
+	// conditional updates compare it
+	`
+	ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	`,
+	// Client-generated UUIDs make creates idempotent
+	`
+	ALTER TABLE todos ADD COLUMN client_id TEXT;
+
+	CREATE UNIQUE INDEX idx_todos_client_id ON todos(client_id);
+	`,
+	// Soft delete: deleted todos stay in the trash until purged. The unique
+	// title index is recreated by Database.SetUniqueActiveTitles so that it
+	// ignores the trash.
+	`
+	ALTER TABLE todos ADD COLUMN deleted_at DATETIME;
+
+	CREATE INDEX idx_todos_deleted_at ON todos(deleted_at);
+	DROP INDEX IF EXISTS idx_todos_unique_active_title;
+	`,
+	// completed_at records when a todo was last completed and is cleared
+	// when it is reopened. Existing completed todos use updated_at.
+	`
+	ALTER TABLE todos ADD COLUMN completed_at DATETIME;
+
+	UPDATE todos SET completed_at = updated_at WHERE completed = 1;
+
+	CREATE INDEX idx_todos_completed_at ON todos(completed_at);
+
+	CREATE TRIGGER todos_completed_at_insert AFTER INSERT ON todos WHEN NEW.completed BEGIN
+		UPDATE todos SET completed_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+	END;
+	CREATE TRIGGER todos_completed_at_update AFTER UPDATE OF completed ON todos
+	WHEN OLD.completed IS NOT NEW.completed
+	BEGIN
+		UPDATE todos SET completed_at = CASE WHEN NEW.completed THEN CURRENT_TIMESTAMP END WHERE id = NEW.id;
+	END;
+	`,
+	// Optional due dates
+	`
+	ALTER TABLE todos ADD COLUMN due_date DATETIME;
+
+	CREATE INDEX idx_todos_due_date ON todos(due_date);
+	`,
+	// Saved searches store list queries as query strings
+	`
+	CREATE TABLE saved_searches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		query TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`,
+	// Tags are lowercase labels, stored one row per todo and tag. Like
+	// revisions they are removed by a trigger when the todo is deleted.
+	`
+	CREATE TABLE todo_tags (
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (todo_id, tag)
+	);
+
+	CREATE INDEX idx_todo_tags_tag ON todo_tags(tag);
+
+	CREATE TRIGGER todo_tags_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM todo_tags WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Workflow status. completed stays and means status = 'done'; the
+	// service updates both, and a trigger derives the status when only
+	// completed changes, as when a revision is reverted.
+	`
+	ALTER TABLE todos ADD COLUMN status TEXT NOT NULL DEFAULT 'todo';
+
+	UPDATE todos SET status = 'done' WHERE completed = 1;
+
+	CREATE INDEX idx_todos_status ON todos(status);
+
+	CREATE TRIGGER todos_status_completed AFTER UPDATE OF completed ON todos
+	WHEN OLD.completed IS NOT NEW.completed AND OLD.status IS NEW.status
+	BEGIN
+		UPDATE todos SET status = CASE WHEN NEW.completed THEN 'done' ELSE 'todo' END WHERE id = NEW.id;
+	END;
+	`,
+	// Free-form metadata for integrations, a JSON object
+	`
+	ALTER TABLE todos ADD COLUMN metadata TEXT CHECK (metadata IS NULL OR json_valid(metadata));
+	`,
+	// Color labels, a palette name or a #rrggbb hex color
+	`
+	ALTER TABLE todos ADD COLUMN color TEXT;
+	ALTER TABLE saved_searches ADD COLUMN color TEXT;
+
+	CREATE INDEX idx_todos_color ON todos(color);
+	`,
+	// Icons, an emoji or an icon name
+	`
+	ALTER TABLE todos ADD COLUMN icon TEXT;
+	ALTER TABLE saved_searches ADD COLUMN icon TEXT;
+	`,
+	// Effort estimates, in minutes
+	`
+	ALTER TABLE todos ADD COLUMN estimate_minutes INTEGER CHECK (estimate_minutes IS NULL OR estimate_minutes > 0);
+	`,
+	// Notes are long-form Markdown attached to a todo, removed with it
+	`
+	CREATE TABLE todo_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX idx_todo_notes_todo_id ON todo_notes(todo_id);
+
+	CREATE TRIGGER todo_notes_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM todo_notes WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Typed references to external URLs, removed with their todo
+	`
+	CREATE TABLE todo_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		type TEXT NOT NULL DEFAULT 'other',
+		url TEXT NOT NULL,
+		title TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (todo_id, url)
+	);
+
+	CREATE TRIGGER todo_links_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM todo_links WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Locations, in WGS 84 degrees. Both are set or both are NULL.
+	`
+	ALTER TABLE todos ADD COLUMN latitude REAL CHECK (latitude BETWEEN -90 AND 90);
+	ALTER TABLE todos ADD COLUMN longitude REAL CHECK (longitude BETWEEN -180 AND 180);
+
+	CREATE INDEX idx_todos_location ON todos(latitude, longitude) WHERE latitude IS NOT NULL;
+	`,
+	// Priority: low, medium, high or urgent, NULL when not prioritized
+	`
+	ALTER TABLE todos ADD COLUMN priority TEXT CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+	`,
+	// The outbox doubles as the activity log; index events by todo
+	`
+	ALTER TABLE outbox ADD COLUMN todo_id INTEGER GENERATED ALWAYS AS (json_extract(payload, '$.todo_id')) VIRTUAL;
+
+	CREATE INDEX idx_outbox_todo_id ON outbox(todo_id) WHERE todo_id IS NOT NULL;
+	`,
+	// In-app notifications, written by the notifications event subscriber.
+	// event_id is the outbox message, so redeliveries are ignored.
+	`
+	CREATE TABLE notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		event_id INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		todo_id INTEGER,
+		message TEXT NOT NULL,
+		read_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, event_id)
+	);
+
+	CREATE INDEX idx_notifications_user_id ON notifications(user_id, id);
+
+	CREATE TRIGGER notifications_todo_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM notifications WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Resource names and UIDs chosen by CalDAV clients for the todos they
+	// create; other todos use todo-<id>.ics and todo-<id>
+	`
+	CREATE TABLE caldav_objects (
+		todo_id INTEGER PRIMARY KEY REFERENCES todos(id) ON DELETE CASCADE,
+		name TEXT NOT NULL UNIQUE,
+		uid TEXT NOT NULL UNIQUE
+	);
+
+	CREATE TRIGGER caldav_objects_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM caldav_objects WHERE todo_id = OLD.id;
+	END;
+	`,
+	// Google accounts linked for Google Tasks sync, and which task each
+	// todo is synced with. A link records the todo version and task
+	// update time of the last sync, to tell which side changed since.
+	`
+	CREATE TABLE google_accounts (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		state TEXT UNIQUE,
+		state_expires_at DATETIME,
+		access_token TEXT,
+		refresh_token TEXT,
+		token_expires_at DATETIME,
+		connected_at DATETIME,
+		last_synced_at DATETIME,
+		last_result TEXT,
+		last_error TEXT
+	);
+
+	CREATE TABLE google_task_links (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		task_id TEXT NOT NULL,
+		todo_version INTEGER NOT NULL,
+		task_updated TEXT NOT NULL,
+		PRIMARY KEY (user_id, todo_id),
+		UNIQUE (user_id, task_id)
+	);
+
+	CREATE TRIGGER google_task_links_delete AFTER DELETE ON todos BEGIN
+		DELETE FROM google_task_links WHERE todo_id = OLD.id;
+	END;
+	`,
+	// REST hook subscriptions: every event of the type is posted to the
+	// target URL until the user unsubscribes or the target answers 410
+	`
+	CREATE TABLE hooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		target_url TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX idx_hooks_user_id ON hooks(user_id);
+	CREATE INDEX idx_hooks_event ON hooks(event);
+	`,
+	// Append-only audit trail of security events, and consecutive failed
+	// logins per account (email) and per client IP, used to slow down and
+	// lock out password guessing
+	`
+	CREATE TABLE audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action TEXT NOT NULL,
+		actor_id INTEGER,
+		ip TEXT,
+		target TEXT,
+		details TEXT CHECK (details IS NULL OR json_valid(details)),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+	CREATE TABLE login_failures (
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		failures INTEGER NOT NULL,
+		last_failed_at DATETIME NOT NULL,
+		locked_until DATETIME,
+		PRIMARY KEY (kind, value)
+	);
+	`,
+	// Tombstones of deleted accounts. Only a hash of the email is kept, to
+	// tell that an account existed without keeping whose it was.
+	`
+	CREATE TABLE deleted_users (
+		user_id INTEGER PRIMARY KEY,
+		email_hash TEXT NOT NULL,
+		deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX idx_deleted_users_email_hash ON deleted_users(email_hash);
+	`,
+	// The plan a user is on; NULL is the default plan
+	`
+	ALTER TABLE users ADD COLUMN plan TEXT;
+	`,
+	// Metered API calls per user, API key (0 for access tokens) and UTC
+	// day
+	`
+	CREATE TABLE api_usage (
+		user_id INTEGER NOT NULL,
+		api_key_id INTEGER NOT NULL DEFAULT 0,
+		day TEXT NOT NULL,
+		calls INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, api_key_id, day)
+	);
+
+	CREATE INDEX idx_api_usage_day ON api_usage(day);
+	`,
+	// Rows written, read back and deleted by the deep health check
+	`
+	CREATE TABLE health_checks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		token TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`,
+}
+
+// SchemaVersion returns the schema version this build migrates to
+func SchemaVersion() int {
+	return len(migrations)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// The tools that can replicate the database file
+const (
+	ReplicationLitestream = "litestream"
+	ReplicationLiteFS     = "litefs"
+)
+
+// checkSchema stands in for migrate on a standby, which cannot migrate:
+// its replica must have been migrated by the primary to this build's
+// schema version
+func (d *Database) checkSchema() error {
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version != SchemaVersion() {
+		return fmt.Errorf("read-only database has schema version %d, this build needs %d; migrate the primary first", version, SchemaVersion())
+	}
+	return nil
+}
+
+// ReadOnly tells whether the database is a standby's read-only replica
+func (d *Database) ReadOnly() bool {
+	return d.readOnly
+}
+
+// Replication reports the journal mode and write-ahead log size, and
+// what the replication tool's files beside the database tell: for LiteFS
+// the .primary file, present on replicas only, and the -pos file, for
+// Litestream the generation it replicates
+func (d *Database) Replication() (models.ReplicationStatus, error) {
+	status := models.ReplicationStatus{Mode: d.replication, ReadOnly: d.readOnly}
+	if status.Mode == "" {
+		status.Mode = "none"
+	}
+
+	if err := d.db.QueryRow("PRAGMA journal_mode").Scan(&status.JournalMode); err != nil {
+		return status, fmt.Errorf("failed to read journal mode: %w", err)
+	}
+	if d.path == ":memory:" {
+		return status, nil
+	}
+
+	if info, err := os.Stat(d.path + "-wal"); err == nil {
+		status.WALBytes = info.Size()
+	}
+
+	dir, name := filepath.Split(d.path)
+	switch d.replication {
+	case ReplicationLiteFS:
+		host, err := readTrimmed(filepath.Join(dir, ".primary"))
+		primary := os.IsNotExist(err)
+		status.Primary, status.PrimaryHost = &primary, host
+		status.Position, _ = readTrimmed(d.path + "-pos")
+	case ReplicationLitestream:
+		status.Generation, _ = readTrimmed(filepath.Join(dir, "."+name+"-litestream", "generation"))
+	}
+
+	return status, nil
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,101 @@
+// Package demo fills the database with generated todos for public
+// playground deployments (DEMO_MODE)
+package demo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// kind is a family of todos: a title generator and the tag it gets
+type kind struct {
+	tag   string
+	title func(f *gofakeit.Faker) string
+}
+
+var kinds = []kind{
+	{"calls", func(f *gofakeit.Faker) string { return "Call " + f.FirstName() }},
+	{"errands", func(f *gofakeit.Faker) string { return "Buy " + f.Fruit() + " and " + f.Vegetable() }},
+	{"errands", func(f *gofakeit.Faker) string { return "Pick up " + f.Dessert() + " for the weekend" }},
+	{"work", func(f *gofakeit.Faker) string {
+		return "Email " + f.Company() + " about the " + f.BuzzWord() + " proposal"
+	}},
+	{"work", func(f *gofakeit.Faker) string { return "Review " + f.AppName() + " release notes" }},
+	{"work", func(f *gofakeit.Faker) string { return "Fix the " + f.HackerNoun() + " before the demo" }},
+	{"reading", func(f *gofakeit.Faker) string { return "Read " + f.BookTitle() }},
+	{"leisure", func(f *gofakeit.Faker) string { return "Watch " + f.MovieName() }},
+	{"travel", func(f *gofakeit.Faker) string { return "Book a trip to " + f.City() }},
+	{"hobbies", func(f *gofakeit.Faker) string { return "Practice " + f.Hobby() }},
+}
+
+// seedBatchSize is how many todos Seed creates at once
+const seedBatchSize = 100
+
+// Seed creates n generated todos through the todo service, so they are
+// validated and tagged like any other, in batches. A fifth are in progress, a third
+// completed, and most have a due date within the next month.
+func Seed(ctx context.Context, todos services.TodoService, n int) error {
+	f := gofakeit.New(time.Now().UnixNano())
+	now := time.Now()
+
+	reqs := make([]models.CreateTodoRequest, 0, n)
+	for i := 0; i < n; i++ {
+		k := kinds[f.IntRange(0, len(kinds)-1)]
+		req := models.CreateTodoRequest{
+			Title: k.title(f),
+			Tags:  []string{k.tag},
+		}
+
+		if f.IntRange(1, 10) <= 4 {
+			description := f.Sentence(f.IntRange(6, 14))
+			req.Description = &description
+		}
+		if f.IntRange(1, 10) <= 7 {
+			priority := f.RandomString(models.TodoPriorities)
+			req.Priority = &priority
+		}
+		if f.IntRange(1, 10) <= 6 {
+			due := f.DateRange(now.AddDate(0, 0, -5), now.AddDate(0, 0, 30)).Format(time.DateOnly)
+			req.DueDate = &due
+		}
+		if f.IntRange(1, 10) <= 4 {
+			estimate := 15 * f.IntRange(1, 16)
+			req.EstimateMinutes = &estimate
+		}
+
+		switch roll := f.IntRange(1, 15); {
+		case roll <= 5:
+			req.Completed = true
+		case roll <= 8:
+			status := models.StatusInProgress
+			req.Status = &status
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	for start := 0; start < len(reqs); start += seedBatchSize {
+		if _, _, err := todos.CreateTodos(ctx, reqs[start:min(start+seedBatchSize, len(reqs))]); err != nil {
+			return fmt.Errorf("failed to create demo todos: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Reset returns a scheduler task that empties the database, including
+// users and API keys, and seeds n todos again
+func Reset(db *database.Database, todos services.TodoService, n int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := db.Clear(); err != nil {
+			return fmt.Errorf("failed to clear demo database: %w", err)
+		}
+		return Seed(ctx, todos, n)
+	}
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+const subscriberBuffer = 256
+
+type subscription struct {
+	name    string
+	types   map[string]bool
+	handler Handler
+	queue   chan Event
+}
+
+func (s *subscription) wants(eventType string) bool {
+	return len(s.types) == 0 || s.types[eventType]
+}
+
+// Bus is an in-process publish/subscribe event bus. Every subscriber gets
+// its own buffered queue and goroutine so a slow subscriber never blocks
+// the publisher or other subscribers.
+type Bus struct {
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	subs   []*subscription
+	wg     sync.WaitGroup
+	closed bool
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	return &Bus{logger: logger}
+}
+
+// Subscribe registers a handler for the given event types, or for every
+// event when no types are given
+func (b *Bus) Subscribe(name string, handler Handler, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.logger.Warn("Ignoring subscription on closed event bus", "subscriber", name)
+		return
+	}
+
+	sub := &subscription{
+		name:    name,
+		types:   make(map[string]bool, len(types)),
+		handler: handler,
+		queue:   make(chan Event, subscriberBuffer),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.subs = append(b.subs, sub)
+	b.wg.Add(1)
+	go b.dispatch(sub)
+
+	b.logger.Info("Event subscriber registered", "subscriber", name, "types", types)
+}
+
+// Publish fans the event out to every interested subscriber
+func (b *Bus) Publish(ctx context.Context, evt Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, sub := range b.subs {
+		if !sub.wants(evt.Type) {
+			continue
+		}
+
+		select {
+		case sub.queue <- evt:
+		default:
+			b.logger.Error("Event subscriber queue full, dropping event", "subscriber", sub.name, "type", evt.Type, "todo_id", evt.TodoID)
+		}
+	}
+}
+
+// Dispatch runs the handlers of every interested subscriber synchronously.
+// It is used by the outbox relay, which needs to know whether delivery
+// succeeded so it can retry.
+func (b *Bus) Dispatch(ctx context.Context, evt Event, skip map[string]bool) ([]string, error) {
+	b.mu.RLock()
+	subs := make([]*subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub.wants(evt.Type) && !skip[sub.name] {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	delivered := make([]string, 0, len(subs))
+	var errs []error
+	for _, sub := range subs {
+		if err := b.call(ctx, sub, evt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.name, err))
+			continue
+		}
+		delivered = append(delivered, sub.name)
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// Close stops accepting events and waits for subscribers to drain their
+// queues
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, sub := range b.subs {
+		close(sub.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	b.logger.Info("Event bus closed")
+}
+
+func (b *Bus) dispatch(sub *subscription) {
+	defer b.wg.Done()
+
+	for evt := range sub.queue {
+		b.deliver(sub, evt)
+	}
+}
+
+func (b *Bus) deliver(sub *subscription, evt Event) {
+	if err := b.call(context.Background(), sub, evt); err != nil {
+		b.logger.Error("Event subscriber failed", "subscriber", sub.name, "type", evt.Type, "todo_id", evt.TodoID, "error", err)
+	}
+}
+
+func (b *Bus) call(ctx context.Context, sub *subscription, evt Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+
+	return sub.handler(ctx, evt)
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// Event types emitted by the todo domain
+const (
+	TodoCreated   = "todo.created"
+	TodoUpdated   = "todo.updated"
+	TodoCompleted = "todo.completed"
+	TodoReopened  = "todo.reopened"
+	// TodoStatusChanged is a move between open statuses and carries the
+	// previous status in Data["from"]
+	TodoStatusChanged = "todo.status_changed"
+	TodoDeleted       = "todo.deleted"
+	TodoRestored      = "todo.restored"
+	TodoPurged        = "todo.purged"
+	TodosPurged       = "todos.purged"
+	TrashPurged       = "trash.purged"
+	// TodoDueSoon is emitted once per due date when an open todo comes due
+	// within the configured window
+	TodoDueSoon = "todo.due_soon"
+	// NoteCreated and NoteUpdated carry the note ID in Data["note_id"] and
+	// the emails of newly mentioned users in Data["mentions"]
+	NoteCreated = "note.created"
+	NoteUpdated = "note.updated"
+)
+
+// Types lists every event type
+var Types = []string{
+	TodoCreated, TodoUpdated, TodoCompleted, TodoReopened, TodoStatusChanged,
+	TodoDeleted, TodoRestored, TodoPurged, TodosPurged, TrashPurged,
+	TodoDueSoon, NoteCreated, NoteUpdated,
+}
+
+// Event is a domain event describing a change to todos. ID is assigned
+// when the event is stored in the outbox and can be used by subscribers to
+// detect redeliveries.
+type Event struct {
+	ID         int                    `json:"id,omitempty"`
+	Type       string                 `json:"type"`
+	TodoID     int                    `json:"todo_id,omitempty"`
+	Todo       *models.Todo           `json:"todo,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// New creates an event for the given todo, stamped with the current time
+func New(eventType string, todo *models.Todo) Event {
+	evt := Event{
+		Type:       eventType,
+		Todo:       todo,
+		OccurredAt: time.Now().UTC(),
+	}
+	if todo != nil {
+		evt.TodoID = todo.ID
+	}
+	return evt
+}
+
+// Publisher is implemented by anything that can accept domain events
+type Publisher interface {
+	Publish(ctx context.Context, evt Event)
+}
+
+// Handler processes a delivered event
+type Handler func(ctx context.Context, evt Event) error
+
+// Dispatcher delivers an event synchronously to every interested
+// subscriber not listed in skip, returning the names of the subscribers
+// that handled it successfully
+type Dispatcher interface {
+	Dispatch(ctx context.Context, evt Event, skip map[string]bool) ([]string, error)
+}
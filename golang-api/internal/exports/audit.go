@@ -0,0 +1,75 @@
+package exports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+var auditCSVHeader = []string{"id", "created_at", "action", "actor_id", "ip", "target", "details"}
+
+// WriteAudit writes the audit entries passed by each to w as
+// newline-delimited JSON or as CSV with a header row, where details are a
+// JSON column, and returns how many there were
+func WriteAudit(w io.Writer, format string, each func(func(models.AuditEntry) error) error) (int, error) {
+	switch format {
+	case "ndjson":
+		return writeAuditNDJSON(w, each)
+	case "csv":
+		return writeAuditCSV(w, each)
+	default:
+		return 0, fmt.Errorf("unsupported audit export format %q", format)
+	}
+}
+
+func writeAuditNDJSON(w io.Writer, each func(func(models.AuditEntry) error) error) (int, error) {
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	count := 0
+	err := each(func(entry models.AuditEntry) error {
+		count++
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, buf.Flush()
+}
+
+func writeAuditCSV(w io.Writer, each func(func(models.AuditEntry) error) error) (int, error) {
+	writer := csv.NewWriter(w)
+	writer.Write(auditCSVHeader)
+	count := 0
+	err := each(func(entry models.AuditEntry) error {
+		details := ""
+		if len(entry.Details) > 0 {
+			encoded, err := json.Marshal(entry.Details)
+			if err != nil {
+				return err
+			}
+			details = string(encoded)
+		}
+
+		count++
+		return writer.Write([]string{
+			strconv.Itoa(entry.ID),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.Action,
+			formatInt(entry.ActorID),
+			entry.IP,
+			entry.Target,
+			details,
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	writer.Flush()
+	return count, writer.Error()
+}
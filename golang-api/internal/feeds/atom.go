@@ -0,0 +1,68 @@
+// Package feeds encodes Atom (RFC 4287) feeds
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+const ContentType = "application/atom+xml; charset=utf-8"
+
+// Feed is an Atom feed. ID, Title and Updated are required; entries are
+// listed newest first.
+type Feed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated Time     `xml:"updated"`
+	Author  *Person  `xml:"author,omitempty"`
+	Links   []Link   `xml:"link"`
+	Entries []Entry  `xml:"entry"`
+}
+
+// Entry is an item of the feed
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    Time       `xml:"updated"`
+	Links      []Link     `xml:"link"`
+	Categories []Category `xml:"category"`
+	Summary    string     `xml:"summary,omitempty"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+}
+
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type Category struct {
+	Term string `xml:"term,attr"`
+}
+
+// Time is encoded as an RFC 3339 date-time in UTC
+type Time time.Time
+
+func (t Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(time.Time(t).UTC().Format(time.RFC3339), start)
+}
+
+// Encode returns the feed as an XML document
+func Encode(feed *Feed) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,238 @@
+package googletasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// Google's endpoints, used when the configuration does not override them
+const (
+	DefaultAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+	DefaultAPIURL   = "https://tasks.googleapis.com/tasks/v1"
+)
+
+// Scope grants read and write access to the user's tasks
+const Scope = "https://www.googleapis.com/auth/tasks"
+
+// DefaultList is the user's default task list
+const DefaultList = "@default"
+
+// Task status values
+const (
+	StatusNeedsAction = "needsAction"
+	StatusCompleted   = "completed"
+)
+
+// ErrNotFound is returned when a task no longer exists
+var ErrNotFound = errors.New("task not found")
+
+// Task is a Google Tasks task. Timestamps are RFC 3339; Due only carries
+// a date, at midnight UTC.
+type Task struct {
+	ID        string  `json:"id,omitempty"`
+	Title     string  `json:"title"`
+	Notes     string  `json:"notes"`
+	Status    string  `json:"status"`
+	Due       *string `json:"due"`
+	Completed *string `json:"completed,omitempty"`
+	Updated   string  `json:"updated,omitempty"`
+	Deleted   bool    `json:"deleted,omitempty"`
+	Hidden    bool    `json:"hidden,omitempty"`
+}
+
+// UpdatedAt parses the task's last modification time
+func (t *Task) UpdatedAt() time.Time {
+	updated, _ := time.Parse(time.RFC3339, t.Updated)
+	return updated
+}
+
+// Client talks to the Google Tasks API on behalf of linked users
+type Client struct {
+	oauth  *oauth2.Config
+	apiURL string
+	client *http.Client
+}
+
+func NewClient(cfg config.GoogleTasksConfig) *Client {
+	authURL, tokenURL, apiURL := cfg.AuthURL, cfg.TokenURL, cfg.APIURL
+	if authURL == "" {
+		authURL = DefaultAuthURL
+	}
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+
+	return &Client{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       []string{Scope},
+			Endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		},
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the consent page URL. Offline access with a forced
+// prompt makes Google return a refresh token on every link.
+func (c *Client) AuthCodeURL(state string) string {
+	return c.oauth.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+}
+
+// Exchange trades an authorization code for tokens
+func (c *Client) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.client)
+	token, err := c.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	return token, nil
+}
+
+// Session returns an API session for a user's token. The access token is
+// refreshed as needed; Token returns the current one so it can be stored.
+func (c *Client) Session(ctx context.Context, token *oauth2.Token) *Session {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.client)
+	source := c.oauth.TokenSource(ctx, token)
+	return &Session{
+		source: source,
+		client: oauth2.NewClient(ctx, source),
+		apiURL: c.apiURL,
+	}
+}
+
+// Session makes API calls for one user
+type Session struct {
+	source oauth2.TokenSource
+	client *http.Client
+	apiURL string
+}
+
+// Token returns the current, possibly refreshed, token
+func (s *Session) Token() (*oauth2.Token, error) {
+	return s.source.Token()
+}
+
+// ListTasks returns every task of the list, including completed, hidden
+// and deleted ones
+func (s *Session) ListTasks(ctx context.Context, list string) ([]Task, error) {
+	var tasks []Task
+	pageToken := ""
+	for {
+		query := url.Values{
+			"maxResults":    {"100"},
+			"showCompleted": {"true"},
+			"showHidden":    {"true"},
+			"showDeleted":   {"true"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Items         []Task `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := s.do(ctx, http.MethodGet, s.tasksURL(list, "")+"?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, page.Items...)
+		if page.NextPageToken == "" {
+			return tasks, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// InsertTask creates a task and returns it as stored
+func (s *Session) InsertTask(ctx context.Context, list string, task *Task) (*Task, error) {
+	var created Task
+	if err := s.do(ctx, http.MethodPost, s.tasksURL(list, ""), task, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateTask replaces a task's fields and returns it as stored
+func (s *Session) UpdateTask(ctx context.Context, list string, task *Task) (*Task, error) {
+	var updated Task
+	if err := s.do(ctx, http.MethodPatch, s.tasksURL(list, task.ID), task, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteTask deletes a task; tasks that are already gone are ignored
+func (s *Session) DeleteTask(ctx context.Context, list, id string) error {
+	err := s.do(ctx, http.MethodDelete, s.tasksURL(list, id), nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *Session) tasksURL(list, id string) string {
+	u := s.apiURL + "/lists/" + url.PathEscape(list) + "/tasks"
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
+
+func (s *Session) do(ctx context.Context, method, endpoint string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("google tasks request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("google tasks returned status %d for %s %s", resp.StatusCode, method, req.URL.Path)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode google tasks response: %w", err)
+	}
+	return nil
+}
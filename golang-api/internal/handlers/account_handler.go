@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/jobs"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountHandler struct {
+	service   services.AccountService
+	jobs      *jobs.Manager
+	syncLimit int
+	logger    *slog.Logger
+}
+
+func NewAccountHandler(service services.AccountService, jobManager *jobs.Manager, syncLimit int, logger *slog.Logger) *AccountHandler {
+	return &AccountHandler{
+		service:   service,
+		jobs:      jobManager,
+		syncLimit: syncLimit,
+		logger:    logger,
+	}
+}
+
+// ExportAccount godoc
+// @Summary Export your data
+// @Description Download everything stored about the caller as JSON: profile, linked identities, API keys, hooks, notifications, Google Tasks link, audit log entries and metered API usage. Secrets are left out, and shared todos record no owner, so they are not included. Accounts with more than ACCOUNT_EXPORT_SYNC_LIMIT records, or requests with async=true, are exported by a background job: the response is 202 with the job, and GET /me/export/{id} returns the export as its result once it succeeded.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Param async query bool false "Always export in the background"
+// @Success 200 {object} models.AccountExport
+// @Success 202 {object} models.Job
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/export [get]
+func (h *AccountHandler) ExportAccount(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	async := c.QueryBool("async")
+	if !async {
+		size, err := h.service.ExportSize(c.UserContext(), userID)
+		if err != nil {
+			return h.exportError(c, userID, err)
+		}
+		async = size > h.syncLimit
+	}
+
+	if async {
+		job, err := h.jobs.Enqueue(jobs.TypeAccountExport, jobs.AccountExportPayload{UserID: userID})
+		if err != nil {
+			requestLogger(c, h.logger).Error("Failed to enqueue account export", "user_id", userID, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:     "Failed to start export",
+				Code:      fiber.StatusInternalServerError,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		requestLogger(c, h.logger).Info("Started account export", "user_id", userID, "job_id", job.ID)
+		c.Location(fmt.Sprintf("/api/me/export/%d", job.ID))
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+
+	export, err := h.service.Export(c.UserContext(), userID)
+	if err != nil {
+		return h.exportError(c, userID, err)
+	}
+
+	c.Attachment(fmt.Sprintf("account-%d.json", userID))
+	return c.JSON(export)
+}
+
+func (h *AccountHandler) exportError(c *fiber.Ctx, userID int, err error) error {
+	if errors.Is(err, services.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "User not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to export account", "user_id", userID, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to export account",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+// GetAccountExport godoc
+// @Summary Get a background export of your data
+// @Description Get an account export job started by GET /me/export. Once it succeeded, result holds the export. Only the user who started it can read it.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/export/{id} [get]
+func (h *AccountHandler) GetAccountExport(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid job ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	job, err := h.jobs.GetJob(id)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get job", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get job",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if job == nil || !jobs.IsAccountExportOf(job, userID) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Export not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// DeleteAccount godoc
+// @Summary Delete your account
+// @Description Erase the caller's account in one transaction: identities, API keys, hooks, notifications, the Google Tasks link and metered usage are deleted, the audit log keeps its entries without the email and IP addresses, and shared notes and activity that mention the email name deleted-user-{id} instead. Todos are shared and stay. A tombstone with a hash of the email records the deletion. Access tokens already issued are rejected from then on.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Tombstone
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me [delete]
+func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	tombstone, err := h.service.Delete(c.UserContext(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "User not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to delete account", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to delete account",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	middleware.AccountErased(c)
+	return c.JSON(tombstone)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ActivityHandler struct {
+	service services.ActivityService
+	logger  *slog.Logger
+}
+
+func NewActivityHandler(service services.ActivityService, logger *slog.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListActivity godoc
+// @Summary List activity
+// @Description Get a paginated feed of recent events on todos, newest first
+// @Tags activity
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param type query string false "Comma-separated event types to include, such as todo.created,todo.completed"
+// @Param todo_id query int false "Only events on this todo"
+// @Param since query string false "Only events at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param until query string false "Only events before this time (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Activity}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /activity [get]
+func (h *ActivityHandler) ListActivity(c *fiber.Ctx) error {
+	values := url.Values{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		values.Add(string(key), string(value))
+	})
+	params, err := models.ParseActivityQueryParams(values)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.ListActivity(c.UserContext(), params)
+	if errors.Is(err, services.ErrInvalidFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list activity", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list activity",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(response)
+}
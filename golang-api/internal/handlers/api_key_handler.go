@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	service services.APIKeyService
+	logger  *slog.Logger
+}
+
+func NewAPIKeyHandler(service services.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListKeys godoc
+// @Summary List API keys
+// @Description List the caller's API keys. Secrets are never returned.
+// @Tags keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /keys [get]
+func (h *APIKeyHandler) ListKeys(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	keys, err := h.service.ListKeys(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list API keys", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list API keys",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(keys)
+}
+
+// CreateKey godoc
+// @Summary Create an API key
+// @Description Create a named API key with scopes and an optional expiration. The secret is only returned in this response. Granting the admin scope requires the X-Admin-Token header. Plans may not include API keys or may limit how many unexpired keys a user has (403).
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body models.CreateAPIKeyRequest true "API key data"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /keys [post]
+func (h *APIKeyHandler) CreateKey(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if slices.Contains(req.Scopes, models.ScopeAdmin) && !middleware.CanGrantAdmin(c) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:     "Granting the admin scope requires the admin token",
+			Code:      fiber.StatusForbidden,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.CreateKey(c.UserContext(), userID, req)
+	if errors.Is(err, services.ErrPlanLimit) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusForbidden,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create API key", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetKey godoc
+// @Summary Get an API key
+// @Description Get one of the caller's API keys by ID
+// @Tags keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /keys/{id} [get]
+func (h *APIKeyHandler) GetKey(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid API key ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	key, err := h.service.GetKey(c.UserContext(), userID, id)
+	if err != nil {
+		return h.keyError(c, id, err)
+	}
+
+	return c.JSON(key)
+}
+
+// DeleteKey godoc
+// @Summary Revoke an API key
+// @Description Delete one of the caller's API keys; it stops working immediately
+// @Tags keys
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /keys/{id} [delete]
+func (h *APIKeyHandler) DeleteKey(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid API key ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if err := h.service.DeleteKey(c.UserContext(), userID, id); err != nil {
+		return h.keyError(c, id, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *APIKeyHandler) keyError(c *fiber.Ctx, id int, err error) error {
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to access API key", "id", id, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to access API key",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
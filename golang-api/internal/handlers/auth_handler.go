@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/auth"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateCookie    = "oidc_state"
+	oidcVerifierCookie = "oidc_verifier"
+)
+
+type AuthHandler struct {
+	service      services.UserService
+	oidc         *auth.OIDCProvider
+	secureCookie bool
+	logger       *slog.Logger
+}
+
+// NewAuthHandler creates the auth handler. oidc may be nil when no
+// external identity provider is configured.
+func NewAuthHandler(service services.UserService, oidc *auth.OIDCProvider, secureCookie bool, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		service:      service,
+		oidc:         oidc,
+		secureCookie: secureCookie,
+		logger:       logger,
+	}
+}
+
+// Register godoc
+// @Summary Register a new user
+// @Description Create an account with email and password and return an access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body models.RegisterRequest true "Registration data"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	var req models.RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.Register(c.UserContext(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailTaken) {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusConflict,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		requestLogger(c, h.logger).Error("Failed to register user", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Authenticate with email and password and return an access token. After a failed login the account and client IP must wait before trying again, longer after every further failure, and too many failures lock them out for a while; refused attempts get 429 with Retry-After.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Login credentials"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req models.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.Login(c.UserContext(), req, c.IP())
+	if err != nil {
+		var blocked *services.LoginBlockedError
+		if errors.As(err, &blocked) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Error:     "Too many failed logins, try again later",
+				Code:      fiber.StatusTooManyRequests,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusUnauthorized,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		requestLogger(c, h.logger).Error("Failed to login", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to login",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// Me godoc
+// @Summary Get the current user
+// @Description Get the account of the authenticated caller
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	user, err := h.service.GetUserByID(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get current user", "id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get user",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "User not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(user)
+}
+
+// IssueToken godoc
+// @Summary Issue a scoped access token
+// @Description Exchange the caller's session for an access token limited to scopes, such as todos:read for a read-only dashboard. Granting the admin scope requires the X-Admin-Token header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body models.TokenRequest true "Scopes of the token"
+// @Success 201 {object} models.TokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /auth/token [post]
+func (h *AuthHandler) IssueToken(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	var req models.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if slices.Contains(req.Scopes, models.ScopeAdmin) && !middleware.CanGrantAdmin(c) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:     "Granting the admin scope requires the admin token",
+			Code:      fiber.StatusForbidden,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.IssueScopedToken(c.UserContext(), userID, req)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusUnauthorized,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to issue scoped token", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// OIDCLogin godoc
+// @Summary Start identity provider login
+// @Description Redirect to the configured OpenID Connect provider (authorization code flow with PKCE)
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	if h.oidc == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "OIDC login is not configured",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	state := hex.EncodeToString(buf)
+	verifier := oauth2.GenerateVerifier()
+
+	url, err := h.oidc.AuthCodeURL(c.Context(), state, verifier)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to build OIDC authorization URL", "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:     "Identity provider is unavailable",
+			Code:      fiber.StatusBadGateway,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	for name, value := range map[string]string{oidcStateCookie: state, oidcVerifierCookie: verifier} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     "/api/auth/oidc",
+			Expires:  time.Now().Add(10 * time.Minute),
+			HTTPOnly: true,
+			Secure:   h.secureCookie,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+	}
+
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Complete identity provider login
+// @Description Exchange the authorization code, map the provider subject to a local user and return an access token
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State issued by /auth/oidc/login"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	if h.oidc == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "OIDC login is not configured",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Identity provider returned an error",
+			Code:      fiber.StatusBadRequest,
+			Details:   providerErr,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	state := c.Query("state")
+	expected := c.Cookies(oidcStateCookie)
+	verifier := c.Cookies(oidcVerifierCookie)
+	c.ClearCookie(oidcStateCookie, oidcVerifierCookie)
+	if state == "" || expected == "" || verifier == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expected)) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid OIDC state",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Missing authorization code",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	identity, err := h.oidc.Exchange(c.Context(), code, verifier)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to complete OIDC login", "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:     "Failed to authenticate with identity provider",
+			Code:      fiber.StatusBadGateway,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.LoginWithIdentity(c.UserContext(), identity)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailTaken) {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+				Error:     "An account with this email already exists and the provider did not verify the email",
+				Code:      fiber.StatusConflict,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+		if errors.Is(err, services.ErrPasswordAccount) {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+				Error:     "An account with this email already exists; log in with its password",
+				Code:      fiber.StatusConflict,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		requestLogger(c, h.logger).Error("Failed to login with identity", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to login",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(response)
+}
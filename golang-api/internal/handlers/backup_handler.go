@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Pausable is background work that must not use the database while a
+// restore replaces it
+type Pausable interface {
+	// Pause stops the work and returns a function that resumes it
+	Pause() (resume func())
+}
+
+type BackupHandler struct {
+	db          *database.Database
+	maintenance *middleware.MaintenanceMode
+	background  []Pausable
+	counts      *repository.TodoCounts
+	logger      *slog.Logger
+}
+
+// NewBackupHandler returns the backup and restore handler. A restore
+// pauses background, in order, and drops counts, when not nil, as the
+// restored todos are counted anew.
+func NewBackupHandler(db *database.Database, maintenance *middleware.MaintenanceMode, background []Pausable, counts *repository.TodoCounts, logger *slog.Logger) *BackupHandler {
+	return &BackupHandler{
+		db:          db,
+		maintenance: maintenance,
+		background:  background,
+		counts:      counts,
+		logger:      logger,
+	}
+}
+
+// Backup godoc
+// @Summary Download a database backup
+// @Description Take a consistent snapshot of the SQLite database and stream it to the caller
+// @Tags admin
+// @Produce application/vnd.sqlite3
+// @Success 200 {file} binary
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/backup [post]
+func (h *BackupHandler) Backup(c *fiber.Ctx) error {
+	dir, err := os.MkdirTemp("", "todo-backup-")
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create backup directory", "error", err)
+		return h.failed(c)
+	}
+	// The open file keeps the snapshot readable until it has been streamed
+	defer os.RemoveAll(dir)
+
+	name := "todos-" + time.Now().UTC().Format("20060102T150405Z") + ".db"
+	path := filepath.Join(dir, name)
+	if err := h.db.Backup(c.Context(), path); err != nil {
+		requestLogger(c, h.logger).Error("Failed to back up database", "error", err)
+		return h.failed(c)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to open database backup", "error", err)
+		return h.failed(c)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		requestLogger(c, h.logger).Error("Failed to stat database backup", "error", err)
+		return h.failed(c)
+	}
+
+	requestLogger(c, h.logger).Info("Database backup created", "size", info.Size())
+
+	c.Attachment(name)
+	c.Set(fiber.HeaderContentType, "application/vnd.sqlite3")
+	return c.SendStream(file, int(info.Size()))
+}
+
+// Restore godoc
+// @Summary Restore a database backup
+// @Description Replace the database with an uploaded backup. The backup is validated, the API is put in maintenance mode and background jobs are paused while it is swapped in, and pending migrations are applied. 409 while another restore runs.
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param backup formData file true "Backup file created by POST /admin/backup"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/restore [post]
+func (h *BackupHandler) Restore(c *fiber.Ctx) error {
+	upload, err := c.FormFile("backup")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "A backup file is required in the \"backup\" form field",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	dir, err := os.MkdirTemp("", "todo-restore-")
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create restore directory", "error", err)
+		return h.restoreFailed(c)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "restore.db")
+	if err := c.SaveFile(upload, path); err != nil {
+		requestLogger(c, h.logger).Error("Failed to save uploaded backup", "error", err)
+		return h.restoreFailed(c)
+	}
+
+	if !h.maintenance.TryEnable() {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:     "Another maintenance operation is in progress",
+			Code:      fiber.StatusConflict,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	defer h.maintenance.Disable()
+
+	// Background work resumes on the restored database, in reverse order
+	for _, work := range h.background {
+		defer work.Pause()()
+	}
+	if h.counts != nil {
+		defer h.counts.Invalidate()
+	}
+
+	requestLogger(c, h.logger).Warn("Restoring database from backup", "size", upload.Size)
+	if err := h.db.Restore(c.Context(), path); err != nil {
+		if errors.Is(err, database.ErrInvalidBackup) {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusBadRequest,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+
+		requestLogger(c, h.logger).Error("Failed to restore database", "error", err)
+		return h.restoreFailed(c)
+	}
+
+	requestLogger(c, h.logger).Warn("Database restored from backup", "schema_version", database.SchemaVersion())
+	return c.JSON(models.SuccessResponse{
+		Message: "Database restored",
+		Data:    map[string]interface{}{"schema_version": database.SchemaVersion()},
+	})
+}
+
+func (h *BackupHandler) restoreFailed(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to restore database",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+func (h *BackupHandler) failed(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to back up database",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"net/url"
+	"path"
+
+	"github.com/centroidsol/todo-api/internal/caldav"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CalDAV paths. The root doubles as the principal and the calendar home,
+// and holds a single tasks collection.
+const (
+	davRoot       = "/dav/"
+	davCollection = "/dav/tasks/"
+	davName       = "Todos"
+)
+
+const calendarContentType = "text/calendar; charset=utf-8"
+
+// CalDAVHandler serves todos as a CalDAV tasks collection for clients
+// such as Apple Reminders and Thunderbird. The endpoints speak WebDAV
+// rather than JSON and are not part of the OpenAPI document.
+type CalDAVHandler struct {
+	service services.CalDAVService
+	logger  *slog.Logger
+}
+
+func NewCalDAVHandler(service services.CalDAVService, logger *slog.Logger) *CalDAVHandler {
+	return &CalDAVHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Options advertises CalDAV support
+func (h *CalDAVHandler) Options(c *fiber.Ctx) error {
+	c.Set("DAV", "1, 3, calendar-access")
+	c.Set(fiber.HeaderAllow, "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PropfindRoot describes the principal and calendar home, and with
+// Depth: 1 the tasks collection in it
+func (h *CalDAVHandler) PropfindRoot(c *fiber.Ctx) error {
+	requested, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return davBadRequest(c, err)
+	}
+
+	responses := []caldav.Response{{
+		Href: davRoot,
+		Props: map[xml.Name]string{
+			caldav.PropResourceType:         "<d:collection/>",
+			caldav.PropDisplayName:          davName,
+			caldav.PropCurrentUserPrincipal: caldav.Href(davRoot),
+			caldav.PropPrincipalURL:         caldav.Href(davRoot),
+			caldav.PropCalendarHomeSet:      caldav.Href(davRoot),
+		},
+	}}
+
+	if c.Get("Depth") != "0" {
+		collection, err := h.collection(c)
+		if err != nil {
+			return h.davError(c, err)
+		}
+		responses = append(responses, collection)
+	}
+
+	return multistatus(c, responses, requested)
+}
+
+// PropfindCollection describes the tasks collection, and with Depth: 1
+// every task in it
+func (h *CalDAVHandler) PropfindCollection(c *fiber.Ctx) error {
+	requested, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return davBadRequest(c, err)
+	}
+
+	collection, err := h.collection(c)
+	if err != nil {
+		return h.davError(c, err)
+	}
+	responses := []caldav.Response{collection}
+
+	if c.Get("Depth") != "0" {
+		objects, err := h.service.ListObjects(c.UserContext())
+		if err != nil {
+			return h.davError(c, err)
+		}
+		for i := range objects {
+			responses = append(responses, objectResponse(&objects[i], false))
+		}
+	}
+
+	return multistatus(c, responses, requested)
+}
+
+// PropfindObject describes one task
+func (h *CalDAVHandler) PropfindObject(c *fiber.Ctx) error {
+	requested, err := caldav.ParsePropfind(c.Body())
+	if err != nil {
+		return davBadRequest(c, err)
+	}
+
+	obj, err := h.service.GetObject(c.UserContext(), c.Params("name"))
+	if err != nil {
+		return h.davError(c, err)
+	}
+
+	return multistatus(c, []caldav.Response{objectResponse(obj, false)}, requested)
+}
+
+// Report answers calendar-query, which returns every task, and
+// calendar-multiget, which returns the tasks at the given URLs
+func (h *CalDAVHandler) Report(c *fiber.Ctx) error {
+	report, err := caldav.ParseReport(c.Body())
+	if err != nil {
+		return davBadRequest(c, err)
+	}
+
+	var responses []caldav.Response
+	if report.Multiget {
+		for _, href := range report.Hrefs {
+			name := href
+			if u, err := url.Parse(href); err == nil {
+				name = u.Path
+			}
+			obj, err := h.service.GetObject(c.UserContext(), path.Base(name))
+			if errors.Is(err, services.ErrTodoNotFound) {
+				responses = append(responses, caldav.Response{Href: href})
+				continue
+			}
+			if err != nil {
+				return h.davError(c, err)
+			}
+			responses = append(responses, objectResponse(obj, true))
+		}
+	} else {
+		objects, err := h.service.ListObjects(c.UserContext())
+		if err != nil {
+			return h.davError(c, err)
+		}
+		for i := range objects {
+			responses = append(responses, objectResponse(&objects[i], true))
+		}
+	}
+
+	return multistatus(c, responses, report.Props)
+}
+
+// GetObject returns a task as an iCalendar object
+func (h *CalDAVHandler) GetObject(c *fiber.Ctx) error {
+	obj, err := h.service.GetObject(c.UserContext(), c.Params("name"))
+	if err != nil {
+		return h.davError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, obj.ETag())
+	c.Set(fiber.HeaderContentType, calendarContentType)
+	return c.Send(caldav.Encode(obj.Todo, obj.UID))
+}
+
+// PutObject creates or replaces a task. If-Match and If-None-Match: *
+// make the write conditional.
+func (h *CalDAVHandler) PutObject(c *fiber.Ctx) error {
+	obj, created, err := h.service.PutObject(c.UserContext(), c.Params("name"), c.Body(), c.Get(fiber.HeaderIfMatch), c.Get(fiber.HeaderIfNoneMatch))
+	if err != nil {
+		return h.davError(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, obj.ETag())
+	if created {
+		return c.SendStatus(fiber.StatusCreated)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteObject moves a task's todo to the trash
+func (h *CalDAVHandler) DeleteObject(c *fiber.Ctx) error {
+	if err := h.service.DeleteObject(c.UserContext(), c.Params("name"), c.Get(fiber.HeaderIfMatch)); err != nil {
+		return h.davError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *CalDAVHandler) collection(c *fiber.Ctx) (caldav.Response, error) {
+	ctag, err := h.service.CTag(c.UserContext())
+	if err != nil {
+		return caldav.Response{}, err
+	}
+
+	return caldav.Response{
+		Href: davCollection,
+		Props: map[xml.Name]string{
+			caldav.PropResourceType:                  "<d:collection/><c:calendar/>",
+			caldav.PropDisplayName:                   davName,
+			caldav.PropCurrentUserPrincipal:          caldav.Href(davRoot),
+			caldav.PropSupportedCalendarComponentSet: `<c:comp name="VTODO"/>`,
+			caldav.PropGetCTag:                       caldav.Text(ctag),
+		},
+	}, nil
+}
+
+// objectResponse describes a task; the calendar data is only included in
+// reports
+func objectResponse(obj *models.CalDAVObject, withData bool) caldav.Response {
+	props := map[xml.Name]string{
+		caldav.PropResourceType:   "",
+		caldav.PropGetETag:        caldav.Text(obj.ETag()),
+		caldav.PropGetContentType: calendarContentType + "; component=VTODO",
+	}
+	if withData {
+		props[caldav.PropCalendarData] = caldav.Text(string(caldav.Encode(obj.Todo, obj.UID)))
+	}
+
+	return caldav.Response{Href: davCollection + url.PathEscape(obj.Name), Props: props}
+}
+
+func multistatus(c *fiber.Ctx, responses []caldav.Response, requested []xml.Name) error {
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+	return c.Status(fiber.StatusMultiStatus).Send(caldav.Multistatus(responses, requested))
+}
+
+func davBadRequest(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		Error:     err.Error(),
+		Code:      fiber.StatusBadRequest,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+func (h *CalDAVHandler) davError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrPreconditionFailed):
+		status = fiber.StatusPreconditionFailed
+	case errors.Is(err, services.ErrUIDConflict), errors.Is(err, services.ErrTitleTaken):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrReservedName):
+		status = fiber.StatusForbidden
+	case errors.Is(err, caldav.ErrInvalidCalendarData):
+	default:
+		requestLogger(c, h.logger).Error("CalDAV request failed", "method", c.Method(), "path", c.Path(), "error", err)
+	}
+
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:     err.Error(),
+		Code:      status,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/exports"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportHandler struct {
+	store  exports.Store
+	logger *slog.Logger
+}
+
+func NewExportHandler(store exports.Store, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// ListExports godoc
+// @Summary List scheduled exports
+// @Description List the export files kept in the export directory or S3 bucket, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.ExportFile
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/exports [get]
+func (h *ExportHandler) ListExports(c *fiber.Ctx) error {
+	files, err := h.store.List(c.UserContext())
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list exports", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list exports",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	// Newest first, like the other admin listings
+	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+		files[i], files[j] = files[j], files[i]
+	}
+
+	return c.JSON(files)
+}
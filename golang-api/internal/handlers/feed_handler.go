@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/feeds"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// feedSize is the number of entries in a feed
+const feedSize = 50
+
+type FeedHandler struct {
+	service services.ActivityService
+	appName string
+	logger  *slog.Logger
+}
+
+// NewFeedHandler creates the handler for the Atom feeds; appName titles
+// the feeds
+func NewFeedHandler(service services.ActivityService, appName string, logger *slog.Logger) *FeedHandler {
+	return &FeedHandler{
+		service: service,
+		appName: appName,
+		logger:  logger,
+	}
+}
+
+// TodoFeed godoc
+// @Summary Atom feed of recent todos
+// @Description The latest 50 todos created or completed, newest first, as an Atom feed for feed readers. Readers that cannot send an Authorization header may pass an API key as the token query parameter or as the Basic auth password.
+// @Tags activity
+// @Produce xml
+// @Security BearerAuth
+// @Param token query string false "API key, for readers that cannot send headers"
+// @Success 200 {string} string "Atom feed"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /feeds/todos.atom [get]
+func (h *FeedHandler) TodoFeed(c *fiber.Ctx) error {
+	response, err := h.service.ListActivity(c.UserContext(), models.ActivityQueryParams{
+		Page:    1,
+		PerPage: feedSize,
+		Types:   []string{events.TodoCreated, events.TodoCompleted},
+	})
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to build todo feed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to build feed",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	base := c.BaseURL()
+	self := base + c.Path()
+	feed := &feeds.Feed{
+		ID:      self,
+		Title:   h.appName + " todos",
+		Updated: feeds.Time(time.Now()),
+		Author:  &feeds.Person{Name: h.appName},
+		Links:   []feeds.Link{{Href: self, Rel: "self", Type: "application/atom+xml"}},
+	}
+
+	activity, _ := response.Data.([]models.Activity)
+	for i, entry := range activity {
+		if i == 0 {
+			// The feed changed when its newest entry did
+			feed.Updated = feeds.Time(entry.OccurredAt)
+		}
+		feed.Entries = append(feed.Entries, feedEntry(base, entry))
+	}
+
+	body, err := feeds.Encode(feed)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to encode todo feed", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to build feed",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, feeds.ContentType)
+	return c.Send(body)
+}
+
+// feedEntry describes an event as an entry linking to its todo
+func feedEntry(base string, activity models.Activity) feeds.Entry {
+	title := "Untitled todo"
+	if activity.Title != nil {
+		title = *activity.Title
+	}
+
+	verb := "Created"
+	if activity.Type == events.TodoCompleted {
+		verb = "Completed"
+	}
+
+	entry := feeds.Entry{
+		ID:         fmt.Sprintf("%s/api/activity#%d", base, activity.ID),
+		Title:      verb + ": " + title,
+		Updated:    feeds.Time(activity.OccurredAt),
+		Categories: []feeds.Category{{Term: activity.Type}},
+	}
+	if activity.TodoID != nil {
+		entry.Links = []feeds.Link{{Href: fmt.Sprintf("%s/api/todos/%d", base, *activity.TodoID), Rel: "alternate", Type: "application/json"}}
+	}
+	return entry
+}
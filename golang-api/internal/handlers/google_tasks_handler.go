@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type GoogleTasksHandler struct {
+	service services.GoogleTasksService
+	logger  *slog.Logger
+}
+
+// NewGoogleTasksHandler creates the handler for the Google Tasks
+// integration. service is nil when the integration is not configured.
+func NewGoogleTasksHandler(service services.GoogleTasksService, logger *slog.Logger) *GoogleTasksHandler {
+	return &GoogleTasksHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Configured rejects requests while the integration is not configured
+func (h *GoogleTasksHandler) Configured(c *fiber.Ctx) error {
+	if h.service == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Google Tasks sync is not configured",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	return c.Next()
+}
+
+// GetStatus godoc
+// @Summary Get Google Tasks sync status
+// @Description Whether the caller has linked a Google account, how many todos are synced and the outcome of the last sync
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.GoogleSyncStatus
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /integrations/google [get]
+func (h *GoogleTasksHandler) GetStatus(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	status, err := h.service.Status(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get Google Tasks status", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get sync status",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(status)
+}
+
+// Connect godoc
+// @Summary Link a Google account
+// @Description Start linking the caller's Google account. Send the user to the returned URL; Google redirects back to the callback once they grant access. The caller's plan must include google_tasks.
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.GoogleConnectResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /integrations/google/connect [post]
+func (h *GoogleTasksHandler) Connect(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	url, err := h.service.Connect(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to start linking Google account", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to start linking Google account",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.GoogleConnectResponse{URL: url})
+}
+
+// Callback godoc
+// @Summary Complete linking a Google account
+// @Description Google's redirect after the user granted access. The state identifies the user who started linking.
+// @Tags integrations
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State issued by /integrations/google/connect"
+// @Success 200 {object} models.GoogleSyncStatus
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /integrations/google/callback [get]
+func (h *GoogleTasksHandler) Callback(c *fiber.Ctx) error {
+	if providerErr := c.Query("error"); providerErr != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Google returned an error",
+			Code:      fiber.StatusBadRequest,
+			Details:   providerErr,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Missing state or authorization code",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	status, err := h.service.CompleteConnect(c.UserContext(), state, code)
+	if errors.Is(err, services.ErrInvalidOAuthState) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid or expired state",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to link Google account", "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:     "Failed to link Google account",
+			Code:      fiber.StatusBadGateway,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(status)
+}
+
+// Sync godoc
+// @Summary Sync with Google Tasks now
+// @Description Sync todos with the caller's default Google task list instead of waiting for the next scheduled sync. The caller's plan must include google_tasks.
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.GoogleSyncResult
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /integrations/google/sync [post]
+func (h *GoogleTasksHandler) Sync(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	result, err := h.service.Sync(c.UserContext(), userID)
+	if errors.Is(err, services.ErrGoogleNotLinked) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "No Google account is linked",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:     "Google Tasks sync failed",
+			Code:      fiber.StatusBadGateway,
+			Details:   err.Error(),
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// Disconnect godoc
+// @Summary Unlink the Google account
+// @Description Stop syncing with Google Tasks. Todos and already synced tasks are kept.
+// @Tags integrations
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /integrations/google [delete]
+func (h *GoogleTasksHandler) Disconnect(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	err := h.service.Disconnect(c.UserContext(), userID)
+	if errors.Is(err, services.ErrGoogleNotLinked) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "No Google account is linked",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to unlink Google account", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to unlink Google account",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
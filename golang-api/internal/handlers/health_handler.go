@@ -1,47 +1,68 @@
 package handlers
 
 import (
+	"context"
 	"log/slog"
+	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/centroidsol/todo-api/internal/config"
 	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/health"
+	"github.com/centroidsol/todo-api/internal/middleware"
 	"github.com/centroidsol/todo-api/internal/models"
 	"github.com/gofiber/fiber/v2"
 )
 
 type HealthHandler struct {
-	db     *database.Database
-	cfg    *config.Config
-	logger *slog.Logger
-	start  time.Time
+	db       *database.Database
+	cfg      *config.Config
+	draining *atomic.Bool
+	monitor  *health.Monitor
+	logger   *slog.Logger
+	start    time.Time
 }
 
-func NewHealthHandler(db *database.Database, cfg *config.Config, logger *slog.Logger) *HealthHandler {
-	return &HealthHandler{
-		db:     db,
-		cfg:    cfg,
-		logger: logger,
-		start:  time.Now(),
+func NewHealthHandler(db *database.Database, cfg *config.Config, draining *atomic.Bool, logger *slog.Logger) *HealthHandler {
+	h := &HealthHandler{
+		db:       db,
+		cfg:      cfg,
+		draining: draining,
+		logger:   logger,
+		start:    time.Now(),
 	}
+	h.monitor = health.NewMonitor(h.deepCheck, cfg.Status.CheckInterval, cfg.Status.History)
+	return h
+}
+
+func (h *HealthHandler) deepCheck(ctx context.Context) map[string]models.HealthCheck {
+	return h.db.DeepCheck(ctx, h.cfg.Logging.SlowQueryThreshold, h.cfg.Database.MinFreeDiskPercent)
 }
 
 // Health godoc
 // @Summary Health check
-// @Description Get health status of the API
+// @Description Get health status of the API. With deep=true the database is also written, read and deleted from, timing each query, and the disk space left for the database file is measured; every measurement is reported under checks. The status is degraded when a query is slow or disk is low, and unhealthy, with 503, when a check failed.
 // @Tags health
 // @Accept json
 // @Produce json
+// @Param deep query bool false "Run the write-read-delete probe and check disk space"
 // @Success 200 {object} models.HealthResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.HealthResponse "Deep check that failed"
 // @Router /health [get]
 func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	if c.QueryBool("deep") {
+		return h.deepHealth(c)
+	}
+
 	// Check database connection
 	if err := h.db.Ping(); err != nil {
-		h.logger.Error("Database health check failed", "error", err)
+		requestLogger(c, h.logger).Error("Database health check failed", "error", err)
 		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
-			Error: "Database connection failed",
-			Code:  fiber.StatusServiceUnavailable,
+			Error:     "Database connection failed",
+			Code:      fiber.StatusServiceUnavailable,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
@@ -57,6 +78,39 @@ func (h *HealthHandler) Health(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// deepHealth reports every measurement of a deep check, failed or not, so
+// alerts can tell a slow database from a full disk. The status page
+// records the check too.
+func (h *HealthHandler) deepHealth(c *fiber.Ctx) error {
+	start := time.Now()
+	checks := h.deepCheck(c.UserContext())
+	h.monitor.Record(checks, start)
+
+	response := models.HealthResponse{
+		Status:    models.HealthOK,
+		Timestamp: time.Now(),
+		Version:   h.cfg.App.Version,
+		Uptime:    time.Since(h.start).String(),
+		Checks:    checks,
+	}
+	for name, check := range checks {
+		switch check.Status {
+		case models.HealthFailed:
+			response.Status = models.HealthUnhealthy
+			requestLogger(c, h.logger).Error("Deep health check failed", "check", name, "error", check.Error)
+		case models.HealthWarn:
+			if response.Status == models.HealthOK {
+				response.Status = models.HealthDegraded
+			}
+		}
+	}
+
+	if response.Status == models.HealthUnhealthy {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+	return c.JSON(response)
+}
+
 // Readiness godoc
 // @Summary Readiness check
 // @Description Check if the API is ready to serve requests
@@ -72,11 +126,17 @@ func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
 		"status":   "ready",
 	}
 
+	// Fail readiness while draining so no new traffic is routed here
+	if h.draining != nil && h.draining.Load() {
+		checks["status"] = "shutting down"
+		return c.Status(fiber.StatusServiceUnavailable).JSON(checks)
+	}
+
 	// Check database
 	if err := h.db.Ping(); err != nil {
 		checks["database"] = "failed: " + err.Error()
 		checks["status"] = "not ready"
-		
+
 		return c.Status(fiber.StatusServiceUnavailable).JSON(checks)
 	}
 
@@ -98,6 +158,34 @@ func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
 	})
 }
 
+// Version godoc
+// @Summary Build information
+// @Description Get the version, git commit, build time and Go version of the running binary
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.VersionResponse
+// @Router /version [get]
+func (h *HealthHandler) Version(c *fiber.Ctx) error {
+	return c.JSON(models.VersionResponse{
+		Version:     h.cfg.App.Version,
+		Commit:      h.cfg.App.Commit,
+		BuildTime:   h.cfg.App.BuildTime,
+		GoVersion:   runtime.Version(),
+		Environment: h.cfg.App.Environment,
+	})
+}
+
+// Status godoc
+// @Summary Status page
+// @Description Get the state of every component of the deep health check, and since when it is in it, along with the incidents, newest first: each time a component warned or failed, while open and for STATUS_HISTORY after it was resolved. A deep check runs first when the last one, including those of /health?deep=true, is older than STATUS_CHECK_INTERVAL. The response is 200 whatever the status, for a status page to show.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.StatusResponse
+// @Router /status [get]
+func (h *HealthHandler) Status(c *fiber.Ctx) error {
+	return c.JSON(h.monitor.Status(c.UserContext()))
+}
+
 // DatabaseStats godoc
 // @Summary Get database statistics
 // @Description Get detailed database connection and data statistics
@@ -110,10 +198,11 @@ func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
 func (h *HealthHandler) DatabaseStats(c *fiber.Ctx) error {
 	stats, err := h.db.Stats()
 	if err != nil {
-		h.logger.Error("Failed to get database stats", "error", err)
+		requestLogger(c, h.logger).Error("Failed to get database stats", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "Failed to get database statistics",
-			Code:  fiber.StatusInternalServerError,
+			Error:     "Failed to get database statistics",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
@@ -123,4 +212,4 @@ func (h *HealthHandler) DatabaseStats(c *fiber.Ctx) error {
 	stats["environment"] = h.cfg.App.Environment
 
 	return c.JSON(stats)
-}
\ No newline at end of file
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type HookHandler struct {
+	service services.HookService
+	logger  *slog.Logger
+}
+
+func NewHookHandler(service services.HookService, logger *slog.Logger) *HookHandler {
+	return &HookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListHooks godoc
+// @Summary List REST hooks
+// @Description The caller's hook subscriptions, oldest first
+// @Tags hooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Hook
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /hooks [get]
+func (h *HookHandler) ListHooks(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	hooks, err := h.service.ListHooks(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list hooks", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list hooks",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(hooks)
+}
+
+// Subscribe godoc
+// @Summary Subscribe a REST hook
+// @Description Post every event of the type to target_url as JSON, the event's id identifying redeliveries. A target that answers 410 Gone is unsubscribed. Plans may not include hooks or may limit how many a user has (403).
+// @Tags hooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param hook body models.HookRequest true "Event type and target URL"
+// @Success 201 {object} models.Hook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /hooks [post]
+func (h *HookHandler) Subscribe(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	var req models.HookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	hook, err := h.service.Subscribe(c.UserContext(), userID, req)
+	if errors.Is(err, services.ErrInvalidHook) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrPlanLimit) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusForbidden,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to subscribe hook", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to subscribe hook",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(hook)
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe a REST hook
+// @Description Stop posting events to the hook's target URL
+// @Tags hooks
+// @Security BearerAuth
+// @Param id path int true "Hook ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /hooks/{id} [delete]
+func (h *HookHandler) Unsubscribe(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid hook ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	err = h.service.Unsubscribe(c.UserContext(), userID, id)
+	if errors.Is(err, services.ErrHookNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Hook not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to unsubscribe hook", "user_id", userID, "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to unsubscribe hook",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Samples godoc
+// @Summary Sample hook payloads
+// @Description Payloads a hook for the event type would receive, newest first: the latest events of the type, or a made-up one when there are none yet. Integration tools use them to set up fields without waiting for an event.
+// @Tags hooks
+// @Produce json
+// @Security BearerAuth
+// @Param event path string true "Event type, such as todo.created"
+// @Success 200 {array} object
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /hooks/samples/{event} [get]
+func (h *HookHandler) Samples(c *fiber.Ctx) error {
+	samples, err := h.service.Samples(c.UserContext(), c.Params("event"))
+	if errors.Is(err, services.ErrInvalidHook) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to load sample payloads", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to load sample payloads",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(samples)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/jobs"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobHandler struct {
+	manager *jobs.Manager
+	logger  *slog.Logger
+}
+
+func NewJobHandler(manager *jobs.Manager, logger *slog.Logger) *JobHandler {
+	return &JobHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// ListJobs godoc
+// @Summary List background jobs
+// @Description List persisted background jobs, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status" Enums(pending,running,succeeded,failed)
+// @Param type query string false "Filter by job type"
+// @Success 200 {object} models.PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/jobs [get]
+func (h *JobHandler) ListJobs(c *fiber.Ctx) error {
+	params := models.DefaultJobQueryParams()
+
+	if page := c.QueryInt("page", 1); page > 0 {
+		params.Page = page
+	}
+
+	if perPage := c.QueryInt("per_page", 20); perPage > 0 && perPage <= 100 {
+		params.PerPage = perPage
+	}
+
+	params.Status = c.Query("status")
+	params.Type = c.Query("type")
+
+	response, err := h.manager.ListJobs(params)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list jobs", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// GetJob godoc
+// @Summary Get a background job
+// @Description Get a background job by ID
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/jobs/{id} [get]
+func (h *JobHandler) GetJob(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid job ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	job, err := h.manager.GetJob(id)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get job", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get job",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if job == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Job not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// GetTodoJob godoc
+// @Summary Get the status of a bulk job
+// @Description Get a job started with async=true by a bulk todo operation, such as an import. While it runs, progress shows how many rows are done; once it succeeded, result holds what the synchronous call would have returned.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /jobs/{id} [get]
+func (h *JobHandler) GetTodoJob(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid job ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	job, err := h.manager.GetJob(id)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get job", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get job",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	// Maintenance jobs are only visible through the admin API
+	if job == nil || !jobs.IsTodoJob(job.Type) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Job not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// RetryJob godoc
+// @Summary Retry a failed background job
+// @Description Put a failed job back in the queue with a fresh attempt budget. 409 if the job is not failed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid job ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	job, err := h.manager.Retry(id)
+	if errors.Is(err, jobs.ErrNotFailed) {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusConflict,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to retry job", "id", id, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if job == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Job not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(job)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LinkHandler struct {
+	service services.LinkService
+	logger  *slog.Logger
+}
+
+func NewLinkHandler(service services.LinkService, logger *slog.Logger) *LinkHandler {
+	return &LinkHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListLinks godoc
+// @Summary List the links of a todo
+// @Description List the external references of a todo, oldest first
+// @Tags links
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.Link
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/links [get]
+func (h *LinkHandler) ListLinks(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	links, err := h.service.ListLinks(c.UserContext(), todoID)
+	if err != nil {
+		return h.linkError(c, todoID, err)
+	}
+
+	return c.JSON(links)
+}
+
+// CreateLink godoc
+// @Summary Add a link to a todo
+// @Description Attach a typed http or https URL to a todo. Without a title, the page title is fetched when TODO_FETCH_LINK_TITLES is enabled.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param link body models.LinkRequest true "Link data"
+// @Success 201 {object} models.Link
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/links [post]
+func (h *LinkHandler) CreateLink(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.LinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	link, err := h.service.CreateLink(c.UserContext(), todoID, req)
+	if errors.Is(err, services.ErrTodoNotFound) || errors.Is(err, services.ErrLinkNotFound) || errors.Is(err, services.ErrLinkExists) {
+		return h.linkError(c, todoID, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create link", "todo_id", todoID, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+// UpdateLink godoc
+// @Summary Update a link
+// @Description Replace the type, URL and title of a link
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param linkId path int true "Link ID"
+// @Param link body models.LinkRequest true "Link data"
+// @Success 200 {object} models.Link
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/links/{linkId} [put]
+func (h *LinkHandler) UpdateLink(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	id, err := c.ParamsInt("linkId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid link ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.LinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	link, err := h.service.UpdateLink(c.UserContext(), todoID, id, req)
+	if errors.Is(err, services.ErrTodoNotFound) || errors.Is(err, services.ErrLinkNotFound) || errors.Is(err, services.ErrLinkExists) {
+		return h.linkError(c, todoID, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to update link", "todo_id", todoID, "id", id, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(link)
+}
+
+// DeleteLink godoc
+// @Summary Delete a link
+// @Description Remove a link from a todo
+// @Tags links
+// @Param id path int true "Todo ID"
+// @Param linkId path int true "Link ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/links/{linkId} [delete]
+func (h *LinkHandler) DeleteLink(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	id, err := c.ParamsInt("linkId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid link ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if err := h.service.DeleteLink(c.UserContext(), todoID, id); err != nil {
+		return h.linkError(c, todoID, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *LinkHandler) linkError(c *fiber.Ctx, todoID int, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound), errors.Is(err, services.ErrLinkNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	case errors.Is(err, services.ErrLinkExists):
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusConflict,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to access links", "todo_id", todoID, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to access links",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
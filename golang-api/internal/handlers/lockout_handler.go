@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LockoutHandler struct {
+	guard  services.LoginGuard
+	logger *slog.Logger
+}
+
+func NewLockoutHandler(guard services.LoginGuard, logger *slog.Logger) *LockoutHandler {
+	return &LockoutHandler{
+		guard:  guard,
+		logger: logger,
+	}
+}
+
+// ListLockouts godoc
+// @Summary List login lockouts
+// @Description List the accounts (by email) and client IPs currently locked out after failed logins, soonest unlock first
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.LoginFailures
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/lockouts [get]
+func (h *LockoutHandler) ListLockouts(c *fiber.Ctx) error {
+	locked, err := h.guard.ListLockouts(c.UserContext())
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list lockouts", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list lockouts",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(locked)
+}
+
+// Unlock godoc
+// @Summary Lift a login lockout
+// @Description Unlock an account and/or client IP and forget their failed logins. The unlock is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Param unlock body models.UnlockRequest true "Email and/or IP to unlock"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/lockouts/unlock [post]
+func (h *LockoutHandler) Unlock(c *fiber.Ctx) error {
+	var req models.UnlockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var actorID *int
+	if userID, ok := middleware.UserID(c); ok {
+		actorID = &userID
+	}
+
+	unlocked, err := h.guard.Unlock(c.UserContext(), req, actorID, c.IP())
+	if errors.Is(err, services.ErrUnlockTargetRequired) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to unlock login", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to unlock",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if !unlocked {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "No failed logins recorded for the email or IP",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LogLevelHandler struct {
+	level  *slog.LevelVar
+	logger *slog.Logger
+}
+
+func NewLogLevelHandler(level *slog.LevelVar, logger *slog.Logger) *LogLevelHandler {
+	return &LogLevelHandler{
+		level:  level,
+		logger: logger,
+	}
+}
+
+// GetLevel godoc
+// @Summary Get the log level
+// @Description Get the current runtime log level
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.LogLevel
+// @Failure 401 {object} models.ErrorResponse
+// @Router /admin/log-level [get]
+func (h *LogLevelHandler) GetLevel(c *fiber.Ctx) error {
+	return c.JSON(models.LogLevel{Level: strings.ToLower(h.level.Level().String())})
+}
+
+// SetLevel godoc
+// @Summary Change the log level
+// @Description Change the runtime log level without restarting. The change is not persisted; LOG_LEVEL applies again after a restart.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param level body models.LogLevel true "New level: debug, info, warn or error"
+// @Success 200 {object} models.LogLevel
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /admin/log-level [put]
+func (h *LogLevelHandler) SetLevel(c *fiber.Ctx) error {
+	var req models.LogLevel
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Level must be one of debug, info, warn or error",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	previous := h.level.Level()
+	h.level.Set(level)
+	requestLogger(c, h.logger).Warn("Log level changed", "from", previous.String(), "to", level.String())
+
+	return c.JSON(models.LogLevel{Level: strings.ToLower(level.String())})
+}
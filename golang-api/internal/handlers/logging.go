@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/gofiber/fiber/v2"
+)
+
+// requestLogger returns the logger scoped to the current request, which
+// carries its request ID
+func requestLogger(c *fiber.Ctx, fallback *slog.Logger) *slog.Logger {
+	return logging.FromContext(c.UserContext(), fallback)
+}
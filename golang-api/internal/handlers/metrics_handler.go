@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/centroidsol/todo-api/internal/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MetricsHandler struct {
+	registry *metrics.Registry
+}
+
+func NewMetricsHandler(registry *metrics.Registry) *MetricsHandler {
+	return &MetricsHandler{registry: registry}
+}
+
+// Metrics godoc
+// @Summary Get request metrics
+// @Description Per-route, per-method and per-status latency and payload size histograms in the Prometheus text format
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (h *MetricsHandler) Metrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	_, err := h.registry.WriteTo(c.Response().BodyWriter())
+	return err
+}
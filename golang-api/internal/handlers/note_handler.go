@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NoteHandler struct {
+	service services.NoteService
+	logger  *slog.Logger
+}
+
+func NewNoteHandler(service services.NoteService, logger *slog.Logger) *NoteHandler {
+	return &NoteHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListNotes godoc
+// @Summary List the notes of a todo
+// @Description List the notes attached to a todo, oldest first
+// @Tags notes
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.Note
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/notes [get]
+func (h *NoteHandler) ListNotes(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	notes, err := h.service.ListNotes(c.UserContext(), todoID)
+	if err != nil {
+		return h.noteError(c, todoID, err)
+	}
+
+	return c.JSON(notes)
+}
+
+// CreateNote godoc
+// @Summary Add a note to a todo
+// @Description Attach a Markdown note of at most 100 KiB to a todo
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param note body models.NoteRequest true "Note data"
+// @Success 201 {object} models.Note
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/notes [post]
+func (h *NoteHandler) CreateNote(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.NoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	note, err := h.service.CreateNote(c.UserContext(), todoID, req)
+	if errors.Is(err, services.ErrTodoNotFound) {
+		return h.noteError(c, todoID, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create note", "todo_id", todoID, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(note)
+}
+
+// GetNote godoc
+// @Summary Get a note
+// @Description Get one note of a todo
+// @Tags notes
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param noteId path int true "Note ID"
+// @Success 200 {object} models.Note
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/notes/{noteId} [get]
+func (h *NoteHandler) GetNote(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	id, err := c.ParamsInt("noteId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid note ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	note, err := h.service.GetNote(c.UserContext(), todoID, id)
+	if err != nil {
+		return h.noteError(c, todoID, err)
+	}
+
+	return c.JSON(note)
+}
+
+// UpdateNote godoc
+// @Summary Update a note
+// @Description Replace the body of a note
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param noteId path int true "Note ID"
+// @Param note body models.NoteRequest true "Note data"
+// @Success 200 {object} models.Note
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/notes/{noteId} [put]
+func (h *NoteHandler) UpdateNote(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	id, err := c.ParamsInt("noteId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid note ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.NoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	note, err := h.service.UpdateNote(c.UserContext(), todoID, id, req)
+	if errors.Is(err, services.ErrTodoNotFound) || errors.Is(err, services.ErrNoteNotFound) {
+		return h.noteError(c, todoID, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to update note", "todo_id", todoID, "id", id, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(note)
+}
+
+// DeleteNote godoc
+// @Summary Delete a note
+// @Description Permanently delete a note of a todo
+// @Tags notes
+// @Param id path int true "Todo ID"
+// @Param noteId path int true "Note ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/notes/{noteId} [delete]
+func (h *NoteHandler) DeleteNote(c *fiber.Ctx) error {
+	todoID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	id, err := c.ParamsInt("noteId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid note ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if err := h.service.DeleteNote(c.UserContext(), todoID, id); err != nil {
+		return h.noteError(c, todoID, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *NoteHandler) noteError(c *fiber.Ctx, todoID int, err error) error {
+	if errors.Is(err, services.ErrTodoNotFound) || errors.Is(err, services.ErrNoteNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to access notes", "todo_id", todoID, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to access notes",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandler struct {
+	service services.NotificationService
+	logger  *slog.Logger
+}
+
+func NewNotificationHandler(service services.NotificationService, logger *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListNotifications godoc
+// @Summary List notifications
+// @Description List the caller's notifications, newest first
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} models.PaginatedResponse{data=[]models.Notification}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications [get]
+func (h *NotificationHandler) ListNotifications(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	response, err := h.service.ListNotifications(c.UserContext(), userID, c.QueryBool("unread"), c.QueryInt("page", 1), c.QueryInt("per_page", 20))
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list notifications", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list notifications",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// MarkRead godoc
+// @Summary Mark a notification as read
+// @Description Mark one of the caller's notifications as read. Marking it again keeps the time it was first read.
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid notification ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	notification, err := h.service.MarkRead(c.UserContext(), userID, id)
+	if err != nil {
+		return h.notificationError(c, id, err)
+	}
+
+	return c.JSON(notification)
+}
+
+// MarkAllRead godoc
+// @Summary Mark all notifications as read
+// @Description Mark every unread notification of the caller as read
+// @Tags notifications
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/read [post]
+func (h *NotificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	if _, err := h.service.MarkAllRead(c.UserContext(), userID); err != nil {
+		requestLogger(c, h.logger).Error("Failed to mark notifications as read", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to mark notifications as read",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteNotification godoc
+// @Summary Delete a notification
+// @Description Delete one of the caller's notifications
+// @Tags notifications
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/{id} [delete]
+func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid notification ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if err := h.service.DeleteNotification(c.UserContext(), userID, id); err != nil {
+		return h.notificationError(c, id, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ClearNotifications godoc
+// @Summary Clear notifications
+// @Description Delete all of the caller's notifications, or only the read ones
+// @Tags notifications
+// @Security BearerAuth
+// @Param read query bool false "Only delete notifications that have been read"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications [delete]
+func (h *NotificationHandler) ClearNotifications(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	if _, err := h.service.ClearNotifications(c.UserContext(), userID, c.QueryBool("read")); err != nil {
+		requestLogger(c, h.logger).Error("Failed to clear notifications", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to clear notifications",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *NotificationHandler) notificationError(c *fiber.Ctx, id int, err error) error {
+	if errors.Is(err, services.ErrNotificationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to access notification", "id", id, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to access notification",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PlanHandler struct {
+	service services.PlanService
+	logger  *slog.Logger
+}
+
+func NewPlanHandler(service services.PlanService, logger *slog.Logger) *PlanHandler {
+	return &PlanHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetPlan godoc
+// @Summary Get your plan
+// @Description Get the plan the caller is on: the features it includes and its limits. Zero limits are unlimited.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Plan
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/plan [get]
+func (h *PlanHandler) GetPlan(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	plan, err := h.service.ForUser(c.UserContext(), userID)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get plan", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get plan",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(plan)
+}
+
+// ListPlans godoc
+// @Summary List plans
+// @Description List the configured plans by name. Without PLANS every user is on the unlimited plan.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Plan
+// @Failure 401 {object} models.ErrorResponse
+// @Router /admin/plans [get]
+func (h *PlanHandler) ListPlans(c *fiber.Ctx) error {
+	return c.JSON(h.service.ListPlans(c.UserContext()))
+}
+
+// SetPlan godoc
+// @Summary Move a user to a plan
+// @Description Move a user to one of the configured plans, or back to the default plan with an empty plan. Hooks, API keys and Google accounts the user already has are kept when the new plan does not include them.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param plan body models.SetPlanRequest true "Plan name"
+// @Success 200 {object} models.Plan
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/plan [put]
+func (h *PlanHandler) SetPlan(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid user ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.SetPlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	plan, err := h.service.SetPlan(c.UserContext(), id, req.Plan)
+	if errors.Is(err, services.ErrUnknownPlan) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "User not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to set plan", "user_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to set plan",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(plan)
+}
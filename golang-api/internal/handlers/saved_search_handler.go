@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SavedSearchHandler struct {
+	service services.SavedSearchService
+	logger  *slog.Logger
+}
+
+func NewSavedSearchHandler(service services.SavedSearchService, logger *slog.Logger) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListSavedSearches godoc
+// @Summary List saved searches
+// @Description List the saved searches, ordered by name
+// @Tags saved-searches
+// @Produce json
+// @Success 200 {array} models.SavedSearch
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches [get]
+func (h *SavedSearchHandler) ListSavedSearches(c *fiber.Ctx) error {
+	searches, err := h.service.ListSavedSearches(c.UserContext())
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list saved searches", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list saved searches",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(searches)
+}
+
+// CreateSavedSearch godoc
+// @Summary Create a saved search
+// @Description Save a named list query. The query takes the same parameters as GET /todos, e.g. "completed=false&due=none"; pagination is dropped.
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param search body models.SavedSearchRequest true "Saved search data"
+// @Success 201 {object} models.SavedSearch
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches [post]
+func (h *SavedSearchHandler) CreateSavedSearch(c *fiber.Ctx) error {
+	var req models.SavedSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	search, err := h.service.CreateSavedSearch(c.UserContext(), req)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create saved search", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(search)
+}
+
+// GetSavedSearch godoc
+// @Summary Get a saved search
+// @Description Get a saved search by ID
+// @Tags saved-searches
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} models.SavedSearch
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches/{id} [get]
+func (h *SavedSearchHandler) GetSavedSearch(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid saved search ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	search, err := h.service.GetSavedSearch(c.UserContext(), id)
+	if err != nil {
+		return h.searchError(c, id, err)
+	}
+
+	return c.JSON(search)
+}
+
+// UpdateSavedSearch godoc
+// @Summary Update a saved search
+// @Description Replace the name and query of a saved search
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param search body models.SavedSearchRequest true "Saved search data"
+// @Success 200 {object} models.SavedSearch
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches/{id} [put]
+func (h *SavedSearchHandler) UpdateSavedSearch(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid saved search ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	var req models.SavedSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	search, err := h.service.UpdateSavedSearch(c.UserContext(), id, req)
+	if errors.Is(err, services.ErrSavedSearchNotFound) {
+		return h.searchError(c, id, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to update saved search", "id", id, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(search)
+}
+
+// DeleteSavedSearch godoc
+// @Summary Delete a saved search
+// @Description Delete a saved search; the todos it matched are not affected
+// @Tags saved-searches
+// @Param id path int true "Saved search ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches/{id} [delete]
+func (h *SavedSearchHandler) DeleteSavedSearch(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid saved search ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if err := h.service.DeleteSavedSearch(c.UserContext(), id); err != nil {
+		return h.searchError(c, id, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetSavedSearchTodos godoc
+// @Summary Run a saved search
+// @Description Get the todos matching a saved search, exactly as GET /todos would return them for its query
+// @Tags saved-searches
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.TodoResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /saved-searches/{id}/todos [get]
+func (h *SavedSearchHandler) GetSavedSearchTodos(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid saved search ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	response, err := h.service.RunSavedSearch(c.UserContext(), id, c.QueryInt("page", 1), c.QueryInt("per_page", 20))
+	if errors.Is(err, services.ErrSavedSearchNotFound) {
+		return h.searchError(c, id, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to run saved search", "id", id, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if todos, ok := response.Data.([]models.Todo); ok {
+		response.Data = models.NewTodoResponses(todos)
+	}
+	return c.JSON(response)
+}
+
+func (h *SavedSearchHandler) searchError(c *fiber.Ctx, id int, err error) error {
+	if errors.Is(err, services.ErrSavedSearchNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Error("Failed to access saved search", "id", id, "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error:     "Failed to access saved search",
+		Code:      fiber.StatusInternalServerError,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
@@ -1,81 +1,107 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/centroidsol/todo-api/internal/exports"
+	"github.com/centroidsol/todo-api/internal/jobs"
+	"github.com/centroidsol/todo-api/internal/middleware"
 	"github.com/centroidsol/todo-api/internal/models"
 	"github.com/centroidsol/todo-api/internal/services"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type TodoHandler struct {
-	service services.TodoService
-	logger  *slog.Logger
+	service     services.TodoService
+	jobs        *jobs.Manager
+	writeBehind bool
+	logger      *slog.Logger
 }
 
-func NewTodoHandler(service services.TodoService, logger *slog.Logger) *TodoHandler {
+// NewTodoHandler creates the todo handler. Bulk operations sent with
+// async=true are queued on jobManager, as are creates, updates and
+// deletes with writeBehind.
+func NewTodoHandler(service services.TodoService, jobManager *jobs.Manager, writeBehind bool, logger *slog.Logger) *TodoHandler {
 	return &TodoHandler{
-		service: service,
-		logger:  logger,
+		service:     service,
+		jobs:        jobManager,
+		writeBehind: writeBehind,
+		logger:      logger,
 	}
 }
 
 // GetTodos godoc
 // @Summary Get all todos
-// @Description Get all todo items
+// @Description Get all todo items. Metadata is filtered with metadata.<key>=value, or metadata.<key>!=value to exclude, where <key> is a dotted path into the metadata object.
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(20)
-// @Param sort query string false "Sort field" Enums(id,title,completed,created_at,updated_at) default(created_at)
+// @Param sort query string false "Sort field" Enums(id,title,completed,status,priority,created_at,updated_at,completed_at,due_date,estimate_minutes) default(created_at)
 // @Param order query string false "Sort order" Enums(asc,desc) default(desc)
 // @Param search query string false "Search in title and description"
 // @Param completed query bool false "Filter by completion status"
-// @Success 200 {object} models.PaginatedResponse
+// @Param completed_after query string false "Only todos completed at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param completed_before query string false "Only todos completed before this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_after query string false "Only todos created at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only todos created before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_after query string false "Only todos due at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_before query string false "Only todos due before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due query string false "none for todos without a due date, any for todos with one" Enums(none,any)
+// @Param ids query string false "Comma-separated todo IDs to fetch, at most 100; returned in this order unless sort is given"
+// @Param tag query string false "Only todos with this tag"
+// @Param status query string false "Comma-separated statuses (todo, in_progress, blocked, done) to include"
+// @Param completed! query bool false "Exclude todos with this completion status (completed!=true)"
+// @Param due! query string false "Exclude todos without (none) or with (any) a due date" Enums(none,any)
+// @Param ids! query string false "Comma-separated todo IDs to leave out"
+// @Param search! query string false "Exclude todos whose title or description contains this text"
+// @Param tag! query string false "Exclude todos with this tag"
+// @Param status! query string false "Comma-separated statuses to leave out"
+// @Param color query string false "Comma-separated colors to include: palette names or hex colors"
+// @Param color! query string false "Comma-separated colors to leave out"
+// @Param count_only query bool false "Return only the total, as models.CountResponse"
+// @Param include_total query bool false "Count the matching todos for total and total_pages; false leaves them 0 and relies on has_more" default(true)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.TodoResponse}
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /todos [get]
 func (h *TodoHandler) GetTodos(c *fiber.Ctx) error {
-	// Parse query parameters
-	params := models.DefaultQueryParams()
-
-	if page := c.QueryInt("page", 1); page > 0 {
-		params.Page = page
-	}
-
-	if perPage := c.QueryInt("per_page", 20); perPage > 0 && perPage <= 100 {
-		params.PerPage = perPage
-	}
-
-	if sort := c.Query("sort"); sort != "" {
-		params.Sort = sort
-	}
-
-	if order := c.Query("order"); order != "" {
-		params.Order = order
-	}
-
-	if search := c.Query("search"); search != "" {
-		params.Search = search
+	params, err := parseQueryParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
 	}
 
-	if completedStr := c.Query("completed"); completedStr != "" {
-		if completed, err := strconv.ParseBool(completedStr); err == nil {
-			params.Completed = &completed
-		}
+	if c.QueryBool("count_only") {
+		return h.countTodos(c, params)
 	}
 
-	response, err := h.service.GetTodos(params)
+	response, err := h.service.GetTodos(c.UserContext(), params)
 	if err != nil {
-		h.logger.Error("Failed to get todos", "error", err)
+		requestLogger(c, h.logger).Error("Failed to get todos", "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: err.Error(),
-			Code:  fiber.StatusBadRequest,
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
+	if todos, ok := response.Data.([]models.Todo); ok {
+		response.Data = models.NewTodoResponses(todos)
+	}
 	return c.JSON(response)
 }
 
@@ -86,7 +112,7 @@ func (h *TodoHandler) GetTodos(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
-// @Success 200 {object} models.Todo
+// @Success 200 {object} models.TodoResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -95,38 +121,43 @@ func (h *TodoHandler) GetTodo(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "Invalid todo ID",
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	todo, err := h.service.GetTodoByID(id)
+	todo, err := h.service.GetTodoByID(c.UserContext(), id)
 	if err != nil {
-		h.logger.Error("Failed to get todo", "id", id, "error", err)
+		requestLogger(c, h.logger).Error("Failed to get todo", "id", id, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "Failed to get todo",
-			Code:  fiber.StatusInternalServerError,
+			Error:     "Failed to get todo",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
 	if todo == nil {
 		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-			Error: "Todo not found",
-			Code:  fiber.StatusNotFound,
+			Error:     "Todo not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	return c.JSON(todo)
+	return c.JSON(models.NewTodoResponse(todo))
 }
 
 // CreateTodo godoc
 // @Summary Create a new todo
-// @Description Create a new todo item
+// @Description Create a new todo item. With a client_id UUID the request is idempotent: repeating it returns the existing todo with 200. In write-behind mode (TODO_WRITE_BEHIND) a valid request is queued instead: the response is 202 with the job, whose result is the todo once GET /api/jobs/{id} shows it succeeded.
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param todo body models.CreateTodoRequest true "Todo data"
-// @Success 201 {object} models.Todo
+// @Success 200 {object} models.TodoResponse "Existing todo with the same client_id"
+// @Success 201 {object} models.TodoResponse
+// @Success 202 {object} models.Job "Queued in write-behind mode"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /todos [post]
@@ -134,80 +165,391 @@ func (h *TodoHandler) CreateTodo(c *fiber.Ctx) error {
 	var req models.CreateTodoRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "Invalid request body",
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if h.writeBehind {
+		if err := services.ValidateCreateTodo(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusBadRequest,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+		// A client ID makes the create idempotent, so a job that runs
+		// again after a crash does not create the todo twice
+		if req.ClientID == nil {
+			clientID := uuid.NewString()
+			req.ClientID = &clientID
+		}
+		return h.enqueue(c, jobs.TypeWriteTodo, jobs.WritePayload{Op: jobs.WriteCreate, Create: &req})
+	}
+
+	todo, created, err := h.service.CreateTodo(c.UserContext(), req)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to create todo", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if !created {
+		return c.JSON(models.NewTodoResponse(todo))
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.NewTodoResponse(todo))
+}
+
+// QuickAddTodo godoc
+// @Summary Quick-add a todo
+// @Description Create a todo from one line of text. #word adds a tag and !low, !medium, !high or !urgent sets the priority; a trailing today, tomorrow, weekday, "next week" or YYYY-MM-DD sets the due date (UTC). The rest is the title.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param todo body models.QuickAddRequest true "Quick-add text"
+// @Success 201 {object} models.TodoResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /todos/quick [post]
+func (h *TodoHandler) QuickAddTodo(c *fiber.Ctx) error {
+	var req models.QuickAddRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	todo, err := h.service.QuickAddTodo(c.UserContext(), req.Text)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to quick-add todo", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.NewTodoResponse(todo))
+}
+
+// ImportTodos godoc
+// @Summary Import todos
+// @Description Create todos from a JSON array of create requests or, with Content-Type text/csv, from CSV with a header row (title is required; description, completed, status, priority, due_date, tags, color, icon, estimate_minutes and client_id are optional). The output of todocli export can be imported as is. The report lists every rejected row with its line. In strict mode (the default) any rejected row rolls back the whole import and the report is returned with 422; in partial mode the valid rows are kept. A dry run validates and inserts every row, reports what would have been created, and rolls back. With async=true the import runs as a background job: the response is 202 with the job, whose result is the report once GET /api/jobs/{id} shows it succeeded.
+// @Tags todos
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param mode query string false "strict or partial" Enums(strict, partial) default(strict)
+// @Param dry_run query bool false "Report without storing anything"
+// @Param async query bool false "Run as a background job"
+// @Param todos body []models.CreateTodoRequest true "Todos to import"
+// @Success 200 {object} models.ImportReport
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ImportReport
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/import [post]
+func (h *TodoHandler) ImportTodos(c *fiber.Ctx) error {
+	var rows []models.ImportRow
+	var err error
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+		rows, err = models.ParseImportCSV(bytes.NewReader(c.Body()))
+	} else {
+		rows, err = models.ParseImportJSON(c.Body())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return h.importRows(c, rows)
+}
+
+// ImportMSTodo godoc
+// @Summary Import a Microsoft To Do export
+// @Description Create todos from a Microsoft To Do export: the lists from Microsoft Graph, each with its tasks, as {"value": [...]}, {"lists": [...]} or a bare array. An array of Outlook tasks is accepted as well. Each task is tagged with its list's name (except the default Tasks list) and its categories; its steps are appended to the description as a checklist. Importance high and low map to priority, inProgress and waitingOnOthers to status in_progress and blocked, and completed tasks are imported completed. Importing the same export again does not duplicate todos. mode, dry_run and async work as for /todos/import; rows are numbered in the order tasks appear.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param mode query string false "strict or partial" Enums(strict, partial) default(strict)
+// @Param dry_run query bool false "Report without storing anything"
+// @Param async query bool false "Run as a background job"
+// @Param export body object true "Microsoft To Do export"
+// @Success 200 {object} models.ImportReport
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ImportReport
+// @Failure 500 {object} models.ErrorResponse
+// @Router /import/mstodo [post]
+func (h *TodoHandler) ImportMSTodo(c *fiber.Ctx) error {
+	rows, err := models.ParseImportMSTodo(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return h.importRows(c, rows)
+}
+
+// importRows imports parsed rows with the mode, dry_run and async of the
+// request
+func (h *TodoHandler) importRows(c *fiber.Ctx, rows []models.ImportRow) error {
+	mode, dryRun := c.Query("mode", models.ImportStrict), c.QueryBool("dry_run")
+	if c.QueryBool("async") {
+		if err := services.ValidateImport(rows, mode); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusBadRequest,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+		return h.enqueue(c, jobs.TypeImportTodos, jobs.ImportPayload{Mode: mode, DryRun: dryRun, Rows: rows})
+	}
+
+	report, err := h.service.ImportTodos(c.UserContext(), rows, mode, dryRun)
+	if errors.Is(err, services.ErrInvalidImport) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to import todos", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to import todos",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if report.Mode == models.ImportStrict && report.Rejected > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	return c.JSON(report)
+}
+
+// CreateTodos godoc
+// @Summary Create many todos
+// @Description Create up to 500 todos in one transaction. Each todo is validated like POST /todos and a single invalid todo or taken title creates none. A todo whose client_id is already stored is returned as is. Todos are listed in the order requested.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param request body models.BulkCreateRequest true "Todos to create"
+// @Success 200 {object} models.BulkCreateResponse "Every client_id was already stored"
+// @Success 201 {object} models.BulkCreateResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/bulk [post]
+func (h *TodoHandler) CreateTodos(c *fiber.Ctx) error {
+	var req models.BulkCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	todo, err := h.service.CreateTodo(req)
+	todos, created, err := h.service.CreateTodos(c.UserContext(), req.Todos)
+	if errors.Is(err, services.ErrInvalidBulkRequest) || errors.Is(err, services.ErrTitleTaken) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
 	if err != nil {
-		h.logger.Error("Failed to create todo", "error", err)
+		requestLogger(c, h.logger).Error("Failed to create todos", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to create todos",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	status := fiber.StatusCreated
+	if created == 0 {
+		status = fiber.StatusOK
+	}
+	return c.Status(status).JSON(models.BulkCreateResponse{Created: created, Todos: models.NewTodoResponses(todos)})
+}
+
+// BulkUpdateTags godoc
+// @Summary Add and remove tags on many todos
+// @Description Add the tags in add and remove the tags in remove on every todo in ids, in one transaction. Every ID must be a todo that is not in the trash. The response lists the todos whose tags changed. With async=true the change runs as a background job: the response is 202 with the job, whose result is the response once GET /api/jobs/{id} shows it succeeded.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param request body models.BulkTagRequest true "Todo IDs and tag changes"
+// @Param async query bool false "Run as a background job"
+// @Success 200 {object} models.BulkTagResponse
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/tags [post]
+func (h *TodoHandler) BulkUpdateTags(c *fiber.Ctx) error {
+	var req models.BulkTagRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: err.Error(),
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(todo)
+	if c.QueryBool("async") {
+		if err := services.ValidateBulkTags(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusBadRequest,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+		return h.enqueue(c, jobs.TypeBulkTags, req)
+	}
+
+	todos, err := h.service.BulkUpdateTags(c.UserContext(), req)
+	if errors.Is(err, services.ErrInvalidBulkRequest) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrTodoNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to bulk update tags", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to update tags",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.BulkTagResponse{Updated: len(todos), Todos: models.NewTodoResponses(todos)})
+}
+
+// enqueue queues a background job and responds with 202, pointing to the
+// job's status in the Location header
+func (h *TodoHandler) enqueue(c *fiber.Ctx, jobType string, payload interface{}) error {
+	job, err := h.jobs.Enqueue(jobType, payload)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to enqueue job", "type", jobType, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to start job",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	requestLogger(c, h.logger).Info("Started job", "id", job.ID, "type", jobType)
+	c.Location(fmt.Sprintf("/api/jobs/%d", job.ID))
+	return c.Status(fiber.StatusAccepted).JSON(job)
 }
 
 // UpdateTodo godoc
 // @Summary Update a todo
-// @Description Update an existing todo item
+// @Description Update an existing todo item. Send the version from the last read to make the update conditional; a stale version returns 409 with the current todo. In write-behind mode (TODO_WRITE_BEHIND) a valid request is queued instead: the response is 202 with the job, whose result is the todo once it succeeded, and a missing todo or stale version fails the job.
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
 // @Param todo body models.UpdateTodoRequest true "Todo update data"
-// @Success 200 {object} models.Todo
+// @Success 200 {object} models.TodoResponse
+// @Success 202 {object} models.Job "Queued in write-behind mode"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ConflictResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /todos/{id} [put]
 func (h *TodoHandler) UpdateTodo(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "Invalid todo ID",
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
 	var req models.UpdateTodoRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "Invalid request body",
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid request body",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	todo, err := h.service.UpdateTodo(id, req)
+	if h.writeBehind {
+		if err := services.ValidateUpdateTodo(req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:     err.Error(),
+				Code:      fiber.StatusBadRequest,
+				RequestID: middleware.GetRequestID(c),
+			})
+		}
+		return h.enqueue(c, jobs.TypeWriteTodo, jobs.WritePayload{Op: jobs.WriteUpdate, ID: id, Update: &req})
+	}
+
+	todo, err := h.service.UpdateTodo(c.UserContext(), id, req)
+	var conflict *services.ConflictError
+	if errors.As(err, &conflict) {
+		return c.Status(fiber.StatusConflict).JSON(models.ConflictResponse{
+			Error:     "Todo has been modified since the given version",
+			Code:      fiber.StatusConflict,
+			RequestID: middleware.GetRequestID(c),
+			Current:   models.NewTodoResponse(conflict.Current),
+		})
+	}
 	if err != nil {
-		h.logger.Error("Failed to update todo", "id", id, "error", err)
+		requestLogger(c, h.logger).Error("Failed to update todo", "id", id, "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: err.Error(),
-			Code:  fiber.StatusBadRequest,
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
 	if todo == nil {
 		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-			Error: "Todo not found",
-			Code:  fiber.StatusNotFound,
+			Error:     "Todo not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	return c.JSON(todo)
+	return c.JSON(models.NewTodoResponse(todo))
 }
 
 // DeleteTodo godoc
 // @Summary Delete a todo
-// @Description Delete a todo item
+// @Description Move a todo item to the trash. It is permanently deleted once TRASH_RETENTION_DAYS have passed. In write-behind mode (TODO_WRITE_BEHIND) the delete is queued instead: the response is 202 with the job, which fails if the todo does not exist.
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
 // @Success 204
+// @Success 202 {object} models.Job "Queued in write-behind mode"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -216,31 +558,364 @@ func (h *TodoHandler) DeleteTodo(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "Invalid todo ID",
-			Code:  fiber.StatusBadRequest,
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
-	if err := h.service.DeleteTodo(id); err != nil {
-		h.logger.Error("Failed to delete todo", "id", id, "error", err)
+	if h.writeBehind {
+		return h.enqueue(c, jobs.TypeWriteTodo, jobs.WritePayload{Op: jobs.WriteDelete, ID: id})
+	}
+
+	if err := h.service.DeleteTodo(c.UserContext(), id); err != nil {
+		requestLogger(c, h.logger).Error("Failed to delete todo", "id", id, "error", err)
 
 		// Check if it's a not found error
 		if err.Error() == "todo with id "+strconv.Itoa(id)+" not found" {
 			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-				Error: err.Error(),
-				Code:  fiber.StatusNotFound,
+				Error:     err.Error(),
+				Code:      fiber.StatusNotFound,
+				RequestID: middleware.GetRequestID(c),
 			})
 		}
 
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "Failed to delete todo",
-			Code:  fiber.StatusInternalServerError,
+			Error:     "Failed to delete todo",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// ListTodoRevisions godoc
+// @Summary List todo revisions
+// @Description Get the edit history of a todo, newest first. Revision 1 is the todo as created.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {array} models.TodoRevision
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/revisions [get]
+func (h *TodoHandler) ListTodoRevisions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	revisions, err := h.service.ListTodoRevisions(c.UserContext(), id)
+	if errors.Is(err, services.ErrTodoNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "Todo not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to list todo revisions", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to list todo revisions",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(revisions)
+}
+
+// RevertTodo godoc
+// @Summary Revert a todo
+// @Description Restore the title, description and completion of an earlier revision. The revert is recorded as a new revision. Restoring the completion is refused with 400 when the workflow does not allow the status change, such as completing a blocked todo.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param revision path int true "Revision to restore"
+// @Success 200 {object} models.TodoResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/revert/{revision} [post]
+func (h *TodoHandler) RevertTodo(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	revision, err := c.ParamsInt("revision")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid revision",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	todo, err := h.service.RevertTodo(c.UserContext(), id, revision)
+	if errors.Is(err, services.ErrTitleTaken) || errors.Is(err, services.ErrInvalidTransition) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrTodoNotFound) || errors.Is(err, services.ErrRevisionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to revert todo", "id", id, "revision", revision, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to revert todo",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.NewTodoResponse(todo))
+}
+
+// GetTodoCount godoc
+// @Summary Count todos
+// @Description Get the number of todos matching the filters, without fetching them. Takes the same metadata.<key> filters as the list.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param search query string false "Search in title and description"
+// @Param completed query bool false "Filter by completion status"
+// @Param completed_after query string false "Only todos completed at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param completed_before query string false "Only todos completed before this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_after query string false "Only todos created at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only todos created before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_after query string false "Only todos due at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_before query string false "Only todos due before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due query string false "none for todos without a due date, any for todos with one" Enums(none,any)
+// @Param ids query string false "Comma-separated todo IDs to fetch, at most 100; returned in this order unless sort is given"
+// @Param tag query string false "Only todos with this tag"
+// @Param status query string false "Comma-separated statuses (todo, in_progress, blocked, done) to include"
+// @Param completed! query bool false "Exclude todos with this completion status (completed!=true)"
+// @Param due! query string false "Exclude todos without (none) or with (any) a due date" Enums(none,any)
+// @Param ids! query string false "Comma-separated todo IDs to leave out"
+// @Param search! query string false "Exclude todos whose title or description contains this text"
+// @Param tag! query string false "Exclude todos with this tag"
+// @Param status! query string false "Comma-separated statuses to leave out"
+// @Param color query string false "Comma-separated colors to include: palette names or hex colors"
+// @Param color! query string false "Comma-separated colors to leave out"
+// @Success 200 {object} models.CountResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/count [get]
+func (h *TodoHandler) GetTodoCount(c *fiber.Ctx) error {
+	params, err := parseQueryParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	return h.countTodos(c, params)
+}
+
+func (h *TodoHandler) countTodos(c *fiber.Ctx, params models.QueryParams) error {
+	total, err := h.service.CountTodos(c.UserContext(), params)
+	if errors.Is(err, services.ErrInvalidFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to count todos", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to count todos",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.CountResponse{Total: total})
+}
+
+// todoExportContentTypes are the formats of the todo export
+var todoExportContentTypes = map[string]string{
+	"json": fiber.MIMEApplicationJSONCharsetUTF8,
+	"csv":  "text/csv; charset=utf-8",
+}
+
+// ExportTodos godoc
+// @Summary Export todos
+// @Description Stream every todo matching the list filters, by ID, as a JSON array or as CSV with a header row, in the format of the scheduled exports. Rows are encoded as they are read, so exports of any size use little memory; a failure midway can only cut the export short. CSV exports can be sent to POST /todos/import as they are.
+// @Tags todos
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "Output format" Enums(json,csv) default(json)
+// @Param search query string false "Search in title and description"
+// @Param completed query bool false "Filter by completion status"
+// @Param tag query string false "Only todos with this tag"
+// @Param status query string false "Comma-separated statuses (todo, in_progress, blocked, done) to include"
+// @Success 200 {array} models.Todo
+// @Failure 400 {object} models.ErrorResponse
+// @Router /todos/export [get]
+func (h *TodoHandler) ExportTodos(c *fiber.Ctx) error {
+	params, err := parseQueryParams(c)
+	if err == nil {
+		err = services.ValidateTodoFilters(params)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	format := c.Query("format", "json")
+	contentType, ok := todoExportContentTypes[format]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "format must be json or csv",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	// A sort the repository rejects would only show once streaming began
+	params.Sort, params.Order = "id", "asc"
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Attachment(exports.FileName(time.Now(), format))
+
+	// The todos are written as they are read, after the handler returned,
+	// so a failure can only cut the export short
+	ctx := c.UserContext()
+	logger := requestLogger(c, h.logger)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		each := func(fn func(models.Todo) error) error {
+			return h.service.StreamTodos(ctx, params, fn)
+		}
+		if _, err := exports.Write(w, format, each); err != nil {
+			logger.Error("Failed to export todos", "error", err)
+		}
+	})
+	return nil
+}
+
+// parseQueryParams reads the list filters, pagination and sorting from the
+// query string with models.ParseQueryParams
+func parseQueryParams(c *fiber.Ctx) (models.QueryParams, error) {
+	values := url.Values{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		values.Add(string(key), string(value))
+	})
+	return models.ParseQueryParams(values)
+}
+
+// GetNearbyTodos godoc
+// @Summary Find todos near a location
+// @Description Get the open todos located within radius meters of a point, nearest first, each with its distance in meters
+// @Tags todos
+// @Produce json
+// @Param lat query number true "Latitude in degrees"
+// @Param lng query number true "Longitude in degrees"
+// @Param radius query number false "Radius in meters, at most 100000" default(1000)
+// @Param limit query int false "Most todos to return (1-100)" default(20)
+// @Success 200 {array} models.NearbyTodoResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/nearby [get]
+func (h *TodoHandler) GetNearbyTodos(c *fiber.Ctx) error {
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "lat and lng must be numbers",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	radius, err := strconv.ParseFloat(c.Query("radius", "1000"), 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "radius must be a number",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	todos, err := h.service.GetNearbyTodos(c.UserContext(), models.Location{Lat: lat, Lng: lng}, radius, c.QueryInt("limit", 20))
+	if errors.Is(err, services.ErrInvalidFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get nearby todos", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get nearby todos",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.NewNearbyTodoResponses(todos))
+}
+
+// SearchTodos godoc
+// @Summary Search todos
+// @Description Full-text search over titles and descriptions. Results are ranked by relevance (title matches weigh more) and include highlighted snippets with matches wrapped in <mark> tags. Every word must match, as a prefix.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param q query string true "Search words"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.SearchResultResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/search [get]
+func (h *TodoHandler) SearchTodos(c *fiber.Ctx) error {
+	response, err := h.service.SearchTodos(c.UserContext(), c.Query("q"), c.QueryInt("page", 1), c.QueryInt("per_page", 20))
+	if errors.Is(err, services.ErrEmptySearch) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to search todos", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to search todos",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if results, ok := response.Data.([]models.SearchResult); ok {
+		response.Data = models.NewSearchResultResponses(results)
+	}
+	return c.JSON(response)
+}
+
 // GetTodoStats godoc
 // @Summary Get todo statistics
 // @Description Get statistics about todos (total, completed, pending)
@@ -251,12 +926,66 @@ func (h *TodoHandler) DeleteTodo(c *fiber.Ctx) error {
 // @Failure 500 {object} models.ErrorResponse
 // @Router /todos/stats [get]
 func (h *TodoHandler) GetTodoStats(c *fiber.Ctx) error {
-	stats, err := h.service.GetTodoStats()
+	stats, err := h.service.GetTodoStats(c.UserContext())
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get todo stats", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get statistics",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// GetEstimateStats godoc
+// @Summary Get estimate statistics
+// @Description Get the estimated minutes of the todos created and completed in each recent week (weeks start on Monday, UTC), and the estimate of the open todos
+// @Tags todos
+// @Produce json
+// @Param weeks query int false "Number of weeks to report, the current week included (1-52)" default(8)
+// @Success 200 {object} models.EstimateStats
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/stats/estimates [get]
+func (h *TodoHandler) GetEstimateStats(c *fiber.Ctx) error {
+	stats, err := h.service.GetEstimateStats(c.UserContext(), c.QueryInt("weeks", 8))
+	if errors.Is(err, services.ErrInvalidFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get estimate stats", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get estimate statistics",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// GetTagStats godoc
+// @Summary Get tag statistics
+// @Description Get every tag in use with the number of open and completed todos carrying it, most used first
+// @Tags tags
+// @Produce json
+// @Success 200 {array} models.TagStats
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tags/stats [get]
+func (h *TodoHandler) GetTagStats(c *fiber.Ctx) error {
+	stats, err := h.service.GetTagStats(c.UserContext())
 	if err != nil {
-		h.logger.Error("Failed to get todo stats", "error", err)
+		requestLogger(c, h.logger).Error("Failed to get tag stats", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "Failed to get statistics",
-			Code:  fiber.StatusInternalServerError,
+			Error:     "Failed to get tag statistics",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
 		})
 	}
 
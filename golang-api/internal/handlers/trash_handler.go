@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrashHandler serves the todos that have been deleted but not yet purged
+type TrashHandler struct {
+	service   services.TodoService
+	retention time.Duration
+	logger    *slog.Logger
+}
+
+// NewTrashHandler creates the trash handler. retention is how long the
+// trash keeps a todo, zero meaning forever; it is only used to tell
+// clients when each todo will be purged.
+func NewTrashHandler(service services.TodoService, retention time.Duration, logger *slog.Logger) *TrashHandler {
+	return &TrashHandler{
+		service:   service,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// GetTrash godoc
+// @Summary List deleted todos
+// @Description List the todos in the trash with when each will be permanently deleted. purge_at and purge_in_seconds are omitted when the trash is never emptied.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param sort query string false "Sort field" Enums(id,title,completed,status,created_at,updated_at,completed_at,due_date,deleted_at) default(deleted_at)
+// @Param order query string false "Sort order" Enums(asc,desc) default(desc)
+// @Param search query string false "Search in title and description"
+// @Param completed query bool false "Filter by completion status"
+// @Param completed_after query string false "Only todos completed at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param completed_before query string false "Only todos completed before this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_after query string false "Only todos created at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only todos created before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_after query string false "Only todos due at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param due_before query string false "Only todos due before this time (RFC3339 or YYYY-MM-DD)"
+// @Param due query string false "none for todos without a due date, any for todos with one" Enums(none,any)
+// @Success 200 {object} models.PaginatedResponse{data=[]models.TrashedTodoResponse}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /todos/trash [get]
+func (h *TrashHandler) GetTrash(c *fiber.Ctx) error {
+	params, err := parseQueryParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if c.Query("sort") == "" && len(params.IDs) == 0 {
+		params.Sort = "deleted_at"
+	}
+
+	response, err := h.service.GetTrash(c.UserContext(), params)
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get trash", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	if todos, ok := response.Data.([]models.Todo); ok {
+		response.Data = models.NewTrashedTodoResponses(todos, h.retention, time.Now())
+	}
+	return c.JSON(response)
+}
+
+// RestoreTodo godoc
+// @Summary Restore a deleted todo
+// @Description Take a todo out of the trash and return it
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} models.TodoResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/restore [post]
+func (h *TrashHandler) RestoreTodo(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	todo, err := h.service.RestoreTodo(c.UserContext(), id)
+	if errors.Is(err, services.ErrTitleTaken) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrNotInTrash) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to restore todo", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to restore todo",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(models.NewTodoResponse(todo))
+}
+
+// PurgeTodo godoc
+// @Summary Permanently delete a todo
+// @Description Permanently delete a todo from the trash, with its revisions. Todos that are not in the trash are refused with 409. Requires admin access: the X-Admin-Token header, or a credential with the admin scope.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /todos/{id}/purge [delete]
+func (h *TrashHandler) PurgeTodo(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:     "Invalid todo ID",
+			Code:      fiber.StatusBadRequest,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	err = h.service.PurgeTodo(c.UserContext(), id)
+	if errors.Is(err, services.ErrNotDeleted) {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusConflict,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if errors.Is(err, services.ErrNotInTrash) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     err.Error(),
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to purge todo", "id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to purge todo",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
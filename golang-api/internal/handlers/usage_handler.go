@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsageHandler struct {
+	service services.UsageService
+	logger  *slog.Logger
+}
+
+func NewUsageHandler(service services.UsageService, logger *slog.Logger) *UsageHandler {
+	return &UsageHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetUsage godoc
+// @Summary Get your usage
+// @Description Get the caller's metered API calls per UTC day and credential, from and to included (default: this month so far), and the API keys, hooks, notifications and Google task links they store. Calls made with access tokens have no api_key_id.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day" example(2024-01-01)
+// @Param to query string false "Last day" example(2024-01-31)
+// @Success 200 {object} models.Usage
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/usage [get]
+func (h *UsageHandler) GetUsage(c *fiber.Ctx) error {
+	userID, _ := middleware.UserID(c)
+
+	query, err := usageQuery(c)
+	if err != nil {
+		return h.badRequest(c, err)
+	}
+
+	usage, err := h.service.ForUser(c.UserContext(), userID, query)
+	if errors.Is(err, services.ErrInvalidUsageRange) {
+		return h.badRequest(c, err)
+	}
+	if errors.Is(err, services.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:     "User not found",
+			Code:      fiber.StatusNotFound,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to get usage", "user_id", userID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to get usage",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(usage)
+}
+
+// GetUsageReport godoc
+// @Summary Report usage of all users
+// @Description Total the metered API calls of all users per UTC day range, from and to included (default: this month so far), with the shared todos, notes and saved searches stored, and list the users with the most calls and what each stores.
+// @Tags admin
+// @Produce json
+// @Param from query string false "First day" example(2024-01-01)
+// @Param to query string false "Last day" example(2024-01-31)
+// @Param limit query int false "Users to list" default(100)
+// @Success 200 {object} models.UsageReport
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/usage [get]
+func (h *UsageHandler) GetUsageReport(c *fiber.Ctx) error {
+	query, err := usageQuery(c)
+	if err != nil {
+		return h.badRequest(c, err)
+	}
+
+	report, err := h.service.Report(c.UserContext(), query, c.QueryInt("limit", 100))
+	if errors.Is(err, services.ErrInvalidUsageRange) || errors.Is(err, services.ErrInvalidFilter) {
+		return h.badRequest(c, err)
+	}
+	if err != nil {
+		requestLogger(c, h.logger).Error("Failed to report usage", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:     "Failed to report usage",
+			Code:      fiber.StatusInternalServerError,
+			RequestID: middleware.GetRequestID(c),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+func (h *UsageHandler) badRequest(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		Error:     err.Error(),
+		Code:      fiber.StatusBadRequest,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+// usageQuery reads the from and to query parameters, UTC dates
+func usageQuery(c *fiber.Ctx) (models.UsageQuery, error) {
+	var query models.UsageQuery
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return query, fmt.Errorf("%s must be a date (YYYY-MM-DD)", param.name)
+		}
+		*param.dst = t
+	}
+	return query, nil
+}
@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// maxIncidents bounds how many incidents a Monitor keeps, however many
+// its history would hold
+const maxIncidents = 100
+
+// CheckFunc runs a deep health check
+type CheckFunc func(ctx context.Context) map[string]models.HealthCheck
+
+// Monitor keeps the state of every component from the latest deep health
+// check, and the incidents within its history, for the status page. Any
+// deep check recorded with it counts, not only the ones it runs.
+type Monitor struct {
+	check    CheckFunc
+	interval time.Duration
+	history  time.Duration
+
+	// checking lets one caller run a check while the others wait for it
+	checking sync.Mutex
+
+	mu         sync.Mutex
+	checkedAt  time.Time
+	components map[string]models.ComponentStatus
+	// incidents are oldest first
+	incidents []models.Incident
+}
+
+// NewMonitor returns a Monitor that runs check when the last one is older
+// than interval, and lists resolved incidents for history
+func NewMonitor(check CheckFunc, interval, history time.Duration) *Monitor {
+	return &Monitor{
+		check:      check,
+		interval:   interval,
+		history:    history,
+		components: make(map[string]models.ComponentStatus),
+	}
+}
+
+// Record updates the components with checks started at at. A component
+// that warns or fails opens an incident unless it has one open, and one
+// that is ok again resolves it; a skipped check does neither.
+func (m *Monitor) Record(checks map[string]models.HealthCheck, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A check that finished after a later one is out of date
+	if at.Before(m.checkedAt) {
+		return
+	}
+	m.checkedAt = at
+
+	for name, check := range checks {
+		component, ok := m.components[name]
+		if !ok || component.Status != check.Status {
+			component.Since = at
+		}
+		component.Status, component.Error = check.Status, check.Error
+		m.components[name] = component
+
+		incident := m.openIncident(name)
+		switch check.Status {
+		case models.HealthWarn, models.HealthFailed:
+			if incident == nil {
+				m.incidents = append(m.incidents, models.Incident{Component: name, Status: check.Status, StartedAt: at})
+				incident = &m.incidents[len(m.incidents)-1]
+			}
+			if check.Status == models.HealthFailed {
+				incident.Status = models.HealthFailed
+			}
+			if check.Error != "" {
+				incident.Error = check.Error
+			}
+		case models.HealthOK:
+			if incident != nil {
+				resolved := at
+				incident.ResolvedAt = &resolved
+			}
+		}
+	}
+
+	m.prune(at)
+}
+
+// openIncident returns the unresolved incident of component, or nil
+func (m *Monitor) openIncident(component string) *models.Incident {
+	for i := len(m.incidents) - 1; i >= 0; i-- {
+		if m.incidents[i].Component == component && m.incidents[i].ResolvedAt == nil {
+			return &m.incidents[i]
+		}
+	}
+	return nil
+}
+
+// prune drops the incidents resolved longer than history before now, and
+// the oldest ones past maxIncidents
+func (m *Monitor) prune(now time.Time) {
+	kept := m.incidents[:0]
+	for _, incident := range m.incidents {
+		if incident.ResolvedAt == nil || now.Sub(*incident.ResolvedAt) < m.history {
+			kept = append(kept, incident)
+		}
+	}
+	if len(kept) > maxIncidents {
+		kept = kept[len(kept)-maxIncidents:]
+	}
+	m.incidents = kept
+}
+
+func (m *Monitor) stale() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkedAt.IsZero() || time.Since(m.checkedAt) >= m.interval
+}
+
+// Status returns the state of every component and the incidents, newest
+// first, running a check first when the last one is out of date. It is
+// unhealthy when a component failed and degraded when one warns.
+func (m *Monitor) Status(ctx context.Context) models.StatusResponse {
+	if m.stale() {
+		m.checking.Lock()
+		if m.stale() {
+			at := time.Now()
+			m.Record(m.check(ctx), at)
+		}
+		m.checking.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+
+	response := models.StatusResponse{
+		Status:     models.HealthOK,
+		CheckedAt:  m.checkedAt,
+		Components: make(map[string]models.ComponentStatus, len(m.components)),
+		Incidents:  make([]models.Incident, 0, len(m.incidents)),
+	}
+	for name, component := range m.components {
+		response.Components[name] = component
+		switch component.Status {
+		case models.HealthFailed:
+			response.Status = models.HealthUnhealthy
+		case models.HealthWarn:
+			if response.Status == models.HealthOK {
+				response.Status = models.HealthDegraded
+			}
+		}
+	}
+	for i := len(m.incidents) - 1; i >= 0; i-- {
+		response.Incidents = append(response.Incidents, m.incidents[i])
+	}
+	return response
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// TypeAccountExport exports a user's data. Its result is only readable by
+// that user, through the account export API.
+const TypeAccountExport = "account_export"
+
+// AccountExportPayload is the payload of an account_export job
+type AccountExportPayload struct {
+	UserID int `json:"user_id"`
+}
+
+// AccountExport returns a handler that stores the export of the user in
+// the payload as the result
+func AccountExport(service services.AccountService) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		var payload AccountExportPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("invalid account export payload: %w", err)
+		}
+
+		return service.Export(ctx, payload.UserID)
+	}
+}
+
+// IsAccountExportOf reports whether job exports the data of the user
+func IsAccountExportOf(job *models.Job, userID int) bool {
+	if job.Type != TypeAccountExport {
+		return false
+	}
+
+	var payload AccountExportPayload
+	return json.Unmarshal(job.Payload, &payload) == nil && payload.UserID == userID
+}
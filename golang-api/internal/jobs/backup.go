@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+const TypeDatabaseBackup = "database_backup"
+
+const backupPrefix = "todos-"
+
+// DatabaseBackup returns a handler that writes a timestamped snapshot of
+// the database to the backup directory and keeps only the newest
+// cfg.Retain snapshots
+func DatabaseBackup(db *database.Database, cfg config.BackupConfig) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+
+		name := backupPrefix + time.Now().UTC().Format("20060102T150405Z") + ".db"
+		path := filepath.Join(cfg.Dir, name)
+		if err := db.Backup(ctx, path); err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		pruned, err := pruneBackups(cfg.Dir, cfg.Retain)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"path": path, "size": info.Size(), "pruned": pruned}, nil
+	}
+}
+
+// pruneBackups removes all but the newest retain backups. Names embed a
+// sortable timestamp, so lexical order is chronological.
+func pruneBackups(dir string, retain int) (int, error) {
+	if retain < 1 {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), backupPrefix) && strings.HasSuffix(entry.Name(), ".db") {
+			backups = append(backups, entry.Name())
+		}
+	}
+	sort.Strings(backups)
+
+	pruned := 0
+	for len(backups)-pruned > retain {
+		if err := os.Remove(filepath.Join(dir, backups[pruned])); err != nil {
+			return pruned, fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// Jobs started through the todo API. Unlike the maintenance jobs, their
+// status can be read by API clients.
+const (
+	TypeImportTodos = "import_todos"
+	TypeBulkTags    = "bulk_tags"
+)
+
+// IsTodoJob reports whether jobs of the given type are started through the
+// todo API
+func IsTodoJob(jobType string) bool {
+	return jobType == TypeImportTodos || jobType == TypeBulkTags || jobType == TypeWriteTodo
+}
+
+// ImportPayload is the payload of an import_todos job
+type ImportPayload struct {
+	Mode   string             `json:"mode"`
+	DryRun bool               `json:"dry_run"`
+	Rows   []models.ImportRow `json:"rows"`
+}
+
+// ImportTodos returns a handler that runs an import and stores its report
+// as the result. Rejected rows are part of the report, not a job failure.
+func ImportTodos(service services.TodoService) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		var payload ImportPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("invalid import payload: %w", err)
+		}
+
+		return service.ImportTodos(ctx, payload.Rows, payload.Mode, payload.DryRun)
+	}
+}
+
+// BulkTags returns a handler that applies a models.BulkTagRequest and
+// stores the changed todos as the result
+func BulkTags(service services.TodoService) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		var req models.BulkTagRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid bulk tag payload: %w", err)
+		}
+
+		todos, err := service.BulkUpdateTags(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return models.BulkTagResponse{Updated: len(todos), Todos: models.NewTodoResponses(todos)}, nil
+	}
+}
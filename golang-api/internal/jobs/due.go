@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+const TypeAnnounceDueSoon = "announce_due_soon"
+
+// AnnounceDueSoon returns a handler that emits todo.due_soon for the open
+// todos coming due within the given time
+func AnnounceDueSoon(service services.TodoService, within time.Duration) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		announced, err := service.AnnounceDueSoon(ctx, within)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"announced": announced}, nil
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/exports"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+const TypeScheduledExport = "scheduled_export"
+
+// ScheduledExport returns a handler that exports all todos, outside the
+// trash, in every configured format to store and keeps only the newest
+// cfg.Retain exports of each format
+func ScheduledExport(service services.TodoService, store exports.Store, cfg config.ExportConfig) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		now := time.Now()
+		each := func(fn func(models.Todo) error) error {
+			return service.StreamTodos(ctx, models.QueryParams{Sort: "id", Order: "asc"}, fn)
+		}
+
+		files := make([]string, 0, len(cfg.Formats))
+		todos := 0
+		for _, format := range cfg.Formats {
+			var buf bytes.Buffer
+			count, err := exports.Write(&buf, format, each)
+			if err != nil {
+				return nil, err
+			}
+
+			name := exports.FileName(now, format)
+			if err := store.Put(ctx, name, buf.Bytes()); err != nil {
+				return nil, err
+			}
+			files = append(files, name)
+			todos = count
+		}
+
+		pruned, err := exports.Prune(ctx, store, cfg.Retain)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"files": files, "todos": todos, "pruned": pruned}, nil
+	}
+}
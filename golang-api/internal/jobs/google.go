@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+const TypeSyncGoogleTasks = "sync_google_tasks"
+
+// SyncGoogleTasks returns a handler that syncs every linked Google
+// account. Accounts that fail keep their error in their sync status.
+func SyncGoogleTasks(service services.GoogleTasksService) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		synced, failed, err := service.SyncAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"synced": synced, "failed": failed}, nil
+	}
+}
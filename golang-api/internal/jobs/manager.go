@@ -0,0 +1,387 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// HandlerFunc executes a job. The returned value, if not nil, is stored as
+// the JSON result of the job.
+type HandlerFunc func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// ErrNotFailed is returned by Retry for a job that is not failed
+var ErrNotFailed = errors.New("only failed jobs can be retried")
+
+// permanentError marks a job error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error so the job fails without using up its
+// remaining attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Manager owns the job handlers registry and the worker pool that executes
+// persisted jobs
+type Manager struct {
+	repo     repository.JobRepository
+	cfg      config.JobsConfig
+	logger   *slog.Logger
+	handlers map[string]HandlerFunc
+	// serial lists the types whose jobs run one at a time, in order
+	serial   []string
+	progress map[int]models.JobProgress
+
+	wake    chan struct{}
+	mu      sync.RWMutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+func NewManager(repo repository.JobRepository, cfg config.JobsConfig, logger *slog.Logger) *Manager {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &Manager{
+		repo:     repo,
+		cfg:      cfg,
+		logger:   logger,
+		handlers: make(map[string]HandlerFunc),
+		progress: make(map[int]models.JobProgress),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register associates a handler with a job type
+func (m *Manager) Register(jobType string, handler HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[jobType] = handler
+}
+
+// RegisterSerial associates a handler with a job type whose jobs run one
+// at a time, in the order they were enqueued: a job waits while an older
+// one of its type is running or waiting for a retry
+func (m *Manager) RegisterSerial(jobType string, handler HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[jobType] = handler
+	m.serial = append(m.serial, jobType)
+}
+
+// Enqueue persists a new pending job. The payload is stored as JSON.
+func (m *Manager) Enqueue(jobType string, payload interface{}) (*models.Job, error) {
+	m.mu.RLock()
+	_, ok := m.handlers[jobType]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job type: %s", jobType)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		MaxAttempts: m.cfg.MaxAttempts,
+		RunAt:       time.Now(),
+	}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %w", err)
+		}
+		job.Payload = data
+	}
+
+	if err := m.repo.Create(job); err != nil {
+		m.logger.Error("Failed to enqueue job", "type", jobType, "error", err)
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	m.logger.Info("Enqueued job", "id", job.ID, "type", jobType)
+	m.notify()
+	return job, nil
+}
+
+// GetJob returns a job by ID, or nil if it does not exist. Running jobs
+// that report progress, and that run in this process, include it.
+func (m *Manager) GetJob(id int) (*models.Job, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("invalid job ID: %d", id)
+	}
+
+	job, err := m.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if job != nil && job.Status == models.JobStatusRunning {
+		m.mu.RLock()
+		if progress, ok := m.progress[id]; ok {
+			job.Progress = &progress
+		}
+		m.mu.RUnlock()
+	}
+
+	return job, nil
+}
+
+// ListJobs returns a page of jobs, newest first
+func (m *Manager) ListJobs(params models.JobQueryParams) (*models.PaginatedResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PerPage < 1 || params.PerPage > 100 {
+		params.PerPage = 20
+	}
+
+	switch params.Status {
+	case "", models.JobStatusPending, models.JobStatusRunning, models.JobStatusSucceeded, models.JobStatusFailed:
+	default:
+		return nil, fmt.Errorf("invalid job status: %s", params.Status)
+	}
+
+	jobs, total, err := m.repo.List(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Data:       jobs,
+		Total:      total,
+		Page:       params.Page,
+		PerPage:    params.PerPage,
+		TotalPages: (total + params.PerPage - 1) / params.PerPage,
+		HasMore:    params.Page*params.PerPage < total,
+	}, nil
+}
+
+// Retry puts a failed job back in the queue. Returns nil if the job does
+// not exist, and ErrNotFailed if it is not failed.
+func (m *Manager) Retry(id int) (*models.Job, error) {
+	job, err := m.GetJob(id)
+	if err != nil || job == nil {
+		return nil, err
+	}
+
+	if job.Status != models.JobStatusFailed {
+		return nil, fmt.Errorf("%w, job %d is %s", ErrNotFailed, id, job.Status)
+	}
+
+	job, err = m.repo.Retry(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	if job == nil {
+		// Another retry or a worker got to it first
+		return nil, fmt.Errorf("%w, job %d has changed", ErrNotFailed, id)
+	}
+
+	m.logger.Info("Job scheduled for retry", "id", id)
+	m.notify()
+	return job, nil
+}
+
+// Start launches the worker pool. Jobs left running by a previous process
+// are requeued first.
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return
+	}
+	m.started = true
+
+	if requeued, err := m.repo.RequeueRunning(); err != nil {
+		m.logger.Error("Failed to requeue interrupted jobs", "error", err)
+	} else if requeued > 0 {
+		m.logger.Warn("Requeued interrupted jobs", "count", requeued)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker(ctx, i+1)
+	}
+
+	m.logger.Info("Job workers started", "workers", m.cfg.Workers)
+}
+
+// Stop signals the workers to exit and waits for in-flight jobs to
+// finish. The workers can be started again afterwards.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.started || m.cancel == nil {
+		m.mu.Unlock()
+		return
+	}
+	m.cancel()
+	m.cancel = nil
+	m.started = false
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	m.logger.Info("Job workers stopped")
+}
+
+// Pause stops the workers like Stop and returns a function that starts
+// them again, if they were running
+func (m *Manager) Pause() (resume func()) {
+	m.mu.RLock()
+	running := m.started
+	m.mu.RUnlock()
+	if !running {
+		return func() {}
+	}
+
+	m.Stop()
+	return m.Start
+}
+
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) worker(ctx context.Context, n int) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going back to sleep
+		for ctx.Err() == nil {
+			m.mu.RLock()
+			serial := m.serial
+			m.mu.RUnlock()
+
+			job, err := m.repo.ClaimNext(time.Now(), serial)
+			if err != nil {
+				m.logger.Error("Failed to claim job", "worker", n, "error", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			m.execute(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) execute(ctx context.Context, job *models.Job) {
+	m.mu.RLock()
+	handler, ok := m.handlers[job.Type]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.fail(job, fmt.Errorf("no handler registered for job type %s", job.Type))
+		return
+	}
+
+	m.logger.Info("Running job", "id", job.ID, "type", job.Type, "attempt", job.Attempts)
+	start := time.Now()
+
+	// Progress is kept in memory: the job's own transaction may hold the
+	// database until it finishes
+	ctx = services.WithProgress(ctx, func(done, total int) {
+		m.mu.Lock()
+		m.progress[job.ID] = models.JobProgress{Done: done, Total: total}
+		m.mu.Unlock()
+	})
+	defer func() {
+		m.mu.Lock()
+		delete(m.progress, job.ID)
+		m.mu.Unlock()
+	}()
+
+	result, err := m.run(ctx, handler, job)
+	if err != nil {
+		m.fail(job, err)
+		return
+	}
+
+	var data []byte
+	if result != nil {
+		data, err = json.Marshal(result)
+		if err != nil {
+			m.fail(job, fmt.Errorf("failed to encode job result: %w", err))
+			return
+		}
+	}
+
+	if err := m.repo.MarkSucceeded(job.ID, data); err != nil {
+		m.logger.Error("Failed to record job success", "id", job.ID, "error", err)
+		return
+	}
+
+	m.logger.Info("Job succeeded", "id", job.ID, "type", job.Type, "duration", time.Since(start).String())
+}
+
+func (m *Manager) run(ctx context.Context, handler HandlerFunc, job *models.Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+func (m *Manager) fail(job *models.Job, jobErr error) {
+	var retryAt *time.Time
+	var permanent *permanentError
+	if job.Attempts < job.MaxAttempts && !errors.As(jobErr, &permanent) {
+		// Exponential backoff: base, 2*base, 4*base, ...
+		next := time.Now().Add(m.cfg.RetryBackoff * time.Duration(1<<(job.Attempts-1)))
+		retryAt = &next
+	}
+
+	if err := m.repo.MarkFailed(job.ID, jobErr.Error(), retryAt); err != nil {
+		m.logger.Error("Failed to record job failure", "id", job.ID, "error", err)
+		return
+	}
+
+	if retryAt != nil {
+		m.logger.Warn("Job failed, will retry", "id", job.ID, "type", job.Type, "attempt", job.Attempts, "retry_at", *retryAt, "error", jobErr)
+		return
+	}
+
+	m.logger.Error("Job failed permanently", "id", job.ID, "type", job.Type, "attempts", job.Attempts, "error", jobErr)
+}
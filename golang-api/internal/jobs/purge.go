@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+const (
+	TypePurgeCompletedTodos = "purge_completed_todos"
+	TypePurgeTrash          = "purge_trash"
+)
+
+// PurgePayload is the payload of a purge_completed_todos or purge_trash
+// job
+type PurgePayload struct {
+	RetentionDays int `json:"retention_days"`
+}
+
+// PurgeCompletedTodos returns a handler that deletes completed todos older
+// than the retention given in the payload, falling back to defaultDays
+func PurgeCompletedTodos(service services.TodoService, audit services.AuditService, defaultDays int) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		payload := PurgePayload{RetentionDays: defaultDays}
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid purge payload: %w", err)
+			}
+		}
+
+		retention := time.Duration(payload.RetentionDays) * 24 * time.Hour
+		purged, err := service.PurgeCompletedTodos(ctx, retention)
+		if err != nil {
+			return nil, err
+		}
+		recordPurge(ctx, audit, job, "completed", payload.RetentionDays, purged)
+
+		return map[string]interface{}{"purged": purged}, nil
+	}
+}
+
+// PurgeTrash returns a handler that permanently deletes the todos that
+// have been in the trash for longer than the retention given in the
+// payload, falling back to defaultDays
+func PurgeTrash(service services.TodoService, audit services.AuditService, defaultDays int) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		payload := PurgePayload{RetentionDays: defaultDays}
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid purge payload: %w", err)
+			}
+		}
+
+		retention := time.Duration(payload.RetentionDays) * 24 * time.Hour
+		purged, err := service.PurgeTrash(ctx, retention)
+		if err != nil {
+			return nil, err
+		}
+		recordPurge(ctx, audit, job, "trash", payload.RetentionDays, purged)
+
+		return map[string]interface{}{"purged": purged}, nil
+	}
+}
+
+// recordPurge puts a purge that deleted todos in the audit log
+func recordPurge(ctx context.Context, audit services.AuditService, job *models.Job, target string, retentionDays int, purged int64) {
+	if purged == 0 {
+		return
+	}
+
+	audit.Record(ctx, models.AuditEntry{
+		Action: models.AuditPurge,
+		Target: target,
+		Details: map[string]interface{}{
+			"job_id":         job.ID,
+			"retention_days": retentionDays,
+			"purged":         purged,
+		},
+	})
+}
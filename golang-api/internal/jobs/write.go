@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/services"
+)
+
+// TypeWriteTodo applies a todo write accepted in write-behind mode
+const TypeWriteTodo = "write_todo"
+
+// The writes a write_todo job applies
+const (
+	WriteCreate = "create"
+	WriteUpdate = "update"
+	WriteDelete = "delete"
+)
+
+// WritePayload is the payload of a write_todo job: Create for a create,
+// ID and Update for an update, ID for a delete
+type WritePayload struct {
+	Op     string                    `json:"op"`
+	ID     int                       `json:"id,omitempty"`
+	Create *models.CreateTodoRequest `json:"create,omitempty"`
+	Update *models.UpdateTodoRequest `json:"update,omitempty"`
+}
+
+// WriteTodo returns a handler that applies a write and stores the todo it
+// created or updated as the result. Register it with RegisterSerial, so
+// writes apply in the order they were accepted. A write that would fail
+// again however often it ran, such as an invalid request, a title clash,
+// a forbidden status change, a write to a todo that does not exist or an
+// update of a todo that has moved past the version it names, fails
+// without retrying, so it does not hold up the writes queued after it.
+func WriteTodo(service services.TodoService) HandlerFunc {
+	return func(ctx context.Context, job *models.Job) (interface{}, error) {
+		var payload WritePayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, Permanent(fmt.Errorf("invalid write payload: %w", err))
+		}
+		if err := validateWrite(payload); err != nil {
+			return nil, Permanent(err)
+		}
+
+		switch payload.Op {
+		case WriteCreate:
+			todo, _, err := service.CreateTodo(ctx, *payload.Create)
+			if err != nil {
+				return nil, permanentWrite(err)
+			}
+			return models.NewTodoResponse(todo), nil
+		case WriteUpdate:
+			todo, err := service.UpdateTodo(ctx, payload.ID, *payload.Update)
+			if err != nil {
+				return nil, permanentWrite(err)
+			}
+			if todo == nil {
+				return nil, Permanent(fmt.Errorf("%w: %d", services.ErrTodoNotFound, payload.ID))
+			}
+			return models.NewTodoResponse(todo), nil
+		default:
+			todo, err := service.GetTodoByID(ctx, payload.ID)
+			if err != nil {
+				return nil, err
+			}
+			if todo == nil {
+				return nil, Permanent(fmt.Errorf("%w: %d", services.ErrTodoNotFound, payload.ID))
+			}
+			return nil, permanentWrite(service.DeleteTodo(ctx, payload.ID))
+		}
+	}
+}
+
+// validateWrite checks a write the way the service will, so that a
+// request that can never be applied is not retried
+func validateWrite(payload WritePayload) error {
+	switch {
+	case payload.Op == WriteCreate && payload.Create != nil:
+		return services.ValidateCreateTodo(*payload.Create)
+	case payload.Op == WriteUpdate && payload.Update != nil:
+		if payload.ID <= 0 {
+			return fmt.Errorf("invalid todo ID: %d", payload.ID)
+		}
+		return services.ValidateUpdateTodo(*payload.Update)
+	case payload.Op == WriteDelete:
+		if payload.ID <= 0 {
+			return fmt.Errorf("invalid todo ID: %d", payload.ID)
+		}
+		return nil
+	case payload.Op == WriteCreate || payload.Op == WriteUpdate:
+		return fmt.Errorf("invalid write payload: %s without a request", payload.Op)
+	default:
+		return fmt.Errorf("invalid write payload: unknown op %q", payload.Op)
+	}
+}
+
+// permanentWrite marks the service errors that depend on the request and
+// the stored todos rather than on the moment, leaving the others, such
+// as a locked database, to be retried
+func permanentWrite(err error) error {
+	var conflict *services.ConflictError
+	if errors.As(err, &conflict) ||
+		errors.Is(err, services.ErrTodoNotFound) ||
+		errors.Is(err, services.ErrTitleTaken) ||
+		errors.Is(err, services.ErrInvalidTransition) ||
+		errors.Is(err, services.ErrMetadataTooLarge) {
+		return Permanent(err)
+	}
+	return err
+}
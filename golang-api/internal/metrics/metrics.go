@@ -0,0 +1,193 @@
+// Package metrics records HTTP request and database query metrics and
+// exports them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bucket upper bounds for the latency (seconds) and size (bytes)
+// histograms
+var (
+	LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	SizeBuckets    = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+	// QueryBuckets are finer than LatencyBuckets, since most queries take
+	// well under a millisecond
+	QueryBuckets = []float64{0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1}
+)
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type seriesKey struct {
+	method string
+	route  string
+	status string
+}
+
+type series struct {
+	latency      *histogram
+	requestSize  *histogram
+	responseSize *histogram
+}
+
+type queryKey struct {
+	repository string
+	operation  string
+	outcome    string
+}
+
+// Registry aggregates request metrics per method, route pattern and
+// status code. Routes are the registered patterns (/api/todos/:id), not
+// raw paths, so the number of series stays bounded. It also aggregates
+// query durations per repository, operation and outcome. It is safe for
+// concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	series  map[seriesKey]*series
+	queries map[queryKey]*histogram
+}
+
+func NewRegistry() *Registry {
+	return &Registry{series: make(map[seriesKey]*series), queries: make(map[queryKey]*histogram)}
+}
+
+// Observe records one completed request
+func (r *Registry) Observe(method, route string, status int, duration time.Duration, requestSize, responseSize int) {
+	key := seriesKey{method: method, route: route, status: strconv.Itoa(status)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.series[key]
+	if !ok {
+		s = &series{
+			latency:      newHistogram(LatencyBuckets),
+			requestSize:  newHistogram(SizeBuckets),
+			responseSize: newHistogram(SizeBuckets),
+		}
+		r.series[key] = s
+	}
+
+	s.latency.observe(duration.Seconds())
+	s.requestSize.observe(float64(requestSize))
+	s.responseSize.observe(float64(responseSize))
+}
+
+// ObserveQuery records one repository operation, which failed when err
+// is not nil
+func (r *Registry) ObserveQuery(repository, operation string, duration time.Duration, err error) {
+	key := queryKey{repository: repository, operation: operation, outcome: "ok"}
+	if err != nil {
+		key.outcome = "error"
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.queries[key]
+	if !ok {
+		h = newHistogram(QueryBuckets)
+		r.queries[key] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// WriteTo writes every histogram in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, r.render())
+	return int64(n), err
+}
+
+func (r *Registry) render() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]seriesKey, 0, len(r.series))
+	for key := range r.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	var out strings.Builder
+	families := []struct {
+		name, help string
+		get        func(*series) *histogram
+	}{
+		{"http_request_duration_seconds", "HTTP request latency in seconds.", func(s *series) *histogram { return s.latency }},
+		{"http_request_size_bytes", "HTTP request body size in bytes.", func(s *series) *histogram { return s.requestSize }},
+		{"http_response_size_bytes", "HTTP response body size in bytes.", func(s *series) *histogram { return s.responseSize }},
+	}
+	for _, family := range families {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s histogram\n", family.name, family.help, family.name)
+		for _, key := range keys {
+			labels := fmt.Sprintf(`method=%q,route=%q,status=%q`, key.method, key.route, key.status)
+			writeHistogram(&out, family.name, labels, family.get(r.series[key]))
+		}
+	}
+
+	queries := make([]queryKey, 0, len(r.queries))
+	for key := range r.queries {
+		queries = append(queries, key)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		a, b := queries[i], queries[j]
+		if a.repository != b.repository {
+			return a.repository < b.repository
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.outcome < b.outcome
+	})
+
+	const queryFamily = "db_query_duration_seconds"
+	fmt.Fprintf(&out, "# HELP %s Repository operation latency in seconds.\n# TYPE %s histogram\n", queryFamily, queryFamily)
+	for _, key := range queries {
+		labels := fmt.Sprintf(`repository=%q,operation=%q,outcome=%q`, key.repository, key.operation, key.outcome)
+		writeHistogram(&out, queryFamily, labels, r.queries[key])
+	}
+	return out.String()
+}
+
+func writeHistogram(out *strings.Builder, name, labels string, h *histogram) {
+	for i, upper := range h.buckets {
+		fmt.Fprintf(out, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(upper, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(out, "%s_count{%s} %d\n", name, labels, h.count)
+}
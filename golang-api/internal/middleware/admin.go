@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"slices"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const adminGrantKey = "adminGrant"
+
+// AdminAuth protects admin routes with the static ADMIN_TOKEN, sent as
+// "X-Admin-Token", or an API key or access token granted the admin scope.
+// When no token is configured the admin API is only open in development
+// and test, and never in demo mode.
+func AdminAuth(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if scopes, ok := Scopes(c); ok && slices.Contains(scopes, models.ScopeAdmin) {
+			return c.Next()
+		}
+
+		if status, message := checkAdminToken(cfg, c); status != 0 {
+			return c.Status(status).JSON(models.ErrorResponse{
+				Error:     message,
+				Code:      status,
+				RequestID: GetRequestID(c),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// AllowAdminGrant records whether the request carries the admin token,
+// which it needs to grant the admin scope. Without a configured token
+// nobody can grant it. Read the result with CanGrantAdmin.
+func AllowAdminGrant(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		status, _ := checkAdminToken(cfg, c)
+		c.Locals(adminGrantKey, cfg.Admin.Token != "" && status == 0)
+		return c.Next()
+	}
+}
+
+// CanGrantAdmin reports whether the request may grant the admin scope
+func CanGrantAdmin(c *fiber.Ctx) bool {
+	allowed, _ := c.Locals(adminGrantKey).(bool)
+	return allowed
+}
+
+// checkAdminToken returns the status and error message rejecting the
+// request's admin token, or 0 when it is let in
+func checkAdminToken(cfg *config.Config, c *fiber.Ctx) (int, string) {
+	if cfg.Admin.Token == "" {
+		if (cfg.IsDevelopment() || cfg.IsTest()) && !cfg.Demo.Enabled {
+			return 0, ""
+		}
+		return fiber.StatusForbidden, "Admin API is disabled"
+	}
+
+	token := c.Get("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Admin.Token)) != 1 {
+		return fiber.StatusUnauthorized, "Invalid admin token"
+	}
+
+	return 0, ""
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditRecorder appends entries to the audit log
+type AuditRecorder interface {
+	Record(ctx context.Context, entry models.AuditEntry)
+}
+
+// AuditRequests records every request that may change something, that is
+// anything but GET and HEAD, in the audit log once it was handled, with
+// its route and response status
+func AuditRequests(audit AuditRecorder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		var actorID *int
+		if userID, ok := UserID(c); ok {
+			actorID = &userID
+		}
+
+		audit.Record(c.UserContext(), models.AuditEntry{
+			Action:  models.AuditAdminRequest,
+			ActorID: actorID,
+			IP:      c.IP(),
+			Target:  c.Path(),
+			Details: map[string]interface{}{
+				"method": c.Method(),
+				"route":  c.Route().Path,
+				"status": status,
+			},
+		})
+
+		return err
+	}
+}
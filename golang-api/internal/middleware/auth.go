@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/auth"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	userIDKey = "userID"
+	apiKeyKey = "apiKey"
+	scopesKey = "scopes"
+)
+
+// APIKeyAuthenticator resolves an API key secret to the stored key
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// UserFinder loads a user by ID, returning nil when there is none
+type UserFinder interface {
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+}
+
+// Authenticate resolves the caller from an "Authorization: Bearer <token>"
+// header carrying either an access token or an API key. Clients that only
+// speak Basic auth, such as CalDAV clients, may send an API key as the
+// password instead; the user name is ignored. Requests without
+// credentials continue anonymously; requests with invalid credentials are
+// rejected, as are access tokens of a user that has since been deleted.
+func Authenticate(tokens *auth.TokenManager, keys APIKeyAuthenticator, users UserFinder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if header == "" {
+			return c.Next()
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Basic") {
+			token = basicPassword(token)
+			if !auth.IsAPIKey(token) {
+				return unauthorized(c, "Basic credentials must use an API key as the password")
+			}
+		} else if !ok || !strings.EqualFold(scheme, "Bearer") {
+			token = ""
+		}
+		if token == "" {
+			return unauthorized(c, "Invalid authorization header")
+		}
+
+		if auth.IsAPIKey(token) {
+			key, err := keys.Authenticate(c.UserContext(), token)
+			if err != nil {
+				return unauthorized(c, "Invalid or expired API key")
+			}
+
+			c.Locals(userIDKey, key.UserID)
+			c.Locals(apiKeyKey, key)
+			c.Locals(scopesKey, key.Scopes)
+			return c.Next()
+		}
+
+		claims, err := tokens.Parse(token)
+		if err != nil {
+			return unauthorized(c, err.Error())
+		}
+
+		// Tokens outlive the account they were issued for; user IDs are
+		// never reused, so one without a user is from a deleted account
+		userID, _ := claims.UserID()
+		user, err := users.GetUserByID(c.UserContext(), userID)
+		if err != nil {
+			return fmt.Errorf("failed to load the token's user: %w", err)
+		}
+		if user == nil {
+			return unauthorized(c, "The account of this token no longer exists")
+		}
+
+		c.Locals(userIDKey, userID)
+		if scopes := claims.Scopes(); len(scopes) > 0 {
+			c.Locals(scopesKey, scopes)
+		}
+
+		return c.Next()
+	}
+}
+
+// QueryToken lets clients that cannot send headers, such as feed
+// readers, pass an API key as the param query parameter. It must run
+// before Authenticate. The key is removed from the URL so it is not
+// logged.
+func QueryToken(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		args := c.Request().URI().QueryArgs()
+		token := string(args.Peek(param))
+		if token == "" || c.Get(fiber.HeaderAuthorization) != "" {
+			return c.Next()
+		}
+
+		args.Del(param)
+		c.Request().Header.SetRequestURIBytes(c.Request().URI().RequestURI())
+		if !auth.IsAPIKey(token) {
+			return unauthorized(c, "The "+param+" parameter must be an API key")
+		}
+
+		c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		return c.Next()
+	}
+}
+
+// RequireAuth rejects requests that were not authenticated
+func RequireAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := UserID(c); !ok {
+			return unauthorized(c, "Authentication required")
+		}
+		return c.Next()
+	}
+}
+
+// RequireBasicAuth is RequireAuth for clients that only send credentials
+// once challenged for them
+func RequireBasicAuth(realm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := UserID(c); !ok {
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="`+realm+`"`)
+			return unauthorized(c, "Authentication required")
+		}
+		return c.Next()
+	}
+}
+
+// RequireScope rejects requests made with an API key or scoped access
+// token that was not granted the scope. Unscoped access tokens carry the
+// user's full permissions. Scopes only narrow what a credential may do:
+// anonymous requests are let through unless authenticated is set.
+func RequireScope(scope string, authenticated bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := UserID(c); !ok && authenticated {
+			return unauthorized(c, "Authentication required")
+		}
+		if scopes, ok := Scopes(c); ok && !slices.Contains(scopes, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:     credentialName(c) + " is missing the " + scope + " scope",
+				Code:      fiber.StatusForbidden,
+				RequestID: GetRequestID(c),
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects requests that were not authenticated with an
+// unscoped access token, so API keys and scoped tokens cannot be used to
+// manage credentials
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := UserID(c); !ok {
+			return unauthorized(c, "Authentication required")
+		}
+		if _, ok := Scopes(c); ok {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:     credentialName(c) + "s cannot be used for this endpoint",
+				Code:      fiber.StatusForbidden,
+				RequestID: GetRequestID(c),
+			})
+		}
+		return c.Next()
+	}
+}
+
+// APIKey returns the API key the request was authenticated with, if any
+func APIKey(c *fiber.Ctx) (*models.APIKey, bool) {
+	key, ok := c.Locals(apiKeyKey).(*models.APIKey)
+	return key, ok
+}
+
+// Scopes returns the scopes the request's credentials are limited to. ok
+// is false for anonymous requests and unscoped access tokens.
+func Scopes(c *fiber.Ctx) ([]string, bool) {
+	scopes, ok := c.Locals(scopesKey).([]string)
+	return scopes, ok
+}
+
+// UserID returns the authenticated user's ID, if any
+func UserID(c *fiber.Ctx) (int, bool) {
+	userID, ok := c.Locals(userIDKey).(int)
+	return userID, ok
+}
+
+// basicPassword returns the password of Basic credentials, or "" when they
+// are malformed
+func basicPassword(credentials string) string {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return ""
+	}
+	_, password, _ := strings.Cut(string(decoded), ":")
+	return password
+}
+
+// credentialName names the kind of limited credential the request was
+// authenticated with, for error messages
+func credentialName(c *fiber.Ctx) string {
+	if _, ok := APIKey(c); ok {
+		return "API key"
+	}
+	return "Scoped access token"
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+		Error:     message,
+		Code:      fiber.StatusUnauthorized,
+		RequestID: GetRequestID(c),
+	})
+}
@@ -6,16 +6,22 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
-func CORS(cfg *config.Config) fiber.Handler {
+// CORS allows the origins in CORS_ALLOWED_ORIGINS, read from store on
+// every request so a config reload takes effect immediately. Development
+// allows every origin.
+func CORS(store *config.Store) fiber.Handler {
 	corsConfig := cors.Config{
-		AllowOrigins:     getAllowedOrigins(cfg),
+		AllowOriginsFunc: func(origin string) bool {
+			return originAllowed(store.Get().CORS.AllowedOrigins, origin)
+		},
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID,X-Admin-Token",
 		AllowCredentials: false,
 		ExposeHeaders:    "X-Request-ID",
 	}
 
-	if cfg.IsDevelopment() {
+	if store.Get().IsDevelopment() {
+		corsConfig.AllowOriginsFunc = nil
 		corsConfig.AllowOrigins = "*"
 		corsConfig.AllowCredentials = true
 	}
@@ -23,11 +29,11 @@ func CORS(cfg *config.Config) fiber.Handler {
 	return cors.New(corsConfig)
 }
 
-func getAllowedOrigins(cfg *config.Config) string {
-	if cfg.IsDevelopment() {
-		return "http://localhost:3000,http://localhost:5173,http://127.0.0.1:3000,http://127.0.0.1:5173"
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || o == origin {
+			return true
+		}
 	}
-	
-	// In production, specify your actual frontend domains
-	return "https://yourdomain.com"
-}
\ No newline at end of file
+	return false
+}
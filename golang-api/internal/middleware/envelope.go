@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// paginationFields are moved from a paginated body into the envelope's meta
+var paginationFields = []string{"total", "page", "per_page", "total_pages", "has_more"}
+
+// Envelope wraps JSON responses in models.Envelope for clients that cannot
+// read status codes or headers. It applies when the request has
+// ?envelope=true, or to every request when RESPONSE_ENVELOPE is set
+// (?envelope=false opts out). Errors returned by later handlers are
+// rendered by the app's error handler first so they are wrapped too.
+func Envelope(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !c.QueryBool("envelope", cfg.App.ResponseEnvelope) {
+			return c.Next()
+		}
+
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		resp := c.Response()
+		if !strings.HasPrefix(string(resp.Header.ContentType()), fiber.MIMEApplicationJSON) || len(resp.Body()) == 0 {
+			return nil
+		}
+
+		if body, ok := envelope(resp.Body(), resp.StatusCode()); ok {
+			resp.SetBody(body)
+		}
+		return nil
+	}
+}
+
+// envelope wraps a JSON body according to the response status. Bodies
+// that are not valid JSON are left alone.
+func envelope(body []byte, status int) ([]byte, bool) {
+	raw := json.RawMessage(body)
+	if !json.Valid(raw) {
+		return nil, false
+	}
+
+	var env models.Envelope
+	var fields map[string]json.RawMessage
+	switch {
+	case status >= fiber.StatusBadRequest:
+		env.Error = raw
+	case json.Unmarshal(raw, &fields) == nil && isPaginated(fields):
+		meta := make(map[string]json.RawMessage, len(paginationFields))
+		for _, name := range paginationFields {
+			meta[name] = fields[name]
+		}
+		env.Data, env.Meta = fields["data"], meta
+	default:
+		env.Data = raw
+	}
+
+	wrapped, err := json.Marshal(env)
+	return wrapped, err == nil
+}
+
+func isPaginated(fields map[string]json.RawMessage) bool {
+	if len(fields) != len(paginationFields)+1 {
+		return false
+	}
+	if _, ok := fields["data"]; !ok {
+		return false
+	}
+	for _, name := range paginationFields {
+		if _, ok := fields[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -4,10 +4,13 @@ import (
 	"log/slog"
 
 	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/reporting"
 	"github.com/gofiber/fiber/v2"
 )
 
-func ErrorHandler(logger *slog.Logger) fiber.ErrorHandler {
+// ErrorHandler renders errors returned by handlers as JSON and reports
+// server errors that Recover has not already reported
+func ErrorHandler(logger *slog.Logger, reporter reporting.Reporter) fiber.ErrorHandler {
 	return func(c *fiber.Ctx, err error) error {
 		code := fiber.StatusInternalServerError
 		message := "Internal Server Error"
@@ -26,19 +29,26 @@ func ErrorHandler(logger *slog.Logger) fiber.ErrorHandler {
 			"status", code,
 			"ip", c.IP(),
 			"user_agent", c.Get("User-Agent"),
+			"request_id", GetRequestID(c),
 		)
 
+		if code >= fiber.StatusInternalServerError && c.Locals(errorReportedKey) == nil {
+			reporter.Report(errorEvent(c, err.Error(), code))
+		}
+
 		// Return error response
 		return c.Status(code).JSON(models.ErrorResponse{
-			Error: message,
-			Code:  code,
+			Error:     message,
+			Code:      code,
+			RequestID: GetRequestID(c),
 		})
 	}
 }
 
 func NotFoundHandler(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-		Error: "Route not found",
-		Code:  fiber.StatusNotFound,
+		Error:     "Route not found",
+		Code:      fiber.StatusNotFound,
+		RequestID: GetRequestID(c),
 	})
-}
\ No newline at end of file
+}
@@ -1,73 +1,152 @@
 package middleware
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
+	"math/rand"
+	"strconv"
 	"time"
 
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/logging"
 	"github.com/gofiber/fiber/v2"
 )
 
-func Logger(logger *slog.Logger) fiber.Handler {
+// Logger writes an access log line per request. Fast 2xx/3xx responses
+// are sampled at LOG_SAMPLE_RATE to cut volume under load; requests slower
+// than SLOW_REQUEST_THRESHOLD additionally get a "Slow request" warning
+// with the full request details. Both are read from store per request.
+// When access is not nil every request is also written to it in Apache
+// combined log format, unsampled.
+func Logger(logger *slog.Logger, store *config.Store, access io.Writer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
+		cfg := store.Get().Logging
 
 		// Process request
 		err := c.Next()
 
 		// Log request
 		duration := time.Since(start)
-		
+		status := c.Response().StatusCode()
+		slow := cfg.SlowRequestThreshold > 0 && duration >= cfg.SlowRequestThreshold
+
+		if access != nil {
+			access.Write(combinedLogLine(c, start))
+		}
+
+		if slow {
+			userID, _ := UserID(c)
+			logger.Log(c.Context(), slog.LevelWarn, "Slow request",
+				"method", c.Method(),
+				"path", c.Path(),
+				"route", c.Route().Path,
+				"query", string(c.Request().URI().QueryString()),
+				"status", status,
+				"duration", duration.String(),
+				"threshold", cfg.SlowRequestThreshold.String(),
+				"request_size", len(c.Request().Body()),
+				"size", len(c.Response().Body()),
+				"user_id", userID,
+				"ip", c.IP(),
+				"user_agent", c.Get("User-Agent"),
+				"request_id", GetRequestID(c),
+			)
+		}
+
 		logLevel := slog.LevelInfo
-		if c.Response().StatusCode() >= 400 {
+		if status >= 400 {
 			logLevel = slog.LevelWarn
 		}
-		if c.Response().StatusCode() >= 500 {
+		if status >= 500 {
 			logLevel = slog.LevelError
 		}
 
+		if logLevel == slog.LevelInfo && !slow && !sampled(cfg.SuccessSampleRate) {
+			return err
+		}
+
 		logger.Log(c.Context(), logLevel, "Request completed",
 			"method", c.Method(),
 			"path", c.Path(),
-			"status", c.Response().StatusCode(),
+			"status", status,
 			"duration", duration.String(),
 			"size", len(c.Response().Body()),
 			"ip", c.IP(),
 			"user_agent", c.Get("User-Agent"),
+			"request_id", GetRequestID(c),
 		)
 
 		return err
 	}
 }
 
-func RequestID() fiber.Handler {
+// combinedLogLine formats the request in Apache combined log format:
+// host ident user [time] "request" status size "referer" "user-agent"
+func combinedLogLine(c *fiber.Ctx, start time.Time) []byte {
+	user := "-"
+	if userID, ok := UserID(c); ok {
+		user = strconv.Itoa(userID)
+	}
+
+	size := "-"
+	if n := len(c.Response().Body()); n > 0 {
+		size = strconv.Itoa(n)
+	}
+
+	request := fmt.Sprintf("%s %s %s", c.Method(), c.OriginalURL(), c.Request().Header.Protocol())
+
+	return []byte(fmt.Sprintf("%s - %s [%s] %s %d %s %s %s\n",
+		c.IP(),
+		user,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(request),
+		c.Response().StatusCode(),
+		size,
+		strconv.Quote(orDash(c.Get(fiber.HeaderReferer))),
+		strconv.Quote(orDash(c.Get(fiber.HeaderUserAgent))),
+	))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func sampled(rate float64) bool {
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+const requestIDKey = "requestID"
+
+// RequestID assigns every request an ID, taken from the X-Request-ID
+// header when the client sent a well-formed one and generated in the
+// given format otherwise (see GenerateRequestID). A logger carrying the ID
+// is stored in the request context for handlers and services.
+func RequestID(logger *slog.Logger, format string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Generate or get request ID
 		requestID := c.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = generateRequestID()
+		if !validRequestID(requestID) {
+			requestID = GenerateRequestID(format)
 		}
 
 		// Set request ID in response header
 		c.Set("X-Request-ID", requestID)
-		
+
 		// Store in locals for use in handlers
-		c.Locals("requestID", requestID)
+		c.Locals(requestIDKey, requestID)
+		c.SetUserContext(logging.NewContext(c.UserContext(), logger.With("request_id", requestID)))
 
 		return c.Next()
 	}
 }
 
-func generateRequestID() string {
-	// Simple request ID generation
-	// In production, consider using UUID or similar
-	return time.Now().Format("20060102150405") + "-" + randomString(6)
+// GetRequestID returns the ID assigned to the current request
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(requestIDKey).(string)
+	return requestID
 }
-
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-	}
-	return string(b)
-}
\ No newline at end of file
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MaintenanceMode rejects new requests while an operation such as a
+// database restore runs. Requests already in flight are not affected.
+type MaintenanceMode struct {
+	enabled atomic.Bool
+}
+
+func (m *MaintenanceMode) Enable()       { m.enabled.Store(true) }
+func (m *MaintenanceMode) Disable()      { m.enabled.Store(false) }
+func (m *MaintenanceMode) Enabled() bool { return m.enabled.Load() }
+
+// TryEnable turns maintenance mode on unless it already is, reporting
+// whether it did, so only one operation at a time holds it
+func (m *MaintenanceMode) TryEnable() bool { return m.enabled.CompareAndSwap(false, true) }
+
+// Handler answers 503 while maintenance mode is on. The liveness probe
+// keeps answering so orchestrators do not restart the process.
+func (m *MaintenanceMode) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.Enabled() || c.Path() == "/live" {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, "30")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+			Error:     "Service is in maintenance mode",
+			Code:      fiber.StatusServiceUnavailable,
+			RequestID: GetRequestID(c),
+		})
+	}
+}
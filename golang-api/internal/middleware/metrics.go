@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records latency and payload sizes for every request in registry,
+// labelled with the matched route pattern
+func Metrics(registry *metrics.Registry) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		// Returned errors are only turned into a response by the error
+		// handler, after this middleware
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// Labels are kept by the registry, so copy them out of Fiber's
+		// reused buffers
+		registry.Observe(strings.Clone(c.Method()), strings.Clone(c.Route().Path), status, time.Since(start), len(c.Request().Body()), len(c.Response().Body()))
+		return err
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlanResolver returns the plan a user is on
+type PlanResolver interface {
+	ForUser(ctx context.Context, userID int) (*models.Plan, error)
+}
+
+// RequireFeature rejects requests from users whose plan does not include
+// feature, and anonymous requests
+func RequireFeature(plans PlanResolver, feature string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := UserID(c)
+		if !ok {
+			return unauthorized(c, "Authentication required")
+		}
+
+		plan, err := plans.ForUser(c.UserContext(), userID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve plan: %w", err)
+		}
+
+		if !plan.Includes(feature) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:     fmt.Sprintf("The %s plan does not include %s", plan.Name, feature),
+				Code:      fiber.StatusForbidden,
+				RequestID: GetRequestID(c),
+			})
+		}
+
+		return c.Next()
+	}
+}
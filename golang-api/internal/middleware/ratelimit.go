@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimiter counts requests per caller in fixed windows
+type rateLimiter struct {
+	mu        sync.Mutex
+	counters  map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// take records a request for key and returns the number of requests left
+// in the current window, when the window resets and whether the request
+// is allowed
+func (l *rateLimiter) take(key string, limit int, window time.Duration, now time.Time) (int, time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop idle callers once per window so the map does not grow unbounded
+	if now.Sub(l.lastSweep) >= window {
+		for k, w := range l.counters {
+			if now.Sub(w.start) >= window {
+				delete(l.counters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.counters[key]
+	if !ok || now.Sub(w.start) >= window {
+		w = &rateWindow{start: now}
+		l.counters[key] = w
+	}
+
+	reset := w.start.Add(window)
+	if w.count >= limit {
+		return 0, reset, false
+	}
+
+	w.count++
+	return limit - w.count, reset, true
+}
+
+// remaining returns the number of requests left for key in the current
+// window and when the window resets, without recording a request
+func (l *rateLimiter) remaining(key string, limit int, window time.Duration, now time.Time) (int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.counters[key]
+	if !ok || now.Sub(w.start) >= window {
+		return limit, now.Add(window)
+	}
+	return max(limit-w.count, 0), w.start.Add(window)
+}
+
+// RateLimit limits requests per caller using the tier that matches how
+// the request was authenticated, and reports the remaining quota in
+// X-RateLimit-* headers. Limits are read from store on every request, so a
+// config reload applies them immediately. It must run after Authenticate.
+func RateLimit(store *config.Store) fiber.Handler {
+	limiter := &rateLimiter{
+		counters: make(map[string]*rateWindow),
+	}
+
+	return func(c *fiber.Ctx) error {
+		cfg := store.Get().RateLimit
+		if !cfg.Enabled {
+			return c.Next()
+		}
+		if cfg.Window <= 0 {
+			cfg.Window = time.Minute
+		}
+
+		key, limit := rateLimitKey(c, cfg)
+		if limit <= 0 {
+			return c.Next()
+		}
+
+		now := time.Now()
+		remaining, reset, allowed := limiter.take(key, limit, cfg.Window, now)
+		resetIn := int(reset.Sub(now).Seconds() + 0.5)
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("X-RateLimit-Reset", strconv.Itoa(resetIn))
+
+		if !allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(resetIn))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Error:     "Rate limit exceeded",
+				Code:      fiber.StatusTooManyRequests,
+				RequestID: GetRequestID(c),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// LimitAuthFailures counts requests with credentials that were answered
+// with 401 per IP, and refuses every request from an IP that failed
+// authentication as often as the anonymous tier allows in a window. Requests with bad credentials stop at
+// Authenticate and never reach RateLimit, so without it they could be used
+// to guess tokens and API keys at full speed. It must run before
+// Authenticate.
+func LimitAuthFailures(store *config.Store) fiber.Handler {
+	limiter := &rateLimiter{
+		counters: make(map[string]*rateWindow),
+	}
+
+	return func(c *fiber.Ctx) error {
+		cfg := store.Get().RateLimit
+		if !cfg.Enabled || cfg.Anonymous <= 0 {
+			return c.Next()
+		}
+		if cfg.Window <= 0 {
+			cfg.Window = time.Minute
+		}
+
+		key := "ip:" + c.IP()
+		now := time.Now()
+		if remaining, reset := limiter.remaining(key, cfg.Anonymous, cfg.Window, now); remaining == 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(reset.Sub(now).Seconds()+0.5)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Error:     "Too many failed authentication attempts",
+				Code:      fiber.StatusTooManyRequests,
+				RequestID: GetRequestID(c),
+			})
+		}
+
+		// Anonymous requests are challenged with 401 on protected routes,
+		// which is no failed attempt
+		credentials := c.Get(fiber.HeaderAuthorization) != ""
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+		if credentials && status == fiber.StatusUnauthorized {
+			limiter.take(key, cfg.Anonymous, cfg.Window, time.Now())
+		}
+
+		return err
+	}
+}
+
+func rateLimitKey(c *fiber.Ctx, cfg config.RateLimitConfig) (string, int) {
+	if key, ok := APIKey(c); ok {
+		return "key:" + strconv.Itoa(key.ID), cfg.APIKey
+	}
+	if userID, ok := UserID(c); ok {
+		return "user:" + strconv.Itoa(userID), cfg.User
+	}
+	return "ip:" + c.IP(), cfg.Anonymous
+}
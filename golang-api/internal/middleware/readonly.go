@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// readOnlyMethods change nothing, so a standby can answer them. CalDAV
+// clients read with PROPFIND and REPORT.
+var readOnlyMethods = map[string]bool{
+	fiber.MethodGet: true, fiber.MethodHead: true, fiber.MethodOptions: true,
+	"PROPFIND": true, "REPORT": true,
+}
+
+// ReadOnly answers 503 to requests that could write, on a standby whose
+// database is a read-only replica, so a load balancer or client can send
+// them to the primary instead
+func ReadOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if readOnlyMethods[c.Method()] {
+			return c.Next()
+		}
+
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+			Error:     "This instance is a read-only replica; send writes to the primary",
+			Code:      fiber.StatusServiceUnavailable,
+			RequestID: GetRequestID(c),
+		})
+	}
+}
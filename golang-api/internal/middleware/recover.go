@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/reporting"
+	"github.com/gofiber/fiber/v2"
+)
+
+const errorReportedKey = "errorReported"
+
+// Recover turns panics into 500 responses and reports them, with the stack
+// trace, to reporter. 5xx responses written directly by handlers are
+// reported too; errors returned to Fiber are reported by ErrorHandler.
+func Recover(reporter reporting.Reporter, logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := string(debug.Stack())
+				logger.Error("Panic recovered",
+					"method", c.Method(),
+					"path", c.Path(),
+					"panic", fmt.Sprint(r),
+					"stack", stack,
+					"request_id", GetRequestID(c),
+				)
+
+				evt := errorEvent(c, fmt.Sprintf("panic: %v", r), fiber.StatusInternalServerError)
+				evt.Panic = true
+				evt.Stack = stack
+				reporter.Report(evt)
+				c.Locals(errorReportedKey, true)
+
+				err = fiber.ErrInternalServerError
+			}
+		}()
+
+		err = c.Next()
+
+		if status := c.Response().StatusCode(); err == nil && status >= fiber.StatusInternalServerError {
+			message := fmt.Sprintf("%s %s returned %d", c.Method(), c.Path(), status)
+			var body models.ErrorResponse
+			if json.Unmarshal(c.Response().Body(), &body) == nil && body.Error != "" {
+				message += ": " + body.Error
+			}
+			reporter.Report(errorEvent(c, message, status))
+		}
+
+		return err
+	}
+}
+
+// errorEvent captures the request details for a report. Values are copied
+// because Fiber reuses its buffers once the request completes.
+func errorEvent(c *fiber.Ctx, message string, status int) reporting.Event {
+	userID, _ := UserID(c)
+	return reporting.Event{
+		Message:   message,
+		Status:    status,
+		Method:    strings.Clone(c.Method()),
+		URL:       strings.Clone(c.OriginalURL()),
+		Route:     strings.Clone(c.Route().Path),
+		RequestID: strings.Clone(GetRequestID(c)),
+		UserID:    userID,
+		IP:        strings.Clone(c.IP()),
+		UserAgent: strings.Clone(c.Get("User-Agent")),
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// Request ID formats
+const (
+	RequestIDUUIDv7 = "uuidv7"
+	RequestIDUUIDv4 = "uuidv4"
+	RequestIDHex    = "hex"
+)
+
+const maxRequestIDLength = 128
+
+// GenerateRequestID returns a new crypto-random request ID in the given
+// format, defaulting to UUIDv7. UUIDv7 IDs start with a millisecond
+// timestamp, so they sort by creation time in logs.
+func GenerateRequestID(format string) string {
+	switch format {
+	case RequestIDUUIDv4:
+		return UUIDv4()
+	case RequestIDHex:
+		return randomHex(16)
+	default:
+		return UUIDv7()
+	}
+}
+
+// UUIDv7 returns a time-ordered UUID as defined in RFC 9562
+func UUIDv7() string {
+	var u [16]byte
+	mustRead(u[6:])
+
+	binary.BigEndian.PutUint64(u[0:8], uint64(time.Now().UnixMilli())<<16|uint64(binary.BigEndian.Uint16(u[6:8])))
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+
+	return formatUUID(u)
+}
+
+// UUIDv4 returns a random UUID
+func UUIDv4() string {
+	var u [16]byte
+	mustRead(u[:])
+
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+
+	return formatUUID(u)
+}
+
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	mustRead(b)
+	return hex.EncodeToString(b)
+}
+
+// mustRead fills b from the system CSPRNG, which only fails if the
+// platform is broken
+func mustRead(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to read random bytes: " + err.Error())
+	}
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to
+// reuse: non-empty, bounded and limited to characters that cannot forge
+// log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == '.' || ch == ':') {
+			return false
+		}
+	}
+	return true
+}
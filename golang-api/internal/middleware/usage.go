@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const accountErasedKey = "accountErased"
+
+// UsageRecorder meters API calls
+type UsageRecorder interface {
+	Record(ctx context.Context, userID int, apiKeyID *int)
+}
+
+// MeterUsage counts every authenticated request once it was handled,
+// against the user and the API key it was made with. Anonymous requests
+// are not metered, nor are requests that erased their user. It must run
+// after Authenticate.
+func MeterUsage(usage UsageRecorder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		if erased, _ := c.Locals(accountErasedKey).(bool); erased {
+			return err
+		}
+		if userID, ok := UserID(c); ok {
+			var apiKeyID *int
+			if key, ok := APIKey(c); ok {
+				apiKeyID = &key.ID
+			}
+			usage.Record(c.UserContext(), userID, apiKeyID)
+		}
+
+		return err
+	}
+}
+
+// AccountErased tells MeterUsage that the request erased its user, whose
+// usage must not be recorded again once they are gone
+func AccountErased(c *fiber.Ctx) {
+	c.Locals(accountErasedKey, true)
+}
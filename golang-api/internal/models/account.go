@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// AccountExport is everything stored about a user, as returned by the
+// account export. Todos, their notes and saved searches are shared by all
+// users and record no owner, so they are not part of it. Secrets such as
+// the password hash, API key hashes and Google tokens are left out.
+type AccountExport struct {
+	ExportedAt      time.Time         `json:"exported_at"`
+	User            *User             `json:"user"`
+	Identities      []UserIdentity    `json:"identities"`
+	APIKeys         []APIKey          `json:"api_keys"`
+	Hooks           []Hook            `json:"hooks"`
+	Notifications   []Notification    `json:"notifications"`
+	GoogleTasks     *GoogleSyncStatus `json:"google_tasks"`
+	GoogleTaskLinks []GoogleTaskLink  `json:"google_task_links"`
+	// AuditLog holds the entries the user acted in, and those naming their
+	// email, such as failed logins
+	AuditLog []AuditEntry `json:"audit_log"`
+	// Usage holds the user's metered API calls per day and credential
+	Usage []UsageRecord `json:"usage"`
+}
+
+// UserIdentity links a user to their account at an OpenID Connect
+// provider
+type UserIdentity struct {
+	Issuer    string    `json:"issuer" db:"issuer"`
+	Subject   string    `json:"subject" db:"subject"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Tombstone records that an account was deleted. EmailHash is the SHA-256
+// of the lowercase email, so a deletion can be confirmed without keeping
+// the address.
+type Tombstone struct {
+	UserID    int       `json:"user_id" db:"user_id"`
+	EmailHash string    `json:"email_hash" db:"email_hash"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Activity is an entry of the activity feed: a domain event, with the
+// title the todo had when it happened
+type Activity struct {
+	ID         int                    `json:"id"`
+	Type       string                 `json:"type" example:"todo.completed"`
+	TodoID     *int                   `json:"todo_id,omitempty"`
+	Title      *string                `json:"title,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// ActivityQueryParams filters the activity feed. Types and TodoID are
+// ignored when empty; Since and Until bound the event time.
+type ActivityQueryParams struct {
+	Page    int
+	PerPage int
+	Types   []string
+	TodoID  int
+	Since   *time.Time
+	Until   *time.Time
+}
+
+// ParseActivityQueryParams reads the activity feed filters and pagination
+// from query string values. Malformed pagination falls back to the
+// defaults; a malformed todo_id or time is an error.
+func ParseActivityQueryParams(values url.Values) (ActivityQueryParams, error) {
+	params := ActivityQueryParams{
+		Page:    queryInt(values, "page", 1),
+		PerPage: queryInt(values, "per_page", 20),
+		Types:   parseList(values.Get("type")),
+	}
+
+	if value := values.Get("todo_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			return params, fmt.Errorf("invalid todo_id: must be a todo ID")
+		}
+		params.TodoID = id
+	}
+
+	var err error
+	if params.Since, err = parseTimeValue(values, "since"); err != nil {
+		return params, err
+	}
+	if params.Until, err = parseTimeValue(values, "until"); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// Scopes of API keys and scoped access tokens
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	// ScopeAdmin opens the admin API. Only callers allowed into the admin
+	// API can grant it.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope that can be granted to an API key or access
+// token
+var Scopes = []string{ScopeTodosRead, ScopeTodosWrite, ScopeAdmin}
+
+// APIKey is a long-lived credential owned by a user. Only a hash of the
+// secret is stored.
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HasScope reports whether the key was granted the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key is past its expiration
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKeyResponse is returned once when a key is created. Key is the
+// secret and cannot be retrieved again.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditLogin       = "auth.login"
+	AuditLoginFailed = "auth.login_failed"
+	// AuditLockout is recorded when failed logins lock an account or a
+	// client IP; Target is the email or IP
+	AuditLockout = "auth.lockout"
+	AuditUnlock  = "admin.unlock"
+	// AuditAccountDeleted is recorded when users delete their account;
+	// Target is the label that replaced their email
+	AuditAccountDeleted = "account.deleted"
+	// AuditAdminRequest is recorded for every admin API request that may
+	// change something; Target is the path
+	AuditAdminRequest = "admin.request"
+	// AuditPurge is recorded when a purge job deletes todos; Target is
+	// "completed" or "trash"
+	AuditPurge = "todos.purge"
+)
+
+// AuditEntry is a security-relevant action recorded in the audit log
+type AuditEntry struct {
+	ID     int    `json:"id" db:"id"`
+	Action string `json:"action" db:"action"`
+	// ActorID is the user who acted, when known
+	ActorID *int   `json:"actor_id,omitempty" db:"actor_id"`
+	IP      string `json:"ip,omitempty" db:"ip"`
+	// Target names what the action applied to, such as an email address
+	Target    string                 `json:"target,omitempty" db:"target"`
+	Details   map[string]interface{} `json:"details,omitempty" db:"details"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+// AuditQuery selects audit entries by time, From inclusive and To
+// exclusive, and by actor
+type AuditQuery struct {
+	From    *time.Time
+	To      *time.Time
+	ActorID *int
+}
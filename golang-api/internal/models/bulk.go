@@ -0,0 +1,27 @@
+package models
+
+// BulkTagRequest adds and removes tags on many todos at once
+type BulkTagRequest struct {
+	IDs    []int    `json:"ids"`
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+// BulkTagResponse lists the todos whose tags changed. Todos that already
+// had the added tags and none of the removed ones are left out.
+type BulkTagResponse struct {
+	Updated int            `json:"updated"`
+	Todos   []TodoResponse `json:"todos"`
+}
+
+// BulkCreateRequest creates many todos at once
+type BulkCreateRequest struct {
+	Todos []CreateTodoRequest `json:"todos"`
+}
+
+// BulkCreateResponse lists the todos in the order requested. Created
+// counts the new ones; the others already had their client ID.
+type BulkCreateResponse struct {
+	Created int            `json:"created"`
+	Todos   []TodoResponse `json:"todos"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "fmt"
+
+// CalDAVObject is a todo as a resource of the CalDAV tasks collection.
+// Name is the last segment of its URL.
+type CalDAVObject struct {
+	TodoID int
+	Name   string
+	UID    string
+	Todo   *Todo
+}
+
+// ETag is the entity tag of the object, which changes with the todo's
+// version
+func (o *CalDAVObject) ETag() string {
+	return fmt.Sprintf(`"%d"`, o.Todo.Version)
+}
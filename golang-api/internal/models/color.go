@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ColorPalette lists the named colors. Clients map them to shades that
+// suit their theme; hex colors are shown as is.
+var ColorPalette = []string{"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink", "gray"}
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// NormalizeColor returns the stored form of a color: a palette name, or a
+// lowercase #rrggbb hex color. #rgb is expanded.
+func NormalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+
+	for _, name := range ColorPalette {
+		if color == name {
+			return color, nil
+		}
+	}
+
+	if !hexColorPattern.MatchString(color) {
+		return "", fmt.Errorf("color must be #rrggbb, #rgb or one of %s", strings.Join(ColorPalette, ", "))
+	}
+	if len(color) == 4 {
+		color = string([]byte{'#', color[1], color[1], color[2], color[2], color[3], color[3]})
+	}
+	return color, nil
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ExportFile is a stored export of all todos
+type ExportFile struct {
+	Name       string    `json:"name"`
+	Format     string    `json:"format" enums:"json,csv"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// GoogleAccount is a Google account linked for Google Tasks sync. State is
+// set while the user is on Google's consent page; the tokens once they
+// have granted access.
+type GoogleAccount struct {
+	UserID         int
+	State          *string
+	StateExpiresAt *time.Time
+	AccessToken    *string
+	RefreshToken   *string
+	TokenExpiresAt *time.Time
+	ConnectedAt    *time.Time
+	LastSyncedAt   *time.Time
+	LastResult     *GoogleSyncResult
+	LastError      *string
+}
+
+// Connected reports whether the user has granted access
+func (a *GoogleAccount) Connected() bool {
+	return a.ConnectedAt != nil && a.RefreshToken != nil
+}
+
+// GoogleTaskLink pairs a todo with a Google task. TodoVersion and
+// TaskUpdated are the state of both sides after the last sync.
+type GoogleTaskLink struct {
+	TodoID      int    `json:"todo_id"`
+	TaskID      string `json:"task_id"`
+	TodoVersion int    `json:"todo_version"`
+	TaskUpdated string `json:"task_updated"`
+}
+
+// GoogleSyncResult counts the changes made by a sync
+type GoogleSyncResult struct {
+	TodosCreated int `json:"todos_created"`
+	TodosUpdated int `json:"todos_updated"`
+	TodosDeleted int `json:"todos_deleted"`
+	TasksCreated int `json:"tasks_created"`
+	TasksUpdated int `json:"tasks_updated"`
+	TasksDeleted int `json:"tasks_deleted"`
+	Conflicts    int `json:"conflicts"`
+	Skipped      int `json:"skipped"`
+}
+
+// GoogleSyncStatus is the caller's Google Tasks sync state
+type GoogleSyncStatus struct {
+	Connected    bool              `json:"connected"`
+	ConnectedAt  *time.Time        `json:"connected_at,omitempty"`
+	LastSyncedAt *time.Time        `json:"last_synced_at,omitempty"`
+	LastError    *string           `json:"last_error,omitempty"`
+	LastResult   *GoogleSyncResult `json:"last_result,omitempty"`
+	LinkedTodos  int               `json:"linked_todos"`
+}
+
+// GoogleConnectResponse carries the Google consent page to send the user
+// to
+type GoogleConnectResponse struct {
+	URL string `json:"url" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=..."`
+}
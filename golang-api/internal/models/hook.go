@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Hook is a REST hook subscription: every event of its type is posted to
+// the target URL
+type Hook struct {
+	ID        int       `json:"id" db:"id"`
+	Event     string    `json:"event" db:"event" example:"todo.created"`
+	TargetURL string    `json:"target_url" db:"target_url" example:"https://hooks.zapier.com/hooks/standard/123/abc"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// HookRequest subscribes TargetURL to an event type
+type HookRequest struct {
+	Event     string `json:"event" example:"todo.created"`
+	TargetURL string `json:"target_url" example:"https://hooks.zapier.com/hooks/standard/123/abc"`
+}
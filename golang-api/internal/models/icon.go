@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxIconLength is the longest icon, in bytes. The longest emoji ZWJ
+// sequences fit comfortably.
+const maxIconLength = 64
+
+// iconNamePattern matches named icons such as "star" or "lucide:check",
+// which clients resolve from their icon set
+var iconNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(:[a-z0-9]+(-[a-z0-9]+)*)?$`)
+
+var errInvalidIcon = errors.New("icon must be a single emoji or an icon name like star or lucide:check")
+
+// NormalizeIcon returns the stored form of an icon: a single emoji, or a
+// lowercase icon name
+func NormalizeIcon(icon string) (string, error) {
+	icon = strings.TrimSpace(icon)
+	if icon == "" || len(icon) > maxIconLength {
+		return "", errInvalidIcon
+	}
+
+	if isSingleEmoji(icon) {
+		return icon, nil
+	}
+
+	icon = strings.ToLower(icon)
+	if !iconNamePattern.MatchString(icon) {
+		return "", errInvalidIcon
+	}
+	return icon, nil
+}
+
+// isSingleEmoji reports whether s is one emoji grapheme: a symbol with
+// optional variation selectors, skin tones and tags, possibly joined to
+// more with zero width joiners, a flag, or a keycap
+func isSingleEmoji(s string) bool {
+	runes := []rune(s)
+	i := 0
+
+	// Flags are a pair of regional indicators
+	if isRegionalIndicator(runes[0]) {
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	}
+
+	// Keycaps: a digit, # or *, an optional variation selector and U+20E3
+	if strings.ContainsRune("0123456789#*", runes[0]) {
+		rest := string(runes[1:])
+		return rest == "\u20e3" || rest == "\ufe0f\u20e3"
+	}
+
+	for {
+		if i >= len(runes) || !unicode.Is(unicode.So, runes[i]) {
+			return false
+		}
+		i++
+		for i < len(runes) && isEmojiModifier(runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != '\u200d' {
+			return false
+		}
+		i++
+	}
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isEmojiModifier matches variation selectors, skin tones and tag
+// characters
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0E || r == 0xFE0F ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Import modes. A strict import stores nothing unless every row is valid; a
+// partial import stores the valid rows and reports the others.
+const (
+	ImportStrict  = "strict"
+	ImportPartial = "partial"
+)
+
+// ImportRow is one todo read from an import file. Line is where the row
+// starts in the file. Error is set instead of Request when the row could
+// not be read.
+type ImportRow struct {
+	Row     int               `json:"row"`
+	Line    int               `json:"line"`
+	Request CreateTodoRequest `json:"request"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// ImportError explains why a row was rejected. Row counts from 1, not
+// including a CSV header.
+type ImportError struct {
+	Row   int    `json:"row"`
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReport is the outcome of an import. Accepted rows were created, or
+// already existed when their client_id matched a stored todo. Committed is
+// false when nothing was stored, because a strict import had errors or
+// because it was a dry run; a dry run still reports what would have been
+// created.
+type ImportReport struct {
+	Mode      string        `json:"mode" enums:"strict,partial"`
+	DryRun    bool          `json:"dry_run"`
+	Committed bool          `json:"committed"`
+	Total     int           `json:"total"`
+	Accepted  int           `json:"accepted"`
+	Created   int           `json:"created"`
+	Existing  int           `json:"existing"`
+	Rejected  int           `json:"rejected"`
+	Errors    []ImportError `json:"errors"`
+}
+
+// ParseImportJSON reads a JSON array of todos in the form of
+// CreateTodoRequest, such as the output of "todocli export". Fields it
+// does not know, like id, are ignored.
+func ParseImportJSON(data []byte) ([]ImportRow, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("body must be a JSON array of todos")
+	}
+
+	rows := make([]ImportRow, 0)
+	for decoder.More() {
+		row := ImportRow{Row: len(rows) + 1, Line: lineAt(data, decoder.InputOffset())}
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON at line %d: %w", row.Line, err)
+		}
+		if err := json.Unmarshal(raw, &row.Request); err != nil {
+			row.Error = "todo must be an object with the fields of a create request"
+		}
+		rows = append(rows, row)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return rows, nil
+}
+
+// lineAt returns the line of the first non-space byte at or after offset
+func lineAt(data []byte, offset int64) int {
+	rest := data[offset:]
+	offset += int64(len(rest) - len(bytes.TrimLeft(rest, " \t\r\n,")))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// ParseImportCSV reads todos from CSV with a header row. title is
+// required; description, completed, status, priority, due_date, tags
+// (comma-separated), color, icon, estimate_minutes and client_id are
+// optional and empty cells are left unset. Other columns, like the id and
+// timestamps of "todocli export", are ignored.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("CSV header must include a title column")
+	}
+
+	rows := make([]ImportRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Row: len(rows) + 1, Line: line}
+		cell := func(name string) *string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+				return nil
+			}
+			return &record[i]
+		}
+
+		if err := parseCSVRow(cell, &row.Request); err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseCSVRow(cell func(string) *string, req *CreateTodoRequest) error {
+	if title := cell("title"); title != nil {
+		req.Title = *title
+	}
+	req.Description = cell("description")
+	req.Status = cell("status")
+	req.Priority = cell("priority")
+	req.DueDate = cell("due_date")
+	req.Color = cell("color")
+	req.Icon = cell("icon")
+	req.ClientID = cell("client_id")
+
+	if completed := cell("completed"); completed != nil {
+		value, err := strconv.ParseBool(strings.TrimSpace(*completed))
+		if err != nil {
+			return errors.New("completed must be true or false")
+		}
+		req.Completed = value
+	}
+	if tags := cell("tags"); tags != nil {
+		req.Tags = parseList(*tags)
+	}
+	if estimate := cell("estimate_minutes"); estimate != nil {
+		minutes, err := strconv.Atoi(strings.TrimSpace(*estimate))
+		if err != nil {
+			return errors.New("estimate_minutes must be a whole number")
+		}
+		req.EstimateMinutes = &minutes
+	}
+	return nil
+}
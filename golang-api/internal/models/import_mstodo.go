@@ -0,0 +1,203 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// msTodoList is a Microsoft To Do list as returned by Microsoft Graph
+// (/me/todo/lists with the tasks of each list)
+type msTodoList struct {
+	DisplayName       string            `json:"displayName"`
+	WellknownListName string            `json:"wellknownListName"`
+	Tasks             []json.RawMessage `json:"tasks"`
+}
+
+// msTodoTask is a Microsoft To Do task, or an Outlook task, which has a
+// subject instead of a title
+type msTodoTask struct {
+	ID             string          `json:"id"`
+	Title          string          `json:"title"`
+	Subject        string          `json:"subject"`
+	Status         string          `json:"status"`
+	Importance     string          `json:"importance"`
+	Body           *msTodoBody     `json:"body"`
+	DueDateTime    *msTodoDateTime `json:"dueDateTime"`
+	Categories     []string        `json:"categories"`
+	ChecklistItems []msTodoStep    `json:"checklistItems"`
+}
+
+type msTodoBody struct {
+	Content     string `json:"content"`
+	ContentType string `json:"contentType"`
+}
+
+type msTodoDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type msTodoStep struct {
+	DisplayName string `json:"displayName"`
+	IsChecked   bool   `json:"isChecked"`
+}
+
+// ParseImportMSTodo reads a Microsoft To Do export: the lists from
+// Microsoft Graph, each with its tasks (and their checklistItems), as
+// {"value": [...]}, {"lists": [...]} or a bare array. An array of Outlook
+// tasks, without lists, is read as well. Todos have no lists or subtasks,
+// so a task is tagged with its list's name, except for the default
+// "Tasks" list, and its steps are appended to the description as a
+// checklist. Rows are numbered in the order tasks appear; Line is not
+// tracked.
+func ParseImportMSTodo(data []byte) ([]ImportRow, error) {
+	var lists []json.RawMessage
+	if err := json.Unmarshal(data, &lists); err != nil {
+		var wrapper struct {
+			Value []json.RawMessage `json:"value"`
+			Lists []json.RawMessage `json:"lists"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, errors.New("body must be a Microsoft To Do export: a JSON array of lists, or an object with value or lists")
+		}
+		lists = append(wrapper.Value, wrapper.Lists...)
+	}
+
+	rows := make([]ImportRow, 0)
+	addTask := func(raw json.RawMessage, list *msTodoList) {
+		row := ImportRow{Row: len(rows) + 1}
+		var task msTodoTask
+		if err := json.Unmarshal(raw, &task); err != nil {
+			row.Error = "task must be a Microsoft To Do task object"
+		} else if err := task.request(list, &row.Request); err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+
+	for _, raw := range lists {
+		var list msTodoList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("list %d must be an object", len(rows)+1)
+		}
+		if list.Tasks == nil && list.DisplayName == "" {
+			// An Outlook task rather than a list
+			addTask(raw, nil)
+			continue
+		}
+		for _, task := range list.Tasks {
+			addTask(task, &list)
+		}
+	}
+	return rows, nil
+}
+
+func (t *msTodoTask) request(list *msTodoList, req *CreateTodoRequest) error {
+	req.Title = strings.TrimSpace(t.Title)
+	if req.Title == "" {
+		req.Title = strings.TrimSpace(t.Subject)
+	}
+	if t.ID != "" {
+		// Importing the same export again finds the todos created before
+		clientID := uuid.NewSHA1(uuid.NameSpaceURL, []byte("mstodo:"+t.ID)).String()
+		req.ClientID = &clientID
+	}
+
+	switch t.Status {
+	case "completed":
+		req.Completed = true
+	case "inProgress":
+		status := StatusInProgress
+		req.Status = &status
+	case "waitingOnOthers":
+		status := StatusBlocked
+		req.Status = &status
+	}
+
+	switch strings.ToLower(t.Importance) {
+	case "high":
+		priority := PriorityHigh
+		req.Priority = &priority
+	case "low":
+		priority := PriorityLow
+		req.Priority = &priority
+	}
+
+	if t.DueDateTime != nil && t.DueDateTime.DateTime != "" {
+		due, err := t.DueDateTime.parse()
+		if err != nil {
+			return err
+		}
+		req.DueDate = &due
+	}
+
+	if list != nil && list.WellknownListName != "defaultList" && list.DisplayName != "" {
+		req.Tags = append(req.Tags, strings.ReplaceAll(list.DisplayName, ",", " "))
+	}
+	for _, category := range t.Categories {
+		req.Tags = append(req.Tags, strings.ReplaceAll(category, ",", " "))
+	}
+
+	var description []string
+	if t.Body != nil {
+		if content := t.Body.text(); content != "" {
+			description = append(description, content)
+		}
+	}
+	if len(t.ChecklistItems) > 0 {
+		steps := make([]string, len(t.ChecklistItems))
+		for i, step := range t.ChecklistItems {
+			check := " "
+			if step.IsChecked {
+				check = "x"
+			}
+			steps[i] = fmt.Sprintf("- [%s] %s", check, strings.TrimSpace(step.DisplayName))
+		}
+		description = append(description, strings.Join(steps, "\n"))
+	}
+	if len(description) > 0 {
+		text := strings.Join(description, "\n\n")
+		req.Description = &text
+	}
+	return nil
+}
+
+var htmlTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// text returns the body as plain text; Outlook task bodies are HTML
+func (b *msTodoBody) text() string {
+	content := b.Content
+	if strings.EqualFold(b.ContentType, "html") {
+		content = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</div>", "\n").Replace(content)
+		content = html.UnescapeString(htmlTag.ReplaceAllString(content, ""))
+	}
+	return strings.TrimSpace(content)
+}
+
+// parse converts the Graph dateTimeTimeZone to RFC 3339. Zones Go does not
+// know, such as Windows zone names, are taken as UTC.
+func (d *msTodoDateTime) parse() (string, error) {
+	loc := time.UTC
+	if d.TimeZone != "" {
+		if l, err := time.LoadLocation(d.TimeZone); err == nil {
+			loc = l
+		}
+	}
+
+	value := strings.TrimSuffix(d.DateTime, "Z")
+	t, err := time.ParseInLocation("2006-01-02T15:04:05.9999999", value, loc)
+	if err != nil {
+		t, err = time.ParseInLocation(time.DateOnly, value, loc)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid dueDateTime %q", d.DateTime)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
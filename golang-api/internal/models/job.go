@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// Job represents a unit of background work persisted in the jobs table
+type Job struct {
+	ID          int             `json:"id" db:"id"`
+	Type        string          `json:"type" db:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty" db:"payload" swaggertype:"object"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	Result      json.RawMessage `json:"result,omitempty" db:"result" swaggertype:"object"`
+	Progress    *JobProgress    `json:"progress,omitempty" db:"-"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// JobProgress is how far a running job has come. It is kept in memory by
+// the process running the job and is not stored.
+type JobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// JobQueryParams represents the filters for listing jobs
+type JobQueryParams struct {
+	Page    int    `query:"page" validate:"min=1"`
+	PerPage int    `query:"per_page" validate:"min=1,max=100"`
+	Status  string `query:"status" validate:"omitempty,oneof=pending running succeeded failed"`
+	Type    string `query:"type" validate:"omitempty,max=255"`
+}
+
+// DefaultJobQueryParams returns default job query parameters
+func DefaultJobQueryParams() JobQueryParams {
+	return JobQueryParams{
+		Page:    1,
+		PerPage: 20,
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// Link types
+const (
+	LinkTypePullRequest = "pull_request"
+	LinkTypeTicket      = "ticket"
+	LinkTypeDoc         = "doc"
+	LinkTypeOther       = "other"
+)
+
+// LinkTypes lists the valid link types
+var LinkTypes = []string{LinkTypePullRequest, LinkTypeTicket, LinkTypeDoc, LinkTypeOther}
+
+// IsValidLinkType reports whether t is one of LinkTypes
+func IsValidLinkType(t string) bool {
+	for _, linkType := range LinkTypes {
+		if t == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+// Link is a typed reference from a todo to an external URL, such as the
+// pull request or ticket it tracks
+type Link struct {
+	ID        int       `json:"id" db:"id"`
+	TodoID    int       `json:"todo_id" db:"todo_id"`
+	Type      string    `json:"type" db:"type" enums:"pull_request,ticket,doc,other"`
+	URL       string    `json:"url" db:"url" example:"https://github.com/acme/api/pull/42"`
+	Title     *string   `json:"title,omitempty" db:"title" example:"Fix login redirect"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LinkRequest creates or replaces a link. Type defaults to other. URL
+// must be an absolute http or https URL. Without a title, the page title
+// is fetched when TODO_FETCH_LINK_TITLES is enabled.
+type LinkRequest struct {
+	Type  string  `json:"type" enums:"pull_request,ticket,doc,other"`
+	URL   string  `json:"url" validate:"required" example:"https://github.com/acme/api/pull/42"`
+	Title *string `json:"title,omitempty" example:"Fix login redirect"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadiusMeters is the mean radius of the Earth
+const earthRadiusMeters = 6371000
+
+// metersPerDegree is the length of one degree of latitude
+const metersPerDegree = earthRadiusMeters * math.Pi / 180
+
+// Location is a point given in WGS 84 degrees
+type Location struct {
+	Lat float64 `json:"lat" example:"52.5200"`
+	Lng float64 `json:"lng" example:"13.4050"`
+}
+
+// Validate checks that the coordinates are on the globe
+func (l Location) Validate() error {
+	if math.IsNaN(l.Lat) || l.Lat < -90 || l.Lat > 90 {
+		return fmt.Errorf("lat must be between -90 and 90")
+	}
+	if math.IsNaN(l.Lng) || l.Lng < -180 || l.Lng > 180 {
+		return fmt.Errorf("lng must be between -180 and 180")
+	}
+	return nil
+}
+
+// DistanceTo returns the great-circle distance to other in meters, using
+// the haversine formula
+func (l Location) DistanceTo(other Location) float64 {
+	lat1 := l.Lat * math.Pi / 180
+	lat2 := other.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (other.Lng - l.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundingBox is a latitude and longitude range. MinLng is greater than
+// MaxLng when the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// BoundingBox returns a box containing every point within radius meters.
+// Near the poles it spans all longitudes.
+func (l Location) BoundingBox(radius float64) BoundingBox {
+	dLat := radius / metersPerDegree
+	box := BoundingBox{
+		MinLat: math.Max(l.Lat-dLat, -90),
+		MaxLat: math.Min(l.Lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+
+	// The circle is widest at the latitude farthest from the equator
+	cos := math.Cos(math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat)) * math.Pi / 180)
+	dLng := radius / (metersPerDegree * cos)
+	if dLng >= 180 {
+		return box
+	}
+
+	box.MinLng = l.Lng - dLng
+	if box.MinLng < -180 {
+		box.MinLng += 360
+	}
+	box.MaxLng = l.Lng + dLng
+	if box.MaxLng > 180 {
+		box.MaxLng -= 360
+	}
+	return box
+}
+
+// NearbyTodo is a todo found by a proximity query, nearest first
+type NearbyTodo struct {
+	Todo
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// NearbyTodoResponse is the API representation of a NearbyTodo
+type NearbyTodoResponse struct {
+	TodoResponse
+	DistanceMeters float64 `json:"distance_meters" example:"420.5"`
+}
+
+// NewNearbyTodoResponses maps the results of a proximity query
+func NewNearbyTodoResponses(todos []NearbyTodo) []NearbyTodoResponse {
+	responses := make([]NearbyTodoResponse, len(todos))
+	for i := range todos {
+		responses[i] = NearbyTodoResponse{
+			TodoResponse:   NewTodoResponse(&todos[i].Todo),
+			DistanceMeters: todos[i].DistanceMeters,
+		}
+	}
+	return responses
+}
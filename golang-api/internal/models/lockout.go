@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of login failure counters
+const (
+	LockoutAccount = "account"
+	LockoutIP      = "ip"
+)
+
+// LoginFailures counts consecutive failed logins for an account, by
+// email, or for a client IP. LockedUntil is set while logins are refused.
+type LoginFailures struct {
+	Kind         string     `json:"kind" db:"kind"`
+	Value        string     `json:"value" db:"value"`
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+}
+
+// Locked reports whether logins are refused at now
+func (f *LoginFailures) Locked(now time.Time) bool {
+	return f.LockedUntil != nil && now.Before(*f.LockedUntil)
+}
+
+// UnlockRequest represents the request to lift a login lockout. At least
+// one of Email and IP is required.
+type UnlockRequest struct {
+	Email string `json:"email,omitempty"`
+	IP    string `json:"ip,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Note is long-form Markdown attached to a todo, for content that does
+// not fit in the description
+type Note struct {
+	ID        int       `json:"id" db:"id"`
+	TodoID    int       `json:"todo_id" db:"todo_id"`
+	Body      string    `json:"body" db:"body" example:"## Agenda\n- Budget\n- Hiring"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NoteRequest creates or replaces a note. Body is Markdown of at most
+// 100 KiB.
+type NoteRequest struct {
+	Body string `json:"body" validate:"required" example:"## Agenda\n- Budget\n- Hiring"`
+}
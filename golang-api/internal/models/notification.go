@@ -0,0 +1,42 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Notification types
+const (
+	NotificationMention = "mention"
+	NotificationDueSoon = "due_soon"
+)
+
+// Notification is an in-app notification for one user
+type Notification struct {
+	ID        int        `json:"id" db:"id"`
+	Type      string     `json:"type" db:"type" example:"mention"`
+	TodoID    *int       `json:"todo_id,omitempty" db:"todo_id"`
+	Message   string     `json:"message" db:"message" example:"You were mentioned in a note on \"Plan offsite\""`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// mentionPattern matches "@" followed by an email address, e.g.
+// "@ana@example.com", when it does not continue a word
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([\w.%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,})`)
+
+// ParseMentions returns the lowercase emails mentioned in text, in order
+// of first mention
+func ParseMentions(text string) []string {
+	var emails []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		email := strings.ToLower(match[1])
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox message statuses
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead"
+)
+
+// OutboxMessage is a domain event persisted alongside the mutation that
+// produced it, waiting to be delivered to subscribers
+type OutboxMessage struct {
+	ID          int             `json:"id" db:"id"`
+	EventType   string          `json:"event_type" db:"event_type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	DeliveredTo []string        `json:"delivered_to" db:"delivered_to"`
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	AvailableAt time.Time       `json:"available_at" db:"available_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
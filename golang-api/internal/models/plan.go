@@ -0,0 +1,36 @@
+package models
+
+import "slices"
+
+// Features a plan can include
+const (
+	FeatureAPIKeys     = "api_keys"
+	FeatureHooks       = "hooks"
+	FeatureGoogleTasks = "google_tasks"
+)
+
+// Features lists every feature a plan can include
+var Features = []string{FeatureAPIKeys, FeatureHooks, FeatureGoogleTasks}
+
+// PlanUnlimited names the plan every user is on when no plans are
+// configured: it includes every feature and has no limits
+const PlanUnlimited = "unlimited"
+
+// Plan is what a user's plan includes. Zero limits are unlimited.
+type Plan struct {
+	Name       string   `json:"name" example:"free"`
+	Features   []string `json:"features" example:"api_keys,hooks"`
+	MaxHooks   int      `json:"max_hooks" example:"5"`
+	MaxAPIKeys int      `json:"max_api_keys" example:"2"`
+}
+
+// Includes reports whether the plan includes feature
+func (p *Plan) Includes(feature string) bool {
+	return slices.Contains(p.Features, feature)
+}
+
+// SetPlanRequest moves a user to a plan. An empty plan returns them to
+// the default plan.
+type SetPlanRequest struct {
+	Plan string `json:"plan" example:"pro"`
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataKeyPattern matches one segment of a metadata key path
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ParseQueryParams reads the list filters, pagination and sorting from
+// query string values. It is shared by the list endpoints and saved
+// searches. Malformed pagination and booleans fall back to their
+// defaults; malformed times and ID lists are an error.
+func ParseQueryParams(values url.Values) (QueryParams, error) {
+	params := DefaultQueryParams()
+
+	if page := queryInt(values, "page", 1); page > 0 {
+		params.Page = page
+	}
+
+	if perPage := queryInt(values, "per_page", 20); perPage > 0 && perPage <= 100 {
+		params.PerPage = perPage
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		params.Sort = sort
+	}
+
+	if order := values.Get("order"); order != "" {
+		params.Order = order
+	}
+
+	if search := values.Get("search"); search != "" {
+		params.Search = search
+	}
+
+	if completedStr := values.Get("completed"); completedStr != "" {
+		if completed, err := strconv.ParseBool(completedStr); err == nil {
+			params.Completed = &completed
+		}
+	}
+
+	var err error
+	if params.CompletedAfter, err = parseTimeValue(values, "completed_after"); err != nil {
+		return params, err
+	}
+	if params.CompletedBefore, err = parseTimeValue(values, "completed_before"); err != nil {
+		return params, err
+	}
+	if params.CreatedAfter, err = parseTimeValue(values, "created_after"); err != nil {
+		return params, err
+	}
+	if params.CreatedBefore, err = parseTimeValue(values, "created_before"); err != nil {
+		return params, err
+	}
+	if params.DueAfter, err = parseTimeValue(values, "due_after"); err != nil {
+		return params, err
+	}
+	if params.DueBefore, err = parseTimeValue(values, "due_before"); err != nil {
+		return params, err
+	}
+	params.Due = values.Get("due")
+	params.Tag = NormalizeTag(values.Get("tag"))
+	params.Status = parseList(values.Get("status"))
+
+	if ids := values.Get("ids"); ids != "" {
+		if params.IDs, err = parseIDList("ids", ids); err != nil {
+			return params, err
+		}
+		// Keep the requested order unless a sort is given
+		if values.Get("sort") == "" {
+			params.Sort = ""
+		}
+	}
+
+	// Exclusions are written field!=value, which arrives as the key
+	// "field!"
+	if completedStr := values.Get("completed!"); completedStr != "" {
+		completed, err := strconv.ParseBool(completedStr)
+		if err != nil {
+			return params, fmt.Errorf("invalid completed!=: must be true or false")
+		}
+		params.Exclude.Completed = &completed
+	}
+	params.Exclude.Due = values.Get("due!")
+	params.Exclude.Search = values.Get("search!")
+	params.Exclude.Tag = NormalizeTag(values.Get("tag!"))
+	params.Exclude.Status = parseList(values.Get("status!"))
+	if params.Color, err = parseColorList("color", values.Get("color")); err != nil {
+		return params, err
+	}
+	if params.Exclude.Color, err = parseColorList("color!=", values.Get("color!")); err != nil {
+		return params, err
+	}
+
+	// Metadata filters are written metadata.key.path=value
+	for key := range values {
+		path, ok := strings.CutPrefix(key, "metadata.")
+		if !ok {
+			continue
+		}
+		filters := &params.Metadata
+		if path, ok = strings.CutSuffix(path, "!"); ok {
+			filters = &params.Exclude.Metadata
+		}
+		for _, segment := range strings.Split(path, ".") {
+			if !metadataKeyPattern.MatchString(segment) {
+				return params, fmt.Errorf("invalid %s: metadata keys may only contain letters, digits, _ and -", key)
+			}
+		}
+		if *filters == nil {
+			*filters = map[string]string{}
+		}
+		(*filters)[path] = values.Get(key)
+	}
+	if ids := values.Get("ids!"); ids != "" {
+		if params.Exclude.IDs, err = parseIDList("ids!=", ids); err != nil {
+			return params, err
+		}
+	}
+	if includeTotal := values.Get("include_total"); includeTotal != "" {
+		include, err := strconv.ParseBool(includeTotal)
+		if err != nil {
+			return params, fmt.Errorf("invalid include_total: must be true or false")
+		}
+		params.SkipTotal = !include
+	}
+
+	return params, nil
+}
+
+// NormalizeTag returns the stored form of a tag: trimmed and lowercase
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseColorList parses a comma-separated list of colors into their
+// stored form
+func parseColorList(key, value string) ([]string, error) {
+	colors := parseList(value)
+	for i, color := range colors {
+		normalized, err := NormalizeColor(color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		colors[i] = normalized
+	}
+	return colors, nil
+}
+
+// parseIDList parses a comma-separated list of todo IDs, dropping
+// duplicates but keeping the order
+func parseIDList(key, value string) ([]int, error) {
+	var ids []int
+	seen := map[int]bool{}
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid %s: must be a comma-separated list of todo IDs", key)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// parseTimeValue reads an optional time in the formats accepted by
+// ParseTime
+func parseTimeValue(values url.Values, key string) (*time.Time, error) {
+	value := values.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := ParseTime(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &t, nil
+}
+
+// queryInt reads an integer, falling back to defaultValue when it is
+// missing or malformed
+func queryInt(values url.Values, key string, defaultValue int) int {
+	n, err := strconv.Atoi(values.Get(key))
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch is a named todo list query, a "smart list". Query holds the
+// same query string parameters as GET /api/todos, without pagination.
+type SavedSearch struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Query     string    `json:"query" db:"query" example:"completed=false&due=none"`
+	Color     *string   `json:"color,omitempty" db:"color" example:"teal"`
+	Icon      *string   `json:"icon,omitempty" db:"icon" example:"lucide:check"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SavedSearchRequest creates or replaces a saved search. An empty query
+// matches every todo. Color and Icon take the same values as on a todo.
+type SavedSearchRequest struct {
+	Name  string  `json:"name" validate:"required,max=100"`
+	Query string  `json:"query" example:"completed=false&due=none"`
+	Color *string `json:"color,omitempty" example:"teal"`
+	Icon  *string `json:"icon,omitempty" example:"lucide:check"`
+}
@@ -1,24 +1,218 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 )
 
+// Workflow statuses of a todo. A todo is completed exactly when its status
+// is StatusDone.
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+)
+
+// TodoStatuses lists the valid statuses in workflow order
+var TodoStatuses = []string{StatusTodo, StatusInProgress, StatusBlocked, StatusDone}
+
+// IsValidStatus reports whether status is one of TodoStatuses
+func IsValidStatus(status string) bool {
+	for _, s := range TodoStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Priorities of a todo, lowest first
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// TodoPriorities lists the valid priorities, lowest first
+var TodoPriorities = []string{PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+// IsValidPriority reports whether priority is one of TodoPriorities
+func IsValidPriority(priority string) bool {
+	for _, p := range TodoPriorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
 // Todo represents a todo item
 type Todo struct {
 	ID          int       `json:"id" db:"id"`
 	Title       string    `json:"title" db:"title" validate:"required,min=1,max=255"`
 	Description *string   `json:"description" db:"description" validate:"omitempty,max=1000"`
 	Completed   bool      `json:"completed" db:"completed"`
+	Status      string    `json:"status" db:"status"`
+	Priority    *string   `json:"priority,omitempty" db:"priority"`
+	Version     int       `json:"version" db:"version"`
+	ClientID    *string   `json:"client_id,omitempty" db:"client_id"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// CompletedAt is when the todo was completed, nil while it is open
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	DueDate     *time.Time `json:"due_date,omitempty" db:"due_date"`
+	// DeletedAt is set while the todo is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// Color is a palette name or a #rrggbb hex color
+	Color *string `json:"color,omitempty" db:"color"`
+	// Icon is a single emoji or an icon name
+	Icon *string `json:"icon,omitempty" db:"icon"`
+	// EstimateMinutes is the expected effort, nil when not estimated
+	EstimateMinutes *int `json:"estimate_minutes,omitempty" db:"estimate_minutes"`
+	// Location is stored in the latitude and longitude columns
+	Location *Location `json:"location,omitempty" db:"-"`
+	// Metadata is a JSON object of fields stored by integrations, nil when
+	// there are none
+	Metadata json.RawMessage `json:"metadata,omitempty" db:"metadata" swaggertype:"object"`
+	// Tags are sorted and lowercase; they are stored in todo_tags
+	Tags []string `json:"tags" db:"-"`
 }
 
-// CreateTodoRequest represents the request to create a todo
+// TodoResponse is the API representation of a todo. Handlers return it
+// instead of Todo so the payload can gain computed fields without changing
+// what is stored. Version increases with every update; clients send it
+// back in UpdateTodoRequest to detect concurrent edits.
+type TodoResponse struct {
+	ID              int             `json:"id"`
+	Title           string          `json:"title"`
+	Description     *string         `json:"description"`
+	Completed       bool            `json:"completed"`
+	Status          string          `json:"status" enums:"todo,in_progress,blocked,done"`
+	Priority        *string         `json:"priority,omitempty" enums:"low,medium,high,urgent"`
+	Version         int             `json:"version"`
+	ClientID        *string         `json:"client_id,omitempty"`
+	CompletedAt     *time.Time      `json:"completed_at,omitempty"`
+	DueDate         *time.Time      `json:"due_date,omitempty"`
+	Tags            []string        `json:"tags"`
+	Color           *string         `json:"color,omitempty" example:"teal"`
+	Icon            *string         `json:"icon,omitempty" example:"lucide:check"`
+	EstimateMinutes *int            `json:"estimate_minutes,omitempty" example:"90"`
+	Location        *Location       `json:"location,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// NewTodoResponse maps a stored todo to its API representation
+func NewTodoResponse(todo *Todo) TodoResponse {
+	return TodoResponse{
+		ID:              todo.ID,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		Completed:       todo.Completed,
+		Status:          todo.Status,
+		Priority:        todo.Priority,
+		Version:         todo.Version,
+		ClientID:        todo.ClientID,
+		CompletedAt:     todo.CompletedAt,
+		DueDate:         todo.DueDate,
+		Tags:            todo.Tags,
+		Color:           todo.Color,
+		Icon:            todo.Icon,
+		EstimateMinutes: todo.EstimateMinutes,
+		Location:        todo.Location,
+		Metadata:        todo.Metadata,
+		CreatedAt:       todo.CreatedAt,
+		UpdatedAt:       todo.UpdatedAt,
+	}
+}
+
+// NewTodoResponses maps a list of stored todos
+func NewTodoResponses(todos []Todo) []TodoResponse {
+	responses := make([]TodoResponse, len(todos))
+	for i := range todos {
+		responses[i] = NewTodoResponse(&todos[i])
+	}
+	return responses
+}
+
+// TrashedTodoResponse is a todo in the trash. PurgeAt is when it will be
+// permanently deleted and PurgeInSeconds how long that is from now; both
+// are omitted when the trash is never emptied.
+type TrashedTodoResponse struct {
+	TodoResponse
+	DeletedAt      time.Time  `json:"deleted_at"`
+	PurgeAt        *time.Time `json:"purge_at,omitempty"`
+	PurgeInSeconds *int64     `json:"purge_in_seconds,omitempty"`
+}
+
+// NewTrashedTodoResponses maps a page of trashed todos, given how long the
+// trash keeps them. A retention of zero keeps them forever.
+func NewTrashedTodoResponses(todos []Todo, retention time.Duration, now time.Time) []TrashedTodoResponse {
+	responses := make([]TrashedTodoResponse, len(todos))
+	for i := range todos {
+		response := TrashedTodoResponse{TodoResponse: NewTodoResponse(&todos[i])}
+		if todos[i].DeletedAt != nil {
+			response.DeletedAt = *todos[i].DeletedAt
+		}
+		if retention > 0 {
+			purgeAt := response.DeletedAt.Add(retention)
+			purgeIn := int64(purgeAt.Sub(now).Seconds())
+			if purgeIn < 0 {
+				// Due, waiting for the next purge run
+				purgeIn = 0
+			}
+			response.PurgeAt = &purgeAt
+			response.PurgeInSeconds = &purgeIn
+		}
+		responses[i] = response
+	}
+	return responses
+}
+
+// TodoRevision is a stored state of a todo. Revision 1 is the todo as
+// created; every change that alters its title, description or completion
+// adds the next one.
+type TodoRevision struct {
+	TodoID      int       `json:"todo_id" db:"todo_id"`
+	Revision    int       `json:"revision" db:"revision"`
+	Title       string    `json:"title" db:"title"`
+	Description *string   `json:"description" db:"description"`
+	Completed   bool      `json:"completed" db:"completed"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateTodoRequest represents the request to create a todo. ClientID is
+// an optional client-generated UUID: creating again with the same one
+// returns the existing todo instead of a duplicate. DueDate is parsed with
+// ParseTime. Status defaults to done for completed todos and todo
+// otherwise.
 type CreateTodoRequest struct {
-	Title       string  `json:"title" validate:"required,min=1,max=255"`
-	Description *string `json:"description" validate:"omitempty,max=1000"`
-	Completed   bool    `json:"completed"`
+	Title       string   `json:"title" validate:"required,min=1,max=255"`
+	Description *string  `json:"description" validate:"omitempty,max=1000"`
+	Completed   bool     `json:"completed"`
+	Status      *string  `json:"status,omitempty" enums:"todo,in_progress,blocked,done"`
+	Priority    *string  `json:"priority,omitempty" enums:"low,medium,high,urgent"`
+	ClientID    *string  `json:"client_id,omitempty" validate:"omitempty,uuid"`
+	DueDate     *string  `json:"due_date,omitempty" example:"2024-03-01"`
+	Tags        []string `json:"tags,omitempty" example:"home,errands"`
+	Color       *string  `json:"color,omitempty" example:"teal"`
+	Icon        *string  `json:"icon,omitempty" example:"lucide:check"`
+	// EstimateMinutes is the expected effort, at most one year; 0 means
+	// not estimated
+	EstimateMinutes *int      `json:"estimate_minutes,omitempty" example:"90"`
+	Location        *Location `json:"location,omitempty"`
+	// Metadata is a JSON object of at most 16 KiB
+	Metadata json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// QuickAddRequest creates a todo from one line of free text, such as
+// "Buy milk #groceries !high tomorrow"
+type QuickAddRequest struct {
+	Text string `json:"text" validate:"required" example:"Buy milk #groceries !high tomorrow"`
 }
 
 // UpdateTodoRequest represents the request to update a todo
@@ -26,13 +220,57 @@ type UpdateTodoRequest struct {
 	Title       *string `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
 	Completed   *bool   `json:"completed,omitempty"`
+	// Status moves the todo through the workflow. Completing a todo sets
+	// it to done and reopening a done todo sets it back to todo.
+	Status *string `json:"status,omitempty" enums:"todo,in_progress,blocked,done"`
+	// Priority is low, medium, high or urgent; an empty string removes it
+	Priority *string `json:"priority,omitempty" enums:"low,medium,high,urgent"`
+	// DueDate is parsed with ParseTime; an empty string removes it
+	DueDate *string `json:"due_date,omitempty" example:"2024-03-01"`
+	// Tags, when set, replace all of the todo's tags; an empty list
+	// removes them
+	Tags *[]string `json:"tags,omitempty"`
+	// Color is a palette name or a hex color; an empty string removes it
+	Color *string `json:"color,omitempty" example:"teal"`
+	// Icon is an emoji or an icon name; an empty string removes it
+	Icon *string `json:"icon,omitempty" example:"lucide:check"`
+	// EstimateMinutes is the expected effort; 0 removes the estimate
+	EstimateMinutes *int `json:"estimate_minutes,omitempty" example:"90"`
+	// Location, when set, replaces the todo's location; null removes it
+	Location json.RawMessage `json:"location,omitempty" swaggertype:"object"`
+	// Metadata is deep-merged into the stored metadata: keys set to null
+	// are removed and null removes all metadata
+	Metadata json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	// Version, when set, makes the update conditional: it fails with 409
+	// Conflict if the todo has been changed since that version was read
+	Version *int `json:"version,omitempty"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. RequestID matches the
+// X-Request-ID response header and the request_id in the server logs.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    int    `json:"code,omitempty"`
-	Details string `json:"details,omitempty"`
+	Error     string `json:"error"`
+	Code      int    `json:"code,omitempty"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Envelope wraps a response body when the client asks for it with
+// ?envelope=true. Data is null on errors; Meta carries pagination for
+// list endpoints.
+type Envelope struct {
+	Data  interface{} `json:"data"`
+	Meta  interface{} `json:"meta,omitempty"`
+	Error interface{} `json:"error,omitempty"`
+}
+
+// ConflictResponse is returned with 409 Conflict when an update names a
+// stale version. Current is the todo as stored, for clients to merge with.
+type ConflictResponse struct {
+	Error     string       `json:"error"`
+	Code      int          `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Current   TodoResponse `json:"current"`
 }
 
 // SuccessResponse represents a success response
@@ -47,6 +285,90 @@ type HealthResponse struct {
 	Timestamp time.Time `json:"timestamp"`
 	Version   string    `json:"version"`
 	Uptime    string    `json:"uptime"`
+	// Checks are the measurements of a deep health check, by name
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// Statuses of a health check. A deep health check is ok, degraded when a
+// check warns, or unhealthy when one failed.
+const (
+	HealthOK        = "ok"
+	HealthWarn      = "warn"
+	HealthFailed    = "failed"
+	HealthSkipped   = "skipped"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+// HealthCheck is one measurement of a deep health check: how long a query
+// took, or how much disk space is left for the database file
+type HealthCheck struct {
+	Status      string  `json:"status" example:"ok"`
+	LatencyMS   float64 `json:"latency_ms,omitempty" example:"0.42"`
+	FreeBytes   uint64  `json:"free_bytes,omitempty"`
+	TotalBytes  uint64  `json:"total_bytes,omitempty"`
+	FreePercent float64 `json:"free_percent,omitempty" example:"63.5"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// StatusResponse is what a status page shows: the state of every
+// component at the last deep health check and the recent incidents
+type StatusResponse struct {
+	Status     string                     `json:"status" example:"ok"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+	Components map[string]ComponentStatus `json:"components"`
+	// Incidents are newest first
+	Incidents []Incident `json:"incidents"`
+}
+
+// ComponentStatus is the state of a component and when it entered it
+type ComponentStatus struct {
+	Status string    `json:"status" example:"ok"`
+	Since  time.Time `json:"since"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Incident is a time a component warned or failed. Status is the worst it
+// got and Error the last error it reported.
+type Incident struct {
+	Component  string     `json:"component" example:"write"`
+	Status     string     `json:"status" example:"failed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ReplicationStatus describes how the database file is replicated, as
+// far as this instance can tell from the files the tool keeps beside it
+type ReplicationStatus struct {
+	// Mode is litestream, litefs or none
+	Mode        string `json:"mode" example:"litefs"`
+	ReadOnly    bool   `json:"read_only"`
+	JournalMode string `json:"journal_mode" example:"wal"`
+	// WALBytes is the size of the write-ahead log not checkpointed yet
+	WALBytes int64 `json:"wal_bytes"`
+	// Primary tells whether this LiteFS node holds the write lease, and
+	// PrimaryHost which node does when it does not
+	Primary     *bool  `json:"primary,omitempty"`
+	PrimaryHost string `json:"primary_host,omitempty" example:"node-1"`
+	// Position is LiteFS's transaction ID and checksum for the database
+	Position string `json:"position,omitempty" example:"000000000000002a/8f1c2a0b9d3e4f56"`
+	// Generation is the Litestream generation the WAL is replicated under
+	Generation string `json:"generation,omitempty" example:"b16ddcf5c697540f"`
+}
+
+// VersionResponse describes the running build
+type VersionResponse struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	BuildTime   string `json:"build_time"`
+	GoVersion   string `json:"go_version"`
+	Environment string `json:"environment"`
+}
+
+// LogLevel reports or changes the runtime log level
+type LogLevel struct {
+	Level string `json:"level" example:"info"`
 }
 
 // PaginatedResponse represents a paginated response
@@ -56,6 +378,77 @@ type PaginatedResponse struct {
 	Page       int         `json:"page"`
 	PerPage    int         `json:"per_page"`
 	TotalPages int         `json:"total_pages"`
+	// HasMore reports whether a later page has data. Todo lists requested
+	// with include_total=false only have this: Total and TotalPages are 0.
+	HasMore bool `json:"has_more"`
+}
+
+// TagStats counts the todos carrying a tag
+type TagStats struct {
+	Tag       string `json:"tag"`
+	Open      int    `json:"open"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+}
+
+// WeeklyEstimate sums the estimates of the todos created and completed in
+// the week starting on WeekStart, a Monday
+type WeeklyEstimate struct {
+	WeekStart          string `json:"week_start" example:"2024-03-04"`
+	Created            int    `json:"created"`
+	EstimatedCreated   int    `json:"estimated_minutes_created"`
+	Completed          int    `json:"completed"`
+	EstimatedCompleted int    `json:"estimated_minutes_completed"`
+}
+
+// EstimateStats reports estimated effort per week, oldest week first,
+// together with the estimate of the work still open
+type EstimateStats struct {
+	Weeks []WeeklyEstimate `json:"weeks"`
+	// RemainingMinutes sums the estimates of the open todos
+	RemainingMinutes int `json:"remaining_minutes"`
+	// Unestimated counts the open todos without an estimate
+	Unestimated int `json:"unestimated"`
+}
+
+// CountResponse is returned by count-only todo queries
+type CountResponse struct {
+	Total int `json:"total"`
+}
+
+// SearchResult is a todo matched by full-text search, most relevant first.
+// Highlights repeat the matched fields with every match wrapped in <mark>
+// tags; long descriptions are cut to the matching fragment.
+type SearchResult struct {
+	Todo
+	Score      float64          `json:"score"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchResultResponse is the API representation of a SearchResult
+type SearchResultResponse struct {
+	TodoResponse
+	Score      float64          `json:"score"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// NewSearchResultResponses maps a page of search results
+func NewSearchResultResponses(results []SearchResult) []SearchResultResponse {
+	responses := make([]SearchResultResponse, len(results))
+	for i := range results {
+		responses[i] = SearchResultResponse{
+			TodoResponse: NewTodoResponse(&results[i].Todo),
+			Score:        results[i].Score,
+			Highlights:   results[i].Highlights,
+		}
+	}
+	return responses
+}
+
+// SearchHighlights holds the highlighted fields of a search result
+type SearchHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
 }
 
 // QueryParams represents common query parameters
@@ -66,6 +459,66 @@ type QueryParams struct {
 	Order     string `query:"order" validate:"omitempty,oneof=asc desc"`
 	Search    string `query:"search" validate:"omitempty,max=255"`
 	Completed *bool  `query:"completed"`
+	// CompletedAfter and CompletedBefore select todos completed in
+	// [CompletedAfter, CompletedBefore)
+	CompletedAfter  *time.Time `query:"completed_after"`
+	CompletedBefore *time.Time `query:"completed_before"`
+	// CreatedAfter and CreatedBefore select todos created in
+	// [CreatedAfter, CreatedBefore)
+	CreatedAfter  *time.Time `query:"created_after"`
+	CreatedBefore *time.Time `query:"created_before"`
+	// DueAfter and DueBefore select todos due in [DueAfter, DueBefore).
+	// Due is "none" for todos without a due date or "any" for those with
+	// one.
+	DueAfter  *time.Time `query:"due_after"`
+	DueBefore *time.Time `query:"due_before"`
+	Due       string     `query:"due" validate:"omitempty,oneof=none any"`
+	// IDs selects exactly these todos. Without an explicit sort they are
+	// returned in the order given.
+	IDs []int `query:"ids"`
+	// Tag selects the todos carrying the tag
+	Tag string `query:"tag"`
+	// Status selects the todos in any of these statuses
+	Status []string `query:"status"`
+	// Color selects the todos with any of these colors, in the form
+	// returned by NormalizeColor
+	Color []string `query:"color"`
+	// Metadata selects the todos whose metadata has each value at the
+	// dotted key path. Booleans match "true" and "false".
+	Metadata map[string]string `query:"-"`
+	// Trashed selects the todos in the trash instead of the live ones
+	Trashed bool `query:"-"`
+	// Exclude holds the filters negated with field!=value
+	Exclude QueryExclusions `query:"-"`
+	// SkipTotal leaves out counting the matching todos, which on a large
+	// table costs more than the page itself (include_total=false)
+	SkipTotal bool `query:"-"`
+}
+
+// QueryExclusions select the todos that do not match each filter. Their
+// values have the same meaning as in QueryParams.
+type QueryExclusions struct {
+	Completed *bool
+	Due       string
+	IDs       []int
+	Search    string
+	Tag       string
+	Status    []string
+	Color     []string
+	Metadata  map[string]string
+}
+
+// ParseTime parses a time accepted by the API: RFC3339, or a YYYY-MM-DD
+// date meaning midnight UTC
+func ParseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC3339 time or a YYYY-MM-DD date")
+	}
+	return t, nil
 }
 
 // DefaultQueryParams returns default query parameters
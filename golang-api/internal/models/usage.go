@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// UsageRecord counts a user's API calls on one UTC day with one
+// credential. APIKeyID is nil for calls made with access tokens.
+type UsageRecord struct {
+	Day      string `json:"day" example:"2026-10-16"`
+	APIKeyID *int   `json:"api_key_id,omitempty"`
+	Calls    int    `json:"calls" example:"120"`
+}
+
+// StoredItems counts what a user stores. Todos, notes and saved searches
+// are shared and record no owner, so they are only counted in total.
+type StoredItems struct {
+	APIKeys         int `json:"api_keys"`
+	Hooks           int `json:"hooks"`
+	Notifications   int `json:"notifications"`
+	GoogleTaskLinks int `json:"google_task_links"`
+}
+
+// UsageQuery selects the UTC days From to To, both included
+type UsageQuery struct {
+	From time.Time
+	To   time.Time
+}
+
+// Usage is a user's metered API calls over a period, per day and
+// credential, and what they store now
+type Usage struct {
+	From   string        `json:"from" example:"2026-10-01"`
+	To     string        `json:"to" example:"2026-10-31"`
+	Calls  int           `json:"calls"`
+	Daily  []UsageRecord `json:"daily"`
+	Stored StoredItems   `json:"stored"`
+}
+
+// UserUsage is one user's line in the usage report
+type UserUsage struct {
+	UserID int         `json:"user_id"`
+	Email  string      `json:"email"`
+	Calls  int         `json:"calls"`
+	Stored StoredItems `json:"stored"`
+}
+
+// UsageReport totals usage over all users for a period, for billing and
+// capacity planning. Calls includes users deleted since; Users lists the
+// users with the most calls first.
+type UsageReport struct {
+	From          string      `json:"from" example:"2026-10-01"`
+	To            string      `json:"to" example:"2026-10-31"`
+	Calls         int         `json:"calls"`
+	Todos         int         `json:"todos"`
+	Notes         int         `json:"notes"`
+	SavedSearches int         `json:"saved_searches"`
+	Users         []UserUsage `json:"users"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// User represents a registered account. Plan is nil for users on the
+// default plan.
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	Name         *string   `json:"name,omitempty" db:"name"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Plan         *string   `json:"plan,omitempty" db:"plan"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RegisterRequest represents the request to create an account
+type RegisterRequest struct {
+	Email    string  `json:"email" validate:"required,email,max=255"`
+	Password string  `json:"password" validate:"required,min=8,max=72"`
+	Name     *string `json:"name,omitempty" validate:"omitempty,max=255"`
+}
+
+// LoginRequest represents the request to authenticate with a password
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// TokenRequest represents the request for an access token limited to
+// scopes, such as a read-only token for a dashboard
+type TokenRequest struct {
+	Scopes []string `json:"scopes" validate:"required"`
+}
+
+// TokenResponse is returned when a scoped access token is issued
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Scopes    []string  `json:"scopes"`
+}
+
+// AuthResponse is returned after a successful registration or login
+type AuthResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+}
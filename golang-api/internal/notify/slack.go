@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/events"
+)
+
+// DefaultSlackTemplates are used for event types without a configured
+// template
+var DefaultSlackTemplates = map[string]string{
+	events.TodoCreated:       `:memo: New todo *{{ .Todo.Title }}*{{ if .Todo.Description }}: {{ .Todo.Description }}{{ end }}`,
+	events.TodoCompleted:     `:white_check_mark: Completed *{{ .Todo.Title }}*`,
+	events.TodoReopened:      `:leftwards_arrow_with_hook: Reopened *{{ .Todo.Title }}*`,
+	events.TodoStatusChanged: `:arrow_right: Moved *{{ .Todo.Title }}* from {{ index .Data "from" }} to {{ .Todo.Status }}`,
+	events.TodoDeleted:       `:wastebasket: Moved todo #{{ .TodoID }} to the trash`,
+	events.TodoRestored:      `:recycle: Restored *{{ .Todo.Title }}* from the trash`,
+	events.TodoPurged:        `:fire: Permanently deleted todo #{{ .TodoID }}`,
+	events.TodosPurged:       `:broom: Purged {{ index .Data "count" }} completed todos`,
+	events.TrashPurged:       `:fire: Permanently deleted {{ index .Data "count" }} todos from the trash`,
+	events.TodoDueSoon:       `:alarm_clock: *{{ .Todo.Title }}* is due {{ .Todo.DueDate.Format "Jan 2 15:04 MST" }}`,
+	events.NoteCreated:       `:speech_balloon: New note on *{{ .Todo.Title }}*`,
+}
+
+// SlackNotifier posts templated messages to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	templates  map[string]*template.Template
+	client     *http.Client
+}
+
+// NewSlackNotifier parses the message templates for the configured event
+// types. cfg.Templates overrides DefaultSlackTemplates per event type.
+func NewSlackNotifier(cfg config.SlackConfig) (*SlackNotifier, error) {
+	templates := make(map[string]*template.Template, len(cfg.Events))
+	for _, eventType := range cfg.Events {
+		text, ok := cfg.Templates[eventType]
+		if !ok {
+			text, ok = DefaultSlackTemplates[eventType]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no Slack template for event type %s", eventType)
+		}
+
+		tmpl, err := template.New(eventType).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Slack template for %s: %w", eventType, err)
+		}
+		templates[eventType] = tmpl
+	}
+
+	return &SlackNotifier{
+		webhookURL: cfg.WebhookURL,
+		templates:  templates,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Events returns the event types the notifier posts about
+func (n *SlackNotifier) Events() []string {
+	types := make([]string, 0, len(n.templates))
+	for eventType := range n.templates {
+		types = append(types, eventType)
+	}
+	return types
+}
+
+// Handle renders the event and posts it to the webhook
+func (n *SlackNotifier) Handle(ctx context.Context, evt events.Event) error {
+	tmpl, ok := n.templates[evt.Type]
+	if !ok {
+		return nil
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, evt); err != nil {
+		return fmt.Errorf("failed to render Slack message: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
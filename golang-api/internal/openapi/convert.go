@@ -0,0 +1,286 @@
+// Package openapi converts the Swagger 2.0 document generated by swag from
+// the handler annotations to OpenAPI 3.0
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Version is the OpenAPI version of converted documents
+const Version = "3.0.3"
+
+// schemaFields are the keys of a Swagger 2.0 non-body parameter that
+// describe its value; OpenAPI 3 moves them into the parameter's schema
+var schemaFields = []string{
+	"type", "format", "items", "enum", "default", "minimum", "maximum",
+	"exclusiveMinimum", "exclusiveMaximum", "minLength", "maxLength", "pattern",
+	"minItems", "maxItems", "uniqueItems", "multipleOf",
+}
+
+// Convert returns the OpenAPI 3 equivalent of a Swagger 2.0 document.
+// Servers are relative to the document's basePath, so the result works
+// behind any host.
+func Convert(swagger []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(swagger, &doc); err != nil {
+		return nil, fmt.Errorf("invalid swagger document: %w", err)
+	}
+	if doc["swagger"] != "2.0" {
+		return nil, fmt.Errorf("unsupported swagger version %v", doc["swagger"])
+	}
+
+	consumes := stringList(doc["consumes"], "application/json")
+	produces := stringList(doc["produces"], "application/json")
+
+	basePath, _ := doc["basePath"].(string)
+	if basePath == "" {
+		basePath = "/"
+	}
+
+	out := map[string]interface{}{
+		"openapi": Version,
+		"info":    doc["info"],
+		"servers": []interface{}{map[string]interface{}{"url": basePath}},
+	}
+	if tags, ok := doc["tags"]; ok {
+		out["tags"] = tags
+	}
+
+	paths := map[string]interface{}{}
+	for path, item := range mapOf(doc["paths"]) {
+		converted := map[string]interface{}{}
+		for method, op := range mapOf(item) {
+			if method == "parameters" {
+				converted[method] = convertParameters(op)
+				continue
+			}
+			converted[method] = convertOperation(mapOf(op), consumes, produces)
+		}
+		paths[path] = converted
+	}
+	out["paths"] = paths
+
+	components := map[string]interface{}{}
+	if definitions, ok := doc["definitions"]; ok {
+		components["schemas"] = definitions
+	}
+	if security := mapOf(doc["securityDefinitions"]); len(security) > 0 {
+		schemes := map[string]interface{}{}
+		for name, scheme := range security {
+			schemes[name] = convertSecurityScheme(mapOf(scheme))
+		}
+		components["securitySchemes"] = schemes
+	}
+	if len(components) > 0 {
+		out["components"] = components
+	}
+	if security, ok := doc["security"]; ok {
+		out["security"] = security
+	}
+
+	return json.Marshal(rewriteRefs(out))
+}
+
+func convertOperation(op map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	consumes = stringList(op["consumes"], consumes...)
+	produces = stringList(op["produces"], produces...)
+
+	out := map[string]interface{}{}
+	for key, value := range op {
+		switch key {
+		case "consumes", "produces", "schemes", "parameters", "responses":
+		default:
+			out[key] = value
+		}
+	}
+
+	var params []interface{}
+	form := map[string]interface{}{}
+	var formRequired []interface{}
+	for _, p := range sliceOf(op["parameters"]) {
+		param := mapOf(p)
+		switch param["in"] {
+		case "body":
+			body := map[string]interface{}{"content": content(consumes, param["schema"])}
+			if description, ok := param["description"]; ok {
+				body["description"] = description
+			}
+			if required, ok := param["required"]; ok {
+				body["required"] = required
+			}
+			out["requestBody"] = body
+		case "formData":
+			schema := parameterSchema(param)
+			if schema["type"] == "file" {
+				schema = map[string]interface{}{"type": "string", "format": "binary"}
+			}
+			if description, ok := param["description"]; ok {
+				schema["description"] = description
+			}
+			form[param["name"].(string)] = schema
+			if required, _ := param["required"].(bool); required {
+				formRequired = append(formRequired, param["name"])
+			}
+		default:
+			params = append(params, convertParameter(param))
+		}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if len(form) > 0 {
+		schema := map[string]interface{}{"type": "object", "properties": form}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		formTypes := consumes
+		if len(formTypes) == 0 || formTypes[0] == "application/json" {
+			formTypes = []string{"multipart/form-data"}
+		}
+		out["requestBody"] = map[string]interface{}{"content": content(formTypes, schema)}
+	}
+
+	responses := map[string]interface{}{}
+	for status, r := range mapOf(op["responses"]) {
+		responses[status] = convertResponse(mapOf(r), produces)
+	}
+	out["responses"] = responses
+
+	return out
+}
+
+func convertParameters(params interface{}) []interface{} {
+	var out []interface{}
+	for _, p := range sliceOf(params) {
+		out = append(out, convertParameter(mapOf(p)))
+	}
+	return out
+}
+
+func convertParameter(param map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for key, value := range param {
+		if !slices.Contains(schemaFields, key) && key != "collectionFormat" && key != "allowEmptyValue" {
+			out[key] = value
+		}
+	}
+	out["schema"] = parameterSchema(param)
+
+	// Swagger 2.0 query arrays are comma-separated unless stated otherwise
+	if param["type"] == "array" {
+		switch param["collectionFormat"] {
+		case "multi":
+			out["style"], out["explode"] = "form", true
+		case "ssv":
+			out["style"] = "spaceDelimited"
+		case "pipes":
+			out["style"] = "pipeDelimited"
+		default:
+			out["style"], out["explode"] = "form", false
+		}
+	}
+	return out
+}
+
+func parameterSchema(param map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range schemaFields {
+		if value, ok := param[key]; ok {
+			schema[key] = value
+		}
+	}
+	return schema
+}
+
+func convertResponse(r map[string]interface{}, produces []string) map[string]interface{} {
+	out := map[string]interface{}{"description": r["description"]}
+	if out["description"] == nil {
+		out["description"] = ""
+	}
+	if schema, ok := r["schema"]; ok {
+		if mapOf(schema)["type"] == "file" {
+			schema = map[string]interface{}{"type": "string", "format": "binary"}
+		}
+		out["content"] = content(produces, schema)
+	}
+	if headers := mapOf(r["headers"]); len(headers) > 0 {
+		converted := map[string]interface{}{}
+		for name, h := range headers {
+			header := mapOf(h)
+			entry := map[string]interface{}{"schema": parameterSchema(header)}
+			if description, ok := header["description"]; ok {
+				entry["description"] = description
+			}
+			converted[name] = entry
+		}
+		out["headers"] = converted
+	}
+	return out
+}
+
+func convertSecurityScheme(scheme map[string]interface{}) map[string]interface{} {
+	if scheme["type"] == "basic" {
+		out := map[string]interface{}{"type": "http", "scheme": "basic"}
+		if description, ok := scheme["description"]; ok {
+			out["description"] = description
+		}
+		return out
+	}
+	return scheme
+}
+
+func content(mediaTypes []string, schema interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, mediaType := range mediaTypes {
+		out[mediaType] = map[string]interface{}{"schema": schema}
+	}
+	return out
+}
+
+// rewriteRefs points references to definitions at the components
+func rewriteRefs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				v[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			v[key] = rewriteRefs(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = rewriteRefs(child)
+		}
+	}
+	return value
+}
+
+func mapOf(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func sliceOf(value interface{}) []interface{} {
+	s, _ := value.([]interface{})
+	return s
+}
+
+// stringList returns value as a list of strings, or fallback when it is
+// not set
+func stringList(value interface{}, fallback ...string) []string {
+	values := sliceOf(value)
+	if len(values) == 0 {
+		return fallback
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
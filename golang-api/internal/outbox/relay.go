@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// Relay polls the outbox table and delivers stored events to subscribers.
+// Delivery is at-least-once: a message is only marked delivered after every
+// subscriber handled it, and subscribers that already succeeded are skipped
+// on retry.
+type Relay struct {
+	repo       repository.OutboxRepository
+	dispatcher events.Dispatcher
+	cfg        config.OutboxConfig
+	logger     *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRelay(repo repository.OutboxRepository, dispatcher events.Dispatcher, cfg config.OutboxConfig, logger *slog.Logger) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 250 * time.Millisecond
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &Relay{
+		repo:       repo,
+		dispatcher: dispatcher,
+		cfg:        cfg,
+		logger:     logger,
+	}
+}
+
+// Start launches the polling loop
+func (r *Relay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.loop(ctx)
+	r.logger.Info("Outbox relay started", "poll_interval", r.cfg.PollInterval.String())
+}
+
+// Stop waits for the current batch to finish and stops polling
+func (r *Relay) Stop() {
+	r.mu.Lock()
+	if r.cancel == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+	done := r.done
+	r.mu.Unlock()
+
+	<-done
+	r.logger.Info("Outbox relay stopped")
+}
+
+// Pause stops polling like Stop and returns a function that starts it
+// again, if the relay was running
+func (r *Relay) Pause() (resume func()) {
+	r.mu.Lock()
+	running := r.cancel != nil
+	r.mu.Unlock()
+	if !running {
+		return func() {}
+	}
+
+	r.Stop()
+	return r.Start
+}
+
+func (r *Relay) loop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RelayPending(ctx); err != nil {
+			r.logger.Error("Failed to relay outbox messages", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending delivers one batch of due messages
+func (r *Relay) RelayPending(ctx context.Context) error {
+	messages, err := r.repo.FetchPending(time.Now(), r.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			return nil
+		}
+		r.deliver(ctx, msg)
+	}
+
+	return nil
+}
+
+func (r *Relay) deliver(ctx context.Context, msg models.OutboxMessage) {
+	var evt events.Event
+	if err := json.Unmarshal(msg.Payload, &evt); err != nil {
+		r.markFailed(msg, msg.DeliveredTo, fmt.Errorf("invalid event payload: %w", err), false)
+		return
+	}
+	evt.ID = msg.ID
+
+	skip := make(map[string]bool, len(msg.DeliveredTo))
+	for _, name := range msg.DeliveredTo {
+		skip[name] = true
+	}
+
+	delivered, err := r.dispatcher.Dispatch(ctx, evt, skip)
+	deliveredTo := append(msg.DeliveredTo, delivered...)
+
+	if err != nil {
+		r.markFailed(msg, deliveredTo, err, true)
+		return
+	}
+
+	if err := r.repo.MarkDelivered(msg.ID, deliveredTo); err != nil {
+		r.logger.Error("Failed to mark outbox message as delivered", "id", msg.ID, "error", err)
+	}
+}
+
+func (r *Relay) markFailed(msg models.OutboxMessage, deliveredTo []string, deliveryErr error, retryable bool) {
+	attempt := msg.Attempts + 1
+
+	var retryAt *time.Time
+	if retryable && attempt < r.cfg.MaxAttempts {
+		// Exponential backoff capped at one hour
+		backoff := r.cfg.RetryBackoff * time.Duration(1<<min(attempt-1, 10))
+		if backoff > time.Hour {
+			backoff = time.Hour
+		}
+		next := time.Now().Add(backoff)
+		retryAt = &next
+	}
+
+	if err := r.repo.MarkFailed(msg.ID, deliveredTo, deliveryErr.Error(), retryAt); err != nil {
+		r.logger.Error("Failed to record outbox delivery failure", "id", msg.ID, "error", err)
+		return
+	}
+
+	if retryAt != nil {
+		r.logger.Warn("Outbox delivery failed, will retry", "id", msg.ID, "type", msg.EventType, "attempt", attempt, "retry_at", *retryAt, "error", deliveryErr)
+		return
+	}
+
+	r.logger.Error("Outbox delivery failed permanently", "id", msg.ID, "type", msg.EventType, "attempts", attempt, "error", deliveryErr)
+}
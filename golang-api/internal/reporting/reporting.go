@@ -0,0 +1,158 @@
+// Package reporting sends panics and server errors to an external error
+// tracker such as Sentry.
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+)
+
+const queueSize = 64
+
+// Event describes a panic or 5xx response
+type Event struct {
+	Message   string    `json:"message"`
+	Panic     bool      `json:"panic"`
+	Stack     string    `json:"stack,omitempty"`
+	Status    int       `json:"status"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Route     string    `json:"route,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	UserID    int       `json:"user_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Reporter accepts error events. Report never blocks the request; events
+// are delivered in the background and dropped if the tracker falls behind.
+type Reporter interface {
+	Report(evt Event)
+	// Close delivers queued events and stops the reporter
+	Close()
+}
+
+type sender interface {
+	send(ctx context.Context, evt Event) error
+}
+
+// New creates the reporter selected by cfg.DSN. An http(s) DSN is treated
+// as a Sentry DSN (https://<key>@<host>/<project>); a webhook+http(s) DSN
+// posts each event as JSON to the URL after the prefix. Without a DSN,
+// events are discarded.
+func New(cfg config.ErrorReportingConfig, app config.AppConfig, logger *slog.Logger) (Reporter, error) {
+	if cfg.DSN == "" {
+		return Nop(), nil
+	}
+
+	u, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error reporting DSN: %w", err)
+	}
+
+	meta := metadata{
+		environment: app.Environment,
+		release:     app.Version,
+	}
+	meta.serverName, _ = os.Hostname()
+
+	var s sender
+	switch {
+	case strings.HasPrefix(u.Scheme, "webhook+"):
+		u.Scheme = strings.TrimPrefix(u.Scheme, "webhook+")
+		s = newWebhookSender(u.String(), meta)
+	case u.Scheme == "http" || u.Scheme == "https":
+		s, err = newSentrySender(u, meta)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported error reporting DSN scheme: %s", u.Scheme)
+	}
+
+	r := &asyncReporter{
+		sender: s,
+		logger: logger,
+		queue:  make(chan Event, queueSize),
+	}
+	r.wg.Add(1)
+	go r.run()
+
+	logger.Info("Error reporting enabled", "host", u.Host)
+	return r, nil
+}
+
+// Nop returns a reporter that discards every event
+func Nop() Reporter {
+	return nopReporter{}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Report(Event) {}
+func (nopReporter) Close()       {}
+
+type metadata struct {
+	environment string
+	release     string
+	serverName  string
+}
+
+type asyncReporter struct {
+	sender sender
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	queue  chan Event
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func (r *asyncReporter) Report(evt Event) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- evt:
+	default:
+		r.logger.Warn("Error report queue full, dropping event", "message", evt.Message, "request_id", evt.RequestID)
+	}
+}
+
+func (r *asyncReporter) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *asyncReporter) run() {
+	defer r.wg.Done()
+
+	for evt := range r.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := r.sender.send(ctx, evt); err != nil {
+			r.logger.Error("Failed to report error", "message", evt.Message, "request_id", evt.RequestID, "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,211 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sentrySender delivers events to Sentry (or a Sentry-compatible tracker
+// such as GlitchTip) using the envelope endpoint
+type sentrySender struct {
+	endpoint string
+	auth     string
+	meta     metadata
+	client   *http.Client
+}
+
+func newSentrySender(dsn *url.URL, meta metadata) (*sentrySender, error) {
+	key := dsn.User.Username()
+	project := path.Base(dsn.Path)
+	if key == "" || project == "." || project == "/" {
+		return nil, fmt.Errorf("invalid Sentry DSN: expected https://<key>@<host>/<project>")
+	}
+
+	endpoint := url.URL{
+		Scheme: dsn.Scheme,
+		Host:   dsn.Host,
+		Path:   path.Join(path.Dir(dsn.Path), "api", project, "envelope") + "/",
+	}
+
+	return &sentrySender{
+		endpoint: endpoint.String(),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=todo-api/%s", key, meta.release),
+		meta:     meta,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+	Request     sentryRequest     `json:"request"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type sentryUser struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+func (s *sentrySender) send(ctx context.Context, evt Event) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate event id: %w", err)
+	}
+	eventID := hex.EncodeToString(id)
+
+	payload := sentryEvent{
+		EventID:     eventID,
+		Timestamp:   evt.Timestamp.UTC().Format(time.RFC3339Nano),
+		Level:       "error",
+		Platform:    "go",
+		Environment: s.meta.environment,
+		Release:     s.meta.release,
+		ServerName:  s.meta.serverName,
+		Transaction: evt.Method + " " + evt.Route,
+		Request: sentryRequest{
+			Method:  evt.Method,
+			URL:     evt.URL,
+			Headers: map[string]string{"User-Agent": evt.UserAgent},
+		},
+		Tags: map[string]string{
+			"status":     strconv.Itoa(evt.Status),
+			"request_id": evt.RequestID,
+		},
+	}
+
+	exception := sentryException{Type: "error", Value: evt.Message}
+	if evt.Panic {
+		payload.Level = "fatal"
+		exception.Type = "panic"
+	}
+	if frames := parseStack(evt.Stack); len(frames) > 0 {
+		exception.Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	payload.Exception.Values = []sentryException{exception}
+
+	if evt.UserID != 0 || evt.IP != "" {
+		payload.User = &sentryUser{IPAddress: evt.IP}
+		if evt.UserID != 0 {
+			payload.User.ID = strconv.Itoa(evt.UserID)
+		}
+	}
+
+	item, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode Sentry event: %w", err)
+	}
+
+	var envelope bytes.Buffer
+	fmt.Fprintf(&envelope, "{\"event_id\":%q,\"sent_at\":%q}\n", eventID, time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&envelope, "{\"type\":\"event\",\"length\":%d}\n", len(item))
+	envelope.Write(item)
+	envelope.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &envelope)
+	if err != nil {
+		return fmt.Errorf("failed to create Sentry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Sentry event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// parseStack converts a runtime/debug.Stack trace into Sentry frames,
+// oldest call first as Sentry expects
+func parseStack(stack string) []sentryFrame {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	if len(lines) < 3 {
+		return nil
+	}
+
+	// The first line is the goroutine header, then function/location pairs
+	var frames []sentryFrame
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := strings.TrimPrefix(lines[i], "created by ")
+		if idx := strings.LastIndex(function, "("); idx > 0 && !strings.HasPrefix(lines[i], "created by ") {
+			function = function[:idx]
+		}
+		if idx := strings.Index(function, " in goroutine "); idx > 0 {
+			function = function[:idx]
+		}
+
+		location := strings.TrimSpace(lines[i+1])
+		if idx := strings.LastIndex(location, " +0x"); idx > 0 {
+			location = location[:idx]
+		}
+		file, lineno := location, 0
+		if idx := strings.LastIndex(location, ":"); idx > 0 {
+			file = location[:idx]
+			lineno, _ = strconv.Atoi(location[idx+1:])
+		}
+
+		frames = append(frames, sentryFrame{
+			Function: function,
+			AbsPath:  file,
+			Lineno:   lineno,
+			InApp:    strings.Contains(function, "todo-api/"),
+		})
+	}
+
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
@@ -0,0 +1,62 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookSender posts events as JSON to an arbitrary endpoint
+type webhookSender struct {
+	url    string
+	meta   metadata
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Event
+	Environment string `json:"environment"`
+	Release     string `json:"release"`
+	ServerName  string `json:"server_name"`
+}
+
+func newWebhookSender(url string, meta metadata) *webhookSender {
+	return &webhookSender{
+		url:    url,
+		meta:   meta,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *webhookSender) send(ctx context.Context, evt Event) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:       evt,
+		Environment: s.meta.environment,
+		Release:     s.meta.release,
+		ServerName:  s.meta.serverName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode error report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create error report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post error report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error report webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
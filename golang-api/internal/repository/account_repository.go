@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// AccountRepository erases user accounts
+type AccountRepository interface {
+	// Erase deletes the user and everything they own, replaces their
+	// email with label where shared data refers to them, and leaves a
+	// tombstone. It must run in a transaction.
+	Erase(ctx context.Context, user *models.User, label string) (*models.Tombstone, error)
+}
+
+type accountRepository struct {
+	db DBTX
+}
+
+func NewAccountRepository(db DBTX) AccountRepository {
+	return &accountRepository{db: db}
+}
+
+// ownedTables hold rows that belong to a single user. Foreign keys are
+// not enforced, so their ON DELETE CASCADE does not remove them.
+var ownedTables = []string{"user_identities", "api_keys", "notifications", "google_task_links", "google_accounts", "hooks", "api_usage"}
+
+// accountExportJob is the type of the jobs that export an account,
+// jobs.TypeAccountExport, named here as the jobs package imports this one
+const accountExportJob = "account_export"
+
+func (r *accountRepository) Erase(ctx context.Context, user *models.User, label string) (*models.Tombstone, error) {
+	for _, table := range ownedTables {
+		if _, err := r.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ?", user.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	// Export jobs hold a copy of everything about the user as their result
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM jobs WHERE type = ? AND json_extract(payload, '$.user_id') = ?", accountExportJob, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete account exports: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM login_failures WHERE kind = ? AND value = ?", models.LockoutAccount, strings.ToLower(user.Email)); err != nil {
+		return nil, fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	// The audit trail keeps what happened, but not who it happened to
+	if _, err := r.db.ExecContext(ctx, "UPDATE audit_log SET target = ?, ip = NULL WHERE target = ? COLLATE NOCASE", label, user.Email); err != nil {
+		return nil, fmt.Errorf("failed to anonymize audit log: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "UPDATE audit_log SET ip = NULL WHERE actor_id = ?", user.ID); err != nil {
+		return nil, fmt.Errorf("failed to anonymize audit log: %w", err)
+	}
+
+	email := regexp.MustCompile("(?i)" + regexp.QuoteMeta(user.Email))
+	if err := r.replaceText(ctx, "todo_notes", "body", user.Email, email, label); err != nil {
+		return nil, err
+	}
+	if err := r.replaceText(ctx, "outbox", "payload", user.Email, email, label); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", user.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	hash := sha256.Sum256([]byte(strings.ToLower(user.Email)))
+	tombstone := &models.Tombstone{UserID: user.ID, EmailHash: hex.EncodeToString(hash[:])}
+	err := r.db.QueryRowContext(ctx, "INSERT INTO deleted_users (user_id, email_hash) VALUES (?, ?) RETURNING deleted_at", tombstone.UserID, tombstone.EmailHash).
+		Scan(&tombstone.DeletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record tombstone: %w", err)
+	}
+
+	return tombstone, nil
+}
+
+// replaceText replaces every match of pattern in column with label, in
+// the rows whose column contains text. LIKE narrows the rows, ignoring
+// case like the pattern; the replacement is done here as SQLite's
+// replace() is case-sensitive.
+func (r *accountRepository) replaceText(ctx context.Context, table, column, text string, pattern *regexp.Regexp, label string) error {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT id, %s FROM %s WHERE %s LIKE ? ESCAPE '\\'", column, table, column), "%"+likeEscaper.Replace(text)+"%")
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", table, err)
+	}
+
+	replaced := map[int]string{}
+	for rows.Next() {
+		var id int
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		replaced[id] = pattern.ReplaceAllLiteralString(value, label)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+
+	for id, value := range replaced {
+		if _, err := r.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table, column), value, id); err != nil {
+			return fmt.Errorf("failed to anonymize %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
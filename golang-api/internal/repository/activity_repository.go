@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// ActivityRepository reads the activity feed from the outbox, which keeps
+// every domain event after delivery
+type ActivityRepository interface {
+	List(ctx context.Context, params models.ActivityQueryParams) ([]models.Activity, int, error)
+}
+
+type activityRepository struct {
+	db DBTX
+}
+
+func NewActivityRepository(db DBTX) ActivityRepository {
+	return &activityRepository{db: db}
+}
+
+// List returns a page of events, newest first, and the number of events
+// matching the filters
+func (r *activityRepository) List(ctx context.Context, params models.ActivityQueryParams) ([]models.Activity, int, error) {
+	where := []string{"1 = 1"}
+	args := []interface{}{}
+	if len(params.Types) > 0 {
+		where = append(where, "event_type IN (?"+strings.Repeat(", ?", len(params.Types)-1)+")")
+		for _, eventType := range params.Types {
+			args = append(args, eventType)
+		}
+	}
+	if params.TodoID > 0 {
+		where = append(where, "todo_id = ?")
+		args = append(args, params.TodoID)
+	}
+	if params.Since != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, sqliteTime(*params.Since))
+	}
+	if params.Until != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, sqliteTime(*params.Until))
+	}
+	whereClause := "WHERE " + strings.Join(where, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count activity: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, event_type, todo_id, json_extract(payload, '$.todo.title'),
+			json_extract(payload, '$.data'), json_extract(payload, '$.occurred_at')
+		FROM outbox
+		%s
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, whereClause)
+	args = append(args, params.PerPage, (params.Page-1)*params.PerPage)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := make([]models.Activity, 0)
+	for rows.Next() {
+		var entry models.Activity
+		var data sql.NullString
+		var occurredAt string
+		if err := rows.Scan(&entry.ID, &entry.Type, &entry.TodoID, &entry.Title, &data, &occurredAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if data.Valid {
+			if err := json.Unmarshal([]byte(data.String), &entry.Data); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode activity data: %w", err)
+			}
+		}
+		entry.OccurredAt, _ = time.Parse(time.RFC3339Nano, occurredAt)
+		activity = append(activity, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return activity, total, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	ListByUser(ctx context.Context, userID int) ([]models.APIKey, error)
+	GetByID(ctx context.Context, userID, id int) (*models.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	Delete(ctx context.Context, userID, id int) error
+	TouchLastUsed(ctx context.Context, id int, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db DBTX
+}
+
+func NewAPIKeyRepository(db DBTX) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at"
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode API key scopes: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key scopes: %w", err)
+	}
+
+	var expiresAt interface{}
+	if key.ExpiresAt != nil {
+		expiresAt = sqliteTime(*key.ExpiresAt)
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, string(scopes), expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created, err := r.GetByID(ctx, key.UserID, int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created API key: %w", err)
+	}
+
+	*key = *created
+	return nil
+}
+
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID int) ([]models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE user_id = ? ORDER BY id", apiKeyColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetByID returns the user's key, or nil if it does not exist or belongs
+// to another user
+func (r *apiKeyRepository) GetByID(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE id = ? AND user_id = ?", apiKeyColumns)
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE key_hash = ?", apiKeyColumns)
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key by hash: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int, usedAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", sqliteTime(usedAt), id); err != nil {
+		return fmt.Errorf("failed to update API key usage: %w", err)
+	}
+	return nil
+}
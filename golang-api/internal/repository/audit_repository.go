@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// AuditRepository appends to the audit log. Entries are never changed.
+type AuditRepository interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// ListByUser returns the entries the user acted in or whose target is
+	// their email, oldest first
+	ListByUser(ctx context.Context, userID int, email string) ([]models.AuditEntry, error)
+	CountByUser(ctx context.Context, userID int, email string) (int, error)
+	// Stream passes the entries matching query to fn, oldest first,
+	// without loading them all
+	Stream(ctx context.Context, query models.AuditQuery, fn func(models.AuditEntry) error) error
+}
+
+type auditRepository struct {
+	db DBTX
+}
+
+func NewAuditRepository(db DBTX) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	var details interface{}
+	if len(entry.Details) > 0 {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = string(encoded)
+	}
+
+	query := "INSERT INTO audit_log (action, actor_id, ip, target, details) VALUES (?, ?, ?, ?, ?) RETURNING id, created_at"
+
+	err := r.db.QueryRowContext(ctx, query, entry.Action, entry.ActorID, nullIfEmpty(entry.IP), nullIfEmpty(entry.Target), details).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+const auditColumns = "id, action, actor_id, ip, target, details, created_at"
+
+func scanAuditEntry(row rowScanner) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	var ip, target, details sql.NullString
+	if err := row.Scan(&entry.ID, &entry.Action, &entry.ActorID, &ip, &target, &details, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	entry.IP = ip.String
+	entry.Target = target.String
+	if details.Valid {
+		if err := json.Unmarshal([]byte(details.String), &entry.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode audit details: %w", err)
+		}
+	}
+
+	return &entry, nil
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID int, email string) ([]models.AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE actor_id = ? OR target = ? COLLATE NOCASE ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, userID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *auditRepository) CountByUser(ctx context.Context, userID int, email string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log WHERE actor_id = ? OR target = ? COLLATE NOCASE", userID, email).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	return count, nil
+}
+
+func (r *auditRepository) Stream(ctx context.Context, query models.AuditQuery, fn func(models.AuditEntry) error) error {
+	where := "WHERE 1 = 1"
+	var args []interface{}
+	if query.From != nil {
+		where += " AND created_at >= ?"
+		args = append(args, sqliteTime(*query.From))
+	}
+	if query.To != nil {
+		where += " AND created_at < ?"
+		args = append(args, sqliteTime(*query.To))
+	}
+	if query.ActorID != nil {
+		where += " AND actor_id = ?"
+		args = append(args, *query.ActorID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+auditColumns+" FROM audit_log "+where+" ORDER BY id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := fn(*entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
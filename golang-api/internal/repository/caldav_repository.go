@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// CalDAVRepository stores the resource names and UIDs that CalDAV clients
+// chose for the todos they created
+type CalDAVRepository interface {
+	List(ctx context.Context) (map[int]models.CalDAVObject, error)
+	GetByName(ctx context.Context, name string) (*models.CalDAVObject, error)
+	GetByUID(ctx context.Context, uid string) (*models.CalDAVObject, error)
+	GetByTodoID(ctx context.Context, todoID int) (*models.CalDAVObject, error)
+	Create(ctx context.Context, obj *models.CalDAVObject) error
+	CTag(ctx context.Context) (int, error)
+}
+
+type calDAVRepository struct {
+	db DBTX
+}
+
+func NewCalDAVRepository(db DBTX) CalDAVRepository {
+	return &calDAVRepository{db: db}
+}
+
+// List returns every stored object by todo ID
+func (r *calDAVRepository) List(ctx context.Context) (map[int]models.CalDAVObject, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT todo_id, name, uid FROM caldav_objects")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query caldav objects: %w", err)
+	}
+	defer rows.Close()
+
+	objects := make(map[int]models.CalDAVObject)
+	for rows.Next() {
+		var obj models.CalDAVObject
+		if err := rows.Scan(&obj.TodoID, &obj.Name, &obj.UID); err != nil {
+			return nil, fmt.Errorf("failed to scan caldav object: %w", err)
+		}
+		objects[obj.TodoID] = obj
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return objects, nil
+}
+
+func (r *calDAVRepository) GetByName(ctx context.Context, name string) (*models.CalDAVObject, error) {
+	return r.get(ctx, "name", name)
+}
+
+func (r *calDAVRepository) GetByUID(ctx context.Context, uid string) (*models.CalDAVObject, error) {
+	return r.get(ctx, "uid", uid)
+}
+
+func (r *calDAVRepository) GetByTodoID(ctx context.Context, todoID int) (*models.CalDAVObject, error) {
+	return r.get(ctx, "todo_id", todoID)
+}
+
+func (r *calDAVRepository) get(ctx context.Context, column string, value interface{}) (*models.CalDAVObject, error) {
+	query := fmt.Sprintf("SELECT todo_id, name, uid FROM caldav_objects WHERE %s = ?", column)
+
+	var obj models.CalDAVObject
+	err := r.db.QueryRowContext(ctx, query, value).Scan(&obj.TodoID, &obj.Name, &obj.UID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caldav object: %w", err)
+	}
+
+	return &obj, nil
+}
+
+func (r *calDAVRepository) Create(ctx context.Context, obj *models.CalDAVObject) error {
+	query := "INSERT INTO caldav_objects (todo_id, name, uid) VALUES (?, ?, ?)"
+
+	if _, err := r.db.ExecContext(ctx, query, obj.TodoID, obj.Name, obj.UID); err != nil {
+		return fmt.Errorf("failed to create caldav object: %w", err)
+	}
+
+	return nil
+}
+
+// CTag returns the ID of the latest domain event. Every change to a todo
+// records one, so it changes whenever the collection does.
+func (r *calDAVRepository) CTag(ctx context.Context) (int, error) {
+	var id int
+	if err := r.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM outbox").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get collection tag: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx so repositories can run
+// inside or outside a transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// TxRepositories are repositories bound to a single transaction
+type TxRepositories struct {
+	Todos    TodoRepository
+	Notes    NoteRepository
+	Outbox   OutboxRepository
+	Accounts AccountRepository
+}
+
+// UnitOfWork runs a function inside a database transaction, committing
+// when it returns nil and rolling back otherwise
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(tx TxRepositories) error) error
+}
+
+type unitOfWork struct {
+	db    *sql.DB
+	stmts Statements
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// NewPreparedUnitOfWork returns a UnitOfWork whose todo repository runs
+// its hot statements through stmts, bound to each transaction
+func NewPreparedUnitOfWork(db *sql.DB, stmts Statements) UnitOfWork {
+	return &unitOfWork{db: db, stmts: stmts}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(tx TxRepositories) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	todos := NewTodoRepository(tx)
+	if u.stmts != nil {
+		todos = NewPreparedTodoRepository(tx, u.stmts)
+	}
+
+	repos := TxRepositories{
+		Todos:    todos,
+		Notes:    NewNoteRepository(tx),
+		Outbox:   NewOutboxRepository(tx),
+		Accounts: NewAccountRepository(tx),
+	}
+
+	if err := fn(repos); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
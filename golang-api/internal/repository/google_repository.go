@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// GoogleTasksRepository stores linked Google accounts and the pairing of
+// todos with Google tasks, per user
+type GoogleTasksRepository interface {
+	Get(ctx context.Context, userID int) (*models.GoogleAccount, error)
+	GetByState(ctx context.Context, state string) (*models.GoogleAccount, error)
+	SetState(ctx context.Context, userID int, state string, expiresAt time.Time) error
+	Connect(ctx context.Context, userID int, accessToken, refreshToken string, expiresAt time.Time) error
+	UpdateToken(ctx context.Context, userID int, accessToken string, expiresAt time.Time) error
+	RecordSync(ctx context.Context, userID int, result *models.GoogleSyncResult, syncErr string) error
+	ListConnected(ctx context.Context) ([]int, error)
+	Delete(ctx context.Context, userID int) (bool, error)
+	Links(ctx context.Context, userID int) (map[int]models.GoogleTaskLink, error)
+	CountLinks(ctx context.Context, userID int) (int, error)
+	SaveLink(ctx context.Context, userID int, link models.GoogleTaskLink) error
+	DeleteLink(ctx context.Context, userID, todoID int) error
+}
+
+type googleTasksRepository struct {
+	db DBTX
+}
+
+func NewGoogleTasksRepository(db DBTX) GoogleTasksRepository {
+	return &googleTasksRepository{db: db}
+}
+
+const googleAccountColumns = "user_id, state, state_expires_at, access_token, refresh_token, token_expires_at, connected_at, last_synced_at, last_result, last_error"
+
+func scanGoogleAccount(row rowScanner) (*models.GoogleAccount, error) {
+	var account models.GoogleAccount
+	var lastResult *string
+	err := row.Scan(
+		&account.UserID,
+		&account.State,
+		&account.StateExpiresAt,
+		&account.AccessToken,
+		&account.RefreshToken,
+		&account.TokenExpiresAt,
+		&account.ConnectedAt,
+		&account.LastSyncedAt,
+		&lastResult,
+		&account.LastError,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastResult != nil {
+		account.LastResult = &models.GoogleSyncResult{}
+		if err := json.Unmarshal([]byte(*lastResult), account.LastResult); err != nil {
+			return nil, fmt.Errorf("failed to decode last sync result: %w", err)
+		}
+	}
+	return &account, nil
+}
+
+func (r *googleTasksRepository) Get(ctx context.Context, userID int) (*models.GoogleAccount, error) {
+	return r.get(ctx, "user_id", userID)
+}
+
+// GetByState returns the account waiting for the OAuth callback with the
+// given state
+func (r *googleTasksRepository) GetByState(ctx context.Context, state string) (*models.GoogleAccount, error) {
+	return r.get(ctx, "state", state)
+}
+
+func (r *googleTasksRepository) get(ctx context.Context, column string, value interface{}) (*models.GoogleAccount, error) {
+	query := fmt.Sprintf("SELECT %s FROM google_accounts WHERE %s = ?", googleAccountColumns, column)
+
+	account, err := scanGoogleAccount(r.db.QueryRowContext(ctx, query, value))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get google account: %w", err)
+	}
+
+	return account, nil
+}
+
+// SetState starts linking an account. An account that is already linked
+// keeps its tokens until the new link completes.
+func (r *googleTasksRepository) SetState(ctx context.Context, userID int, state string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO google_accounts (user_id, state, state_expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET state = excluded.state, state_expires_at = excluded.state_expires_at
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, state, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to store oauth state: %w", err)
+	}
+
+	return nil
+}
+
+// Connect stores the tokens granted by the user and clears the state
+func (r *googleTasksRepository) Connect(ctx context.Context, userID int, accessToken, refreshToken string, expiresAt time.Time) error {
+	query := `
+		UPDATE google_accounts
+		SET access_token = ?, refresh_token = ?, token_expires_at = ?, connected_at = CURRENT_TIMESTAMP,
+			state = NULL, state_expires_at = NULL, last_error = NULL
+		WHERE user_id = ?
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, accessToken, refreshToken, expiresAt.UTC(), userID); err != nil {
+		return fmt.Errorf("failed to connect google account: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateToken stores a refreshed access token
+func (r *googleTasksRepository) UpdateToken(ctx context.Context, userID int, accessToken string, expiresAt time.Time) error {
+	query := "UPDATE google_accounts SET access_token = ?, token_expires_at = ? WHERE user_id = ?"
+
+	if _, err := r.db.ExecContext(ctx, query, accessToken, expiresAt.UTC(), userID); err != nil {
+		return fmt.Errorf("failed to update google token: %w", err)
+	}
+
+	return nil
+}
+
+// RecordSync stores the outcome of a sync; syncErr is empty when it
+// succeeded
+func (r *googleTasksRepository) RecordSync(ctx context.Context, userID int, result *models.GoogleSyncResult, syncErr string) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode sync result: %w", err)
+	}
+
+	var lastError *string
+	if syncErr != "" {
+		lastError = &syncErr
+	}
+
+	query := "UPDATE google_accounts SET last_synced_at = CURRENT_TIMESTAMP, last_result = ?, last_error = ? WHERE user_id = ?"
+	if _, err := r.db.ExecContext(ctx, query, string(encoded), lastError, userID); err != nil {
+		return fmt.Errorf("failed to record sync: %w", err)
+	}
+
+	return nil
+}
+
+// ListConnected returns the users with a linked account
+func (r *googleTasksRepository) ListConnected(ctx context.Context) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id FROM google_accounts WHERE connected_at IS NOT NULL ORDER BY user_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list google accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan google account: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// Delete unlinks the account and forgets which tasks the user's todos
+// were synced with
+func (r *googleTasksRepository) Delete(ctx context.Context, userID int) (bool, error) {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM google_task_links WHERE user_id = ?", userID); err != nil {
+		return false, fmt.Errorf("failed to delete google task links: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM google_accounts WHERE user_id = ?", userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete google account: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Links returns the user's links by todo ID
+func (r *googleTasksRepository) Links(ctx context.Context, userID int) (map[int]models.GoogleTaskLink, error) {
+	query := "SELECT todo_id, task_id, todo_version, task_updated FROM google_task_links WHERE user_id = ?"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query google task links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make(map[int]models.GoogleTaskLink)
+	for rows.Next() {
+		var link models.GoogleTaskLink
+		if err := rows.Scan(&link.TodoID, &link.TaskID, &link.TodoVersion, &link.TaskUpdated); err != nil {
+			return nil, fmt.Errorf("failed to scan google task link: %w", err)
+		}
+		links[link.TodoID] = link
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return links, nil
+}
+
+func (r *googleTasksRepository) CountLinks(ctx context.Context, userID int) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM google_task_links WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count google task links: %w", err)
+	}
+
+	return count, nil
+}
+
+// SaveLink creates or replaces the link of the todo
+func (r *googleTasksRepository) SaveLink(ctx context.Context, userID int, link models.GoogleTaskLink) error {
+	query := `
+		INSERT OR REPLACE INTO google_task_links (user_id, todo_id, task_id, todo_version, task_updated)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, link.TodoID, link.TaskID, link.TodoVersion, link.TaskUpdated); err != nil {
+		return fmt.Errorf("failed to save google task link: %w", err)
+	}
+
+	return nil
+}
+
+func (r *googleTasksRepository) DeleteLink(ctx context.Context, userID, todoID int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM google_task_links WHERE user_id = ? AND todo_id = ?", userID, todoID); err != nil {
+		return fmt.Errorf("failed to delete google task link: %w", err)
+	}
+
+	return nil
+}
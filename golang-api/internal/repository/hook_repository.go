@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// HookRepository stores REST hook subscriptions
+type HookRepository interface {
+	Create(ctx context.Context, userID int, hook *models.Hook) error
+	List(ctx context.Context, userID int) ([]models.Hook, error)
+	ListByEvent(ctx context.Context, eventType string) ([]models.Hook, error)
+	Delete(ctx context.Context, userID, id int) (bool, error)
+	DeleteByID(ctx context.Context, id int) error
+	// Samples returns the newest stored events of the type, newest first
+	Samples(ctx context.Context, eventType string, limit int) ([]models.OutboxMessage, error)
+}
+
+type hookRepository struct {
+	db DBTX
+}
+
+func NewHookRepository(db DBTX) HookRepository {
+	return &hookRepository{db: db}
+}
+
+const hookColumns = "id, event, target_url, created_at"
+
+func (r *hookRepository) Create(ctx context.Context, userID int, hook *models.Hook) error {
+	query := "INSERT INTO hooks (user_id, event, target_url) VALUES (?, ?, ?) RETURNING " + hookColumns
+
+	err := r.db.QueryRowContext(ctx, query, userID, hook.Event, hook.TargetURL).
+		Scan(&hook.ID, &hook.Event, &hook.TargetURL, &hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create hook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *hookRepository) List(ctx context.Context, userID int) ([]models.Hook, error) {
+	return r.list(ctx, "SELECT "+hookColumns+" FROM hooks WHERE user_id = ? ORDER BY id", userID)
+}
+
+// ListByEvent returns every user's hooks for the event type
+func (r *hookRepository) ListByEvent(ctx context.Context, eventType string) ([]models.Hook, error) {
+	return r.list(ctx, "SELECT "+hookColumns+" FROM hooks WHERE event = ? ORDER BY id", eventType)
+}
+
+func (r *hookRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Hook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := make([]models.Hook, 0)
+	for rows.Next() {
+		var hook models.Hook
+		if err := rows.Scan(&hook.ID, &hook.Event, &hook.TargetURL, &hook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan hook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return hooks, nil
+}
+
+func (r *hookRepository) Delete(ctx context.Context, userID, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM hooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete hook: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteByID removes a hook whatever its owner, for targets that asked to
+// be unsubscribed
+func (r *hookRepository) DeleteByID(ctx context.Context, id int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM hooks WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete hook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *hookRepository) Samples(ctx context.Context, eventType string, limit int) ([]models.OutboxMessage, error) {
+	query := "SELECT id, event_type, payload FROM outbox WHERE event_type = ? ORDER BY id DESC LIMIT ?"
+
+	rows, err := r.db.QueryContext(ctx, query, eventType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sample events: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var msg models.OutboxMessage
+		var payload string
+		if err := rows.Scan(&msg.ID, &msg.EventType, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan sample event: %w", err)
+		}
+		msg.Payload = json.RawMessage(payload)
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type JobRepository interface {
+	Create(job *models.Job) error
+	GetByID(id int) (*models.Job, error)
+	List(params models.JobQueryParams) ([]models.Job, int, error)
+	ClaimNext(now time.Time, serial []string) (*models.Job, error)
+	MarkSucceeded(id int, result []byte) error
+	MarkFailed(id int, errMsg string, retryAt *time.Time) error
+	Retry(id int) (*models.Job, error)
+	RequeueRunning() (int64, error)
+}
+
+type jobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) JobRepository {
+	return &jobRepository{db: db}
+}
+
+const jobColumns = "id, type, payload, status, attempts, max_attempts, last_error, result, run_at, created_at, updated_at"
+
+// sqliteTime formats a time the same way SQLite's CURRENT_TIMESTAMP does
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row rowScanner) (*models.Job, error) {
+	var job models.Job
+	var payload, result sql.NullString
+
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.LastError,
+		&result,
+		&job.RunAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Valid {
+		job.Payload = []byte(payload.String)
+	}
+	if result.Valid {
+		job.Result = []byte(result.String)
+	}
+
+	return &job, nil
+}
+
+func (r *jobRepository) Create(job *models.Job) error {
+	query := `
+		INSERT INTO jobs (type, payload, status, max_attempts, run_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	var payload interface{}
+	if len(job.Payload) > 0 {
+		payload = string(job.Payload)
+	}
+
+	result, err := r.db.Exec(query, job.Type, payload, models.JobStatusPending, job.MaxAttempts, sqliteTime(job.RunAt))
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	createdJob, err := r.GetByID(int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created job: %w", err)
+	}
+
+	*job = *createdJob
+	return nil
+}
+
+func (r *jobRepository) GetByID(id int) (*models.Job, error) {
+	query := fmt.Sprintf("SELECT %s FROM jobs WHERE id = ?", jobColumns)
+
+	job, err := scanJob(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job by id: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *jobRepository) List(params models.JobQueryParams) ([]models.Job, int, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+
+	if params.Status != "" {
+		whereClause += " AND status = ?"
+		args = append(args, params.Status)
+	}
+
+	if params.Type != "" {
+		whereClause += " AND type = ?"
+		args = append(args, params.Type)
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM jobs %s", whereClause)
+	var total int
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	offset := (params.Page - 1) * params.PerPage
+	query := fmt.Sprintf(
+		"SELECT %s FROM jobs %s ORDER BY id DESC LIMIT %d OFFSET %d",
+		jobColumns, whereClause, params.PerPage, offset,
+	)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]models.Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// ClaimNext atomically moves the oldest due pending job to running and
+// returns it, or returns nil when there is nothing to do. A job of a type
+// in serial is only due when it is the oldest of its type not finished.
+func (r *jobRepository) ClaimNext(now time.Time, serial []string) (*models.Job, error) {
+	args := []interface{}{models.JobStatusRunning, models.JobStatusPending, sqliteTime(now)}
+
+	serialClause := ""
+	if len(serial) > 0 {
+		serialClause = fmt.Sprintf(`
+			AND (type NOT IN (%s) OR id = (
+				SELECT MIN(id) FROM jobs AS older
+				WHERE older.type = jobs.type AND older.status IN (?, ?)
+			))`, strings.TrimSuffix(strings.Repeat("?, ", len(serial)), ", "))
+		for _, jobType := range serial {
+			args = append(args, jobType)
+		}
+		args = append(args, models.JobStatusPending, models.JobStatusRunning)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE jobs
+		SET status = ?, attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= ?%s
+			ORDER BY run_at, id
+			LIMIT 1
+		)
+		RETURNING %s
+	`, serialClause, jobColumns)
+
+	job, err := scanJob(r.db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *jobRepository) MarkSucceeded(id int, result []byte) error {
+	query := `
+		UPDATE jobs
+		SET status = ?, result = ?, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	var value interface{}
+	if len(result) > 0 {
+		value = string(result)
+	}
+
+	if _, err := r.db.Exec(query, models.JobStatusSucceeded, value, id); err != nil {
+		return fmt.Errorf("failed to mark job as succeeded: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt. When retryAt is set the job goes
+// back to pending and will be picked up again after that time.
+func (r *jobRepository) MarkFailed(id int, errMsg string, retryAt *time.Time) error {
+	status := models.JobStatusFailed
+	runAt := sqliteTime(time.Now())
+	if retryAt != nil {
+		status = models.JobStatusPending
+		runAt = sqliteTime(*retryAt)
+	}
+
+	query := `
+		UPDATE jobs
+		SET status = ?, last_error = ?, run_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, status, errMsg, runAt, id); err != nil {
+		return fmt.Errorf("failed to mark job as failed: %w", err)
+	}
+
+	return nil
+}
+
+// Retry resets a failed job so that it is picked up again with a fresh
+// attempt budget. Returns nil if the job does not exist or is not failed,
+// so that concurrent retries reset it only once.
+func (r *jobRepository) Retry(id int) (*models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = ?, attempts = 0, run_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = ?
+	`
+
+	result, err := r.db.Exec(query, models.JobStatusPending, sqliteTime(time.Now()), id, models.JobStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, nil // Job not found or not failed
+	}
+
+	return r.GetByID(id)
+}
+
+// RequeueRunning returns jobs left running by a previous process (for
+// example after a crash) to the pending state
+func (r *jobRepository) RequeueRunning() (int64, error) {
+	query := "UPDATE jobs SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE status = ?"
+
+	result, err := r.db.Exec(query, models.JobStatusPending, models.JobStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue running jobs: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
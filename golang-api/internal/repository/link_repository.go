@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrDuplicateLink is returned by Create and Update when the todo already
+// links to the URL
+var ErrDuplicateLink = errors.New("duplicate link")
+
+type LinkRepository interface {
+	List(ctx context.Context, todoID int) ([]models.Link, error)
+	GetByID(ctx context.Context, todoID, id int) (*models.Link, error)
+	Create(ctx context.Context, link *models.Link) error
+	Update(ctx context.Context, link *models.Link) error
+	Delete(ctx context.Context, todoID, id int) error
+}
+
+type linkRepository struct {
+	db DBTX
+}
+
+func NewLinkRepository(db DBTX) LinkRepository {
+	return &linkRepository{db: db}
+}
+
+const linkColumns = "id, todo_id, type, url, title, created_at, updated_at"
+
+func scanLink(row rowScanner) (*models.Link, error) {
+	var link models.Link
+	err := row.Scan(
+		&link.ID,
+		&link.TodoID,
+		&link.Type,
+		&link.URL,
+		&link.Title,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// duplicateLink translates a violation of the (todo_id, url) unique
+// constraint into ErrDuplicateLink and returns nil for any other error
+func duplicateLink(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrDuplicateLink
+	}
+	return nil
+}
+
+// List returns the links of a todo, oldest first
+func (r *linkRepository) List(ctx context.Context, todoID int) ([]models.Link, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_links WHERE todo_id = ? ORDER BY created_at, id", linkColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]models.Link, 0)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan link: %w", err)
+		}
+		links = append(links, *link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return links, nil
+}
+
+// GetByID returns the link only when it belongs to the todo
+func (r *linkRepository) GetByID(ctx context.Context, todoID, id int) (*models.Link, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_links WHERE id = ? AND todo_id = ?", linkColumns)
+
+	link, err := scanLink(r.db.QueryRowContext(ctx, query, id, todoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link: %w", err)
+	}
+
+	return link, nil
+}
+
+func (r *linkRepository) Create(ctx context.Context, link *models.Link) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO todo_links (todo_id, type, url, title) VALUES (?, ?, ?, ?)", link.TodoID, link.Type, link.URL, link.Title)
+	if duplicate := duplicateLink(err); duplicate != nil {
+		return duplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create link: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created, err := r.GetByID(ctx, link.TodoID, int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created link: %w", err)
+	}
+
+	*link = *created
+	return nil
+}
+
+// Update replaces the type, URL and title of the link
+func (r *linkRepository) Update(ctx context.Context, link *models.Link) error {
+	query := "UPDATE todo_links SET type = ?, url = ?, title = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND todo_id = ?"
+
+	result, err := r.db.ExecContext(ctx, query, link.Type, link.URL, link.Title, link.ID, link.TodoID)
+	if duplicate := duplicateLink(err); duplicate != nil {
+		return duplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("link with id %d not found", link.ID)
+	}
+
+	updated, err := r.GetByID(ctx, link.TodoID, link.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch updated link: %w", err)
+	}
+
+	*link = *updated
+	return nil
+}
+
+func (r *linkRepository) Delete(ctx context.Context, todoID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM todo_links WHERE id = ? AND todo_id = ?", id, todoID)
+	if err != nil {
+		return fmt.Errorf("failed to delete link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("link with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// LoginFailureRepository stores failed login counters per account and
+// per client IP
+type LoginFailureRepository interface {
+	// Get returns the counter, or nil when there were no recent failures
+	Get(ctx context.Context, kind, value string) (*models.LoginFailures, error)
+	// Increment counts a failure at now and returns the counter. A counter
+	// whose lockout has ended at now, or that is not locked and last
+	// failed before staleBefore, starts over.
+	Increment(ctx context.Context, kind, value string, now, staleBefore time.Time) (*models.LoginFailures, error)
+	// Lock locks the counter until until, reporting false when it already
+	// was locked
+	Lock(ctx context.Context, kind, value string, until time.Time) (bool, error)
+	Delete(ctx context.Context, kind, value string) (bool, error)
+	// ListLocked returns the counters locked at now, soonest unlock first
+	ListLocked(ctx context.Context, now time.Time) ([]models.LoginFailures, error)
+}
+
+type loginFailureRepository struct {
+	db DBTX
+}
+
+func NewLoginFailureRepository(db DBTX) LoginFailureRepository {
+	return &loginFailureRepository{db: db}
+}
+
+const loginFailureColumns = "kind, value, failures, last_failed_at, locked_until"
+
+func scanLoginFailures(row rowScanner) (*models.LoginFailures, error) {
+	var f models.LoginFailures
+	if err := row.Scan(&f.Kind, &f.Value, &f.Failures, &f.LastFailedAt, &f.LockedUntil); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *loginFailureRepository) Get(ctx context.Context, kind, value string) (*models.LoginFailures, error) {
+	query := "SELECT " + loginFailureColumns + " FROM login_failures WHERE kind = ? AND value = ?"
+
+	f, err := scanLoginFailures(r.db.QueryRowContext(ctx, query, kind, value))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+
+	return f, nil
+}
+
+// loginFailuresStale is the condition, on the now and staleBefore
+// parameters, under which Increment starts a counter over
+const loginFailuresStale = `(login_failures.locked_until <= ? OR (login_failures.locked_until IS NULL AND login_failures.last_failed_at <= ?))`
+
+func (r *loginFailureRepository) Increment(ctx context.Context, kind, value string, now, staleBefore time.Time) (*models.LoginFailures, error) {
+	// One statement, so concurrent failures cannot count over each other
+	query := `
+		INSERT INTO login_failures (kind, value, failures, last_failed_at, locked_until)
+		VALUES (?, ?, 1, ?, NULL)
+		ON CONFLICT (kind, value) DO UPDATE SET
+			failures = CASE WHEN ` + loginFailuresStale + ` THEN 1 ELSE login_failures.failures + 1 END,
+			locked_until = CASE WHEN ` + loginFailuresStale + ` THEN NULL ELSE login_failures.locked_until END,
+			last_failed_at = excluded.last_failed_at
+		RETURNING ` + loginFailureColumns
+
+	at, before := sqliteTime(now), sqliteTime(staleBefore)
+	f, err := scanLoginFailures(r.db.QueryRowContext(ctx, query, kind, value, at, at, before, at, before))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count login failure: %w", err)
+	}
+
+	return f, nil
+}
+
+func (r *loginFailureRepository) Lock(ctx context.Context, kind, value string, until time.Time) (bool, error) {
+	query := "UPDATE login_failures SET locked_until = ? WHERE kind = ? AND value = ? AND locked_until IS NULL"
+
+	result, err := r.db.ExecContext(ctx, query, sqliteTime(until), kind, value)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock login: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *loginFailureRepository) Delete(ctx context.Context, kind, value string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM login_failures WHERE kind = ? AND value = ?", kind, value)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete login failures: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *loginFailureRepository) ListLocked(ctx context.Context, now time.Time) ([]models.LoginFailures, error) {
+	query := "SELECT " + loginFailureColumns + " FROM login_failures WHERE locked_until > ? ORDER BY locked_until"
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	locked := make([]models.LoginFailures, 0)
+	for rows.Next() {
+		f, err := scanLoginFailures(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lockout: %w", err)
+		}
+		locked = append(locked, *f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return locked, nil
+}
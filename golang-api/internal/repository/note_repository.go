@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type NoteRepository interface {
+	List(ctx context.Context, todoID int) ([]models.Note, error)
+	GetByID(ctx context.Context, todoID, id int) (*models.Note, error)
+	Create(ctx context.Context, note *models.Note) error
+	Update(ctx context.Context, note *models.Note) error
+	Delete(ctx context.Context, todoID, id int) error
+}
+
+type noteRepository struct {
+	db DBTX
+}
+
+func NewNoteRepository(db DBTX) NoteRepository {
+	return &noteRepository{db: db}
+}
+
+const noteColumns = "id, todo_id, body, created_at, updated_at"
+
+func scanNote(row rowScanner) (*models.Note, error) {
+	var note models.Note
+	err := row.Scan(
+		&note.ID,
+		&note.TodoID,
+		&note.Body,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// List returns the notes of a todo, oldest first
+func (r *noteRepository) List(ctx context.Context, todoID int) ([]models.Note, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_notes WHERE todo_id = ? ORDER BY created_at, id", noteColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]models.Note, 0)
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, *note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
+
+// GetByID returns the note only when it belongs to the todo
+func (r *noteRepository) GetByID(ctx context.Context, todoID, id int) (*models.Note, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_notes WHERE id = ? AND todo_id = ?", noteColumns)
+
+	note, err := scanNote(r.db.QueryRowContext(ctx, query, id, todoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+
+	return note, nil
+}
+
+func (r *noteRepository) Create(ctx context.Context, note *models.Note) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO todo_notes (todo_id, body) VALUES (?, ?)", note.TodoID, note.Body)
+	if err != nil {
+		return fmt.Errorf("failed to create note: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created, err := r.GetByID(ctx, note.TodoID, int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created note: %w", err)
+	}
+
+	*note = *created
+	return nil
+}
+
+// Update replaces the body of the note
+func (r *noteRepository) Update(ctx context.Context, note *models.Note) error {
+	query := "UPDATE todo_notes SET body = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND todo_id = ?"
+
+	result, err := r.db.ExecContext(ctx, query, note.Body, note.ID, note.TodoID)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("note with id %d not found", note.ID)
+	}
+
+	updated, err := r.GetByID(ctx, note.TodoID, note.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch updated note: %w", err)
+	}
+
+	*note = *updated
+	return nil
+}
+
+func (r *noteRepository) Delete(ctx context.Context, todoID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM todo_notes WHERE id = ? AND todo_id = ?", id, todoID)
+	if err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("note with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, eventID int, userIDs []int, notification models.Notification) (int64, error)
+	List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error)
+	MarkRead(ctx context.Context, userID, id int) (*models.Notification, error)
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	Delete(ctx context.Context, userID, id int) (bool, error)
+	DeleteAll(ctx context.Context, userID int, readOnly bool) (int64, error)
+}
+
+type notificationRepository struct {
+	db DBTX
+}
+
+func NewNotificationRepository(db DBTX) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+const notificationColumns = "id, type, todo_id, message, read_at, created_at"
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var notification models.Notification
+	err := row.Scan(
+		&notification.ID,
+		&notification.Type,
+		&notification.TodoID,
+		&notification.Message,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// Create gives each of the users a copy of the notification. Users that
+// already have a notification for the event are skipped, so redelivered
+// events do not notify twice. It returns the number of notifications
+// created.
+func (r *notificationRepository) Create(ctx context.Context, eventID int, userIDs []int, notification models.Notification) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	encoded, err := json.Marshal(userIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode user ids: %w", err)
+	}
+
+	query := `
+		INSERT OR IGNORE INTO notifications (user_id, event_id, type, todo_id, message)
+		SELECT value, ?, ?, ?, ? FROM json_each(?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, eventID, notification.Type, notification.TodoID, notification.Message, string(encoded))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create notifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// List returns a page of the user's notifications, newest first, and the
+// number of notifications matching
+func (r *notificationRepository) List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	where := "WHERE user_id = ?"
+	if unreadOnly {
+		where += " AND read_at IS NULL"
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications "+where, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM notifications %s ORDER BY id DESC LIMIT ? OFFSET ?", notificationColumns, where)
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// MarkRead marks one of the user's notifications as read, keeping the
+// time it was first read. It returns nil when the user has no such
+// notification.
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id int) (*models.Notification, error) {
+	query := fmt.Sprintf(`
+		UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = ? AND user_id = ?
+		RETURNING %s
+	`, notificationColumns)
+
+	notification, err := scanNotification(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+
+	return notification, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	query := "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL"
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Delete removes one of the user's notifications, reporting whether it
+// existed
+func (r *notificationRepository) Delete(ctx context.Context, userID, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM notifications WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete notification: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteAll removes the user's notifications, or only the read ones
+func (r *notificationRepository) DeleteAll(ctx context.Context, userID int, readOnly bool) (int64, error) {
+	query := "DELETE FROM notifications WHERE user_id = ?"
+	if readOnly {
+		query += " AND read_at IS NOT NULL"
+	}
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear notifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type OutboxRepository interface {
+	Add(msg *models.OutboxMessage) error
+	FetchPending(now time.Time, limit int) ([]models.OutboxMessage, error)
+	MarkDelivered(id int, deliveredTo []string) error
+	MarkFailed(id int, deliveredTo []string, errMsg string, retryAt *time.Time) error
+}
+
+type outboxRepository struct {
+	db DBTX
+}
+
+func NewOutboxRepository(db DBTX) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Add(msg *models.OutboxMessage) error {
+	query := `
+		INSERT INTO outbox (event_type, payload, status)
+		VALUES (?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, msg.EventType, string(msg.Payload), models.OutboxStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to add outbox message: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	msg.ID = int(id)
+	msg.Status = models.OutboxStatusPending
+	return nil
+}
+
+// FetchPending returns pending messages that are due, oldest first
+func (r *outboxRepository) FetchPending(now time.Time, limit int) ([]models.OutboxMessage, error) {
+	query := `
+		SELECT id, event_type, payload, status, attempts, delivered_to, last_error, available_at, created_at
+		FROM outbox
+		WHERE status = ? AND available_at <= ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, models.OutboxStatusPending, sqliteTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.OutboxMessage, 0)
+	for rows.Next() {
+		var msg models.OutboxMessage
+		var payload string
+		var deliveredTo sql.NullString
+
+		err := rows.Scan(
+			&msg.ID,
+			&msg.EventType,
+			&payload,
+			&msg.Status,
+			&msg.Attempts,
+			&deliveredTo,
+			&msg.LastError,
+			&msg.AvailableAt,
+			&msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+
+		msg.Payload = []byte(payload)
+		if deliveredTo.Valid && deliveredTo.String != "" {
+			if err := json.Unmarshal([]byte(deliveredTo.String), &msg.DeliveredTo); err != nil {
+				return nil, fmt.Errorf("failed to decode outbox deliveries: %w", err)
+			}
+		}
+
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkDelivered(id int, deliveredTo []string) error {
+	encoded, err := json.Marshal(deliveredTo)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox deliveries: %w", err)
+	}
+
+	query := `
+		UPDATE outbox
+		SET status = ?, attempts = attempts + 1, delivered_to = ?, last_error = NULL
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, models.OutboxStatusDelivered, string(encoded), id); err != nil {
+		return fmt.Errorf("failed to mark outbox message as delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt. Subscribers that already
+// received the message are remembered so a retry does not duplicate it.
+// Without retryAt the message is moved to the dead status.
+func (r *outboxRepository) MarkFailed(id int, deliveredTo []string, errMsg string, retryAt *time.Time) error {
+	encoded, err := json.Marshal(deliveredTo)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox deliveries: %w", err)
+	}
+
+	status := models.OutboxStatusDead
+	availableAt := sqliteTime(time.Now())
+	if retryAt != nil {
+		status = models.OutboxStatusPending
+		availableAt = sqliteTime(*retryAt)
+	}
+
+	query := `
+		UPDATE outbox
+		SET status = ?, attempts = attempts + 1, delivered_to = ?, last_error = ?, available_at = ?
+		WHERE id = ?
+	`
+
+	if _, err := r.db.Exec(query, status, string(encoded), errMsg, availableAt, id); err != nil {
+		return fmt.Errorf("failed to mark outbox message as failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+type SavedSearchRepository interface {
+	List(ctx context.Context) ([]models.SavedSearch, error)
+	GetByID(ctx context.Context, id int) (*models.SavedSearch, error)
+	Create(ctx context.Context, search *models.SavedSearch) error
+	Update(ctx context.Context, search *models.SavedSearch) error
+	Delete(ctx context.Context, id int) error
+}
+
+type savedSearchRepository struct {
+	db DBTX
+}
+
+func NewSavedSearchRepository(db DBTX) SavedSearchRepository {
+	return &savedSearchRepository{db: db}
+}
+
+const savedSearchColumns = "id, name, query, color, icon, created_at, updated_at"
+
+func scanSavedSearch(row rowScanner) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := row.Scan(
+		&search.ID,
+		&search.Name,
+		&search.Query,
+		&search.Color,
+		&search.Icon,
+		&search.CreatedAt,
+		&search.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (r *savedSearchRepository) List(ctx context.Context) ([]models.SavedSearch, error) {
+	query := fmt.Sprintf("SELECT %s FROM saved_searches ORDER BY name COLLATE NOCASE, id", savedSearchColumns)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := make([]models.SavedSearch, 0)
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, *search)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return searches, nil
+}
+
+func (r *savedSearchRepository) GetByID(ctx context.Context, id int) (*models.SavedSearch, error) {
+	query := fmt.Sprintf("SELECT %s FROM saved_searches WHERE id = ?", savedSearchColumns)
+
+	search, err := scanSavedSearch(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	return search, nil
+}
+
+func (r *savedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO saved_searches (name, query, color, icon) VALUES (?, ?, ?, ?)", search.Name, search.Query, search.Color, search.Icon)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created, err := r.GetByID(ctx, int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created saved search: %w", err)
+	}
+
+	*search = *created
+	return nil
+}
+
+// Update replaces the name, query, color and icon of the saved search
+func (r *savedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	query := "UPDATE saved_searches SET name = ?, query = ?, color = ?, icon = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	result, err := r.db.ExecContext(ctx, query, search.Name, search.Query, search.Color, search.Icon, search.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search with id %d not found", search.ID)
+	}
+
+	updated, err := r.GetByID(ctx, search.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch updated saved search: %w", err)
+	}
+
+	*search = *updated
+	return nil
+}
+
+func (r *savedSearchRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Statements prepares a query once and returns the same statement for it
+// afterwards. database.Database implements it and closes the statements
+// when it is closed.
+type Statements interface {
+	Prepare(ctx context.Context, query string) (*sql.Stmt, error)
+	// Prepared returns the statement for query if it has been prepared,
+	// and nil otherwise
+	Prepared(query string) *sql.Stmt
+}
+
+// preparedDB runs queries through the statements prepared by stmts, and
+// through db when it has none
+type preparedDB struct {
+	db    DBTX
+	stmts Statements
+}
+
+// stmt returns the statement for query, or nil to run it unprepared, as
+// when it fails to prepare: the query then fails with the same error.
+// Preparing takes a connection besides the one a transaction holds, which
+// an in-memory database does not have, so a transaction only uses the
+// statements prepared already, bound to it.
+func (p *preparedDB) stmt(ctx context.Context, query string) *sql.Stmt {
+	if tx, ok := p.db.(*sql.Tx); ok {
+		if stmt := p.stmts.Prepared(query); stmt != nil {
+			return tx.StmtContext(ctx, stmt)
+		}
+		return nil
+	}
+	stmt, err := p.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil
+	}
+	return stmt
+}
+
+func (p *preparedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+func (p *preparedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+func (p *preparedDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return p.QueryRowContext(context.Background(), query, args...)
+}
+
+func (p *preparedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt := p.stmt(ctx, query)
+	if stmt == nil {
+		return p.db.ExecContext(ctx, query, args...)
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (p *preparedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt := p.stmt(ctx, query)
+	if stmt == nil {
+		return p.db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (p *preparedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt := p.stmt(ctx, query)
+	if stmt == nil {
+		return p.db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// todoBatchSize is the most rows one INSERT stores. A todo binds 13
+// values, so a batch stays well below SQLite's 32766 bound parameters.
+const todoBatchSize = 500
+
+// CreateMany stores todos with multi-row INSERTs and updates each of them
+// like Create does. Either every todo is stored or none is. Unique
+// violations return ErrDuplicateTitle or ErrDuplicateClientID like Create,
+// without telling which todo caused them.
+func (r *todoRepository) CreateMany(ctx context.Context, todos []*models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	// Outside a transaction the batch gets its own, so every statement
+	// runs on the same connection
+	if db, ok := r.db.(*sql.DB); ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin batch: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := (&todoRepository{db: tx, hot: tx}).createMany(ctx, todos); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit batch: %w", err)
+		}
+		return nil
+	}
+
+	// Within one, a savepoint undoes a failed batch without aborting it
+	if _, err := r.db.ExecContext(ctx, "SAVEPOINT create_many"); err != nil {
+		return fmt.Errorf("failed to begin batch: %w", err)
+	}
+	if err := r.createMany(ctx, todos); err != nil {
+		if _, rbErr := r.db.ExecContext(ctx, "ROLLBACK TO create_many; RELEASE create_many"); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, "RELEASE create_many"); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+func (r *todoRepository) createMany(ctx context.Context, todos []*models.Todo) error {
+	for start := 0; start < len(todos); start += todoBatchSize {
+		batch := todos[start:min(start+todoBatchSize, len(todos))]
+
+		values := make([]map[string]interface{}, len(batch))
+		for i, todo := range batch {
+			values[i] = todoValues(todo)
+		}
+		query, args, err := sqlx.Named(insertTodoQuery, values)
+		if err != nil {
+			return fmt.Errorf("failed to bind todos: %w", err)
+		}
+
+		result, err := r.db.ExecContext(ctx, query, args...)
+		if unique := uniqueViolation(err); unique != nil {
+			return unique
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create todos: %w", err)
+		}
+
+		// The rows of one INSERT get consecutive IDs, ending with the
+		// last one inserted
+		last, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		first := int(last) - len(batch) + 1
+
+		if err := r.addTags(ctx, first, batch); err != nil {
+			return err
+		}
+
+		// Fetch the created todos to get timestamps
+		created, err := selectTodos(ctx, r.db, fmt.Sprintf("SELECT %s FROM todos WHERE id BETWEEN ? AND ? ORDER BY id", todoColumns), first, last)
+		if err != nil {
+			return fmt.Errorf("failed to fetch created todos: %w", err)
+		}
+		if len(created) != len(batch) {
+			return fmt.Errorf("fetched %d created todos, expected %d", len(created), len(batch))
+		}
+		for i := range batch {
+			*batch[i] = created[i]
+		}
+	}
+
+	return nil
+}
+
+// addTags tags the todos of a batch whose IDs start at first
+func (r *todoRepository) addTags(ctx context.Context, first int, batch []*models.Todo) error {
+	var rows []string
+	var args []interface{}
+	for i, todo := range batch {
+		for _, tag := range todo.Tags {
+			rows = append(rows, "(?, ?)")
+			args = append(args, first+i, tag)
+		}
+	}
+
+	for start := 0; start < len(rows); start += todoBatchSize {
+		end := min(start+todoBatchSize, len(rows))
+		query := "INSERT OR IGNORE INTO todo_tags (todo_id, tag) VALUES " + strings.Join(rows[start:end], ", ")
+		if _, err := r.db.ExecContext(ctx, query, args[2*start:2*end]...); err != nil {
+			return fmt.Errorf("failed to tag todos: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// maxCachedCounts bounds how many filter combinations TodoCounts keeps.
+// Reaching it starts over with an empty cache.
+const maxCachedCounts = 1000
+
+// TodoCounts caches how many todos match each set of list filters, so a
+// page of a large table does not count it again. Every write through
+// the repository or unit of work it wraps drops the cache, so everything
+// in the process that writes todos must share them. Writes from other
+// processes, such as todocli, show after at most ttl.
+type TodoCounts struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	generation uint64
+	counts     map[string]cachedCount
+}
+
+type cachedCount struct {
+	count   int
+	expires time.Time
+}
+
+func NewTodoCounts(ttl time.Duration) *TodoCounts {
+	return &TodoCounts{ttl: ttl, counts: make(map[string]cachedCount)}
+}
+
+// Invalidate drops every cached count
+func (c *TodoCounts) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.counts = make(map[string]cachedCount)
+}
+
+// countKey identifies the todos params select, whatever the page and order
+func countKey(params models.QueryParams) (string, bool) {
+	params.Page, params.PerPage, params.Sort, params.Order, params.SkipTotal = 0, 0, "", "", false
+	key, err := json.Marshal(params)
+	return string(key), err == nil
+}
+
+// get returns the cached count for key, or false, and the generation to
+// store a count for key with
+func (c *TodoCounts) get(key string) (int, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.counts[key]
+	if !ok || time.Now().After(cached.expires) {
+		return 0, false, c.generation
+	}
+	return cached.count, true, c.generation
+}
+
+// put caches count unless the todos changed since generation, while it
+// was being counted
+func (c *TodoCounts) put(key string, count int, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if len(c.counts) >= maxCachedCounts {
+		c.counts = make(map[string]cachedCount)
+	}
+	c.counts[key] = cachedCount{count: count, expires: time.Now().Add(c.ttl)}
+}
+
+// Decorator returns a decorator that answers Count, and the total of
+// GetAll, from the cache, and drops it on every write
+func (c *TodoCounts) Decorator() TodoDecorator {
+	return func(next TodoRepository) TodoRepository {
+		return &countedTodos{TodoRepository: next, counts: c}
+	}
+}
+
+// UnitOfWork returns uow dropping the cache after every transaction it
+// commits, since any of them may have changed todos
+func (c *TodoCounts) UnitOfWork(uow UnitOfWork) UnitOfWork {
+	return &countedUnitOfWork{next: uow, counts: c}
+}
+
+type countedUnitOfWork struct {
+	next   UnitOfWork
+	counts *TodoCounts
+}
+
+func (u *countedUnitOfWork) Do(ctx context.Context, fn func(tx TxRepositories) error) error {
+	err := u.next.Do(ctx, fn)
+	if err == nil {
+		u.counts.Invalidate()
+	}
+	return err
+}
+
+type countedTodos struct {
+	TodoRepository
+	counts *TodoCounts
+}
+
+func (r *countedTodos) GetAll(ctx context.Context, params models.QueryParams) ([]models.Todo, int, error) {
+	key, ok := countKey(params)
+	if !ok || params.SkipTotal {
+		return r.TodoRepository.GetAll(ctx, params)
+	}
+
+	count, cached, generation := r.counts.get(key)
+	if !cached {
+		todos, total, err := r.TodoRepository.GetAll(ctx, params)
+		if err == nil {
+			r.counts.put(key, total, generation)
+		}
+		return todos, total, err
+	}
+
+	params.SkipTotal = true
+	todos, _, err := r.TodoRepository.GetAll(ctx, params)
+	return todos, count, err
+}
+
+func (r *countedTodos) Count(ctx context.Context, params models.QueryParams) (int, error) {
+	key, ok := countKey(params)
+	if !ok {
+		return r.TodoRepository.Count(ctx, params)
+	}
+
+	count, cached, generation := r.counts.get(key)
+	if cached {
+		return count, nil
+	}
+	count, err := r.TodoRepository.Count(ctx, params)
+	if err == nil {
+		r.counts.put(key, count, generation)
+	}
+	return count, err
+}
+
+// written drops the cache once a write returns, whether it failed or not
+func (r *countedTodos) written() {
+	r.counts.Invalidate()
+}
+
+func (r *countedTodos) Create(ctx context.Context, todo *models.Todo) error {
+	defer r.written()
+	return r.TodoRepository.Create(ctx, todo)
+}
+
+func (r *countedTodos) CreateMany(ctx context.Context, todos []*models.Todo) error {
+	defer r.written()
+	return r.TodoRepository.CreateMany(ctx, todos)
+}
+
+func (r *countedTodos) Update(ctx context.Context, id int, updates map[string]interface{}) (*models.Todo, error) {
+	defer r.written()
+	return r.TodoRepository.Update(ctx, id, updates)
+}
+
+func (r *countedTodos) UpdateIfVersion(ctx context.Context, id, version int, updates map[string]interface{}) (*models.Todo, error) {
+	defer r.written()
+	return r.TodoRepository.UpdateIfVersion(ctx, id, version, updates)
+}
+
+func (r *countedTodos) Delete(ctx context.Context, id int) error {
+	defer r.written()
+	return r.TodoRepository.Delete(ctx, id)
+}
+
+func (r *countedTodos) Restore(ctx context.Context, id int) (*models.Todo, error) {
+	defer r.written()
+	return r.TodoRepository.Restore(ctx, id)
+}
+
+func (r *countedTodos) Purge(ctx context.Context, id int) (bool, error) {
+	defer r.written()
+	return r.TodoRepository.Purge(ctx, id)
+}
+
+func (r *countedTodos) DeleteCompletedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	defer r.written()
+	return r.TodoRepository.DeleteCompletedBefore(ctx, cutoff)
+}
+
+func (r *countedTodos) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer r.written()
+	return r.TodoRepository.PurgeDeletedBefore(ctx, cutoff)
+}
+
+func (r *countedTodos) UpdateTags(ctx context.Context, ids []int, add, remove []string) ([]int, error) {
+	defer r.written()
+	return r.TodoRepository.UpdateTags(ctx, ids, add, remove)
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// TodoDecorator wraps a TodoRepository with a cross-cutting concern such
+// as metrics, logging or caching. Decorators that only change a few
+// methods can embed the TodoRepository they wrap.
+type TodoDecorator func(TodoRepository) TodoRepository
+
+// DecorateTodos wraps repo with decorators. The first decorator is the
+// outermost, so it sees every call before the ones after it.
+func DecorateTodos(repo TodoRepository, decorators ...TodoDecorator) TodoRepository {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		repo = decorators[i](repo)
+	}
+	return repo
+}
+
+// TodoInterceptor runs call, the TodoRepository method named op, and
+// returns its error. It may run call more than once, or not at all.
+type TodoInterceptor func(ctx context.Context, op string, call func(context.Context) error) error
+
+// InterceptTodos returns a decorator that passes every TodoRepository
+// call through interceptor
+func InterceptTodos(interceptor TodoInterceptor) TodoDecorator {
+	return func(next TodoRepository) TodoRepository {
+		return &interceptedTodos{next: next, intercept: interceptor}
+	}
+}
+
+// QueryObserver records how long a repository operation took
+type QueryObserver interface {
+	ObserveQuery(repository, operation string, duration time.Duration, err error)
+}
+
+// ObserveTodoQueries reports the duration and outcome of every call to
+// observer
+func ObserveTodoQueries(observer QueryObserver) TodoDecorator {
+	return InterceptTodos(func(ctx context.Context, op string, call func(context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		observer.ObserveQuery("todos", op, time.Since(start), err)
+		return err
+	})
+}
+
+// LogSlowTodoQueries logs a warning, with the request's logger, for calls
+// that take threshold or longer
+func LogSlowTodoQueries(logger *slog.Logger, threshold time.Duration) TodoDecorator {
+	return InterceptTodos(func(ctx context.Context, op string, call func(context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		if duration := time.Since(start); duration >= threshold {
+			logging.FromContext(ctx, logger).Warn("Slow query",
+				"repository", "todos",
+				"operation", op,
+				"duration", duration.String(),
+				"threshold", threshold.String(),
+				"error", err,
+			)
+		}
+		return err
+	})
+}
+
+// todoReads are the operations that change nothing and so can be retried.
+// GetAllStream is left out because it may have passed todos on already.
+var todoReads = map[string]bool{
+	"GetAll": true, "Count": true, "Search": true, "GetByID": true, "GetByClientID": true,
+	"Exists": true, "ListRevisions": true, "TagStats": true, "MissingIDs": true, "Nearby": true,
+	"EstimateStats": true, "GetRevision": true, "DueSoon": true,
+}
+
+// RetryBusyTodoReads retries reads up to retries times, waiting a little
+// longer each time, while SQLite reports the database busy or locked
+func RetryBusyTodoReads(retries int, wait time.Duration) TodoDecorator {
+	return InterceptTodos(func(ctx context.Context, op string, call func(context.Context) error) error {
+		err := call(ctx)
+		if !todoReads[op] {
+			return err
+		}
+		for attempt := 1; attempt <= retries && isBusy(err); attempt++ {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(time.Duration(attempt) * wait):
+			}
+			err = call(ctx)
+		}
+		return err
+	})
+}
+
+// TimeoutTodoQueries cancels the context of calls still running after
+// timeout, which makes SQLite interrupt their statement. GetAllStream is
+// left out because it lasts as long as its caller takes with the todos.
+func TimeoutTodoQueries(timeout time.Duration) TodoDecorator {
+	return InterceptTodos(func(ctx context.Context, op string, call func(context.Context) error) error {
+		if op == "GetAllStream" {
+			return call(ctx)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return call(ctx)
+	})
+}
+
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+type interceptedTodos struct {
+	next      TodoRepository
+	intercept TodoInterceptor
+}
+
+func (r *interceptedTodos) GetAll(ctx context.Context, params models.QueryParams) (todos []models.Todo, total int, err error) {
+	err = r.intercept(ctx, "GetAll", func(ctx context.Context) (err error) {
+		todos, total, err = r.next.GetAll(ctx, params)
+		return err
+	})
+	return todos, total, err
+}
+
+func (r *interceptedTodos) GetAllStream(ctx context.Context, params models.QueryParams, fn func(models.Todo) error) error {
+	return r.intercept(ctx, "GetAllStream", func(ctx context.Context) error {
+		return r.next.GetAllStream(ctx, params, fn)
+	})
+}
+
+func (r *interceptedTodos) Count(ctx context.Context, params models.QueryParams) (count int, err error) {
+	err = r.intercept(ctx, "Count", func(ctx context.Context) (err error) {
+		count, err = r.next.Count(ctx, params)
+		return err
+	})
+	return count, err
+}
+
+func (r *interceptedTodos) Search(ctx context.Context, q string, limit, offset int) (results []models.SearchResult, total int, err error) {
+	err = r.intercept(ctx, "Search", func(ctx context.Context) (err error) {
+		results, total, err = r.next.Search(ctx, q, limit, offset)
+		return err
+	})
+	return results, total, err
+}
+
+func (r *interceptedTodos) GetByID(ctx context.Context, id int) (todo *models.Todo, err error) {
+	err = r.intercept(ctx, "GetByID", func(ctx context.Context) (err error) {
+		todo, err = r.next.GetByID(ctx, id)
+		return err
+	})
+	return todo, err
+}
+
+func (r *interceptedTodos) GetByClientID(ctx context.Context, clientID string) (todo *models.Todo, err error) {
+	err = r.intercept(ctx, "GetByClientID", func(ctx context.Context) (err error) {
+		todo, err = r.next.GetByClientID(ctx, clientID)
+		return err
+	})
+	return todo, err
+}
+
+func (r *interceptedTodos) Create(ctx context.Context, todo *models.Todo) error {
+	return r.intercept(ctx, "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, todo)
+	})
+}
+
+func (r *interceptedTodos) CreateMany(ctx context.Context, todos []*models.Todo) error {
+	return r.intercept(ctx, "CreateMany", func(ctx context.Context) error {
+		return r.next.CreateMany(ctx, todos)
+	})
+}
+
+func (r *interceptedTodos) Update(ctx context.Context, id int, updates map[string]interface{}) (todo *models.Todo, err error) {
+	err = r.intercept(ctx, "Update", func(ctx context.Context) (err error) {
+		todo, err = r.next.Update(ctx, id, updates)
+		return err
+	})
+	return todo, err
+}
+
+func (r *interceptedTodos) UpdateIfVersion(ctx context.Context, id, version int, updates map[string]interface{}) (todo *models.Todo, err error) {
+	err = r.intercept(ctx, "UpdateIfVersion", func(ctx context.Context) (err error) {
+		todo, err = r.next.UpdateIfVersion(ctx, id, version, updates)
+		return err
+	})
+	return todo, err
+}
+
+func (r *interceptedTodos) Delete(ctx context.Context, id int) error {
+	return r.intercept(ctx, "Delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id)
+	})
+}
+
+func (r *interceptedTodos) Restore(ctx context.Context, id int) (todo *models.Todo, err error) {
+	err = r.intercept(ctx, "Restore", func(ctx context.Context) (err error) {
+		todo, err = r.next.Restore(ctx, id)
+		return err
+	})
+	return todo, err
+}
+
+func (r *interceptedTodos) Purge(ctx context.Context, id int) (purged bool, err error) {
+	err = r.intercept(ctx, "Purge", func(ctx context.Context) (err error) {
+		purged, err = r.next.Purge(ctx, id)
+		return err
+	})
+	return purged, err
+}
+
+func (r *interceptedTodos) Exists(ctx context.Context, id int) (exists bool, err error) {
+	err = r.intercept(ctx, "Exists", func(ctx context.Context) (err error) {
+		exists, err = r.next.Exists(ctx, id)
+		return err
+	})
+	return exists, err
+}
+
+func (r *interceptedTodos) DeleteCompletedBefore(ctx context.Context, cutoff time.Time) (deleted []int, err error) {
+	err = r.intercept(ctx, "DeleteCompletedBefore", func(ctx context.Context) (err error) {
+		deleted, err = r.next.DeleteCompletedBefore(ctx, cutoff)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *interceptedTodos) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (purged int64, err error) {
+	err = r.intercept(ctx, "PurgeDeletedBefore", func(ctx context.Context) (err error) {
+		purged, err = r.next.PurgeDeletedBefore(ctx, cutoff)
+		return err
+	})
+	return purged, err
+}
+
+func (r *interceptedTodos) ListRevisions(ctx context.Context, todoID int) (revisions []models.TodoRevision, err error) {
+	err = r.intercept(ctx, "ListRevisions", func(ctx context.Context) (err error) {
+		revisions, err = r.next.ListRevisions(ctx, todoID)
+		return err
+	})
+	return revisions, err
+}
+
+func (r *interceptedTodos) TagStats(ctx context.Context) (stats []models.TagStats, err error) {
+	err = r.intercept(ctx, "TagStats", func(ctx context.Context) (err error) {
+		stats, err = r.next.TagStats(ctx)
+		return err
+	})
+	return stats, err
+}
+
+func (r *interceptedTodos) MissingIDs(ctx context.Context, ids []int) (missing []int, err error) {
+	err = r.intercept(ctx, "MissingIDs", func(ctx context.Context) (err error) {
+		missing, err = r.next.MissingIDs(ctx, ids)
+		return err
+	})
+	return missing, err
+}
+
+func (r *interceptedTodos) UpdateTags(ctx context.Context, ids []int, add, remove []string) (updated []int, err error) {
+	err = r.intercept(ctx, "UpdateTags", func(ctx context.Context) (err error) {
+		updated, err = r.next.UpdateTags(ctx, ids, add, remove)
+		return err
+	})
+	return updated, err
+}
+
+func (r *interceptedTodos) Nearby(ctx context.Context, box models.BoundingBox) (todos []models.Todo, err error) {
+	err = r.intercept(ctx, "Nearby", func(ctx context.Context) (err error) {
+		todos, err = r.next.Nearby(ctx, box)
+		return err
+	})
+	return todos, err
+}
+
+func (r *interceptedTodos) EstimateStats(ctx context.Context, since time.Time) (stats *models.EstimateStats, err error) {
+	err = r.intercept(ctx, "EstimateStats", func(ctx context.Context) (err error) {
+		stats, err = r.next.EstimateStats(ctx, since)
+		return err
+	})
+	return stats, err
+}
+
+func (r *interceptedTodos) GetRevision(ctx context.Context, todoID, revision int) (rev *models.TodoRevision, err error) {
+	err = r.intercept(ctx, "GetRevision", func(ctx context.Context) (err error) {
+		rev, err = r.next.GetRevision(ctx, todoID, revision)
+		return err
+	})
+	return rev, err
+}
+
+func (r *interceptedTodos) DueSoon(ctx context.Context, from, to time.Time) (todos []models.Todo, err error) {
+	err = r.intercept(ctx, "DueSoon", func(ctx context.Context) (err error) {
+		todos, err = r.next.DueSoon(ctx, from, to)
+		return err
+	})
+	return todos, err
+}
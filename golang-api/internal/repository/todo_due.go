@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// DueSoon returns the open todos due after from and at or before to that
+// have no todo.due_soon event in the outbox for their current due date,
+// soonest first. A todo whose due date moves is announced again.
+func (r *todoRepository) DueSoon(ctx context.Context, from, to time.Time) ([]models.Todo, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM todos
+		WHERE deleted_at IS NULL AND NOT completed AND due_date > ? AND due_date <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox
+				WHERE outbox.todo_id = todos.id AND outbox.event_type = 'todo.due_soon'
+					AND datetime(json_extract(outbox.payload, '$.todo.due_date')) = datetime(todos.due_date)
+			)
+		ORDER BY due_date, id
+	`, todoColumns)
+
+	todos, err := selectTodos(ctx, r.db, query, sqliteTime(from), sqliteTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos due soon: %w", err)
+	}
+
+	return todos, nil
+}
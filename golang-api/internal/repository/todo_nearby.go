@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// Nearby returns the open todos located inside the bounding box. The box
+// is only a cheap prefilter on the location index; callers compute exact
+// distances.
+func (r *todoRepository) Nearby(ctx context.Context, box models.BoundingBox) ([]models.Todo, error) {
+	query := fmt.Sprintf("SELECT %s FROM todos WHERE deleted_at IS NULL AND NOT completed AND latitude BETWEEN ? AND ?", todoColumns)
+	args := []interface{}{box.MinLat, box.MaxLat}
+	if box.MinLng <= box.MaxLng {
+		query += " AND longitude BETWEEN ? AND ?"
+	} else {
+		// The box crosses the antimeridian
+		query += " AND (longitude >= ? OR longitude <= ?)"
+	}
+	args = append(args, box.MinLng, box.MaxLng)
+
+	todos, err := selectTodos(ctx, r.db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nearby todos: %w", err)
+	}
+
+	return todos, nil
+}
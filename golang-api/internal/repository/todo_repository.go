@@ -1,129 +1,553 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/mattn/go-sqlite3"
 )
 
+var (
+	// ErrInvalidSortField is returned by GetAll for a sort field that is not
+	// in todoSortColumns
+	ErrInvalidSortField = errors.New("invalid sort field")
+	// ErrInvalidSortOrder is returned by GetAll for an order other than asc
+	// or desc
+	ErrInvalidSortOrder = errors.New("invalid order")
+	// ErrVersionConflict is returned by UpdateIfVersion when the todo has
+	// been updated since the expected version
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrDuplicateClientID is returned by Create when another todo already
+	// has the client ID
+	ErrDuplicateClientID = errors.New("duplicate client ID")
+	// ErrDuplicateTitle is returned by Create and Update when unique active
+	// titles are enforced and another uncompleted todo has the title
+	ErrDuplicateTitle = errors.New("duplicate title")
+)
+
+// uniqueViolation translates violations of the todos unique indexes into
+// the matching errors, and returns nil for any other error
+func uniqueViolation(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return nil
+	}
+	if strings.Contains(sqliteErr.Error(), "client_id") {
+		return ErrDuplicateClientID
+	}
+	return ErrDuplicateTitle
+}
+
+// todoSortColumns maps the sort fields accepted by the API to the SQL
+// expressions they order by. Only these expressions reach ORDER BY, so new
+// sortable fields, including computed ones, are added here.
+var todoSortColumns = map[string]string{
+	"id":               "id",
+	"title":            "title",
+	"completed":        "completed",
+	"status":           "CASE status WHEN 'todo' THEN 0 WHEN 'in_progress' THEN 1 WHEN 'blocked' THEN 2 ELSE 3 END",
+	"priority":         "CASE priority WHEN 'low' THEN 0 WHEN 'medium' THEN 1 WHEN 'high' THEN 2 WHEN 'urgent' THEN 3 END",
+	"created_at":       "created_at",
+	"updated_at":       "updated_at",
+	"completed_at":     "completed_at",
+	"due_date":         "due_date",
+	"deleted_at":       "deleted_at",
+	"estimate_minutes": "estimate_minutes",
+}
+
+// todoSortOrders maps the accepted sort orders to SQL
+var todoSortOrders = map[string]string{
+	"asc":  "ASC",
+	"desc": "DESC",
+}
+
 type TodoRepository interface {
-	GetAll(params models.QueryParams) ([]models.Todo, int, error)
-	GetByID(id int) (*models.Todo, error)
-	Create(todo *models.Todo) error
-	Update(id int, updates map[string]interface{}) (*models.Todo, error)
-	Delete(id int) error
-	Exists(id int) (bool, error)
+	GetAll(ctx context.Context, params models.QueryParams) ([]models.Todo, int, error)
+	GetAllStream(ctx context.Context, params models.QueryParams, fn func(models.Todo) error) error
+	Count(ctx context.Context, params models.QueryParams) (int, error)
+	Search(ctx context.Context, q string, limit, offset int) ([]models.SearchResult, int, error)
+	GetByID(ctx context.Context, id int) (*models.Todo, error)
+	GetByClientID(ctx context.Context, clientID string) (*models.Todo, error)
+	Create(ctx context.Context, todo *models.Todo) error
+	CreateMany(ctx context.Context, todos []*models.Todo) error
+	Update(ctx context.Context, id int, updates map[string]interface{}) (*models.Todo, error)
+	UpdateIfVersion(ctx context.Context, id, version int, updates map[string]interface{}) (*models.Todo, error)
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) (*models.Todo, error)
+	Purge(ctx context.Context, id int) (bool, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	DeleteCompletedBefore(ctx context.Context, cutoff time.Time) ([]int, error)
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	ListRevisions(ctx context.Context, todoID int) ([]models.TodoRevision, error)
+	TagStats(ctx context.Context) ([]models.TagStats, error)
+	MissingIDs(ctx context.Context, ids []int) ([]int, error)
+	UpdateTags(ctx context.Context, ids []int, add, remove []string) ([]int, error)
+	Nearby(ctx context.Context, box models.BoundingBox) ([]models.Todo, error)
+	EstimateStats(ctx context.Context, since time.Time) (*models.EstimateStats, error)
+	GetRevision(ctx context.Context, todoID, revision int) (*models.TodoRevision, error)
+	DueSoon(ctx context.Context, from, to time.Time) ([]models.Todo, error)
+}
+
+// todoColumns ends with the todo's tags, joined with commas. Rows are
+// scanned into todoRow by column name, so the order does not matter.
+const todoColumns = "id, title, description, completed, status, priority, version, client_id, created_at, updated_at, completed_at, due_date, deleted_at, color, icon, estimate_minutes, latitude, longitude, metadata, " +
+	"(SELECT group_concat(tag, ',') FROM todo_tags WHERE todo_tags.todo_id = todos.id) AS tags"
+
+// todoRow is a row selected with todoColumns. The embedded todo takes the
+// columns it has a db tag for; the others are scanned into the fields
+// below, which shadow the todo's, and converted by todo.
+type todoRow struct {
+	models.Todo
+	Latitude  sql.NullFloat64 `db:"latitude"`
+	Longitude sql.NullFloat64 `db:"longitude"`
+	Metadata  sql.NullString  `db:"metadata"`
+	Tags      sql.NullString  `db:"tags"`
+}
+
+func (row *todoRow) todo() models.Todo {
+	todo := row.Todo
+	if row.Latitude.Valid && row.Longitude.Valid {
+		todo.Location = &models.Location{Lat: row.Latitude.Float64, Lng: row.Longitude.Float64}
+	}
+	if row.Metadata.Valid {
+		todo.Metadata = json.RawMessage(row.Metadata.String)
+	}
+	todo.Tags = splitTags(row.Tags)
+	return todo
+}
+
+// The statements NewPreparedTodoRepository prepares: the queries run on
+// almost every request
+var (
+	getTodoByIDQuery       = fmt.Sprintf("SELECT %s FROM todos WHERE id = ? AND deleted_at IS NULL", todoColumns)
+	getTodoByClientIDQuery = fmt.Sprintf("SELECT %s FROM todos WHERE client_id = ?", todoColumns)
+	todoExistsQuery        = "SELECT EXISTS(SELECT 1 FROM todos WHERE id = ? AND deleted_at IS NULL)"
+)
+
+// insertTodoQuery binds the values of todoValues by name. Binding renders
+// the same statement for every todo.
+const insertTodoQuery = `
+	INSERT INTO todos (title, description, completed, status, priority, client_id, due_date, color, icon, estimate_minutes, latitude, longitude, metadata)
+	VALUES (:title, :description, :completed, :status, :priority, :client_id, :due_date, :color, :icon, :estimate_minutes, :latitude, :longitude, :metadata)
+`
+
+// PrepareTodoStatements prepares the statements of prepared todo
+// repositories up front. Transactions only use statements prepared
+// already, so without this a repository in a transaction prepares none.
+func PrepareTodoStatements(ctx context.Context, stmts Statements) error {
+	insert, _, err := sqlx.Named(insertTodoQuery, todoValues(&models.Todo{}))
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{getTodoByIDQuery, getTodoByClientIDQuery, todoExistsQuery, insert} {
+		if _, err := stmts.Prepare(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// todoMapper maps columns to db tags the way sqlx does by default
+var todoMapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
 type todoRepository struct {
-	db *sql.DB
+	db DBTX
+	// hot runs the statements called on almost every request: lookups by
+	// ID, existence checks and inserts. It is db, or db through prepared
+	// statements.
+	hot DBTX
 }
 
-func NewTodoRepository(db *sql.DB) TodoRepository {
-	return &todoRepository{db: db}
+func NewTodoRepository(db DBTX) TodoRepository {
+	return &todoRepository{db: db, hot: db}
 }
 
-func (r *todoRepository) GetAll(params models.QueryParams) ([]models.Todo, int, error) {
-	// Build query with filters
-	whereClause := "WHERE 1=1"
+// NewPreparedTodoRepository returns a TodoRepository that prepares its
+// hot statements once with stmts and reuses them, instead of having
+// SQLite parse them on every call
+func NewPreparedTodoRepository(db DBTX, stmts Statements) TodoRepository {
+	return &todoRepository{db: db, hot: &preparedDB{db: db, stmts: stmts}}
+}
+
+// selectTodos runs a query selecting todoColumns on db and returns the
+// todos
+func selectTodos(ctx context.Context, db DBTX, query string, args ...interface{}) ([]models.Todo, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var selected []todoRow
+	if err := sqlx.StructScan(rows, &selected); err != nil {
+		return nil, fmt.Errorf("failed to scan todo: %w", err)
+	}
+
+	todos := make([]models.Todo, len(selected))
+	for i := range selected {
+		todos[i] = selected[i].todo()
+	}
+	return todos, nil
+}
+
+// getTodo runs a query selecting todoColumns and returns the first todo,
+// or nil if there is none
+func (r *todoRepository) getTodo(ctx context.Context, query string, args ...interface{}) (*models.Todo, error) {
+	todos, err := selectTodos(ctx, r.hot, query, args...)
+	if err != nil || len(todos) == 0 {
+		return nil, err
+	}
+	return &todos[0], nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input, using \ as the
+// ESCAPE character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// metadataValue renders the metadata value at a JSON path, bound twice,
+// as text: strings as is, numbers in JSON notation and booleans as true or
+// false. It is NULL when the key is missing.
+const metadataValue = "CASE json_type(metadata, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' " +
+	"ELSE CAST(json_extract(metadata, ?) AS TEXT) END"
+
+// metadataPath turns a dotted metadata key path into a JSON path. Keys
+// are quoted; the parser only accepts plain key segments.
+func metadataPath(path string) string {
+	return `$."` + strings.ReplaceAll(path, ".", `"."`) + `"`
+}
+
+// sortedKeys returns the keys of m in order, so that filters render the
+// same SQL every time
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// todoFilter builds the WHERE clause shared by GetAll and Count. Search
+// matches a literal substring of the title or description, ignoring ASCII
+// case. Tag matches todos carrying the tag and Metadata compares JSON
+// values with json_extract. Todos in the trash are only matched when params.Trashed is set,
+// and then exclusively. Every exclusion in params.Exclude adds the
+// negation of the matching filter.
+func todoFilter(params models.QueryParams) (string, []interface{}) {
+	whereClause := "WHERE deleted_at IS NULL"
+	if params.Trashed {
+		whereClause = "WHERE deleted_at IS NOT NULL"
+	}
 	args := []interface{}{}
-	argIndex := 1
+
+	if len(params.IDs) > 0 {
+		whereClause += " AND id IN (?" + strings.Repeat(", ?", len(params.IDs)-1) + ")"
+		for _, id := range params.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(params.Exclude.IDs) > 0 {
+		whereClause += " AND id NOT IN (?" + strings.Repeat(", ?", len(params.Exclude.IDs)-1) + ")"
+		for _, id := range params.Exclude.IDs {
+			args = append(args, id)
+		}
+	}
+
+	if len(params.Status) > 0 {
+		whereClause += " AND status IN (?" + strings.Repeat(", ?", len(params.Status)-1) + ")"
+		for _, status := range params.Status {
+			args = append(args, status)
+		}
+	}
+	if len(params.Exclude.Status) > 0 {
+		whereClause += " AND status NOT IN (?" + strings.Repeat(", ?", len(params.Exclude.Status)-1) + ")"
+		for _, status := range params.Exclude.Status {
+			args = append(args, status)
+		}
+	}
+
+	if len(params.Color) > 0 {
+		whereClause += " AND color IN (?" + strings.Repeat(", ?", len(params.Color)-1) + ")"
+		for _, color := range params.Color {
+			args = append(args, color)
+		}
+	}
+	if len(params.Exclude.Color) > 0 {
+		// Todos without a color are not excluded
+		whereClause += " AND (color IS NULL OR color NOT IN (?" + strings.Repeat(", ?", len(params.Exclude.Color)-1) + "))"
+		for _, color := range params.Exclude.Color {
+			args = append(args, color)
+		}
+	}
+
+	for _, path := range sortedKeys(params.Metadata) {
+		whereClause += " AND " + metadataValue + " = ?"
+		jsonPath := metadataPath(path)
+		args = append(args, jsonPath, jsonPath, params.Metadata[path])
+	}
+	for _, path := range sortedKeys(params.Exclude.Metadata) {
+		// Todos without the key are not excluded
+		whereClause += " AND NOT COALESCE(" + metadataValue + " = ?, 0)"
+		jsonPath := metadataPath(path)
+		args = append(args, jsonPath, jsonPath, params.Exclude.Metadata[path])
+	}
+
+	if params.Tag != "" {
+		whereClause += " AND id IN (SELECT todo_id FROM todo_tags WHERE tag = ?)"
+		args = append(args, params.Tag)
+	}
+	if params.Exclude.Tag != "" {
+		whereClause += " AND id NOT IN (SELECT todo_id FROM todo_tags WHERE tag = ?)"
+		args = append(args, params.Exclude.Tag)
+	}
 
 	if params.Search != "" {
-		whereClause += fmt.Sprintf(" AND (title LIKE $%d OR description LIKE $%d)", argIndex, argIndex+1)
-		searchTerm := "%" + params.Search + "%"
+		whereClause += ` AND (title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`
+		searchTerm := "%" + likeEscaper.Replace(params.Search) + "%"
+		args = append(args, searchTerm, searchTerm)
+	}
+	if params.Exclude.Search != "" {
+		// A missing description must not turn the negation into NULL
+		whereClause += ` AND NOT (title LIKE ? ESCAPE '\' OR COALESCE(description, '') LIKE ? ESCAPE '\')`
+		searchTerm := "%" + likeEscaper.Replace(params.Exclude.Search) + "%"
 		args = append(args, searchTerm, searchTerm)
-		argIndex += 2
 	}
 
 	if params.Completed != nil {
-		whereClause += fmt.Sprintf(" AND completed = $%d", argIndex)
+		whereClause += " AND completed = ?"
 		args = append(args, *params.Completed)
-		argIndex++
 	}
+	if params.Exclude.Completed != nil {
+		whereClause += " AND completed != ?"
+		args = append(args, *params.Exclude.Completed)
+	}
+
+	if params.CompletedAfter != nil {
+		whereClause += " AND completed_at >= ?"
+		args = append(args, sqliteTime(*params.CompletedAfter))
+	}
+	if params.CompletedBefore != nil {
+		whereClause += " AND completed_at < ?"
+		args = append(args, sqliteTime(*params.CompletedBefore))
+	}
+
+	if params.CreatedAfter != nil {
+		whereClause += " AND created_at >= ?"
+		args = append(args, sqliteTime(*params.CreatedAfter))
+	}
+	if params.CreatedBefore != nil {
+		whereClause += " AND created_at < ?"
+		args = append(args, sqliteTime(*params.CreatedBefore))
+	}
+
+	switch params.Due {
+	case "none":
+		whereClause += " AND due_date IS NULL"
+	case "any":
+		whereClause += " AND due_date IS NOT NULL"
+	}
+	switch params.Exclude.Due {
+	case "none":
+		whereClause += " AND due_date IS NOT NULL"
+	case "any":
+		whereClause += " AND due_date IS NULL"
+	}
+	if params.DueAfter != nil {
+		whereClause += " AND due_date >= ?"
+		args = append(args, sqliteTime(*params.DueAfter))
+	}
+	if params.DueBefore != nil {
+		whereClause += " AND due_date < ?"
+		args = append(args, sqliteTime(*params.DueBefore))
+	}
+
+	return whereClause, args
+}
+
+func (r *todoRepository) Count(ctx context.Context, params models.QueryParams) (int, error) {
+	whereClause, args := todoFilter(params)
 
-	// Count total records
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM todos %s", whereClause)
 	var total int
-	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("failed to count todos: %w", err)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM todos %s", whereClause)
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count todos: %w", err)
 	}
 
-	// Build main query with pagination and sorting
-	orderClause := fmt.Sprintf("ORDER BY %s %s", params.Sort, strings.ToUpper(params.Order))
+	return total, nil
+}
+
+// todoOrder builds the ORDER BY clause from the whitelisted sort columns.
+// Todos selected by ID without a sort field keep the order of params.IDs.
+func todoOrder(params models.QueryParams) (string, error) {
+	if params.Sort == "" && len(params.IDs) > 0 {
+		var order strings.Builder
+		order.WriteString("ORDER BY CASE id")
+		for i, id := range params.IDs {
+			fmt.Fprintf(&order, " WHEN %d THEN %d", id, i)
+		}
+		order.WriteString(" END")
+		return order.String(), nil
+	}
+
+	column, ok := todoSortColumns[params.Sort]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSortField, params.Sort)
+	}
+	order, ok := todoSortOrders[params.Order]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSortOrder, params.Order)
+	}
+	return fmt.Sprintf("ORDER BY %s %s", column, order), nil
+}
+
+// GetAll returns a page of the todos matching params and how many match.
+// With params.SkipTotal they are not counted: the total is then the todos
+// up to the end of the page, plus one when another todo follows.
+func (r *todoRepository) GetAll(ctx context.Context, params models.QueryParams) ([]models.Todo, int, error) {
+	orderClause, err := todoOrder(params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	whereClause, args := todoFilter(params)
+
 	offset := (params.Page - 1) * params.PerPage
-	limitClause := fmt.Sprintf("LIMIT %d OFFSET %d", params.PerPage, offset)
+	limit := params.PerPage
+	total := 0
+	if params.SkipTotal {
+		// One more todo than the page holds tells whether another follows
+		limit++
+	} else if total, err = r.Count(ctx, params); err != nil {
+		return nil, 0, err
+	}
 
-	query := fmt.Sprintf(`
-		SELECT id, title, description, completed, created_at, updated_at 
-		FROM todos %s %s %s
-	`, whereClause, orderClause, limitClause)
+	// Build main query with pagination and sorting
+	limitClause := fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
 
-	rows, err := r.db.Query(query, args...)
+	query := fmt.Sprintf("SELECT %s FROM todos %s %s %s", todoColumns, whereClause, orderClause, limitClause)
+
+	todos, err := selectTodos(ctx, r.db, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query todos: %w", err)
 	}
+
+	if params.SkipTotal {
+		total = offset + len(todos)
+		todos = todos[:min(len(todos), params.PerPage)]
+	}
+
+	return todos, total, nil
+}
+
+// GetAllStream calls fn for every todo matching the filters in params, in
+// the requested order, scanning one row at a time. Pagination is ignored.
+// Iteration stops at the first error returned by fn, which is returned
+// as is. fn must not use the database: the query holds a connection until
+// iteration ends, and an in-memory database only has one.
+func (r *todoRepository) GetAllStream(ctx context.Context, params models.QueryParams, fn func(models.Todo) error) error {
+	orderClause, err := todoOrder(params)
+	if err != nil {
+		return err
+	}
+
+	whereClause, args := todoFilter(params)
+	query := fmt.Sprintf("SELECT %s FROM todos %s %s", todoColumns, whereClause, orderClause)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query todos: %w", err)
+	}
 	defer rows.Close()
 
-	todos := make([]models.Todo, 0)
-	for rows.Next() {
-		var todo models.Todo
-		err := rows.Scan(
-			&todo.ID,
-			&todo.Title,
-			&todo.Description,
-			&todo.Completed,
-			&todo.CreatedAt,
-			&todo.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan todo: %w", err)
+	scanner := &sqlx.Rows{Rows: rows, Mapper: todoMapper}
+	for scanner.Next() {
+		var row todoRow
+		if err := scanner.StructScan(&row); err != nil {
+			return fmt.Errorf("failed to scan todo: %w", err)
+		}
+		if err := fn(row.todo()); err != nil {
+			return err
 		}
-		todos = append(todos, todo)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+		return fmt.Errorf("row iteration error: %w", err)
 	}
 
-	return todos, total, nil
+	return nil
 }
 
-func (r *todoRepository) GetByID(id int) (*models.Todo, error) {
-	query := `
-		SELECT id, title, description, completed, created_at, updated_at 
-		FROM todos WHERE id = ?
-	`
-	
-	var todo models.Todo
-	err := r.db.QueryRow(query, id).Scan(
-		&todo.ID,
-		&todo.Title,
-		&todo.Description,
-		&todo.Completed,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// GetByID returns the todo, or nil if it does not exist or is in the trash
+func (r *todoRepository) GetByID(ctx context.Context, id int) (*models.Todo, error) {
+	todo, err := r.getTodo(ctx, getTodoByIDQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todo by id: %w", err)
 	}
 
-	return &todo, nil
+	return todo, nil
 }
 
-func (r *todoRepository) Create(todo *models.Todo) error {
-	query := `
-		INSERT INTO todos (title, description, completed) 
-		VALUES (?, ?, ?)
-	`
-	
-	result, err := r.db.Exec(query, todo.Title, todo.Description, todo.Completed)
+// GetByClientID returns the todo created with the client ID, or nil. A
+// todo in the trash is returned too: the client ID stays taken until it is
+// purged.
+func (r *todoRepository) GetByClientID(ctx context.Context, clientID string) (*models.Todo, error) {
+	todo, err := r.getTodo(ctx, getTodoByClientIDQuery, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo by client id: %w", err)
+	}
+
+	return todo, nil
+}
+
+// todoValues are the stored values of todo, by column
+func todoValues(todo *models.Todo) map[string]interface{} {
+	values := map[string]interface{}{
+		"title":            todo.Title,
+		"description":      todo.Description,
+		"completed":        todo.Completed,
+		"status":           todo.Status,
+		"priority":         todo.Priority,
+		"client_id":        todo.ClientID,
+		"due_date":         nil,
+		"color":            todo.Color,
+		"icon":             todo.Icon,
+		"estimate_minutes": todo.EstimateMinutes,
+		"latitude":         nil,
+		"longitude":        nil,
+		"metadata":         nil,
+	}
+	if todo.DueDate != nil {
+		values["due_date"] = sqliteTime(*todo.DueDate)
+	}
+	if todo.Location != nil {
+		values["latitude"], values["longitude"] = todo.Location.Lat, todo.Location.Lng
+	}
+	if todo.Metadata != nil {
+		values["metadata"] = string(todo.Metadata)
+	}
+	return values
+}
+
+func (r *todoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	query, args, err := sqlx.Named(insertTodoQuery, todoValues(todo))
+	if err != nil {
+		return fmt.Errorf("failed to bind todo: %w", err)
+	}
+
+	result, err := r.hot.ExecContext(ctx, query, args...)
+	if unique := uniqueViolation(err); unique != nil {
+		return unique
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create todo: %w", err)
 	}
@@ -133,8 +557,12 @@ func (r *todoRepository) Create(todo *models.Todo) error {
 		return fmt.Errorf("failed to get last insert id: %w", err)
 	}
 
+	if err := r.setTags(ctx, int(id), todo.Tags); err != nil {
+		return err
+	}
+
 	// Fetch the created todo to get timestamps
-	createdTodo, err := r.GetByID(int(id))
+	createdTodo, err := r.GetByID(ctx, int(id))
 	if err != nil {
 		return fmt.Errorf("failed to fetch created todo: %w", err)
 	}
@@ -143,32 +571,69 @@ func (r *todoRepository) Create(todo *models.Todo) error {
 	return nil
 }
 
-func (r *todoRepository) Update(id int, updates map[string]interface{}) (*models.Todo, error) {
+func (r *todoRepository) Update(ctx context.Context, id int, updates map[string]interface{}) (*models.Todo, error) {
+	return r.update(ctx, id, nil, updates)
+}
+
+// UpdateIfVersion applies updates only if the todo is still at version,
+// in the same statement, and returns ErrVersionConflict otherwise. Like
+// Update it returns nil if the todo does not exist.
+func (r *todoRepository) UpdateIfVersion(ctx context.Context, id, version int, updates map[string]interface{}) (*models.Todo, error) {
+	return r.update(ctx, id, &version, updates)
+}
+
+func (r *todoRepository) update(ctx context.Context, id int, version *int, updates map[string]interface{}) (*models.Todo, error) {
 	if len(updates) == 0 {
-		return r.GetByID(id)
+		todo, err := r.GetByID(ctx, id)
+		if err == nil && todo != nil && version != nil && todo.Version != *version {
+			return nil, ErrVersionConflict
+		}
+		return todo, err
 	}
 
-	// Build dynamic update query
+	// Build dynamic update query. Every column is bound by its own name, and
+	// the WHERE clause by names no column has. Tags are not a column: they
+	// are replaced once the todo row is updated.
 	setParts := []string{}
-	args := []interface{}{}
-	
+	values := map[string]interface{}{"todo_id": id}
+	tags, setTags := updates["tags"].([]string)
+
 	for field, value := range updates {
-		setParts = append(setParts, fmt.Sprintf("%s = ?", field))
-		args = append(args, value)
+		if field == "tags" {
+			continue
+		}
+		// Times are stored in the format SQLite compares and parses
+		if t, ok := value.(time.Time); ok {
+			value = sqliteTime(t)
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = :%s", field, field))
+		values[field] = value
 	}
-	
-	// Add updated_at
-	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
-	
-	// Add id for WHERE clause
-	args = append(args, id)
-	
-	query := fmt.Sprintf(
-		"UPDATE todos SET %s WHERE id = ?",
+	// Keep the statement the same for the same fields
+	sort.Strings(setParts)
+
+	// Add updated_at and bump the version
+	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP", "version = version + 1")
+
+	whereClause := "WHERE id = :todo_id AND deleted_at IS NULL"
+	if version != nil {
+		whereClause += " AND version = :expected_version"
+		values["expected_version"] = *version
+	}
+
+	query, args, err := sqlx.Named(fmt.Sprintf(
+		"UPDATE todos SET %s %s",
 		strings.Join(setParts, ", "),
-	)
+		whereClause,
+	), values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind todo update: %w", err)
+	}
 
-	result, err := r.db.Exec(query, args...)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if unique := uniqueViolation(err); unique != nil {
+		return nil, unique
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update todo: %w", err)
 	}
@@ -179,16 +644,31 @@ func (r *todoRepository) Update(id int, updates map[string]interface{}) (*models
 	}
 
 	if rowsAffected == 0 {
-		return nil, nil // Todo not found
+		if version == nil {
+			return nil, nil // Todo not found
+		}
+		exists, err := r.Exists(ctx, id)
+		if err != nil || !exists {
+			return nil, err
+		}
+		return nil, ErrVersionConflict
 	}
 
-	return r.GetByID(id)
+	if setTags {
+		if err := r.setTags(ctx, id, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.GetByID(ctx, id)
 }
 
-func (r *todoRepository) Delete(id int) error {
-	query := "DELETE FROM todos WHERE id = ?"
+// Delete moves the todo to the trash. It stays there until
+// PurgeDeletedBefore removes it.
+func (r *todoRepository) Delete(ctx context.Context, id int) error {
+	query := "UPDATE todos SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL"
 	
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete todo: %w", err)
 	}
@@ -205,14 +685,101 @@ func (r *todoRepository) Delete(id int) error {
 	return nil
 }
 
-func (r *todoRepository) Exists(id int) (bool, error) {
-	query := "SELECT EXISTS(SELECT 1 FROM todos WHERE id = ?)"
-	
+// Restore takes the todo out of the trash and returns it, or nil if it is
+// not in the trash. Like an update it bumps the version.
+func (r *todoRepository) Restore(ctx context.Context, id int) (*models.Todo, error) {
+	query := "UPDATE todos SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL"
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if unique := uniqueViolation(err); unique != nil {
+		return nil, unique
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Purge permanently deletes the todo if it is in the trash, and reports
+// whether it was. Its revisions and search entry go with it.
+func (r *todoRepository) Purge(ctx context.Context, id int) (bool, error) {
+	query := "DELETE FROM todos WHERE id = ? AND deleted_at IS NOT NULL"
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to purge todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *todoRepository) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := r.db.QueryRow(query, id).Scan(&exists)
+	err := r.hot.QueryRowContext(ctx, todoExistsQuery, id).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check todo existence: %w", err)
 	}
 
 	return exists, nil
-}
\ No newline at end of file
+}
+
+// DeleteCompletedBefore deletes the todos completed before cutoff and
+// returns their IDs. It goes by completed_at, so editing a completed todo
+// does not keep it any longer.
+func (r *todoRepository) DeleteCompletedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	query := "DELETE FROM todos WHERE completed = 1 AND completed_at < ? RETURNING id"
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteTime(cutoff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete completed todos: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan todo ID: %w", err)
+		}
+		deleted = append(deleted, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete completed todos: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// PurgeDeletedBefore permanently deletes the todos moved to the trash
+// before cutoff
+func (r *todoRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := "DELETE FROM todos WHERE deleted_at IS NOT NULL AND deleted_at < ?"
+
+	result, err := r.db.ExecContext(ctx, query, sqliteTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted todos: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// Revisions are written by triggers on the todos table (see the
+// migrations), so the repository only reads them
+
+const todoRevisionColumns = "todo_id, revision, title, description, completed, created_at"
+
+func scanTodoRevision(row rowScanner) (*models.TodoRevision, error) {
+	var revision models.TodoRevision
+	err := row.Scan(
+		&revision.TodoID,
+		&revision.Revision,
+		&revision.Title,
+		&revision.Description,
+		&revision.Completed,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListRevisions returns the revisions of a todo, newest first
+func (r *todoRepository) ListRevisions(ctx context.Context, todoID int) ([]models.TodoRevision, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_revisions WHERE todo_id = ? ORDER BY revision DESC", todoRevisionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todo revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.TodoRevision, 0)
+	for rows.Next() {
+		revision, err := scanTodoRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan todo revision: %w", err)
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns one revision of a todo, or nil if it does not exist
+func (r *todoRepository) GetRevision(ctx context.Context, todoID, revision int) (*models.TodoRevision, error) {
+	query := fmt.Sprintf("SELECT %s FROM todo_revisions WHERE todo_id = ? AND revision = ?", todoRevisionColumns)
+
+	rev, err := scanTodoRevision(r.db.QueryRowContext(ctx, query, todoID, revision))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo revision: %w", err)
+	}
+
+	return rev, nil
+}
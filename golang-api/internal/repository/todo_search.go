@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// searchWeights scores a match in the title higher than one in the
+// description, in todos_fts column order
+var searchWeights = []float64{2, 1}
+
+// Search runs a full-text query against todos_fts and returns one page of
+// results ranked by relevance, together with the total number of matches.
+// Every search term is matched as a word prefix and all terms must match.
+func (r *todoRepository) Search(ctx context.Context, q string, limit, offset int) ([]models.SearchResult, int, error) {
+	match := ftsQuery(q)
+	if match == "" {
+		return []models.SearchResult{}, 0, nil
+	}
+
+	query := `
+		SELECT t.id, t.title, t.description, t.completed, t.status, t.priority, t.version, t.client_id, t.created_at, t.updated_at, t.completed_at, t.due_date, t.deleted_at, t.color, t.icon, t.estimate_minutes, t.latitude, t.longitude, t.metadata,
+			(SELECT group_concat(tag, ',') FROM todo_tags WHERE todo_tags.todo_id = t.id),
+			snippet(todos_fts, '<mark>', '</mark>', '…', 0, 16),
+			snippet(todos_fts, '<mark>', '</mark>', '…', 1, 32),
+			matchinfo(todos_fts, 'pcx')
+		FROM todos_fts
+		JOIN todos t ON t.id = todos_fts.docid
+		WHERE todos_fts MATCH ? AND t.deleted_at IS NULL
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, match)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search todos: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0)
+	for rows.Next() {
+		var result models.SearchResult
+		var description, metadata, tags sql.NullString
+		var latitude, longitude sql.NullFloat64
+		var info []byte
+		err := rows.Scan(
+			&result.ID,
+			&result.Title,
+			&result.Description,
+			&result.Completed,
+			&result.Status,
+			&result.Priority,
+			&result.Version,
+			&result.ClientID,
+			&result.CreatedAt,
+			&result.UpdatedAt,
+			&result.CompletedAt,
+			&result.DueDate,
+			&result.DeletedAt,
+			&result.Color,
+			&result.Icon,
+			&result.EstimateMinutes,
+			&latitude,
+			&longitude,
+			&metadata,
+			&tags,
+			&result.Highlights.Title,
+			&description,
+			&info,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		if latitude.Valid && longitude.Valid {
+			result.Location = &models.Location{Lat: latitude.Float64, Lng: longitude.Float64}
+		}
+		if metadata.Valid {
+			result.Metadata = json.RawMessage(metadata.String)
+		}
+		result.Tags = splitTags(tags)
+		result.Highlights.Description = description.String
+		result.Score = rank(info)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID > results[j].ID
+	})
+
+	total := len(results)
+	if offset >= total {
+		return []models.SearchResult{}, total, nil
+	}
+	return results[offset:min(offset+limit, total)], total, nil
+}
+
+// ftsQuery turns free text into an FTS MATCH expression. Only letters and
+// digits are kept, so user input can never be parsed as query syntax.
+func ftsQuery(q string) string {
+	terms := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, term := range terms {
+		terms[i] = term + "*"
+	}
+	return strings.Join(terms, " ")
+}
+
+// rank scores a row from its matchinfo 'pcx' blob: for every phrase and
+// column, the hits in this row relative to the hits in all rows, weighted
+// by searchWeights
+func rank(info []byte) float64 {
+	if len(info) < 8 {
+		return 0
+	}
+	value := func(i int) float64 {
+		return float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+
+	phrases, columns := int(value(0)), int(value(1))
+	if len(info) < (2+phrases*columns*3)*4 {
+		return 0
+	}
+
+	var score float64
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(searchWeights); c++ {
+			base := 2 + (p*columns+c)*3
+			hits, allHits := value(base), value(base+1)
+			if hits > 0 && allHits > 0 {
+				score += searchWeights[c] * hits / allHits
+			}
+		}
+	}
+	return score
+}
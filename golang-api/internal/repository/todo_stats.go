@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// estimateWeeksQuery sums estimates per week of creation and per week of
+// completion. date(x, 'weekday 0', '-6 days') is the Monday starting the
+// week of x.
+const estimateWeeksQuery = `
+	SELECT week, SUM(created), SUM(created_minutes), SUM(completed), SUM(completed_minutes)
+	FROM (
+		SELECT date(created_at, 'weekday 0', '-6 days') AS week,
+			1 AS created, COALESCE(estimate_minutes, 0) AS created_minutes,
+			0 AS completed, 0 AS completed_minutes
+		FROM todos
+		WHERE deleted_at IS NULL AND created_at >= ?
+		UNION ALL
+		SELECT date(completed_at, 'weekday 0', '-6 days'),
+			0, 0,
+			1, COALESCE(estimate_minutes, 0)
+		FROM todos
+		WHERE deleted_at IS NULL AND completed AND completed_at >= ?
+	)
+	GROUP BY week
+	ORDER BY week
+`
+
+// EstimateStats sums the estimates of the todos created and completed in
+// every week since the given time, and of the todos still open. Weeks
+// without activity are left out. Todos in the trash are not counted.
+func (r *todoRepository) EstimateStats(ctx context.Context, since time.Time) (*models.EstimateStats, error) {
+	rows, err := r.db.QueryContext(ctx, estimateWeeksQuery, sqliteTime(since), sqliteTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query estimate stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &models.EstimateStats{Weeks: make([]models.WeeklyEstimate, 0)}
+	for rows.Next() {
+		var week models.WeeklyEstimate
+		if err := rows.Scan(&week.WeekStart, &week.Created, &week.EstimatedCreated, &week.Completed, &week.EstimatedCompleted); err != nil {
+			return nil, fmt.Errorf("failed to scan estimate stats: %w", err)
+		}
+		stats.Weeks = append(stats.Weeks, week)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	query := `
+		SELECT COALESCE(SUM(estimate_minutes), 0),
+			COALESCE(SUM(CASE WHEN estimate_minutes IS NULL THEN 1 ELSE 0 END), 0)
+		FROM todos
+		WHERE deleted_at IS NULL AND NOT completed
+	`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&stats.RemainingMinutes, &stats.Unestimated); err != nil {
+		return nil, fmt.Errorf("failed to query remaining estimates: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// splitTags turns the comma-joined tags selected with a todo into a sorted
+// list. Tags never contain commas (see the service validation).
+func splitTags(tags sql.NullString) []string {
+	if !tags.Valid || tags.String == "" {
+		return []string{}
+	}
+	list := strings.Split(tags.String, ",")
+	sort.Strings(list)
+	return list
+}
+
+// setTags replaces the tags of a todo
+func (r *todoRepository) setTags(ctx context.Context, todoID int, tags []string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM todo_tags WHERE todo_id = ?", todoID); err != nil {
+		return fmt.Errorf("failed to clear todo tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := r.db.ExecContext(ctx, "INSERT OR IGNORE INTO todo_tags (todo_id, tag) VALUES (?, ?)", todoID, tag); err != nil {
+			return fmt.Errorf("failed to tag todo: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TagStats counts the open and completed todos carrying each tag, most
+// used tags first. Todos in the trash are not counted.
+func (r *todoRepository) TagStats(ctx context.Context) ([]models.TagStats, error) {
+	query := `
+		SELECT tt.tag,
+			SUM(CASE WHEN t.completed THEN 0 ELSE 1 END),
+			SUM(CASE WHEN t.completed THEN 1 ELSE 0 END),
+			COUNT(*)
+		FROM todo_tags tt
+		JOIN todos t ON t.id = tt.todo_id
+		WHERE t.deleted_at IS NULL
+		GROUP BY tt.tag
+		ORDER BY COUNT(*) DESC, tt.tag
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.TagStats, 0)
+	for rows.Next() {
+		var s models.TagStats
+		if err := rows.Scan(&s.Tag, &s.Open, &s.Completed, &s.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan tag stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return stats, nil
+}
+
+// MissingIDs returns the given IDs that are not live todos, because they
+// do not exist or are in the trash
+func (r *todoRepository) MissingIDs(ctx context.Context, ids []int) ([]int, error) {
+	idList, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT value FROM json_each(?)
+		WHERE value NOT IN (SELECT id FROM todos WHERE deleted_at IS NULL)
+		ORDER BY value
+	`
+	rows, err := r.db.QueryContext(ctx, query, string(idList))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check todo IDs: %w", err)
+	}
+	defer rows.Close()
+
+	missing := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan todo ID: %w", err)
+		}
+		missing = append(missing, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return missing, nil
+}
+
+// UpdateTags adds and removes tags on many todos and returns the IDs of
+// the todos whose tags changed, in ascending order. Those todos get a new
+// version first; the tags are then inserted and deleted with one
+// statement each. Todos in the trash are left alone.
+func (r *todoRepository) UpdateTags(ctx context.Context, ids []int, add, remove []string) ([]int, error) {
+	idList, _ := json.Marshal(ids)
+	addList, _ := json.Marshal(add)
+	removeList, _ := json.Marshal(remove)
+
+	query := `
+		UPDATE todos SET updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE deleted_at IS NULL
+			AND id IN (SELECT value FROM json_each(?))
+			AND (
+				EXISTS (
+					SELECT 1 FROM json_each(?) AS added
+					WHERE added.value NOT IN (SELECT tag FROM todo_tags WHERE todo_id = todos.id)
+				)
+				OR EXISTS (
+					SELECT 1 FROM todo_tags
+					WHERE todo_id = todos.id AND tag IN (SELECT value FROM json_each(?))
+				)
+			)
+		RETURNING id
+	`
+	rows, err := r.db.QueryContext(ctx, query, string(idList), string(addList), string(removeList))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tagged todos: %w", err)
+	}
+	defer rows.Close()
+
+	changed := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan todo ID: %w", err)
+		}
+		changed = append(changed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	rows.Close()
+
+	if len(changed) == 0 {
+		return changed, nil
+	}
+	sort.Ints(changed)
+	changedList, _ := json.Marshal(changed)
+
+	insert := `
+		INSERT OR IGNORE INTO todo_tags (todo_id, tag)
+		SELECT todo.value, tag.value FROM json_each(?) AS todo, json_each(?) AS tag
+	`
+	if _, err := r.db.ExecContext(ctx, insert, string(changedList), string(addList)); err != nil {
+		return nil, fmt.Errorf("failed to tag todos: %w", err)
+	}
+
+	del := `
+		DELETE FROM todo_tags
+		WHERE todo_id IN (SELECT value FROM json_each(?))
+			AND tag IN (SELECT value FROM json_each(?))
+	`
+	if _, err := r.db.ExecContext(ctx, del, string(changedList), string(removeList)); err != nil {
+		return nil, fmt.Errorf("failed to untag todos: %w", err)
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// UsageRepository stores metered API calls. Days are UTC dates formatted
+// as YYYY-MM-DD, which sort like the dates they name.
+type UsageRepository interface {
+	// Increment counts one call by the user on day, with the API key or,
+	// for nil, an access token. Calls by users that no longer exist are
+	// not counted.
+	Increment(ctx context.Context, userID int, apiKeyID *int, day string) error
+	// ListByUser returns the user's calls from one day to another, both
+	// included, by day and credential. An empty day leaves that end open.
+	ListByUser(ctx context.Context, userID int, from, to string) ([]models.UsageRecord, error)
+	CountByUser(ctx context.Context, userID int) (int, error)
+	Stored(ctx context.Context, userID int) (*models.StoredItems, error)
+	// Report totals the calls from one day to another and lists the limit
+	// users with the most calls
+	Report(ctx context.Context, from, to string, limit int) (*models.UsageReport, error)
+}
+
+type usageRepository struct {
+	db DBTX
+}
+
+func NewUsageRepository(db DBTX) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+// storedColumns count a user's rows, for the user aliased u
+const storedColumns = `
+	(SELECT COUNT(*) FROM api_keys WHERE user_id = u.id),
+	(SELECT COUNT(*) FROM hooks WHERE user_id = u.id),
+	(SELECT COUNT(*) FROM notifications WHERE user_id = u.id),
+	(SELECT COUNT(*) FROM google_task_links WHERE user_id = u.id)`
+
+func scanStored(stored *models.StoredItems) []interface{} {
+	return []interface{}{&stored.APIKeys, &stored.Hooks, &stored.Notifications, &stored.GoogleTaskLinks}
+}
+
+func (r *usageRepository) Increment(ctx context.Context, userID int, apiKeyID *int, day string) error {
+	keyID := 0
+	if apiKeyID != nil {
+		keyID = *apiKeyID
+	}
+
+	// A request authenticated just before its user was deleted is
+	// recorded after the deletion; it must not bring back the usage the
+	// deletion erased
+	query := `
+		INSERT INTO api_usage (user_id, api_key_id, day, calls)
+		SELECT ?, ?, ?, 1 WHERE EXISTS (SELECT 1 FROM users WHERE id = ?)
+		ON CONFLICT (user_id, api_key_id, day) DO UPDATE SET calls = calls + 1
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, keyID, day, userID); err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	return nil
+}
+
+func (r *usageRepository) ListByUser(ctx context.Context, userID int, from, to string) ([]models.UsageRecord, error) {
+	query := `
+		SELECT day, api_key_id, calls FROM api_usage
+		WHERE user_id = ? AND (? = '' OR day >= ?) AND (? = '' OR day <= ?)
+		ORDER BY day, api_key_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from, from, to, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]models.UsageRecord, 0)
+	for rows.Next() {
+		var record models.UsageRecord
+		var keyID int
+		if err := rows.Scan(&record.Day, &keyID, &record.Calls); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		if keyID != 0 {
+			record.APIKeyID = &keyID
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return records, nil
+}
+
+func (r *usageRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM api_usage WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count usage: %w", err)
+	}
+	return count, nil
+}
+
+func (r *usageRepository) Stored(ctx context.Context, userID int) (*models.StoredItems, error) {
+	var stored models.StoredItems
+	err := r.db.QueryRowContext(ctx, "SELECT "+storedColumns+" FROM users u WHERE u.id = ?", userID).Scan(scanStored(&stored)...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stored items: %w", err)
+	}
+	return &stored, nil
+}
+
+func (r *usageRepository) Report(ctx context.Context, from, to string, limit int) (*models.UsageReport, error) {
+	report := &models.UsageReport{From: from, To: to}
+
+	totals := `
+		SELECT
+			(SELECT COALESCE(SUM(calls), 0) FROM api_usage WHERE day BETWEEN ? AND ?),
+			(SELECT COUNT(*) FROM todos),
+			(SELECT COUNT(*) FROM todo_notes),
+			(SELECT COUNT(*) FROM saved_searches)
+	`
+	if err := r.db.QueryRowContext(ctx, totals, from, to).Scan(&report.Calls, &report.Todos, &report.Notes, &report.SavedSearches); err != nil {
+		return nil, fmt.Errorf("failed to total usage: %w", err)
+	}
+
+	query := `
+		SELECT u.id, u.email, COALESCE(c.calls, 0),` + storedColumns + `
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, SUM(calls) AS calls FROM api_usage
+			WHERE day BETWEEN ? AND ?
+			GROUP BY user_id
+		) c ON c.user_id = u.id
+		ORDER BY COALESCE(c.calls, 0) DESC, u.id
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	report.Users = make([]models.UserUsage, 0)
+	for rows.Next() {
+		var usage models.UserUsage
+		dest := append([]interface{}{&usage.UserID, &usage.Email, &usage.Calls}, scanStored(&usage.Stored)...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		report.Users = append(report.Users, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrDuplicateEmail is returned by Create when another user already has
+// the email
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	GetByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	LinkIdentity(ctx context.Context, userID int, issuer, subject string) error
+	ListIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error)
+	ListIDs(ctx context.Context) ([]int, error)
+	IDsByEmail(ctx context.Context, emails []string) ([]int, error)
+	// SetPlan moves the user to plan, nil for the default plan, reporting
+	// whether the user exists
+	SetPlan(ctx context.Context, id int, plan *string) (bool, error)
+}
+
+type userRepository struct {
+	db DBTX
+}
+
+func NewUserRepository(db DBTX) UserRepository {
+	return &userRepository{db: db}
+}
+
+const userColumns = "id, email, name, password_hash, plan, created_at, updated_at"
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.PasswordHash,
+		&user.Plan,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO users (email, name, password_hash)
+		VALUES (?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, strings.ToLower(user.Email), user.Name, user.PasswordHash)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrDuplicateEmail
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	createdUser, err := r.GetByID(ctx, int(id))
+	if err != nil {
+		return fmt.Errorf("failed to fetch created user: %w", err)
+	}
+
+	*user = *createdUser
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = ?", userColumns)
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE email = ?", userColumns)
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, strings.ToLower(email)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)"
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, strings.ToLower(email)).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check email existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetByIdentity returns the user linked to an external identity provider
+// subject, or nil if none is linked
+func (r *userRepository) GetByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	query := `
+		SELECT u.id, u.email, u.name, u.password_hash, u.plan, u.created_at, u.updated_at
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = ? AND i.subject = ?
+	`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, issuer, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) LinkIdentity(ctx context.Context, userID int, issuer, subject string) error {
+	query := "INSERT INTO user_identities (user_id, issuer, subject) VALUES (?, ?, ?)"
+
+	if _, err := r.db.ExecContext(ctx, query, userID, issuer, subject); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) ListIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT issuer, subject, created_at FROM user_identities WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := make([]models.UserIdentity, 0)
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.Issuer, &identity.Subject, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (r *userRepository) SetPlan(ctx context.Context, id int, plan *string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE users SET plan = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", plan, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set plan: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ListIDs returns the IDs of every user
+func (r *userRepository) ListIDs(ctx context.Context) ([]int, error) {
+	return r.queryIDs(ctx, "SELECT id FROM users ORDER BY id")
+}
+
+// IDsByEmail returns the IDs of the users with the given emails; unknown
+// emails are skipped
+func (r *userRepository) IDsByEmail(ctx context.Context, emails []string) ([]int, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	lower := make([]string, len(emails))
+	for i, email := range emails {
+		lower[i] = strings.ToLower(email)
+	}
+	encoded, err := json.Marshal(lower)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode emails: %w", err)
+	}
+
+	return r.queryIDs(ctx, "SELECT id FROM users WHERE email IN (SELECT value FROM json_each(?)) ORDER BY id", string(encoded))
+}
+
+func (r *userRepository) queryIDs(ctx context.Context, query string, args ...interface{}) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return ids, nil
+}
@@ -1,62 +1,383 @@
 package routes
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
+	"github.com/centroidsol/todo-api/docs"
+	"github.com/centroidsol/todo-api/internal/auth"
 	"github.com/centroidsol/todo-api/internal/config"
 	"github.com/centroidsol/todo-api/internal/database"
+	"github.com/centroidsol/todo-api/internal/exports"
+	"github.com/centroidsol/todo-api/internal/googletasks"
 	"github.com/centroidsol/todo-api/internal/handlers"
+	"github.com/centroidsol/todo-api/internal/jobs"
+	"github.com/centroidsol/todo-api/internal/metrics"
 	"github.com/centroidsol/todo-api/internal/middleware"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/openapi"
+	"github.com/centroidsol/todo-api/internal/reporting"
 	"github.com/centroidsol/todo-api/internal/repository"
 	"github.com/centroidsol/todo-api/internal/services"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
 )
 
-func Setup(app *fiber.App, db *database.Database, cfg *config.Config, logger *slog.Logger) {
+// RequestMethods are the HTTP methods the app accepts: the standard ones
+// plus the WebDAV methods CalDAV clients use
+var RequestMethods = append(append([]string(nil), fiber.DefaultMethods...), "PROPFIND", "REPORT")
+
+// NewApp creates the Fiber app with the server settings from cfg
+func NewApp(cfg *config.Config, logger *slog.Logger, reporter reporting.Reporter) *fiber.App {
+	appConfig := fiber.Config{
+		AppName:      cfg.App.Name,
+		ErrorHandler: middleware.ErrorHandler(logger, reporter),
+		Prefork:      cfg.Server.Prefork,
+		ServerHeader: "Todo-API/" + cfg.App.Version,
+		BodyLimit:    cfg.Server.BodyLimit,
+		// Custom methods are rejected unless listed
+		RequestMethods: RequestMethods,
+	}
+
+	// Behind a load balancer, take the client address from ProxyHeader,
+	// but only on connections from a trusted proxy so clients cannot
+	// spoof it
+	if len(cfg.Server.TrustedProxies) > 0 {
+		appConfig.EnableTrustedProxyCheck = true
+		appConfig.TrustedProxies = cfg.Server.TrustedProxies
+		appConfig.ProxyHeader = cfg.Server.ProxyHeader
+	}
+
+	return fiber.New(appConfig)
+}
+
+// todoDecorators are the concerns wrapped around the todo repository,
+// outermost first: metrics time the whole call, retries included, and
+// each attempt gets its own timeout
+func todoDecorators(cfg *config.Config, registry *metrics.Registry, logger *slog.Logger) []repository.TodoDecorator {
+	var decorators []repository.TodoDecorator
+	if registry != nil {
+		decorators = append(decorators, repository.ObserveTodoQueries(registry))
+	}
+	if cfg.Logging.SlowQueryThreshold > 0 {
+		decorators = append(decorators, repository.LogSlowTodoQueries(logger, cfg.Logging.SlowQueryThreshold))
+	}
+	if cfg.Database.BusyRetries > 0 {
+		decorators = append(decorators, repository.RetryBusyTodoReads(cfg.Database.BusyRetries, 10*time.Millisecond))
+	}
+	if cfg.Database.QueryTimeout > 0 {
+		decorators = append(decorators, repository.TimeoutTodoQueries(cfg.Database.QueryTimeout))
+	}
+	return decorators
+}
+
+// Setup registers middleware and routes. Middleware reads reloadable
+// settings from store on every request. accessLog, when not nil, receives
+// the Apache-format access log. logLevel controls the root
+// logger's level and can be changed through the admin API. Panics and
+// server errors go to reporter. draining is set once shutdown has begun
+// and makes the readiness probe fail. A restore pauses the workers of
+// jobManager and the other background work, such as the scheduler. It
+// returns the todo service the routes use, for the todo jobs to share, so
+// their writes go through the same count cache.
+func Setup(app *fiber.App, db *database.Database, store *config.Store, logger *slog.Logger, accessLog io.Writer, logLevel *slog.LevelVar, reporter reporting.Reporter, jobManager *jobs.Manager, draining *atomic.Bool, background ...handlers.Pausable) services.TodoService {
+	cfg := store.Get()
+
 	// Global middleware
-	app.Use(recover.New())
-	app.Use(middleware.RequestID())
-	app.Use(middleware.Logger(logger))
-	app.Use(middleware.CORS(cfg))
+	app.Use(middleware.RequestID(logger, cfg.Logging.RequestIDFormat))
+	app.Use(middleware.Logger(logger, store, accessLog))
+	var registry *metrics.Registry
+	if cfg.Metrics.Enabled {
+		registry = metrics.NewRegistry()
+		app.Use(middleware.Metrics(registry))
+	}
+	app.Use(middleware.Envelope(cfg))
+	app.Use(middleware.Recover(reporter, logger))
+	app.Use(middleware.CORS(store))
+
+	maintenance := &middleware.MaintenanceMode{}
+	app.Use(maintenance.Handler())
+	if db.ReadOnly() {
+		app.Use(middleware.ReadOnly())
+	}
 
 	// Initialize dependencies
-	todoRepo := repository.NewTodoRepository(db.DB())
-	todoService := services.NewTodoService(todoRepo, logger)
-	todoHandler := handlers.NewTodoHandler(todoService, logger)
-	healthHandler := handlers.NewHealthHandler(db, cfg, logger)
+	tokens := auth.NewTokenManager(jwtSecret(cfg, logger), cfg.Auth.TokenTTL, cfg.App.Name)
+	auditService := services.NewAuditService(repository.NewAuditRepository(db.DB()), logger)
+	loginGuard := services.NewLoginGuard(repository.NewLoginFailureRepository(db.DB()), auditService, cfg.Auth, logger)
+	userService := services.NewUserService(repository.NewUserRepository(db.DB()), loginGuard, tokens, cfg.Auth.PasswordCost, logger)
+	var oidcProvider *auth.OIDCProvider
+	if cfg.OIDC.Enabled() {
+		oidcProvider = auth.NewOIDCProvider(cfg.OIDC)
+	}
+	authHandler := handlers.NewAuthHandler(userService, oidcProvider, cfg.IsProduction(), logger)
+	planService := services.NewPlanService(repository.NewUserRepository(db.DB()), store, logger)
+	planHandler := handlers.NewPlanHandler(planService, logger)
+	apiKeyService := services.NewAPIKeyService(repository.NewAPIKeyRepository(db.DB()), planService, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, logger)
+	if err := repository.PrepareTodoStatements(context.Background(), db); err != nil {
+		logger.Warn("Failed to prepare todo statements", "error", err)
+	}
+	uow := repository.NewPreparedUnitOfWork(db.DB(), db)
+	decorators := todoDecorators(cfg, registry, logger)
+	var counts *repository.TodoCounts
+	if cfg.Database.CountCacheTTL > 0 {
+		counts = repository.NewTodoCounts(cfg.Database.CountCacheTTL)
+		uow = counts.UnitOfWork(uow)
+		decorators = append(decorators, counts.Decorator())
+	}
+	todoRepo := repository.DecorateTodos(repository.NewPreparedTodoRepository(db.DB(), db), decorators...)
+	todoService := services.NewTodoService(todoRepo, uow, logger)
+	todoHandler := handlers.NewTodoHandler(todoService, jobManager, cfg.Todos.WriteBehind, logger)
+	trashHandler := handlers.NewTrashHandler(todoService, cfg.Trash.Retention(), logger)
+	savedSearchService := services.NewSavedSearchService(repository.NewSavedSearchRepository(db.DB()), todoService, logger)
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchService, logger)
+	noteHandler := handlers.NewNoteHandler(services.NewNoteService(repository.NewNoteRepository(db.DB()), todoRepo, uow, logger), logger)
+	var titleFetcher services.TitleFetcher
+	if cfg.Todos.FetchLinkTitles {
+		titleFetcher = services.NewHTTPTitleFetcher()
+	}
+	linkHandler := handlers.NewLinkHandler(services.NewLinkService(repository.NewLinkRepository(db.DB()), todoRepo, titleFetcher, logger), logger)
+	healthHandler := handlers.NewHealthHandler(db, cfg, draining, logger)
+	activityService := services.NewActivityService(repository.NewActivityRepository(db.DB()), logger)
+	activityHandler := handlers.NewActivityHandler(activityService, logger)
+	feedHandler := handlers.NewFeedHandler(activityService, cfg.App.Name, logger)
+	hookHandler := handlers.NewHookHandler(services.NewHookService(repository.NewHookRepository(db.DB()), planService, cfg.Hooks.AllowPrivateTargets, logger), logger)
+	notificationHandler := handlers.NewNotificationHandler(services.NewNotificationService(repository.NewNotificationRepository(db.DB()), repository.NewUserRepository(db.DB()), logger), logger)
+	calDAVHandler := handlers.NewCalDAVHandler(services.NewCalDAVService(repository.NewCalDAVRepository(db.DB()), todoService, logger), logger)
+	var googleTasksService services.GoogleTasksService
+	if cfg.Google.Enabled() {
+		googleTasksService = services.NewGoogleTasksService(repository.NewGoogleTasksRepository(db.DB()), todoService, googletasks.NewClient(cfg.Google), logger)
+	}
+	googleTasksHandler := handlers.NewGoogleTasksHandler(googleTasksService, logger)
+	jobHandler := handlers.NewJobHandler(jobManager, logger)
+	backupHandler := handlers.NewBackupHandler(db, maintenance, append([]handlers.Pausable{jobManager}, background...), counts, logger)
+	exportHandler := handlers.NewExportHandler(exports.NewStore(cfg.Export), logger)
+	logLevelHandler := handlers.NewLogLevelHandler(logLevel, logger)
+	lockoutHandler := handlers.NewLockoutHandler(loginGuard, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, logger)
+	usageService := services.NewUsageService(repository.NewUsageRepository(db.DB()), logger)
+	usageHandler := handlers.NewUsageHandler(usageService, logger)
+	accountService := services.NewAccountService(services.AccountRepositories{
+		Users:         repository.NewUserRepository(db.DB()),
+		APIKeys:       repository.NewAPIKeyRepository(db.DB()),
+		Hooks:         repository.NewHookRepository(db.DB()),
+		Notifications: repository.NewNotificationRepository(db.DB()),
+		Google:        repository.NewGoogleTasksRepository(db.DB()),
+		Audit:         repository.NewAuditRepository(db.DB()),
+		Usage:         repository.NewUsageRepository(db.DB()),
+	}, uow, auditService, logger)
+	accountHandler := handlers.NewAccountHandler(accountService, jobManager, cfg.Account.ExportSyncLimit, logger)
 
 	// Health endpoints (outside /api prefix for load balancers)
 	app.Get("/health", healthHandler.Health)
 	app.Get("/ready", healthHandler.Readiness)
 	app.Get("/live", healthHandler.Liveness)
 	app.Get("/stats", healthHandler.DatabaseStats)
+	app.Get("/status", healthHandler.Status)
+	app.Get("/version", healthHandler.Version)
+	if registry != nil {
+		app.Get("/metrics", handlers.NewMetricsHandler(registry).Metrics)
+	}
+
+	// Feed readers may authenticate with ?token=
+	app.Use("/api/feeds", middleware.QueryToken("token"))
+
+	// API routes. Failed authentication is limited per IP before the
+	// credentials are checked. Authenticated calls are metered, except on a
+	// standby, which cannot store the counts.
+	authFailures := middleware.LimitAuthFailures(store)
+	apiMiddleware := []fiber.Handler{authFailures, middleware.Authenticate(tokens, apiKeyService, userService), middleware.RateLimit(store)}
+	if !db.ReadOnly() {
+		apiMiddleware = append(apiMiddleware, middleware.MeterUsage(usageService))
+	}
+	api := app.Group("/api", apiMiddleware...)
+
+	// Auth routes
+	authRoutes := api.Group("/auth")
+	authRoutes.Post("/register", authHandler.Register)
+	authRoutes.Post("/login", authHandler.Login)
+	authRoutes.Get("/me", middleware.RequireAuth(), authHandler.Me)
+	authRoutes.Post("/token", middleware.RequireSession(), middleware.AllowAdminGrant(cfg), authHandler.IssueToken)
+	authRoutes.Get("/oidc/login", authHandler.OIDCLogin)
+	authRoutes.Get("/oidc/callback", authHandler.OIDCCallback)
+
+	// Account data routes. A leaked API key must not be able to download
+	// or erase everything about its owner.
+	me := api.Group("/me", middleware.RequireSession())
+	me.Delete("/", accountHandler.DeleteAccount)
+	me.Get("/export", accountHandler.ExportAccount)
+	me.Get("/export/:id", accountHandler.GetAccountExport)
+	me.Get("/plan", planHandler.GetPlan)
+	me.Get("/usage", usageHandler.GetUsage)
 
-	// API routes
-	api := app.Group("/api")
+	// API key routes
+	keys := api.Group("/keys", middleware.RequireSession())
+	keys.Get("/", apiKeyHandler.ListKeys)
+	keys.Post("/", middleware.RequireFeature(planService, models.FeatureAPIKeys), middleware.AllowAdminGrant(cfg), apiKeyHandler.CreateKey)
+	keys.Get("/:id", apiKeyHandler.GetKey)
+	keys.Delete("/:id", apiKeyHandler.DeleteKey)
 
-	// Todo routes
+	// Todo routes. Scopes narrow credentials; without AUTH_REQUIRED
+	// anonymous callers still have full access.
+	canRead := middleware.RequireScope(models.ScopeTodosRead, cfg.Auth.Required)
+	canWrite := middleware.RequireScope(models.ScopeTodosWrite, cfg.Auth.Required)
 	todos := api.Group("/todos")
-	todos.Get("/stats", todoHandler.GetTodoStats) // Must be before /:id route
-	todos.Get("/", todoHandler.GetTodos)
-	todos.Post("/", todoHandler.CreateTodo)
-	todos.Get("/:id", todoHandler.GetTodo)
-	todos.Put("/:id", todoHandler.UpdateTodo)
-	todos.Delete("/:id", todoHandler.DeleteTodo)
+	todos.Get("/stats", canRead, todoHandler.GetTodoStats) // Must be before /:id route
+	todos.Get("/stats/estimates", canRead, todoHandler.GetEstimateStats)
+	todos.Get("/count", canRead, todoHandler.GetTodoCount)
+	todos.Get("/search", canRead, todoHandler.SearchTodos)
+	todos.Get("/nearby", canRead, todoHandler.GetNearbyTodos)
+	todos.Get("/trash", canRead, trashHandler.GetTrash)
+	todos.Get("/export", canRead, todoHandler.ExportTodos)
+	todos.Get("/", canRead, todoHandler.GetTodos)
+	todos.Post("/", canWrite, todoHandler.CreateTodo)
+	todos.Post("/bulk", canWrite, todoHandler.CreateTodos)
+	todos.Post("/quick", canWrite, todoHandler.QuickAddTodo)
+	todos.Post("/import", canWrite, todoHandler.ImportTodos)
+	todos.Post("/tags", canWrite, todoHandler.BulkUpdateTags)
+	todos.Get("/:id", canRead, todoHandler.GetTodo)
+	todos.Put("/:id", canWrite, todoHandler.UpdateTodo)
+	todos.Delete("/:id", canWrite, todoHandler.DeleteTodo)
+	todos.Get("/:id/revisions", canRead, todoHandler.ListTodoRevisions)
+	todos.Post("/:id/revert/:revision", canWrite, todoHandler.RevertTodo)
+	todos.Post("/:id/restore", canWrite, trashHandler.RestoreTodo)
+	// Purging cannot be undone, so it takes admin access like the purge
+	// job, and is audited
+	todos.Delete("/:id/purge", middleware.RequireScope(models.ScopeAdmin, false), middleware.AdminAuth(cfg), middleware.AuditRequests(auditService), trashHandler.PurgeTodo)
+	todos.Get("/:id/notes", canRead, noteHandler.ListNotes)
+	todos.Post("/:id/notes", canWrite, noteHandler.CreateNote)
+	todos.Get("/:id/notes/:noteId", canRead, noteHandler.GetNote)
+	todos.Put("/:id/notes/:noteId", canWrite, noteHandler.UpdateNote)
+	todos.Delete("/:id/notes/:noteId", canWrite, noteHandler.DeleteNote)
+	todos.Get("/:id/links", canRead, linkHandler.ListLinks)
+	todos.Post("/:id/links", canWrite, linkHandler.CreateLink)
+	todos.Put("/:id/links/:linkId", canWrite, linkHandler.UpdateLink)
+	todos.Delete("/:id/links/:linkId", canWrite, linkHandler.DeleteLink)
+
+	// Imports from other apps
+	api.Post("/import/mstodo", canWrite, todoHandler.ImportMSTodo)
+
+	// Status of bulk operations run as jobs
+	api.Get("/jobs/:id", canRead, jobHandler.GetTodoJob)
+
+	// Activity feed
+	api.Get("/activity", canRead, activityHandler.ListActivity)
+	api.Get("/feeds/todos.atom", middleware.RequireBasicAuth(cfg.App.Name), canRead, feedHandler.TodoFeed)
+
+	// REST hooks. Hooks receive todos, so they need read access. Plans
+	// gate new hooks; existing ones keep working after a downgrade.
+	hooks := api.Group("/hooks", middleware.RequireAuth(), canRead)
+	hooks.Get("/", hookHandler.ListHooks)
+	hooks.Post("/", middleware.RequireFeature(planService, models.FeatureHooks), hookHandler.Subscribe)
+	hooks.Get("/samples/:event", hookHandler.Samples)
+	hooks.Delete("/:id", hookHandler.Unsubscribe)
+
+	// Notification routes
+	notifications := api.Group("/notifications", middleware.RequireAuth())
+	notifications.Get("/", notificationHandler.ListNotifications)
+	notifications.Delete("/", notificationHandler.ClearNotifications)
+	notifications.Post("/read", notificationHandler.MarkAllRead)
+	notifications.Post("/:id/read", notificationHandler.MarkRead)
+	notifications.Delete("/:id", notificationHandler.DeleteNotification)
 
-	// Swagger documentation (only in development)
-	if cfg.IsDevelopment() {
-		// Serve Swagger JSON spec
-		app.Get("/swagger/doc.json", func(c *fiber.Ctx) error {
-			c.Set("Content-Type", "application/json")
-			return c.SendFile("./docs/swagger.json")
-		})
+	// Google Tasks sync. Google redirects to the callback without
+	// credentials; the state identifies the user.
+	google := api.Group("/integrations/google", googleTasksHandler.Configured)
+	google.Get("/callback", googleTasksHandler.Callback)
+	google.Get("/", middleware.RequireAuth(), googleTasksHandler.GetStatus)
+	google.Post("/connect", middleware.RequireSession(), middleware.RequireFeature(planService, models.FeatureGoogleTasks), googleTasksHandler.Connect)
+	google.Post("/sync", middleware.RequireAuth(), canWrite, middleware.RequireFeature(planService, models.FeatureGoogleTasks), googleTasksHandler.Sync)
+	google.Delete("/", middleware.RequireSession(), googleTasksHandler.Disconnect)
 
-		// Serve Swagger UI
-		app.Get("/swagger/*", swagger.HandlerDefault)
+	// Tag routes
+	tags := api.Group("/tags")
+	tags.Get("/stats", canRead, todoHandler.GetTagStats)
+
+	// Saved search routes
+	searches := api.Group("/saved-searches")
+	searches.Get("/", canRead, savedSearchHandler.ListSavedSearches)
+	searches.Post("/", canWrite, savedSearchHandler.CreateSavedSearch)
+	searches.Get("/:id", canRead, savedSearchHandler.GetSavedSearch)
+	searches.Put("/:id", canWrite, savedSearchHandler.UpdateSavedSearch)
+	searches.Delete("/:id", canWrite, savedSearchHandler.DeleteSavedSearch)
+	searches.Get("/:id/todos", canRead, savedSearchHandler.GetSavedSearchTodos)
+
+	// Admin routes
+	admin := api.Group("/admin", middleware.AdminAuth(cfg), middleware.AuditRequests(auditService))
+	admin.Get("/jobs", jobHandler.ListJobs)
+	admin.Get("/jobs/:id", jobHandler.GetJob)
+	admin.Post("/jobs/:id/retry", jobHandler.RetryJob)
+	admin.Post("/backup", backupHandler.Backup)
+	admin.Post("/restore", backupHandler.Restore)
+	admin.Get("/exports", exportHandler.ListExports)
+	admin.Get("/log-level", logLevelHandler.GetLevel)
+	admin.Put("/log-level", logLevelHandler.SetLevel)
+	admin.Get("/lockouts", lockoutHandler.ListLockouts)
+	admin.Post("/lockouts/unlock", lockoutHandler.Unlock)
+	admin.Get("/audit", auditHandler.ExportAudit)
+	admin.Get("/plans", planHandler.ListPlans)
+	admin.Put("/users/:id/plan", planHandler.SetPlan)
+	admin.Get("/usage", usageHandler.GetUsageReport)
+
+	// CalDAV tasks collection. Clients send an API key as the Basic auth
+	// password.
+	app.Get("/.well-known/caldav", func(c *fiber.Ctx) error {
+		return c.Redirect("/dav/", fiber.StatusMovedPermanently)
+	})
+	dav := app.Group("/dav", authFailures, middleware.Authenticate(tokens, apiKeyService, userService), middleware.RateLimit(store), middleware.MeterUsage(usageService), middleware.RequireBasicAuth(cfg.App.Name))
+	dav.Options("/*", calDAVHandler.Options)
+	dav.Add("PROPFIND", "/", canRead, calDAVHandler.PropfindRoot)
+	dav.Add("PROPFIND", "/tasks", canRead, calDAVHandler.PropfindCollection)
+	dav.Add("PROPFIND", "/tasks/:name", canRead, calDAVHandler.PropfindObject)
+	dav.Add("REPORT", "/tasks", canRead, calDAVHandler.Report)
+	dav.Get("/tasks/:name", canRead, calDAVHandler.GetObject)
+	dav.Put("/tasks/:name", canWrite, calDAVHandler.PutObject)
+	dav.Delete("/tasks/:name", canWrite, calDAVHandler.DeleteObject)
+
+	// API documentation, generated from the handler annotations compiled
+	// into the docs package. Outside development it needs an API key,
+	// sent as the Basic password so browsers prompt for it.
+	if cfg.Docs.Enabled {
+		spec, err := openapi.Convert([]byte(docs.SwaggerInfo.ReadDoc()))
+		if err != nil {
+			logger.Error("Failed to generate OpenAPI document", "error", err)
+		} else {
+			swaggerRoutes := app.Group("/swagger")
+			if !cfg.IsDevelopment() {
+				swaggerRoutes.Use(middleware.Authenticate(tokens, apiKeyService, userService), middleware.RequireBasicAuth(cfg.App.Name))
+			}
+			swaggerRoutes.Get("/doc.json", func(c *fiber.Ctx) error {
+				c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				return c.Send(spec)
+			})
+			swaggerRoutes.Get("/*", swagger.HandlerDefault)
+		}
 	}
 
 	// 404 handler
 	app.Use("*", middleware.NotFoundHandler)
+
+	return todoService
+}
+
+// jwtSecret returns the configured signing secret, or a random one when
+// none is configured (tokens then do not survive a restart)
+func jwtSecret(cfg *config.Config, logger *slog.Logger) string {
+	if cfg.Auth.JWTSecret != "" {
+		return cfg.Auth.JWTSecret
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic("failed to generate JWT secret: " + err.Error())
+	}
+
+	logger.Warn("JWT_SECRET is not set, using a random secret; issued tokens will not survive a restart")
+	return hex.EncodeToString(buf)
 }
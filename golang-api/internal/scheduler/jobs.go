@@ -0,0 +1,16 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/centroidsol/todo-api/internal/jobs"
+)
+
+// EnqueueJob returns a task that enqueues a background job on every tick,
+// so that scheduled work gets the job subsystem's persistence and retries
+func EnqueueJob(manager *jobs.Manager, jobType string, payload interface{}) TaskFunc {
+	return func(ctx context.Context) error {
+		_, err := manager.Enqueue(jobType, payload)
+		return err
+	}
+}
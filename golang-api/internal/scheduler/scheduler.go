@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TaskFunc is the unit of work executed by the scheduler on every tick
+type TaskFunc func(ctx context.Context) error
+
+type task struct {
+	name     string
+	interval time.Duration
+	run      TaskFunc
+}
+
+// Scheduler runs registered tasks at fixed intervals inside the server process
+type Scheduler struct {
+	logger *slog.Logger
+	tasks  []task
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+func New(logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+	}
+}
+
+// Every registers a task to be run once per interval. Tasks must be
+// registered before Start is called.
+func (s *Scheduler) Every(name string, interval time.Duration, run TaskFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		s.logger.Warn("Ignoring task registered after scheduler start", "task", name)
+		return
+	}
+
+	if interval <= 0 {
+		s.logger.Warn("Ignoring task with non-positive interval", "task", name, "interval", interval.String())
+		return
+	}
+
+	s.tasks = append(s.tasks, task{name: name, interval: interval, run: run})
+}
+
+// Start launches one goroutine per registered task
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.loop(ctx, t)
+	}
+
+	s.logger.Info("Scheduler started", "tasks", len(s.tasks))
+}
+
+// Stop cancels all tasks and waits for any in-flight run to finish. The
+// tasks can be started again afterwards.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started || s.cancel == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.cancel = nil
+	s.started = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+// Pause stops the tasks like Stop and returns a function that starts them
+// again, if they were running
+func (s *Scheduler) Pause() (resume func()) {
+	s.mu.Lock()
+	running := s.started
+	s.mu.Unlock()
+	if !running {
+		return func() {}
+	}
+
+	s.Stop()
+	return s.Start
+}
+
+func (s *Scheduler) loop(ctx context.Context, t task) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Scheduled task registered", "task", t.name, "interval", t.interval.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, t)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, t task) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Scheduled task panicked", "task", t.name, "panic", r)
+		}
+	}()
+
+	start := time.Now()
+	if err := t.run(ctx); err != nil {
+		s.logger.Error("Scheduled task failed", "task", t.name, "error", err, "duration", time.Since(start).String())
+		return
+	}
+
+	s.logger.Debug("Scheduled task completed", "task", t.name, "duration", time.Since(start).String())
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrUserNotFound is returned when the account no longer exists
+var ErrUserNotFound = errors.New("user not found")
+
+// exportPageSize is the number of notifications read at a time for an
+// export
+const exportPageSize = 500
+
+// AccountService gives users access to the data stored about them, and
+// lets them erase it
+type AccountService interface {
+	// Export collects everything stored about the user
+	Export(ctx context.Context, userID int) (*models.AccountExport, error)
+	// ExportSize counts the records an export of the user would hold, to
+	// tell whether it should run as a background job
+	ExportSize(ctx context.Context, userID int) (int, error)
+	// Delete erases the user in one transaction: everything they own is
+	// deleted, shared notes and events that mention their email name a
+	// placeholder instead, and a tombstone records the deletion
+	Delete(ctx context.Context, userID int) (*models.Tombstone, error)
+}
+
+// AccountRepositories are the stores holding data about a user
+type AccountRepositories struct {
+	Users         repository.UserRepository
+	APIKeys       repository.APIKeyRepository
+	Hooks         repository.HookRepository
+	Notifications repository.NotificationRepository
+	Google        repository.GoogleTasksRepository
+	Audit         repository.AuditRepository
+	Usage         repository.UsageRepository
+}
+
+type accountService struct {
+	repos  AccountRepositories
+	uow    repository.UnitOfWork
+	audit  AuditService
+	logger *slog.Logger
+}
+
+func NewAccountService(repos AccountRepositories, uow repository.UnitOfWork, audit AuditService, logger *slog.Logger) AccountService {
+	return &accountService{
+		repos:  repos,
+		uow:    uow,
+		audit:  audit,
+		logger: logger,
+	}
+}
+
+func (s *accountService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *accountService) user(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.repos.Users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *accountService) Export(ctx context.Context, userID int) (*models.AccountExport, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.AccountExport{ExportedAt: time.Now().UTC(), User: user}
+
+	if export.Identities, err = s.repos.Users.ListIdentities(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.APIKeys, err = s.repos.APIKeys.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Hooks, err = s.repos.Hooks.List(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Notifications, err = s.notifications(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.GoogleTasks, export.GoogleTaskLinks, err = s.google(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.AuditLog, err = s.repos.Audit.ListByUser(ctx, userID, user.Email); err != nil {
+		return nil, err
+	}
+	if export.Usage, err = s.repos.Usage.ListByUser(ctx, userID, "", ""); err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Info("Exported account", "user_id", userID, "notifications", len(export.Notifications), "audit_entries", len(export.AuditLog))
+	return export, nil
+}
+
+func (s *accountService) notifications(ctx context.Context, userID int) ([]models.Notification, error) {
+	all := make([]models.Notification, 0)
+	for {
+		page, total, err := s.repos.Notifications.List(ctx, userID, false, exportPageSize, len(all))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) == 0 || len(all) >= total {
+			return all, nil
+		}
+	}
+}
+
+// google returns the user's Google Tasks connection, without its tokens,
+// and the todos it links to tasks
+func (s *accountService) google(ctx context.Context, userID int) (*models.GoogleSyncStatus, []models.GoogleTaskLink, error) {
+	status := &models.GoogleSyncStatus{}
+	links := make([]models.GoogleTaskLink, 0)
+
+	account, err := s.repos.Google.Get(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account == nil {
+		return status, links, nil
+	}
+
+	byTodo, err := s.repos.Google.Links(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, link := range byTodo {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].TodoID < links[j].TodoID })
+
+	status.Connected = account.Connected()
+	status.ConnectedAt = account.ConnectedAt
+	status.LastSyncedAt = account.LastSyncedAt
+	status.LastError = account.LastError
+	status.LastResult = account.LastResult
+	status.LinkedTodos = len(links)
+	return status, links, nil
+}
+
+func (s *accountService) ExportSize(ctx context.Context, userID int) (int, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Notifications, audit entries, task links and usage grow with use;
+	// the other records are few
+	_, notifications, err := s.repos.Notifications.List(ctx, userID, false, 1, 0)
+	if err != nil {
+		return 0, err
+	}
+	audit, err := s.repos.Audit.CountByUser(ctx, userID, user.Email)
+	if err != nil {
+		return 0, err
+	}
+	links, err := s.repos.Google.CountLinks(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	usage, err := s.repos.Usage.CountByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return notifications + audit + links + usage, nil
+}
+
+func (s *accountService) Delete(ctx context.Context, userID int) (*models.Tombstone, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	label := fmt.Sprintf("deleted-user-%d", userID)
+	var tombstone *models.Tombstone
+	err = s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		tombstone, err = tx.Accounts.Erase(ctx, user, label)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	s.log(ctx).Info("Deleted account", "user_id", userID)
+	// Without the IP, which the erasure just removed from the audit log
+	s.audit.Record(ctx, models.AuditEntry{Action: models.AuditAccountDeleted, ActorID: &userID, Target: label})
+	return tombstone, nil
+}
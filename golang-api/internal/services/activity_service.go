@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+type ActivityService interface {
+	ListActivity(ctx context.Context, params models.ActivityQueryParams) (*models.PaginatedResponse, error)
+}
+
+type activityService struct {
+	repo   repository.ActivityRepository
+	logger *slog.Logger
+}
+
+// NewActivityService returns the service behind the activity feed
+func NewActivityService(repo repository.ActivityRepository, logger *slog.Logger) ActivityService {
+	return &activityService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *activityService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// ListActivity returns a page of the activity feed, newest first. Filters
+// that cannot match, such as an unknown event type, are rejected with
+// ErrInvalidFilter.
+func (s *activityService) ListActivity(ctx context.Context, params models.ActivityQueryParams) (*models.PaginatedResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PerPage < 1 || params.PerPage > 100 {
+		params.PerPage = 20
+	}
+
+	for _, eventType := range params.Types {
+		if !slices.Contains(events.Types, eventType) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidFilter, eventType)
+		}
+	}
+	if params.Since != nil && params.Until != nil && !params.Since.Before(*params.Until) {
+		return nil, fmt.Errorf("%w: since must be before until", ErrInvalidFilter)
+	}
+
+	activity, total, err := s.repo.List(ctx, params)
+	if err != nil {
+		s.log(ctx).Error("Failed to list activity", "error", err)
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Data:       activity,
+		Total:      total,
+		Page:       params.Page,
+		PerPage:    params.PerPage,
+		TotalPages: (total + params.PerPage - 1) / params.PerPage,
+		HasMore:    params.Page*params.PerPage < total,
+	}, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/auth"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for keys that do not exist or belong to another user
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned for unknown and expired keys alike
+	ErrInvalidAPIKey = errors.New("invalid or expired API key")
+)
+
+type APIKeyService interface {
+	CreateKey(ctx context.Context, userID int, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error)
+	ListKeys(ctx context.Context, userID int) ([]models.APIKey, error)
+	GetKey(ctx context.Context, userID, id int) (*models.APIKey, error)
+	DeleteKey(ctx context.Context, userID, id int) error
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	repo   repository.APIKeyRepository
+	plans  PlanService
+	logger *slog.Logger
+}
+
+// NewAPIKeyService creates the API key service. plans limits the keys
+// each user can create.
+func NewAPIKeyService(repo repository.APIKeyRepository, plans PlanService, logger *slog.Logger) APIKeyService {
+	return &apiKeyService{
+		repo:   repo,
+		plans:  plans,
+		logger: logger,
+	}
+}
+
+func (s *apiKeyService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *apiKeyService) CreateKey(ctx context.Context, userID int, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	if err := s.validateCreateRequest(&req); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	secret, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		s.log(ctx).Error("Failed to create API key", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.log(ctx).Info("Created API key", "id", key.ID, "user_id", userID, "scopes", key.Scopes)
+	return &models.CreateAPIKeyResponse{APIKey: *key, Key: secret}, nil
+}
+
+// checkLimit returns ErrPlanLimit when the user has as many unexpired keys
+// as their plan allows
+func (s *apiKeyService) checkLimit(ctx context.Context, userID int) error {
+	plan, err := s.plans.ForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxAPIKeys == 0 {
+		return nil
+	}
+
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	now := time.Now()
+	active := 0
+	for _, key := range keys {
+		if !key.Expired(now) {
+			active++
+		}
+	}
+	return checkPlanLimit(plan, plan.MaxAPIKeys, active, "API keys")
+}
+
+func (s *apiKeyService) ListKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) GetKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	key, err := s.repo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if key == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *apiKeyService) DeleteKey(ctx context.Context, userID, id int) error {
+	if _, err := s.GetKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, userID, id); err != nil {
+		s.log(ctx).Error("Failed to delete API key", "id", id, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Deleted API key", "id", id, "user_id", userID)
+	return nil
+}
+
+// Authenticate resolves an API key secret to the stored key and records
+// its use
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	key, err := s.repo.GetByHash(ctx, auth.HashAPIKey(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	now := time.Now()
+	if key == nil || key.Expired(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Usage tracking is informational; failing it must not reject the request
+	if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		s.log(ctx).Warn("Failed to record API key usage", "id", key.ID, "error", err)
+	}
+
+	return key, nil
+}
+
+func (s *apiKeyService) validateCreateRequest(req *models.CreateAPIKeyRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name cannot exceed 100 characters")
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return err
+	}
+	req.Scopes = scopes
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+// normalizeScopes validates requested scopes and drops duplicates
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !isValidScope(scope) {
+			return nil, fmt.Errorf("unknown scope %q, must be one of: %s", scope, strings.Join(models.Scopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func isValidScope(scope string) bool {
+	for _, s := range models.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
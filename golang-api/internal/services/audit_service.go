@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrInvalidAuditRange is returned when an audit query ends before it
+// starts
+var ErrInvalidAuditRange = errors.New("from must be before to")
+
+// AuditService records security events in the audit log
+type AuditService interface {
+	// Record appends an entry. A failure is logged rather than returned,
+	// so auditing never fails the action it records.
+	Record(ctx context.Context, entry models.AuditEntry)
+	// Stream passes the entries matching query to fn, oldest first
+	Stream(ctx context.Context, query models.AuditQuery, fn func(models.AuditEntry) error) error
+}
+
+type auditService struct {
+	repo   repository.AuditRepository
+	logger *slog.Logger
+}
+
+func NewAuditService(repo repository.AuditRepository, logger *slog.Logger) AuditService {
+	return &auditService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *auditService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *auditService) Record(ctx context.Context, entry models.AuditEntry) {
+	if err := s.repo.Record(ctx, &entry); err != nil {
+		s.log(ctx).Error("Failed to record audit entry", "action", entry.Action, "target", entry.Target, "error", err)
+	}
+}
+
+// ValidateAuditQuery checks a query before an export starts streaming
+func ValidateAuditQuery(query models.AuditQuery) error {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return ErrInvalidAuditRange
+	}
+	return nil
+}
+
+func (s *auditService) Stream(ctx context.Context, query models.AuditQuery, fn func(models.AuditEntry) error) error {
+	if err := ValidateAuditQuery(query); err != nil {
+		return err
+	}
+	return s.repo.Stream(ctx, query, fn)
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/caldav"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+var (
+	// ErrPreconditionFailed is returned when If-Match or If-None-Match
+	// does not hold
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUIDConflict is returned when a task's UID belongs to another
+	// task, or an update changes the UID
+	ErrUIDConflict = errors.New("UID is used by another task")
+	// ErrReservedName is returned when a client creates a task under a
+	// name of the todo-<id>.ics form used for todos created elsewhere
+	ErrReservedName = errors.New("resource names starting with todo- are reserved")
+)
+
+// defaultPrefix starts the names and UIDs of todos not created over
+// CalDAV
+const defaultPrefix = "todo-"
+
+type CalDAVService interface {
+	ListObjects(ctx context.Context) ([]models.CalDAVObject, error)
+	GetObject(ctx context.Context, name string) (*models.CalDAVObject, error)
+	PutObject(ctx context.Context, name string, data []byte, ifMatch, ifNoneMatch string) (*models.CalDAVObject, bool, error)
+	DeleteObject(ctx context.Context, name, ifMatch string) error
+	CTag(ctx context.Context) (string, error)
+}
+
+type calDAVService struct {
+	repo   repository.CalDAVRepository
+	todos  TodoService
+	logger *slog.Logger
+}
+
+// NewCalDAVService returns the service behind the CalDAV tasks
+// collection. Changes go through the todo service, so they are validated,
+// versioned and emit events like changes made through the REST API.
+func NewCalDAVService(repo repository.CalDAVRepository, todos TodoService, logger *slog.Logger) CalDAVService {
+	return &calDAVService{
+		repo:   repo,
+		todos:  todos,
+		logger: logger,
+	}
+}
+
+func (s *calDAVService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// ListObjects returns every todo outside the trash as a CalDAV object
+func (s *calDAVService) ListObjects(ctx context.Context) ([]models.CalDAVObject, error) {
+	named, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]models.CalDAVObject, 0)
+	err = s.todos.StreamTodos(ctx, models.DefaultQueryParams(), func(todo models.Todo) error {
+		obj, ok := named[todo.ID]
+		if !ok {
+			obj = defaultObject(todo.ID)
+		}
+		obj.Todo = &todo
+		objects = append(objects, obj)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetObject returns the object with the given resource name, or
+// ErrTodoNotFound
+func (s *calDAVService) GetObject(ctx context.Context, name string) (*models.CalDAVObject, error) {
+	obj, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if obj == nil {
+		id, ok := defaultID(name)
+		if !ok {
+			return nil, ErrTodoNotFound
+		}
+		// Todos created over CalDAV are only found under their own name
+		named, err := s.repo.GetByTodoID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if named != nil {
+			return nil, ErrTodoNotFound
+		}
+		def := defaultObject(id)
+		obj = &def
+	}
+
+	todo, err := s.todos.GetTodoByID(ctx, obj.TodoID)
+	if err != nil {
+		return nil, err
+	}
+	if todo == nil {
+		return nil, ErrTodoNotFound
+	}
+
+	obj.Todo = todo
+	return obj, nil
+}
+
+// PutObject creates or replaces the todo stored under name from an
+// iCalendar body, reporting whether it was created. ifMatch and
+// ifNoneMatch are the request's conditional headers.
+func (s *calDAVService) PutObject(ctx context.Context, name string, data []byte, ifMatch, ifNoneMatch string) (*models.CalDAVObject, bool, error) {
+	task, err := caldav.Decode(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := s.GetObject(ctx, name)
+	if err != nil && !errors.Is(err, ErrTodoNotFound) {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		if ifMatch != "" {
+			return nil, false, ErrPreconditionFailed
+		}
+		obj, err := s.createObject(ctx, name, task)
+		return obj, err == nil, err
+	}
+
+	if ifNoneMatch == "*" {
+		return nil, false, ErrPreconditionFailed
+	}
+	if task.UID != existing.UID {
+		return nil, false, ErrUIDConflict
+	}
+
+	req := updateRequest(task, existing.Todo)
+	if ifMatch != "" && ifMatch != "*" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+		if err != nil {
+			return nil, false, ErrPreconditionFailed
+		}
+		req.Version = &version
+	}
+
+	todo, err := s.todos.UpdateTodo(ctx, existing.TodoID, req)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return nil, false, ErrPreconditionFailed
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if todo == nil {
+		return nil, false, ErrTodoNotFound
+	}
+
+	existing.Todo = todo
+	return existing, false, nil
+}
+
+func (s *calDAVService) createObject(ctx context.Context, name string, task *caldav.Task) (*models.CalDAVObject, error) {
+	if strings.HasPrefix(name, defaultPrefix) {
+		return nil, ErrReservedName
+	}
+	if strings.HasPrefix(task.UID, defaultPrefix) {
+		return nil, ErrUIDConflict
+	}
+	taken, err := s.repo.GetByUID(ctx, task.UID)
+	if err != nil {
+		return nil, err
+	}
+	if taken != nil {
+		return nil, ErrUIDConflict
+	}
+
+	todo, _, err := s.todos.CreateTodo(ctx, createRequest(task))
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &models.CalDAVObject{TodoID: todo.ID, Name: name, UID: task.UID, Todo: todo}
+	if err := s.repo.Create(ctx, obj); err != nil {
+		s.log(ctx).Error("Failed to store CalDAV object name", "todo_id", todo.ID, "name", name, "error", err)
+		return nil, err
+	}
+
+	s.log(ctx).Info("Created todo over CalDAV", "id", todo.ID, "name", name)
+	return obj, nil
+}
+
+// DeleteObject moves the todo stored under name to the trash
+func (s *calDAVService) DeleteObject(ctx context.Context, name, ifMatch string) error {
+	obj, err := s.GetObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if ifMatch != "" && ifMatch != "*" && ifMatch != obj.ETag() {
+		return ErrPreconditionFailed
+	}
+
+	return s.todos.DeleteTodo(ctx, obj.TodoID)
+}
+
+// CTag returns the collection's entity tag, which changes with any of
+// its todos
+func (s *calDAVService) CTag(ctx context.Context) (string, error) {
+	id, err := s.repo.CTag(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+func defaultObject(todoID int) models.CalDAVObject {
+	uid := defaultPrefix + strconv.Itoa(todoID)
+	return models.CalDAVObject{TodoID: todoID, Name: uid + ".ics", UID: uid}
+}
+
+// defaultID parses a todo-<id>.ics name
+func defaultID(name string) (int, bool) {
+	rest, ok := strings.CutPrefix(name, defaultPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(rest, ".ics"))
+	return id, err == nil && id > 0
+}
+
+func createRequest(task *caldav.Task) models.CreateTodoRequest {
+	req := models.CreateTodoRequest{
+		Title:     task.Summary,
+		Completed: task.Done(),
+		Tags:      task.Categories,
+	}
+	if task.Description != "" {
+		req.Description = &task.Description
+	}
+	if !task.Done() && task.Status == caldav.StatusInProcess {
+		status := models.StatusInProgress
+		req.Status = &status
+	}
+	if priority := task.TodoPriority(); priority != "" {
+		req.Priority = &priority
+	}
+	if task.Due != nil {
+		due := task.Due.Format(time.RFC3339)
+		req.DueDate = &due
+	}
+	return req
+}
+
+// updateRequest replaces every field a VTODO carries. Blocked todos stay
+// blocked while the task is not started, since VTODO has no such status.
+func updateRequest(task *caldav.Task, current *models.Todo) models.UpdateTodoRequest {
+	done := task.Done()
+	priority := task.TodoPriority()
+	due := ""
+	if task.Due != nil {
+		due = task.Due.Format(time.RFC3339)
+	}
+	tags := task.Categories
+	if tags == nil {
+		tags = []string{}
+	}
+
+	req := models.UpdateTodoRequest{
+		Title:       &task.Summary,
+		Description: &task.Description,
+		Completed:   &done,
+		Priority:    &priority,
+		DueDate:     &due,
+		Tags:        &tags,
+	}
+
+	if !done {
+		status := models.StatusTodo
+		switch {
+		case task.Status == caldav.StatusInProcess:
+			status = models.StatusInProgress
+		case current.Status == models.StatusBlocked:
+			status = models.StatusBlocked
+		}
+		if status != current.Status {
+			req.Status = &status
+		}
+	}
+	return req
+}
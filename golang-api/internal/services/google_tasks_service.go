@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/googletasks"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrGoogleNotLinked is returned when the user has no linked Google
+	// account
+	ErrGoogleNotLinked = errors.New("no Google account is linked")
+	// ErrInvalidOAuthState is returned for OAuth callbacks that do not
+	// match a pending link
+	ErrInvalidOAuthState = errors.New("invalid or expired OAuth state")
+)
+
+// oauthStateTTL is how long the user has to grant access on Google's
+// consent page
+const oauthStateTTL = 10 * time.Minute
+
+// googleSyncMu serializes syncs, so a manual sync never interleaves with a
+// scheduled one and duplicates tasks. It is shared because the API and the
+// job runner each create a service.
+var googleSyncMu sync.Mutex
+
+type GoogleTasksService interface {
+	Connect(ctx context.Context, userID int) (string, error)
+	CompleteConnect(ctx context.Context, state, code string) (*models.GoogleSyncStatus, error)
+	Status(ctx context.Context, userID int) (*models.GoogleSyncStatus, error)
+	Disconnect(ctx context.Context, userID int) error
+	Sync(ctx context.Context, userID int) (*models.GoogleSyncResult, error)
+	SyncAll(ctx context.Context) (synced, failed int, err error)
+}
+
+type googleTasksService struct {
+	repo   repository.GoogleTasksRepository
+	todos  TodoService
+	client *googletasks.Client
+	logger *slog.Logger
+}
+
+// NewGoogleTasksService returns the service that links Google accounts
+// and syncs todos with each linked user's default task list. Changes go
+// through the todo service, so they are validated, versioned and emit
+// events like changes made through the REST API.
+func NewGoogleTasksService(repo repository.GoogleTasksRepository, todos TodoService, client *googletasks.Client, logger *slog.Logger) GoogleTasksService {
+	return &googleTasksService{
+		repo:   repo,
+		todos:  todos,
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *googleTasksService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// Connect starts linking the user's Google account and returns the
+// consent page to send them to
+func (s *googleTasksService) Connect(ctx context.Context, userID int) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	if err := s.repo.SetState(ctx, userID, state, time.Now().Add(oauthStateTTL)); err != nil {
+		return "", err
+	}
+
+	return s.client.AuthCodeURL(state), nil
+}
+
+// CompleteConnect handles Google's redirect back: it finds the user by
+// state and stores the tokens granted for the code
+func (s *googleTasksService) CompleteConnect(ctx context.Context, state, code string) (*models.GoogleSyncStatus, error) {
+	account, err := s.repo.GetByState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.StateExpiresAt == nil || time.Now().After(*account.StateExpiresAt) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	token, err := s.client.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Google only returns a refresh token when consent is given; keep the
+	// previous one when relinking without it
+	refreshToken := token.RefreshToken
+	if refreshToken == "" && account.RefreshToken != nil {
+		refreshToken = *account.RefreshToken
+	}
+	if refreshToken == "" {
+		return nil, errors.New("google did not grant offline access")
+	}
+
+	if err := s.repo.Connect(ctx, account.UserID, token.AccessToken, refreshToken, token.Expiry); err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Info("Linked Google account", "user_id", account.UserID)
+	return s.Status(ctx, account.UserID)
+}
+
+// Status returns the user's link and the outcome of the last sync
+func (s *googleTasksService) Status(ctx context.Context, userID int) (*models.GoogleSyncStatus, error) {
+	account, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.Connected() {
+		return &models.GoogleSyncStatus{}, nil
+	}
+
+	linked, err := s.repo.CountLinks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GoogleSyncStatus{
+		Connected:    true,
+		ConnectedAt:  account.ConnectedAt,
+		LastSyncedAt: account.LastSyncedAt,
+		LastError:    account.LastError,
+		LastResult:   account.LastResult,
+		LinkedTodos:  linked,
+	}, nil
+}
+
+// Disconnect unlinks the user's account. Synced tasks are left in Google
+// Tasks and todos are kept.
+func (s *googleTasksService) Disconnect(ctx context.Context, userID int) error {
+	googleSyncMu.Lock()
+	defer googleSyncMu.Unlock()
+
+	deleted, err := s.repo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrGoogleNotLinked
+	}
+
+	s.log(ctx).Info("Unlinked Google account", "user_id", userID)
+	return nil
+}
+
+// SyncAll syncs every linked account. A failing account does not stop the
+// others; its error is recorded in its status.
+func (s *googleTasksService) SyncAll(ctx context.Context) (int, int, error) {
+	userIDs, err := s.repo.ListConnected(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	synced, failed := 0, 0
+	for _, userID := range userIDs {
+		if _, err := s.Sync(ctx, userID); err != nil {
+			failed++
+			continue
+		}
+		synced++
+	}
+
+	return synced, failed, nil
+}
+
+// Sync brings the user's default task list and the todos in line. Todos
+// and tasks are paired on their first sync by title, and otherwise
+// created on the other side. For a paired todo and task, the side that
+// changed since the last sync wins; when both did, the one modified last
+// wins, and the todo on a tie. Moving a todo to the trash deletes its
+// task, and deleting a task moves its todo to the trash.
+func (s *googleTasksService) Sync(ctx context.Context, userID int) (*models.GoogleSyncResult, error) {
+	googleSyncMu.Lock()
+	defer googleSyncMu.Unlock()
+
+	account, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.Connected() {
+		return nil, ErrGoogleNotLinked
+	}
+
+	token := &oauth2.Token{RefreshToken: *account.RefreshToken}
+	if account.AccessToken != nil && account.TokenExpiresAt != nil {
+		token.AccessToken = *account.AccessToken
+		token.Expiry = *account.TokenExpiresAt
+	}
+	session := s.client.Session(ctx, token)
+
+	result := &models.GoogleSyncResult{}
+	syncErr := s.sync(ctx, userID, session, result)
+
+	// Keep a refreshed access token even when the sync failed part way
+	if current, err := session.Token(); err == nil && current.AccessToken != token.AccessToken {
+		if err := s.repo.UpdateToken(ctx, userID, current.AccessToken, current.Expiry); err != nil {
+			s.log(ctx).Error("Failed to store refreshed Google token", "user_id", userID, "error", err)
+		}
+	}
+
+	message := ""
+	if syncErr != nil {
+		message = syncErr.Error()
+	}
+	if err := s.repo.RecordSync(ctx, userID, result, message); err != nil {
+		return nil, err
+	}
+
+	if syncErr != nil {
+		s.log(ctx).Error("Google Tasks sync failed", "user_id", userID, "error", syncErr)
+		return result, syncErr
+	}
+
+	s.log(ctx).Info("Synced Google Tasks", "user_id", userID,
+		"todos_created", result.TodosCreated, "todos_updated", result.TodosUpdated, "todos_deleted", result.TodosDeleted,
+		"tasks_created", result.TasksCreated, "tasks_updated", result.TasksUpdated, "tasks_deleted", result.TasksDeleted,
+		"conflicts", result.Conflicts, "skipped", result.Skipped)
+	return result, nil
+}
+
+func (s *googleTasksService) sync(ctx context.Context, userID int, session *googletasks.Session, result *models.GoogleSyncResult) error {
+	tasks, err := session.ListTasks(ctx, googletasks.DefaultList)
+	if err != nil {
+		return err
+	}
+	taskByID := make(map[string]*googletasks.Task, len(tasks))
+	for i := range tasks {
+		taskByID[tasks[i].ID] = &tasks[i]
+	}
+
+	links, err := s.repo.Links(ctx, userID)
+	if err != nil {
+		return err
+	}
+	linkedTasks := make(map[string]bool, len(links))
+	for _, link := range links {
+		linkedTasks[link.TaskID] = true
+	}
+
+	todos := make(map[int]*models.Todo)
+	err = s.todos.StreamTodos(ctx, models.DefaultQueryParams(), func(todo models.Todo) error {
+		todos[todo.ID] = &todo
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	// Pair new tasks with new todos of the same title rather than
+	// duplicating both. The pair has no sync state yet, so the side
+	// modified last wins.
+	byTitle := make(map[string]*models.Todo)
+	for id, todo := range todos {
+		if _, linked := links[id]; !linked {
+			byTitle[strings.ToLower(todo.Title)] = todo
+		}
+	}
+	for _, task := range tasks {
+		if linkedTasks[task.ID] || task.Deleted {
+			continue
+		}
+		if todo, ok := byTitle[strings.ToLower(task.Title)]; ok {
+			links[todo.ID] = models.GoogleTaskLink{TodoID: todo.ID, TaskID: task.ID, TodoVersion: -1}
+			linkedTasks[task.ID] = true
+			delete(byTitle, strings.ToLower(task.Title))
+		}
+	}
+
+	for _, todoID := range sortedIDs(links) {
+		link := links[todoID]
+		todo := todos[todoID]
+		task := taskByID[link.TaskID]
+
+		switch {
+		case todo == nil:
+			if task != nil && !task.Deleted {
+				if err := session.DeleteTask(ctx, googletasks.DefaultList, task.ID); err != nil {
+					return err
+				}
+				result.TasksDeleted++
+			}
+			if err := s.repo.DeleteLink(ctx, userID, todoID); err != nil {
+				return err
+			}
+		case task == nil || task.Deleted:
+			if err := s.todos.DeleteTodo(ctx, todoID); err != nil {
+				return err
+			}
+			result.TodosDeleted++
+			if err := s.repo.DeleteLink(ctx, userID, todoID); err != nil {
+				return err
+			}
+		default:
+			if err := s.reconcile(ctx, userID, session, link, todo, task, result); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, todoID := range sortedIDs(todos) {
+		if _, linked := links[todoID]; linked {
+			continue
+		}
+		todo := todos[todoID]
+		created, err := session.InsertTask(ctx, googletasks.DefaultList, taskFromTodo(todo))
+		if err != nil {
+			return err
+		}
+		result.TasksCreated++
+		if err := s.repo.SaveLink(ctx, userID, models.GoogleTaskLink{TodoID: todo.ID, TaskID: created.ID, TodoVersion: todo.Version, TaskUpdated: created.Updated}); err != nil {
+			return err
+		}
+	}
+
+	for _, task := range tasks {
+		// Hidden tasks are completed ones the user cleared
+		if linkedTasks[task.ID] || task.Deleted || task.Hidden {
+			continue
+		}
+		todo, _, err := s.todos.CreateTodo(ctx, createRequestFromTask(&task))
+		if err != nil {
+			s.log(ctx).Warn("Skipped Google task", "user_id", userID, "task_id", task.ID, "error", err)
+			result.Skipped++
+			continue
+		}
+		result.TodosCreated++
+		if err := s.repo.SaveLink(ctx, userID, models.GoogleTaskLink{TodoID: todo.ID, TaskID: task.ID, TodoVersion: todo.Version, TaskUpdated: task.Updated}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcile syncs a paired todo and task
+func (s *googleTasksService) reconcile(ctx context.Context, userID int, session *googletasks.Session, link models.GoogleTaskLink, todo *models.Todo, task *googletasks.Task, result *models.GoogleSyncResult) error {
+	localChanged := todo.Version != link.TodoVersion
+	remoteChanged := task.Updated != link.TaskUpdated
+
+	if (localChanged || remoteChanged) && !sameTask(taskFromTodo(todo), task) {
+		pushLocal := localChanged
+		if localChanged && remoteChanged {
+			result.Conflicts++
+			pushLocal = !task.UpdatedAt().After(todo.UpdatedAt)
+		}
+
+		if pushLocal {
+			update := taskFromTodo(todo)
+			update.ID = task.ID
+			updated, err := session.UpdateTask(ctx, googletasks.DefaultList, update)
+			if err != nil {
+				return err
+			}
+			task = updated
+			result.TasksUpdated++
+		} else {
+			req := updateRequestFromTask(task, todo)
+			req.Version = &todo.Version
+			updated, err := s.todos.UpdateTodo(ctx, todo.ID, req)
+			var conflict *ConflictError
+			if errors.As(err, &conflict) {
+				// Changed while syncing; the next sync sees both sides
+				// changed again
+				return nil
+			}
+			if err != nil {
+				s.log(ctx).Warn("Skipped Google task update", "user_id", userID, "todo_id", todo.ID, "task_id", task.ID, "error", err)
+				result.Skipped++
+				return nil
+			}
+			todo = updated
+			result.TodosUpdated++
+		}
+	}
+
+	return s.repo.SaveLink(ctx, userID, models.GoogleTaskLink{TodoID: todo.ID, TaskID: task.ID, TodoVersion: todo.Version, TaskUpdated: task.Updated})
+}
+
+// taskFromTodo maps the fields Google Tasks has onto a task. Tasks only
+// keep the date of a due date.
+func taskFromTodo(todo *models.Todo) *googletasks.Task {
+	task := &googletasks.Task{Title: todo.Title, Status: googletasks.StatusNeedsAction}
+	if todo.Description != nil {
+		task.Notes = *todo.Description
+	}
+	if todo.Completed {
+		task.Status = googletasks.StatusCompleted
+		if todo.CompletedAt != nil {
+			completed := todo.CompletedAt.UTC().Format(time.RFC3339)
+			task.Completed = &completed
+		}
+	}
+	if todo.DueDate != nil {
+		due := todo.DueDate.UTC().Truncate(24 * time.Hour).Format(time.RFC3339)
+		task.Due = &due
+	}
+	return task
+}
+
+func sameTask(a, b *googletasks.Task) bool {
+	return a.Title == b.Title && a.Notes == b.Notes && a.Status == b.Status && taskDueDate(a) == taskDueDate(b)
+}
+
+// taskDueDate returns the task's due date as YYYY-MM-DD, or ""
+func taskDueDate(task *googletasks.Task) string {
+	if task.Due == nil {
+		return ""
+	}
+	due, err := time.Parse(time.RFC3339, *task.Due)
+	if err != nil {
+		return ""
+	}
+	return due.UTC().Format("2006-01-02")
+}
+
+func createRequestFromTask(task *googletasks.Task) models.CreateTodoRequest {
+	req := models.CreateTodoRequest{
+		Title:     task.Title,
+		Completed: task.Status == googletasks.StatusCompleted,
+	}
+	if task.Notes != "" {
+		req.Description = &task.Notes
+	}
+	if date := taskDueDate(task); date != "" {
+		req.DueDate = &date
+	}
+	return req
+}
+
+// updateRequestFromTask replaces the todo's fields that tasks have. A due
+// time is kept while the task's due date is unchanged.
+func updateRequestFromTask(task *googletasks.Task, todo *models.Todo) models.UpdateTodoRequest {
+	done := task.Status == googletasks.StatusCompleted
+	due := taskDueDate(task)
+	if todo.DueDate != nil && todo.DueDate.UTC().Format("2006-01-02") == due {
+		due = todo.DueDate.UTC().Format(time.RFC3339)
+	}
+
+	return models.UpdateTodoRequest{
+		Title:       &task.Title,
+		Description: &task.Notes,
+		Completed:   &done,
+		DueDate:     &due,
+	}
+}
+
+func sortedIDs[T any](m map[int]T) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
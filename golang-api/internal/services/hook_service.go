@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+var (
+	// ErrHookNotFound is returned for hooks that do not exist or belong to
+	// another user
+	ErrHookNotFound = errors.New("hook not found")
+	// ErrInvalidHook is returned for subscriptions with an unknown event
+	// type or an unusable target URL
+	ErrInvalidHook = errors.New("invalid hook")
+)
+
+// hookSamples is the number of events returned as samples
+const hookSamples = 3
+
+type HookService interface {
+	Subscribe(ctx context.Context, userID int, req models.HookRequest) (*models.Hook, error)
+	Unsubscribe(ctx context.Context, userID, id int) error
+	ListHooks(ctx context.Context, userID int) ([]models.Hook, error)
+	Samples(ctx context.Context, eventType string) ([]events.Event, error)
+	HandleEvent(ctx context.Context, evt events.Event) error
+}
+
+type hookService struct {
+	repo         repository.HookRepository
+	plans        PlanService
+	client       *http.Client
+	allowPrivate bool
+	logger       *slog.Logger
+}
+
+// NewHookService returns the service behind the REST hooks API. Its
+// HandleEvent subscribes to the event bus and posts events to the hooks.
+// plans, when not nil, limits the hooks each user can subscribe. Hooks
+// only reach public addresses unless allowPrivate is set.
+func NewHookService(repo repository.HookRepository, plans PlanService, allowPrivate bool, logger *slog.Logger) HookService {
+	client := newPublicClient(10 * time.Second)
+	if allowPrivate {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &hookService{
+		repo:         repo,
+		plans:        plans,
+		client:       client,
+		allowPrivate: allowPrivate,
+		logger:       logger,
+	}
+}
+
+func (s *hookService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *hookService) Subscribe(ctx context.Context, userID int, req models.HookRequest) (*models.Hook, error) {
+	if !slices.Contains(events.Types, req.Event) {
+		return nil, fmt.Errorf("%w: event must be one of %s", ErrInvalidHook, strings.Join(events.Types, ", "))
+	}
+
+	target := strings.TrimSpace(req.TargetURL)
+	if len(target) > maxLinkURLLength {
+		return nil, fmt.Errorf("%w: target_url cannot exceed %d characters", ErrInvalidHook, maxLinkURLLength)
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: target_url must be an absolute http or https URL", ErrInvalidHook)
+	}
+	if !s.allowPrivate && !isPublicHost(parsed) {
+		return nil, fmt.Errorf("%w: target_url must be a public address", ErrInvalidHook)
+	}
+
+	if err := s.checkLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	hook := &models.Hook{Event: req.Event, TargetURL: target}
+	if err := s.repo.Create(ctx, userID, hook); err != nil {
+		s.log(ctx).Error("Failed to create hook", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to create hook: %w", err)
+	}
+
+	s.log(ctx).Info("Subscribed hook", "user_id", userID, "id", hook.ID, "event", hook.Event)
+	return hook, nil
+}
+
+// checkLimit returns ErrPlanLimit when the user has as many hooks as
+// their plan allows
+func (s *hookService) checkLimit(ctx context.Context, userID int) error {
+	if s.plans == nil {
+		return nil
+	}
+
+	plan, err := s.plans.ForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxHooks == 0 {
+		return nil
+	}
+
+	hooks, err := s.repo.List(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count hooks: %w", err)
+	}
+	return checkPlanLimit(plan, plan.MaxHooks, len(hooks), "hooks")
+}
+
+func (s *hookService) Unsubscribe(ctx context.Context, userID, id int) error {
+	deleted, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		s.log(ctx).Error("Failed to delete hook", "user_id", userID, "id", id, "error", err)
+		return fmt.Errorf("failed to delete hook: %w", err)
+	}
+	if !deleted {
+		return ErrHookNotFound
+	}
+
+	s.log(ctx).Info("Unsubscribed hook", "user_id", userID, "id", id)
+	return nil
+}
+
+func (s *hookService) ListHooks(ctx context.Context, userID int) ([]models.Hook, error) {
+	hooks, err := s.repo.List(ctx, userID)
+	if err != nil {
+		s.log(ctx).Error("Failed to list hooks", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to list hooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// Samples returns payloads a hook for the event type would receive, for
+// tools that show sample data while a hook is set up: the newest events of
+// the type, or a made-up one when there are none yet
+func (s *hookService) Samples(ctx context.Context, eventType string) ([]events.Event, error) {
+	if !slices.Contains(events.Types, eventType) {
+		return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidHook, eventType)
+	}
+
+	messages, err := s.repo.Samples(ctx, eventType, hookSamples)
+	if err != nil {
+		s.log(ctx).Error("Failed to load sample events", "event", eventType, "error", err)
+		return nil, fmt.Errorf("failed to load sample events: %w", err)
+	}
+
+	samples := make([]events.Event, 0, len(messages))
+	for _, msg := range messages {
+		var evt events.Event
+		if err := json.Unmarshal(msg.Payload, &evt); err != nil {
+			continue
+		}
+		evt.ID = msg.ID
+		samples = append(samples, evt)
+	}
+
+	if len(samples) == 0 {
+		now := time.Now().UTC().Truncate(time.Second)
+		description := "An example todo"
+		evt := events.New(eventType, &models.Todo{
+			ID:          1,
+			Title:       "Example todo",
+			Description: &description,
+			Status:      models.StatusTodo,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Tags:        []string{"example"},
+		})
+		evt.ID = 1
+		evt.OccurredAt = now
+		samples = append(samples, evt)
+	}
+
+	return samples, nil
+}
+
+// HandleEvent posts the event to every hook subscribed to its type.
+// Targets that answer 410 Gone are unsubscribed. Failed deliveries are
+// returned so the outbox retries the event; a retry goes to every hook
+// again, and the event ID tells targets it is a redelivery.
+func (s *hookService) HandleEvent(ctx context.Context, evt events.Event) error {
+	hooks, err := s.repo.ListByEvent(ctx, evt.Type)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var errs []error
+	for _, hook := range hooks {
+		status, err := s.post(ctx, hook.TargetURL, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hook %d: %w", hook.ID, err))
+			continue
+		}
+
+		if status == http.StatusGone {
+			if err := s.repo.DeleteByID(ctx, hook.ID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.log(ctx).Info("Unsubscribed hook whose target is gone", "id", hook.ID, "event", hook.Event)
+			continue
+		}
+		if status >= 300 {
+			errs = append(errs, fmt.Errorf("hook %d: target returned status %d", hook.ID, status))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *hookService) post(ctx context.Context, target string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+var (
+	// ErrLinkNotFound is returned for links that do not exist or belong
+	// to another todo
+	ErrLinkNotFound = errors.New("link not found")
+	// ErrLinkExists is returned when the todo already links to the URL
+	ErrLinkExists = errors.New("todo already links to this URL")
+)
+
+// maxLinkURLLength is the longest link URL, in bytes
+const maxLinkURLLength = 2048
+
+type LinkService interface {
+	ListLinks(ctx context.Context, todoID int) ([]models.Link, error)
+	CreateLink(ctx context.Context, todoID int, req models.LinkRequest) (*models.Link, error)
+	UpdateLink(ctx context.Context, todoID, id int, req models.LinkRequest) (*models.Link, error)
+	DeleteLink(ctx context.Context, todoID, id int) error
+}
+
+type linkService struct {
+	repo    repository.LinkRepository
+	todos   repository.TodoRepository
+	fetcher TitleFetcher
+	logger  *slog.Logger
+}
+
+// NewLinkService returns a service for the links of todos. fetcher, when
+// not nil, fills in the title of links saved without one.
+func NewLinkService(repo repository.LinkRepository, todos repository.TodoRepository, fetcher TitleFetcher, logger *slog.Logger) LinkService {
+	return &linkService{
+		repo:    repo,
+		todos:   todos,
+		fetcher: fetcher,
+		logger:  logger,
+	}
+}
+
+func (s *linkService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// checkTodo returns ErrTodoNotFound unless the todo exists
+func (s *linkService) checkTodo(ctx context.Context, todoID int) error {
+	exists, err := s.todos.Exists(ctx, todoID)
+	if err != nil {
+		return fmt.Errorf("failed to get todo: %w", err)
+	}
+	if !exists {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+func (s *linkService) ListLinks(ctx context.Context, todoID int) ([]models.Link, error) {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.List(ctx, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, nil
+}
+
+func (s *linkService) CreateLink(ctx context.Context, todoID int, req models.LinkRequest) (*models.Link, error) {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+	if err := validateLinkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	link := &models.Link{TodoID: todoID, Type: req.Type, URL: req.URL, Title: s.title(ctx, req)}
+	err := s.repo.Create(ctx, link)
+	if errors.Is(err, repository.ErrDuplicateLink) {
+		return nil, ErrLinkExists
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to create link", "todo_id", todoID, "error", err)
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+
+	s.log(ctx).Info("Created link", "todo_id", todoID, "id", link.ID, "type", link.Type)
+	return link, nil
+}
+
+func (s *linkService) UpdateLink(ctx context.Context, todoID, id int, req models.LinkRequest) (*models.Link, error) {
+	if err := s.checkLink(ctx, todoID, id); err != nil {
+		return nil, err
+	}
+	if err := validateLinkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	link := &models.Link{ID: id, TodoID: todoID, Type: req.Type, URL: req.URL, Title: s.title(ctx, req)}
+	err := s.repo.Update(ctx, link)
+	if errors.Is(err, repository.ErrDuplicateLink) {
+		return nil, ErrLinkExists
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to update link", "todo_id", todoID, "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update link: %w", err)
+	}
+
+	s.log(ctx).Info("Updated link", "todo_id", todoID, "id", id, "type", link.Type)
+	return link, nil
+}
+
+func (s *linkService) DeleteLink(ctx context.Context, todoID, id int) error {
+	if err := s.checkLink(ctx, todoID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, todoID, id); err != nil {
+		s.log(ctx).Error("Failed to delete link", "todo_id", todoID, "id", id, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Deleted link", "todo_id", todoID, "id", id)
+	return nil
+}
+
+// checkLink returns ErrTodoNotFound or ErrLinkNotFound unless the link
+// exists on the todo
+func (s *linkService) checkLink(ctx context.Context, todoID, id int) error {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return err
+	}
+
+	link, err := s.repo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return fmt.Errorf("failed to get link: %w", err)
+	}
+	if link == nil {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// title returns the requested title or, when there is none and fetching is
+// enabled, the page title. A page that cannot be fetched leaves the link
+// without a title rather than failing the request.
+func (s *linkService) title(ctx context.Context, req models.LinkRequest) *string {
+	if req.Title != nil || s.fetcher == nil {
+		return req.Title
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	title, err := s.fetcher.FetchTitle(ctx, req.URL)
+	if err != nil {
+		s.log(ctx).Warn("Failed to fetch link title", "url", req.URL, "error", err)
+		return nil
+	}
+	if title == "" {
+		return nil
+	}
+	return &title
+}
+
+// validateLinkRequest checks the type, URL and title, defaulting the type
+// to other and dropping an empty title
+func validateLinkRequest(req *models.LinkRequest) error {
+	req.Type = strings.TrimSpace(req.Type)
+	if req.Type == "" {
+		req.Type = models.LinkTypeOther
+	}
+	if !models.IsValidLinkType(req.Type) {
+		return fmt.Errorf("type must be one of %s", strings.Join(models.LinkTypes, ", "))
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if len(req.URL) > maxLinkURLLength {
+		return fmt.Errorf("url cannot exceed %d characters", maxLinkURLLength)
+	}
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("url cannot contain credentials")
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if len(title) > maxLinkTitleLength {
+			return fmt.Errorf("title cannot exceed %d characters", maxLinkTitleLength)
+		}
+		req.Title = &title
+		if title == "" {
+			req.Title = nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TitleFetcher looks up the title of a web page
+type TitleFetcher interface {
+	FetchTitle(ctx context.Context, url string) (string, error)
+}
+
+const (
+	// maxTitlePageSize is how much of a page is read looking for its title
+	maxTitlePageSize = 512 << 10
+	// maxLinkTitleLength is the longest link title, in bytes
+	maxLinkTitleLength = 255
+)
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+type httpTitleFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPTitleFetcher returns a TitleFetcher that reads the <title> of
+// HTML pages. It only connects to public addresses, so link titles cannot
+// be used to probe the network the API runs in.
+func NewHTTPTitleFetcher() TitleFetcher {
+	return &httpTitleFetcher{client: newPublicClient(10 * time.Second)}
+}
+
+func (f *httpTitleFetcher) FetchTitle(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return "", fmt.Errorf("not an HTML page: %q", mediaType)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxTitlePageSize))
+	if err != nil {
+		return "", err
+	}
+
+	return extractTitle(page), nil
+}
+
+// extractTitle returns the unescaped, whitespace-collapsed <title> of an
+// HTML page, truncated to maxLinkTitleLength, or "" when there is none
+func extractTitle(page []byte) string {
+	match := titlePattern.FindSubmatch(page)
+	if match == nil {
+		return ""
+	}
+
+	title := strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(string(match[1])), " "))
+	if len(title) > maxLinkTitleLength {
+		title = strings.ToValidUTF8(title[:maxLinkTitleLength], "")
+	}
+	return title
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// maxLoginDelay caps the wait that doubles with every failed login
+const maxLoginDelay = time.Minute
+
+// ErrUnlockTargetRequired is returned when an unlock names neither an
+// email nor an IP
+var ErrUnlockTargetRequired = errors.New("email or ip is required")
+
+// LoginBlockedError is returned while earlier failed logins keep an
+// account or client IP from trying again. Locked tells a lockout from the
+// delay after a failure.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "too many failed logins, login is temporarily locked"
+	}
+	return "too many failed logins, try again later"
+}
+
+// LoginGuard protects password logins against guessing. Consecutive
+// failures are counted per account and per client IP; each one delays the
+// next attempt, and enough of them lock the account or IP out for a
+// while. Every attempt is recorded in the audit log.
+type LoginGuard interface {
+	// Check returns a *LoginBlockedError when the account or IP may not
+	// try to log in yet
+	Check(ctx context.Context, email, ip string) error
+	Failed(ctx context.Context, email, ip string)
+	Succeeded(ctx context.Context, userID int, email, ip string)
+	ListLockouts(ctx context.Context) ([]models.LoginFailures, error)
+	// Unlock lifts the lockout and forgets the failures of the account
+	// and/or IP, reporting whether there were any. actorID and ip identify
+	// the admin for the audit log.
+	Unlock(ctx context.Context, req models.UnlockRequest, actorID *int, ip string) (bool, error)
+}
+
+type loginGuard struct {
+	repo   repository.LoginFailureRepository
+	audit  AuditService
+	cfg    config.AuthConfig
+	logger *slog.Logger
+}
+
+func NewLoginGuard(repo repository.LoginFailureRepository, audit AuditService, cfg config.AuthConfig, logger *slog.Logger) LoginGuard {
+	return &loginGuard{
+		repo:   repo,
+		audit:  audit,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+func (g *loginGuard) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, g.logger)
+}
+
+// loginCounter names a failure counter and the failures that lock it
+type loginCounter struct {
+	kind  string
+	value string
+	limit int
+}
+
+func (g *loginGuard) counters(email, ip string) []loginCounter {
+	return []loginCounter{
+		{kind: models.LockoutAccount, value: email, limit: g.cfg.LoginMaxFailures},
+		{kind: models.LockoutIP, value: ip, limit: g.cfg.LoginMaxFailuresPerIP},
+	}
+}
+
+func (g *loginGuard) enabled() bool {
+	return g.cfg.LoginDelay > 0 || g.cfg.LoginMaxFailures > 0 || g.cfg.LoginMaxFailuresPerIP > 0
+}
+
+func (g *loginGuard) Check(ctx context.Context, email, ip string) error {
+	if !g.enabled() {
+		return nil
+	}
+
+	now := time.Now()
+	var blocked *LoginBlockedError
+	for _, counter := range g.counters(email, ip) {
+		failures, err := g.repo.Get(ctx, counter.kind, counter.value)
+		if err != nil {
+			return fmt.Errorf("failed to check login failures: %w", err)
+		}
+		if failures == nil {
+			continue
+		}
+
+		if wait, locked := g.wait(failures, now); wait > 0 && (blocked == nil || wait > blocked.RetryAfter) {
+			blocked = &LoginBlockedError{RetryAfter: wait, Locked: locked}
+		}
+	}
+
+	if blocked != nil {
+		g.log(ctx).Warn("Login refused after failed attempts", "email", email, "ip", ip, "locked", blocked.Locked, "retry_after", blocked.RetryAfter.String())
+		return blocked
+	}
+	return nil
+}
+
+// wait returns how long the counter keeps logins refused, and whether
+// that is because it is locked
+func (g *loginGuard) wait(failures *models.LoginFailures, now time.Time) (time.Duration, bool) {
+	if failures.Locked(now) {
+		return failures.LockedUntil.Sub(now), true
+	}
+	if g.cfg.LoginDelay <= 0 || g.stale(failures, now) {
+		return 0, false
+	}
+
+	delay := g.cfg.LoginDelay
+	for i := 1; i < failures.Failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxLoginDelay)
+
+	if ready := failures.LastFailedAt.Add(delay); now.Before(ready) {
+		return ready.Sub(now), false
+	}
+	return 0, false
+}
+
+// stale reports whether the counter should start over: its lockout has
+// ended, or its last failure is older than the lockout period
+func (g *loginGuard) stale(failures *models.LoginFailures, now time.Time) bool {
+	if failures.LockedUntil != nil {
+		return !failures.Locked(now)
+	}
+	return now.Sub(failures.LastFailedAt) >= g.cfg.LoginLockout
+}
+
+func (g *loginGuard) Failed(ctx context.Context, email, ip string) {
+	g.audit.Record(ctx, models.AuditEntry{Action: models.AuditLoginFailed, IP: ip, Target: email})
+	if !g.enabled() {
+		return
+	}
+
+	now := time.Now()
+	for _, counter := range g.counters(email, ip) {
+		if err := g.fail(ctx, counter, ip, now); err != nil {
+			g.log(ctx).Error("Failed to count failed login", "kind", counter.kind, "error", err)
+		}
+	}
+}
+
+func (g *loginGuard) fail(ctx context.Context, counter loginCounter, ip string, now time.Time) error {
+	failures, err := g.repo.Increment(ctx, counter.kind, counter.value, now, now.Add(-g.cfg.LoginLockout))
+	if err != nil {
+		return err
+	}
+	if counter.limit <= 0 || failures.Failures < counter.limit || failures.LockedUntil != nil {
+		return nil
+	}
+
+	// Of concurrent failures reaching the limit, the one that locks the
+	// counter reports it
+	until := now.Add(g.cfg.LoginLockout)
+	locked, err := g.repo.Lock(ctx, counter.kind, counter.value, until)
+	if err != nil || !locked {
+		return err
+	}
+
+	g.log(ctx).Warn("Login locked out after failed attempts", "kind", counter.kind, "value", counter.value, "failures", failures.Failures, "locked_until", until)
+	g.audit.Record(ctx, models.AuditEntry{
+		Action: models.AuditLockout,
+		IP:     ip,
+		Target: counter.value,
+		Details: map[string]interface{}{
+			"kind":         counter.kind,
+			"failures":     failures.Failures,
+			"locked_until": until.UTC(),
+		},
+	})
+	return nil
+}
+
+func (g *loginGuard) Succeeded(ctx context.Context, userID int, email, ip string) {
+	g.audit.Record(ctx, models.AuditEntry{Action: models.AuditLogin, ActorID: &userID, IP: ip, Target: email})
+	if !g.enabled() {
+		return
+	}
+
+	// The client IP keeps its count: one good password does not vouch for
+	// other accounts tried from the same address
+	if _, err := g.repo.Delete(ctx, models.LockoutAccount, email); err != nil {
+		g.log(ctx).Error("Failed to reset failed logins", "user_id", userID, "error", err)
+	}
+}
+
+func (g *loginGuard) ListLockouts(ctx context.Context) ([]models.LoginFailures, error) {
+	locked, err := g.repo.ListLocked(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+	return locked, nil
+}
+
+func (g *loginGuard) Unlock(ctx context.Context, req models.UnlockRequest, actorID *int, ip string) (bool, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	addr := strings.TrimSpace(req.IP)
+	if email == "" && addr == "" {
+		return false, ErrUnlockTargetRequired
+	}
+
+	unlocked := false
+	for _, counter := range g.counters(email, addr) {
+		if counter.value == "" {
+			continue
+		}
+
+		removed, err := g.repo.Delete(ctx, counter.kind, counter.value)
+		if err != nil {
+			return false, fmt.Errorf("failed to unlock: %w", err)
+		}
+		if !removed {
+			continue
+		}
+
+		unlocked = true
+		g.log(ctx).Info("Login unlocked", "kind", counter.kind, "value", counter.value)
+		g.audit.Record(ctx, models.AuditEntry{
+			Action:  models.AuditUnlock,
+			ActorID: actorID,
+			IP:      ip,
+			Target:  counter.value,
+			Details: map[string]interface{}{"kind": counter.kind},
+		})
+	}
+
+	return unlocked, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// maxMetadataSize is the largest metadata a todo can carry, in bytes of
+// compact JSON
+const maxMetadataSize = 16 << 10
+
+// ErrMetadataTooLarge is returned when metadata, after merging an update,
+// exceeds maxMetadataSize
+var ErrMetadataTooLarge = fmt.Errorf("metadata cannot exceed %d bytes", maxMetadataSize)
+
+// isJSONNull reports whether raw is the JSON literal null
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
+
+// decodeMetadata parses metadata that must be a JSON object. Numbers are
+// kept as written.
+func decodeMetadata(raw json.RawMessage) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil || object == nil {
+		return nil, errors.New("metadata must be a JSON object")
+	}
+	return object, nil
+}
+
+// validateMetadata checks metadata given in a request. null means no
+// metadata.
+func validateMetadata(raw json.RawMessage) error {
+	if raw == nil || isJSONNull(raw) {
+		return nil
+	}
+	if len(raw) > 4*maxMetadataSize {
+		// Not worth parsing; whitespace aside it cannot fit
+		return ErrMetadataTooLarge
+	}
+	_, err := decodeMetadata(raw)
+	return err
+}
+
+// encodeMetadata returns the stored form of a metadata object: compact
+// JSON, or nil when it is empty
+func encodeMetadata(object map[string]interface{}) (json.RawMessage, error) {
+	if len(object) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) > maxMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	return encoded, nil
+}
+
+// mergeMetadata applies patch to the stored metadata as a JSON merge patch
+// (RFC 7396): objects are merged recursively, keys set to null are removed
+// and any other value replaces the stored one
+func mergeMetadata(stored, patch json.RawMessage) (json.RawMessage, error) {
+	if isJSONNull(patch) {
+		return nil, nil
+	}
+
+	target := map[string]interface{}{}
+	if stored != nil {
+		var err error
+		if target, err = decodeMetadata(stored); err != nil {
+			return nil, err
+		}
+	}
+	changes, err := decodeMetadata(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeMetadata(mergeObject(target, changes))
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			target[key] = value
+			continue
+		}
+		current, ok := target[key].(map[string]interface{})
+		if !ok {
+			current = map[string]interface{}{}
+		}
+		target[key] = mergeObject(current, nested)
+	}
+	return target
+}
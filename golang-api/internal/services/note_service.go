@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrNoteNotFound is returned for notes that do not exist or belong to
+// another todo
+var ErrNoteNotFound = errors.New("note not found")
+
+// maxNoteLength is the longest note body, 100 KiB
+const maxNoteLength = 100 << 10
+
+type NoteService interface {
+	ListNotes(ctx context.Context, todoID int) ([]models.Note, error)
+	GetNote(ctx context.Context, todoID, id int) (*models.Note, error)
+	CreateNote(ctx context.Context, todoID int, req models.NoteRequest) (*models.Note, error)
+	UpdateNote(ctx context.Context, todoID, id int, req models.NoteRequest) (*models.Note, error)
+	DeleteNote(ctx context.Context, todoID, id int) error
+}
+
+type noteService struct {
+	repo   repository.NoteRepository
+	todos  repository.TodoRepository
+	uow    repository.UnitOfWork
+	logger *slog.Logger
+}
+
+// NewNoteService returns a service for the notes of todos. Todos in the
+// trash are treated as missing, like everywhere else. Notes are written in
+// a unit of work together with their note.created and note.updated
+// events.
+func NewNoteService(repo repository.NoteRepository, todos repository.TodoRepository, uow repository.UnitOfWork, logger *slog.Logger) NoteService {
+	return &noteService{
+		repo:   repo,
+		todos:  todos,
+		uow:    uow,
+		logger: logger,
+	}
+}
+
+func (s *noteService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// checkTodo returns ErrTodoNotFound unless the todo exists
+func (s *noteService) checkTodo(ctx context.Context, todoID int) error {
+	exists, err := s.todos.Exists(ctx, todoID)
+	if err != nil {
+		return fmt.Errorf("failed to get todo: %w", err)
+	}
+	if !exists {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+func (s *noteService) ListNotes(ctx context.Context, todoID int) ([]models.Note, error) {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+
+	notes, err := s.repo.List(ctx, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	return notes, nil
+}
+
+func (s *noteService) GetNote(ctx context.Context, todoID, id int) (*models.Note, error) {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+
+	note, err := s.repo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+	return note, nil
+}
+
+func (s *noteService) CreateNote(ctx context.Context, todoID int, req models.NoteRequest) (*models.Note, error) {
+	if err := s.checkTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+	if err := validateNoteRequest(req); err != nil {
+		return nil, err
+	}
+
+	note := &models.Note{TodoID: todoID, Body: req.Body}
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		if err := tx.Notes.Create(ctx, note); err != nil {
+			return err
+		}
+		return recordNoteEvent(ctx, tx, events.NoteCreated, note, models.ParseMentions(note.Body))
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to create note", "todo_id", todoID, "error", err)
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	s.log(ctx).Info("Created note", "todo_id", todoID, "id", note.ID)
+	return note, nil
+}
+
+func (s *noteService) UpdateNote(ctx context.Context, todoID, id int, req models.NoteRequest) (*models.Note, error) {
+	before, err := s.GetNote(ctx, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNoteRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Users already mentioned before the edit are not notified again
+	mentioned := models.ParseMentions(before.Body)
+	var mentions []string
+	for _, email := range models.ParseMentions(req.Body) {
+		if !slices.Contains(mentioned, email) {
+			mentions = append(mentions, email)
+		}
+	}
+
+	note := &models.Note{ID: id, TodoID: todoID, Body: req.Body}
+	err = s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		if err := tx.Notes.Update(ctx, note); err != nil {
+			return err
+		}
+		return recordNoteEvent(ctx, tx, events.NoteUpdated, note, mentions)
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to update note", "todo_id", todoID, "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update note: %w", err)
+	}
+
+	s.log(ctx).Info("Updated note", "todo_id", todoID, "id", id)
+	return note, nil
+}
+
+func (s *noteService) DeleteNote(ctx context.Context, todoID, id int) error {
+	if _, err := s.GetNote(ctx, todoID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, todoID, id); err != nil {
+		s.log(ctx).Error("Failed to delete note", "todo_id", todoID, "id", id, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Deleted note", "todo_id", todoID, "id", id)
+	return nil
+}
+
+// recordNoteEvent stores a note event carrying the todo, so that
+// subscribers and the activity feed can show its title
+func recordNoteEvent(ctx context.Context, tx repository.TxRepositories, eventType string, note *models.Note, mentions []string) error {
+	todo, err := tx.Todos.GetByID(ctx, note.TodoID)
+	if err != nil {
+		return err
+	}
+
+	evt := events.New(eventType, todo)
+	evt.TodoID = note.TodoID
+	evt.Data = map[string]interface{}{"note_id": note.ID}
+	if len(mentions) > 0 {
+		evt.Data["mentions"] = mentions
+	}
+	return recordEvent(tx, evt)
+}
+
+func validateNoteRequest(req models.NoteRequest) error {
+	if strings.TrimSpace(req.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+
+	if len(req.Body) > maxNoteLength {
+		return fmt.Errorf("body cannot exceed %d bytes", maxNoteLength)
+	}
+
+	return nil
+}
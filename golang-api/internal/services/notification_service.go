@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrNotificationNotFound is returned for notifications that do not exist
+// or belong to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationEvents are the event types HandleEvent turns into
+// notifications
+var NotificationEvents = []string{events.NoteCreated, events.NoteUpdated, events.TodoDueSoon}
+
+type NotificationService interface {
+	ListNotifications(ctx context.Context, userID int, unreadOnly bool, page, perPage int) (*models.PaginatedResponse, error)
+	MarkRead(ctx context.Context, userID, id int) (*models.Notification, error)
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	DeleteNotification(ctx context.Context, userID, id int) error
+	ClearNotifications(ctx context.Context, userID int, readOnly bool) (int64, error)
+	HandleEvent(ctx context.Context, evt events.Event) error
+}
+
+type notificationService struct {
+	repo   repository.NotificationRepository
+	users  repository.UserRepository
+	logger *slog.Logger
+}
+
+// NewNotificationService returns the service behind the notifications
+// API. Its HandleEvent subscribes to the event bus and writes the
+// notifications.
+func NewNotificationService(repo repository.NotificationRepository, users repository.UserRepository, logger *slog.Logger) NotificationService {
+	return &notificationService{
+		repo:   repo,
+		users:  users,
+		logger: logger,
+	}
+}
+
+func (s *notificationService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *notificationService) ListNotifications(ctx context.Context, userID int, unreadOnly bool, page, perPage int) (*models.PaginatedResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	notifications, total, err := s.repo.List(ctx, userID, unreadOnly, perPage, (page-1)*perPage)
+	if err != nil {
+		s.log(ctx).Error("Failed to list notifications", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Data:       notifications,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: (total + perPage - 1) / perPage,
+		HasMore:    page*perPage < total,
+	}, nil
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, id int) (*models.Notification, error) {
+	notification, err := s.repo.MarkRead(ctx, userID, id)
+	if err != nil {
+		s.log(ctx).Error("Failed to mark notification as read", "user_id", userID, "id", id, "error", err)
+		return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	if notification == nil {
+		return nil, ErrNotificationNotFound
+	}
+	return notification, nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	marked, err := s.repo.MarkAllRead(ctx, userID)
+	if err != nil {
+		s.log(ctx).Error("Failed to mark notifications as read", "user_id", userID, "error", err)
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return marked, nil
+}
+
+func (s *notificationService) DeleteNotification(ctx context.Context, userID, id int) error {
+	deleted, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		s.log(ctx).Error("Failed to delete notification", "user_id", userID, "id", id, "error", err)
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	if !deleted {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *notificationService) ClearNotifications(ctx context.Context, userID int, readOnly bool) (int64, error) {
+	cleared, err := s.repo.DeleteAll(ctx, userID, readOnly)
+	if err != nil {
+		s.log(ctx).Error("Failed to clear notifications", "user_id", userID, "error", err)
+		return 0, fmt.Errorf("failed to clear notifications: %w", err)
+	}
+
+	s.log(ctx).Info("Cleared notifications", "user_id", userID, "count", cleared)
+	return cleared, nil
+}
+
+// HandleEvent notifies the users mentioned in a note, and every user when
+// a todo comes due; todos have no owner or assignee to narrow it down.
+// Other events are ignored.
+func (s *notificationService) HandleEvent(ctx context.Context, evt events.Event) error {
+	title := fmt.Sprintf("todo #%d", evt.TodoID)
+	if evt.Todo != nil {
+		title = fmt.Sprintf("%q", evt.Todo.Title)
+	}
+
+	var (
+		userIDs      []int
+		notification = models.Notification{TodoID: &evt.TodoID}
+		err          error
+	)
+	switch evt.Type {
+	case events.NoteCreated, events.NoteUpdated:
+		userIDs, err = s.users.IDsByEmail(ctx, mentions(evt))
+		notification.Type = models.NotificationMention
+		notification.Message = "You were mentioned in a note on " + title
+	case events.TodoDueSoon:
+		userIDs, err = s.users.ListIDs(ctx)
+		notification.Type = models.NotificationDueSoon
+		notification.Message = title + " is due soon"
+		if evt.Todo != nil && evt.Todo.DueDate != nil {
+			notification.Message = fmt.Sprintf("%s is due %s", title, evt.Todo.DueDate.UTC().Format("2006-01-02 15:04 MST"))
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find users to notify: %w", err)
+	}
+
+	created, err := s.repo.Create(ctx, evt.ID, userIDs, notification)
+	if err != nil {
+		return err
+	}
+
+	if created > 0 {
+		s.log(ctx).Info("Created notifications", "type", notification.Type, "todo_id", evt.TodoID, "count", created)
+	}
+	return nil
+}
+
+// mentions returns the emails in Data["mentions"], which is a []string
+// when published directly and a []interface{} after the outbox round trip
+func mentions(evt events.Event) []string {
+	switch list := evt.Data["mentions"].(type) {
+	case []string:
+		return list
+	case []interface{}:
+		emails := make([]string, 0, len(list))
+		for _, item := range list {
+			if email, ok := item.(string); ok {
+				emails = append(emails, email)
+			}
+		}
+		return emails
+	}
+	return nil
+}
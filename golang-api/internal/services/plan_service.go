@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/config"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+var (
+	// ErrUnknownPlan is returned when moving a user to a plan that is not
+	// configured
+	ErrUnknownPlan = errors.New("unknown plan")
+	// ErrPlanLimit is returned when a user already has as many of
+	// something as their plan allows
+	ErrPlanLimit = errors.New("plan limit reached")
+)
+
+// PlanService resolves the plan each user is on. Plans are read from the
+// configuration on every call, so they can be changed with a reload.
+type PlanService interface {
+	// ForUser returns the plan the user is on. Users that were moved to a
+	// plan that is no longer configured are on the default plan.
+	ForUser(ctx context.Context, userID int) (*models.Plan, error)
+	ListPlans(ctx context.Context) []models.Plan
+	// SetPlan moves the user to plan; an empty plan returns them to the
+	// default plan
+	SetPlan(ctx context.Context, userID int, plan string) (*models.Plan, error)
+}
+
+type planService struct {
+	users  repository.UserRepository
+	store  *config.Store
+	logger *slog.Logger
+}
+
+func NewPlanService(users repository.UserRepository, store *config.Store, logger *slog.Logger) PlanService {
+	return &planService{
+		users:  users,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (s *planService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *planService) ForUser(ctx context.Context, userID int) (*models.Plan, error) {
+	plans := s.store.Get().Plans
+	if len(plans.Plans) == 0 {
+		return unlimitedPlan(), nil
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	name := plans.Default
+	if user != nil && user.Plan != nil {
+		if _, ok := plans.Plans[*user.Plan]; ok {
+			name = *user.Plan
+		} else {
+			s.log(ctx).Warn("User is on a plan that is not configured, using the default plan", "user_id", userID, "plan", *user.Plan, "default", plans.Default)
+		}
+	}
+
+	return toPlan(name, plans.Plans[name]), nil
+}
+
+func (s *planService) ListPlans(ctx context.Context) []models.Plan {
+	plans := s.store.Get().Plans
+	if len(plans.Plans) == 0 {
+		return []models.Plan{*unlimitedPlan()}
+	}
+
+	list := make([]models.Plan, 0, len(plans.Plans))
+	for name, plan := range plans.Plans {
+		list = append(list, *toPlan(name, plan))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *planService) SetPlan(ctx context.Context, userID int, plan string) (*models.Plan, error) {
+	plans := s.store.Get().Plans
+	plan = strings.TrimSpace(plan)
+
+	var stored *string
+	if plan != "" {
+		if _, ok := plans.Plans[plan]; !ok {
+			names := make([]string, 0, len(plans.Plans))
+			for name := range plans.Plans {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("%w %q, must be one of: %s", ErrUnknownPlan, plan, strings.Join(names, ", "))
+		}
+		stored = &plan
+	}
+
+	found, err := s.users.SetPlan(ctx, userID, stored)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrUserNotFound
+	}
+
+	s.log(ctx).Info("Changed user plan", "user_id", userID, "plan", plan)
+	return s.ForUser(ctx, userID)
+}
+
+// checkPlanLimit returns ErrPlanLimit when a user with used of something
+// may not add another under max, where zero is unlimited
+func checkPlanLimit(plan *models.Plan, max, used int, what string) error {
+	if max > 0 && used >= max {
+		return fmt.Errorf("%w: the %s plan allows %d %s", ErrPlanLimit, plan.Name, max, what)
+	}
+	return nil
+}
+
+func unlimitedPlan() *models.Plan {
+	return &models.Plan{Name: models.PlanUnlimited, Features: slices.Clone(models.Features)}
+}
+
+func toPlan(name string, plan config.PlanConfig) *models.Plan {
+	return &models.Plan{
+		Name:       name,
+		Features:   slices.Clone(plan.Features),
+		MaxHooks:   plan.MaxHooks,
+		MaxAPIKeys: plan.MaxAPIKeys,
+	}
+}
@@ -0,0 +1,22 @@
+package services
+
+import "context"
+
+// ProgressFunc receives the progress of a long operation, such as an
+// import run by a background job
+type ProgressFunc func(done, total int)
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx whose long operations report their
+// progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// reportProgress passes progress to the ProgressFunc in ctx, if any
+func reportProgress(ctx context.Context, done, total int) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(done, total)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/models"
+)
+
+// maxQuickAddLength is the longest quick-add line, in bytes
+const maxQuickAddLength = 1000
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseQuickAdd turns a quick-add line such as "Buy milk #groceries !high
+// tomorrow" into a create request. #word adds a tag and !priority sets the
+// priority anywhere in the line. A due date is only recognized at the end,
+// optionally after "on", "by" or "due": today, tomorrow, a weekday (the
+// next one, today included), "next week" (next Monday) or a YYYY-MM-DD
+// date. Relative dates are resolved against now in UTC. Everything else is
+// the title.
+func parseQuickAdd(text string, now time.Time) (models.CreateTodoRequest, error) {
+	var req models.CreateTodoRequest
+	if len(text) > maxQuickAddLength {
+		return req, fmt.Errorf("text cannot exceed %d characters", maxQuickAddLength)
+	}
+
+	var words []string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && word[0] == '#':
+			req.Tags = append(req.Tags, word[1:])
+		case len(word) > 1 && word[0] == '!' && models.IsValidPriority(strings.ToLower(word[1:])):
+			priority := strings.ToLower(word[1:])
+			req.Priority = &priority
+		default:
+			words = append(words, word)
+		}
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if due, n := trailingDate(words, today); n > 0 {
+		words = words[:len(words)-n]
+		if len(words) > 1 {
+			switch strings.ToLower(words[len(words)-1]) {
+			case "on", "by", "due":
+				words = words[:len(words)-1]
+			}
+		}
+		dueDate := due.Format(time.DateOnly)
+		req.DueDate = &dueDate
+	}
+
+	req.Title = strings.Join(words, " ")
+	if req.Title == "" {
+		return req, fmt.Errorf("text must contain a title")
+	}
+	return req, nil
+}
+
+// trailingDate parses the date expression ending words and returns it with
+// the number of words it used, or 0 when words do not end in a date
+func trailingDate(words []string, today time.Time) (time.Time, int) {
+	if len(words) < 2 {
+		// A lone word is the title, even if it reads like a date
+		return time.Time{}, 0
+	}
+
+	last := strings.ToLower(words[len(words)-1])
+	if len(words) > 2 && last == "week" && strings.EqualFold(words[len(words)-2], "next") {
+		days := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), 2
+	}
+
+	switch last {
+	case "today":
+		return today, 1
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), 1
+	}
+	if weekday, ok := weekdays[last]; ok {
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), 1
+	}
+	if date, err := time.Parse(time.DateOnly, last); err == nil {
+		return date, 1
+	}
+	return time.Time{}, 0
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrSavedSearchNotFound is returned for saved searches that do not exist
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+type SavedSearchService interface {
+	ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error)
+	GetSavedSearch(ctx context.Context, id int) (*models.SavedSearch, error)
+	CreateSavedSearch(ctx context.Context, req models.SavedSearchRequest) (*models.SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, id int, req models.SavedSearchRequest) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id int) error
+	RunSavedSearch(ctx context.Context, id, page, perPage int) (*models.PaginatedResponse, error)
+}
+
+type savedSearchService struct {
+	repo   repository.SavedSearchRepository
+	todos  TodoService
+	logger *slog.Logger
+}
+
+// NewSavedSearchService returns a service that runs saved searches through
+// todos, so they match exactly what the list endpoint would return
+func NewSavedSearchService(repo repository.SavedSearchRepository, todos TodoService, logger *slog.Logger) SavedSearchService {
+	return &savedSearchService{
+		repo:   repo,
+		todos:  todos,
+		logger: logger,
+	}
+}
+
+func (s *savedSearchService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *savedSearchService) ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	searches, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+func (s *savedSearchService) GetSavedSearch(ctx context.Context, id int) (*models.SavedSearch, error) {
+	search, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	if search == nil {
+		return nil, ErrSavedSearchNotFound
+	}
+	return search, nil
+}
+
+func (s *savedSearchService) CreateSavedSearch(ctx context.Context, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	if err := validateSavedSearchRequest(&req); err != nil {
+		return nil, err
+	}
+
+	search := &models.SavedSearch{Name: req.Name, Query: req.Query, Color: req.Color, Icon: req.Icon}
+	if err := s.repo.Create(ctx, search); err != nil {
+		s.log(ctx).Error("Failed to create saved search", "error", err)
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	s.log(ctx).Info("Created saved search", "id", search.ID, "query", search.Query)
+	return search, nil
+}
+
+func (s *savedSearchService) UpdateSavedSearch(ctx context.Context, id int, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	if _, err := s.GetSavedSearch(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := validateSavedSearchRequest(&req); err != nil {
+		return nil, err
+	}
+
+	search := &models.SavedSearch{ID: id, Name: req.Name, Query: req.Query, Color: req.Color, Icon: req.Icon}
+	if err := s.repo.Update(ctx, search); err != nil {
+		s.log(ctx).Error("Failed to update saved search", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	s.log(ctx).Info("Updated saved search", "id", id, "query", search.Query)
+	return search, nil
+}
+
+func (s *savedSearchService) DeleteSavedSearch(ctx context.Context, id int) error {
+	if _, err := s.GetSavedSearch(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log(ctx).Error("Failed to delete saved search", "id", id, "error", err)
+		return err
+	}
+
+	s.log(ctx).Info("Deleted saved search", "id", id)
+	return nil
+}
+
+// RunSavedSearch returns one page of the todos matching the saved search.
+// The query is parsed again on every run, so a search keeps following
+// the list endpoint's defaults.
+func (s *savedSearchService) RunSavedSearch(ctx context.Context, id, page, perPage int) (*models.PaginatedResponse, error) {
+	search, err := s.GetSavedSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := url.ParseQuery(search.Query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	values.Set("page", strconv.Itoa(page))
+	values.Set("per_page", strconv.Itoa(perPage))
+
+	params, err := models.ParseQueryParams(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	return s.todos.GetTodos(ctx, params)
+}
+
+// validateSavedSearchRequest checks the name, color, icon and filters, and
+// stores the query in canonical form without pagination
+func validateSavedSearchRequest(req *models.SavedSearchRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name cannot exceed 100 characters")
+	}
+
+	if req.Color != nil {
+		color, err := models.NormalizeColor(*req.Color)
+		if err != nil {
+			return err
+		}
+		req.Color = &color
+	}
+
+	if req.Icon != nil {
+		icon, err := models.NormalizeIcon(*req.Icon)
+		if err != nil {
+			return err
+		}
+		req.Icon = &icon
+	}
+
+	values, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(req.Query), "?"))
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	values.Del("page")
+	values.Del("per_page")
+
+	params, err := models.ParseQueryParams(values)
+	if err != nil {
+		return err
+	}
+	if err := validateFilters(params); err != nil {
+		return err
+	}
+
+	req.Query = values.Encode()
+	return nil
+}
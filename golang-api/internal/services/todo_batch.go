@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// CreateTodos creates many todos in one transaction, with as few INSERTs
+// as possible, and returns them in the order requested along with how
+// many were created. Requests are validated like CreateTodo's and a single
+// invalid one, or a taken title, fails them all. A request whose client ID
+// is already stored, or used by an earlier request, returns that todo.
+func (s *todoService) CreateTodos(ctx context.Context, reqs []models.CreateTodoRequest) ([]models.Todo, int, error) {
+	s.log(ctx).Info("Creating todos", "todos", len(reqs))
+
+	if len(reqs) == 0 || len(reqs) > maxBulkTodos {
+		return nil, 0, fmt.Errorf("%w: todos must list between 1 and %d todos", ErrInvalidBulkRequest, maxBulkTodos)
+	}
+
+	todos := make([]*models.Todo, len(reqs))
+	for i, req := range reqs {
+		todo, err := s.newTodo(req)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: todo %d: %v", ErrInvalidBulkRequest, i, err)
+		}
+		todos[i] = todo
+	}
+
+	created := 0
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		pending, err := newTodos(ctx, tx, todos)
+		if err != nil {
+			return err
+		}
+		if err := tx.Todos.CreateMany(ctx, pending); err != nil {
+			return err
+		}
+		for _, todo := range pending {
+			if err := recordEvent(tx, events.New(events.TodoCreated, todo)); err != nil {
+				return err
+			}
+		}
+		created = len(pending)
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicateTitle) {
+		return nil, 0, ErrTitleTaken
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to create todos", "error", err)
+		return nil, 0, fmt.Errorf("failed to create todos: %w", err)
+	}
+
+	result := make([]models.Todo, len(todos))
+	for i, todo := range todos {
+		result[i] = *todo
+	}
+
+	s.log(ctx).Info("Created todos successfully", "created", created, "existing", len(todos)-created)
+	return result, created, nil
+}
+
+// newTodos returns the todos built by newTodo that are not stored yet. A
+// todo whose client ID is stored already, or used by an earlier todo, is
+// replaced in todos by that todo.
+func newTodos(ctx context.Context, tx repository.TxRepositories, todos []*models.Todo) ([]*models.Todo, error) {
+	pending := make([]*models.Todo, 0, len(todos))
+	byClientID := make(map[string]*models.Todo)
+
+	for i, todo := range todos {
+		if todo.ClientID != nil {
+			if earlier, ok := byClientID[*todo.ClientID]; ok {
+				todos[i] = earlier
+				continue
+			}
+
+			existing, err := tx.Todos.GetByClientID(ctx, *todo.ClientID)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				todos[i] = existing
+				byClientID[*todo.ClientID] = existing
+				continue
+			}
+			byClientID[*todo.ClientID] = todo
+		}
+		pending = append(pending, todo)
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// AnnounceDueSoon emits todo.due_soon for every open todo coming due
+// within the given time that has not been announced for its current due
+// date yet. It returns the number of todos announced.
+func (s *todoService) AnnounceDueSoon(ctx context.Context, within time.Duration) (int, error) {
+	if within <= 0 {
+		return 0, fmt.Errorf("invalid due soon window: %s", within)
+	}
+
+	now := time.Now()
+	var announced int
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		todos, err := tx.Todos.DueSoon(ctx, now, now.Add(within))
+		if err != nil {
+			return err
+		}
+		for i := range todos {
+			if err := recordEvent(tx, events.New(events.TodoDueSoon, &todos[i])); err != nil {
+				return err
+			}
+		}
+		announced = len(todos)
+		return nil
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to announce todos due soon", "error", err)
+		return 0, fmt.Errorf("failed to announce todos due soon: %w", err)
+	}
+
+	if announced > 0 {
+		s.log(ctx).Info("Announced todos due soon", "count", announced, "within", within.String())
+	}
+	return announced, nil
+}
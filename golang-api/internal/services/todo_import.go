@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// maxImportRows caps the todos in a single import, which runs in one
+// transaction
+const maxImportRows = 1000
+
+// ErrInvalidImport is returned for imports that cannot be run at all, such
+// as an unknown mode or too many rows
+var ErrInvalidImport = errors.New("invalid import")
+
+var (
+	// errImportRejected rolls back a strict import that had rejected rows
+	errImportRejected = errors.New("import has rejected rows")
+	// errImportDryRun rolls back a dry run once every row was tried
+	errImportDryRun = errors.New("import is a dry run")
+)
+
+// ImportTodos creates the todos of an import in one transaction and reports
+// what happened to every row. Rows are validated like CreateTodo requests
+// and rows whose client ID is already stored count as existing. In strict
+// mode a single rejected row rolls back the whole import; in partial mode
+// the valid rows are kept. A dry run inserts the rows like a real import,
+// so that duplicates are found too, and then rolls everything back.
+func (s *todoService) ImportTodos(ctx context.Context, rows []models.ImportRow, mode string, dryRun bool) (*models.ImportReport, error) {
+	s.log(ctx).Info("Importing todos", "rows", len(rows), "mode", mode, "dry_run", dryRun)
+
+	if err := ValidateImport(rows, mode); err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{Mode: mode, DryRun: dryRun, Total: len(rows), Errors: make([]models.ImportError, 0)}
+	reject := func(row models.ImportRow, reason string) {
+		report.Rejected++
+		report.Errors = append(report.Errors, models.ImportError{Row: row.Row, Line: row.Line, Error: reason})
+	}
+
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		var todos []*models.Todo
+		rowOf := make(map[*models.Todo]models.ImportRow)
+		for i, row := range rows {
+			reportProgress(ctx, i, len(rows))
+			if row.Error != "" {
+				reject(row, row.Error)
+				continue
+			}
+
+			todo, err := s.newTodo(row.Request)
+			if err != nil {
+				reject(row, err.Error())
+				continue
+			}
+			todos = append(todos, todo)
+			rowOf[todo] = row
+		}
+
+		pending, err := newTodos(ctx, tx, todos)
+		if err != nil {
+			return err
+		}
+		report.Existing = len(todos) - len(pending)
+
+		// One batch stores every row unless a title is taken. The batch is
+		// then undone and the rows are stored one by one to find out which.
+		err = tx.Todos.CreateMany(ctx, pending)
+		if errors.Is(err, repository.ErrDuplicateTitle) {
+			stored := pending[:0]
+			for _, todo := range pending {
+				// A failed insert only undoes its own statement, so the
+				// transaction can carry on with the next row
+				err := tx.Todos.Create(ctx, todo)
+				if errors.Is(err, repository.ErrDuplicateTitle) {
+					reject(rowOf[todo], ErrTitleTaken.Error())
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("row %d: %w", rowOf[todo].Row, err)
+				}
+				stored = append(stored, todo)
+			}
+			pending, err = stored, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, todo := range pending {
+			if err := recordEvent(tx, events.New(events.TodoCreated, todo)); err != nil {
+				return err
+			}
+		}
+		report.Created = len(pending)
+		report.Accepted = report.Created + report.Existing
+		sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+
+		reportProgress(ctx, len(rows), len(rows))
+		if mode == models.ImportStrict && report.Rejected > 0 {
+			return errImportRejected
+		}
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errImportRejected) {
+		s.log(ctx).Info("Strict import rolled back", "rejected", report.Rejected)
+		report.Created, report.Existing = 0, 0
+		return report, nil
+	}
+	if errors.Is(err, errImportDryRun) {
+		s.log(ctx).Info("Dry run of import rolled back", "created", report.Created, "existing", report.Existing, "rejected", report.Rejected)
+		return report, nil
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to import todos", "error", err)
+		return nil, fmt.Errorf("failed to import todos: %w", err)
+	}
+
+	report.Committed = true
+	s.log(ctx).Info("Imported todos", "created", report.Created, "existing", report.Existing, "rejected", report.Rejected)
+	return report, nil
+}
+
+// ValidateImport checks what can be checked about an import before it
+// runs: the mode and the number of rows. The rows themselves are validated
+// by ImportTodos.
+func ValidateImport(rows []models.ImportRow, mode string) error {
+	if mode != models.ImportStrict && mode != models.ImportPartial {
+		return fmt.Errorf("%w: mode must be strict or partial", ErrInvalidImport)
+	}
+	if len(rows) > maxImportRows {
+		return fmt.Errorf("%w: at most %d todos can be imported at once", ErrInvalidImport, maxImportRows)
+	}
+	return nil
+}
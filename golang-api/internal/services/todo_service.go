@@ -1,38 +1,216 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/logging"
 	"github.com/centroidsol/todo-api/internal/models"
 	"github.com/centroidsol/todo-api/internal/repository"
+	"github.com/google/uuid"
 )
 
+var (
+	// ErrEmptySearch is returned when a search query has no searchable words
+	ErrEmptySearch = errors.New("search query must contain at least one word")
+	// ErrInvalidFilter is returned for list filters that cannot match
+	// anything, such as an empty date range
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrTodoNotFound is returned for operations on a todo that does not exist
+	ErrTodoNotFound = errors.New("todo not found")
+	// ErrRevisionNotFound is returned when reverting to an unknown revision
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrNotInTrash is returned when restoring a todo that is not in the
+	// trash
+	ErrNotInTrash = errors.New("todo not found in trash")
+	// ErrNotDeleted is returned when purging a todo that has not been
+	// moved to the trash first
+	ErrNotDeleted = errors.New("todo must be deleted before it can be purged")
+	// ErrTitleTaken is returned when unique active titles are enforced and
+	// another uncompleted todo already has the title
+	ErrTitleTaken = errors.New("an uncompleted todo with this title already exists")
+	// ErrInvalidTransition is returned for status changes the workflow
+	// does not allow
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// statusTransitions lists the statuses each status can move to. Blocked
+// todos must be unblocked before they can be done, and done todos are
+// reopened rather than blocked.
+var statusTransitions = map[string][]string{
+	models.StatusTodo:       {models.StatusInProgress, models.StatusBlocked, models.StatusDone},
+	models.StatusInProgress: {models.StatusTodo, models.StatusBlocked, models.StatusDone},
+	models.StatusBlocked:    {models.StatusTodo, models.StatusInProgress},
+	models.StatusDone:       {models.StatusTodo, models.StatusInProgress},
+}
+
+// checkTransition returns ErrInvalidTransition unless the workflow allows
+// moving from one status to the other. Keeping the status is always
+// allowed.
+func checkTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+}
+
+// ConflictError is returned by UpdateTodo when the request names a version
+// that is no longer current. Current is the todo as stored.
+type ConflictError struct {
+	Current *models.Todo
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("todo %d has been modified, current version is %d", e.Current.ID, e.Current.Version)
+}
+
 type TodoService interface {
-	GetTodos(params models.QueryParams) (*models.PaginatedResponse, error)
-	GetTodoByID(id int) (*models.Todo, error)
-	CreateTodo(req models.CreateTodoRequest) (*models.Todo, error)
-	UpdateTodo(id int, req models.UpdateTodoRequest) (*models.Todo, error)
-	DeleteTodo(id int) error
-	GetTodoStats() (map[string]interface{}, error)
+	GetTodos(ctx context.Context, params models.QueryParams) (*models.PaginatedResponse, error)
+	GetTrash(ctx context.Context, params models.QueryParams) (*models.PaginatedResponse, error)
+	CountTodos(ctx context.Context, params models.QueryParams) (int, error)
+	StreamTodos(ctx context.Context, params models.QueryParams, fn func(models.Todo) error) error
+	SearchTodos(ctx context.Context, q string, page, perPage int) (*models.PaginatedResponse, error)
+	GetTodoByID(ctx context.Context, id int) (*models.Todo, error)
+	CreateTodo(ctx context.Context, req models.CreateTodoRequest) (*models.Todo, bool, error)
+	CreateTodos(ctx context.Context, reqs []models.CreateTodoRequest) ([]models.Todo, int, error)
+	QuickAddTodo(ctx context.Context, text string) (*models.Todo, error)
+	ImportTodos(ctx context.Context, rows []models.ImportRow, mode string, dryRun bool) (*models.ImportReport, error)
+	BulkUpdateTags(ctx context.Context, req models.BulkTagRequest) ([]models.Todo, error)
+	UpdateTodo(ctx context.Context, id int, req models.UpdateTodoRequest) (*models.Todo, error)
+	DeleteTodo(ctx context.Context, id int) error
+	RestoreTodo(ctx context.Context, id int) (*models.Todo, error)
+	PurgeTodo(ctx context.Context, id int) error
+	ListTodoRevisions(ctx context.Context, id int) ([]models.TodoRevision, error)
+	RevertTodo(ctx context.Context, id, revision int) (*models.Todo, error)
+	GetTodoStats(ctx context.Context) (map[string]interface{}, error)
+	GetTagStats(ctx context.Context) ([]models.TagStats, error)
+	GetEstimateStats(ctx context.Context, weeks int) (*models.EstimateStats, error)
+	GetNearbyTodos(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyTodo, error)
+	PurgeCompletedTodos(ctx context.Context, olderThan time.Duration) (int64, error)
+	PurgeTrash(ctx context.Context, olderThan time.Duration) (int64, error)
+	AnnounceDueSoon(ctx context.Context, within time.Duration) (int, error)
 }
 
 type todoService struct {
 	repo   repository.TodoRepository
+	uow    repository.UnitOfWork
 	logger *slog.Logger
 }
 
-func NewTodoService(repo repository.TodoRepository, logger *slog.Logger) TodoService {
+// NewTodoService creates the todo service. Reads go through repo while
+// mutations run in a unit of work so that the domain events they produce
+// are stored in the outbox in the same transaction.
+func NewTodoService(repo repository.TodoRepository, uow repository.UnitOfWork, logger *slog.Logger) TodoService {
 	return &todoService{
 		repo:   repo,
+		uow:    uow,
 		logger: logger,
 	}
 }
 
-func (s *todoService) GetTodos(params models.QueryParams) (*models.PaginatedResponse, error) {
-	s.logger.Info("Getting todos", "params", params)
+// log returns the request-scoped logger, which carries the request ID
+func (s *todoService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+const (
+	// maxTags is the most tags a todo can carry
+	maxTags = 20
+	// maxTagLength is the longest tag, in bytes
+	maxTagLength = 50
+	// maxEstimateMinutes is the largest estimate, one year
+	maxEstimateMinutes = 365 * 24 * 60
+	// maxEstimateWeeks is the most weeks estimate stats cover
+	maxEstimateWeeks = 52
+	// maxNearbyRadius is the largest proximity query radius, in meters
+	maxNearbyRadius = 100000
+	// maxNearbyTodos is the most todos a proximity query returns
+	maxNearbyTodos = 100
+)
+
+// maxIDs is the most todos that can be selected by ID in one request,
+// the same as the largest page
+const maxIDs = 100
+
+// ValidateTodoFilters checks list filters before an export starts
+// streaming
+func ValidateTodoFilters(params models.QueryParams) error {
+	return validateFilters(params)
+}
+
+// ValidateCreateTodo and ValidateUpdateTodo check a request the way
+// CreateTodo and UpdateTodo do, before it is queued to be applied later
+func ValidateCreateTodo(req models.CreateTodoRequest) error {
+	return (&todoService{}).validateCreateRequest(req)
+}
+
+func ValidateUpdateTodo(req models.UpdateTodoRequest) error {
+	return (&todoService{}).validateUpdateRequest(req)
+}
+
+// validateFilters rejects list filters that contradict each other
+func validateFilters(params models.QueryParams) error {
+	if len(params.IDs) > maxIDs || len(params.Exclude.IDs) > maxIDs {
+		return fmt.Errorf("%w: at most %d ids can be given at once", ErrInvalidFilter, maxIDs)
+	}
+
+	for _, status := range append(append([]string(nil), params.Status...), params.Exclude.Status...) {
+		if !models.IsValidStatus(status) {
+			return fmt.Errorf("%w: status must be one of %s", ErrInvalidFilter, strings.Join(models.TodoStatuses, ", "))
+		}
+	}
+
+	ranges := []struct {
+		name          string
+		after, before *time.Time
+	}{
+		{"completed", params.CompletedAfter, params.CompletedBefore},
+		{"created", params.CreatedAfter, params.CreatedBefore},
+		{"due", params.DueAfter, params.DueBefore},
+	}
+	for _, r := range ranges {
+		if r.after != nil && r.before != nil && !r.after.Before(*r.before) {
+			return fmt.Errorf("%w: %s_after must be before %s_before", ErrInvalidFilter, r.name, r.name)
+		}
+	}
+
+	switch params.Due {
+	case "", "any":
+	case "none":
+		if params.DueAfter != nil || params.DueBefore != nil {
+			return fmt.Errorf("%w: due=none cannot be combined with due_after or due_before", ErrInvalidFilter)
+		}
+	default:
+		return fmt.Errorf("%w: due must be none or any", ErrInvalidFilter)
+	}
+	switch params.Exclude.Due {
+	case "", "none", "any":
+	default:
+		return fmt.Errorf("%w: due!= must be none or any", ErrInvalidFilter)
+	}
+	return nil
+}
+
+func (s *todoService) GetTodos(ctx context.Context, params models.QueryParams) (*models.PaginatedResponse, error) {
+	s.log(ctx).Info("Getting todos", "params", params)
+
+	if err := validateFilters(params); err != nil {
+		return nil, err
+	}
 
 	// Validate and set defaults
 	if params.Page < 1 {
@@ -41,79 +219,203 @@ func (s *todoService) GetTodos(params models.QueryParams) (*models.PaginatedResp
 	if params.PerPage < 1 || params.PerPage > 100 {
 		params.PerPage = 20
 	}
-	if params.Sort == "" {
+	if len(params.IDs) > 0 {
+		// Every requested todo fits on one page
+		params.Page, params.PerPage = 1, len(params.IDs)
+	}
+	if params.Sort == "" && len(params.IDs) == 0 {
 		params.Sort = "created_at"
 	}
 	if params.Order == "" {
 		params.Order = "desc"
 	}
 
-	// Validate sort field
-	validSortFields := []string{"id", "title", "completed", "created_at", "updated_at"}
-	if !contains(validSortFields, params.Sort) {
-		return nil, fmt.Errorf("invalid sort field: %s", params.Sort)
+	todos, total, err := s.repo.GetAll(ctx, params)
+	if errors.Is(err, repository.ErrInvalidSortField) || errors.Is(err, repository.ErrInvalidSortOrder) {
+		return nil, err
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to get todos", "error", err)
+		return nil, fmt.Errorf("failed to get todos: %w", err)
+	}
+
+	response := &models.PaginatedResponse{
+		Data:    todos,
+		Page:    params.Page,
+		PerPage: params.PerPage,
+		HasMore: params.Page*params.PerPage < total,
+	}
+	if !params.SkipTotal {
+		response.Total = total
+		response.TotalPages = (total + params.PerPage - 1) / params.PerPage
+	}
+
+	s.log(ctx).Info("Retrieved todos successfully", "count", len(todos), "total", total)
+	return response, nil
+}
+
+// GetTrash returns a page of the todos in the trash, most recently deleted
+// first unless params sort otherwise
+func (s *todoService) GetTrash(ctx context.Context, params models.QueryParams) (*models.PaginatedResponse, error) {
+	params.Trashed = true
+	if params.Sort == "" && len(params.IDs) == 0 {
+		params.Sort = "deleted_at"
 	}
+	return s.GetTodos(ctx, params)
+}
 
-	// Validate order
-	if params.Order != "asc" && params.Order != "desc" {
-		return nil, fmt.Errorf("invalid order: %s", params.Order)
+// CountTodos returns the number of todos matching the filters in params.
+// Pagination and sorting are ignored.
+func (s *todoService) CountTodos(ctx context.Context, params models.QueryParams) (int, error) {
+	s.log(ctx).Info("Counting todos", "search", params.Search, "completed", params.Completed)
+
+	if err := validateFilters(params); err != nil {
+		return 0, err
 	}
 
-	todos, total, err := s.repo.GetAll(params)
+	total, err := s.repo.Count(ctx, params)
 	if err != nil {
-		s.logger.Error("Failed to get todos", "error", err)
-		return nil, fmt.Errorf("failed to get todos: %w", err)
+		s.log(ctx).Error("Failed to count todos", "error", err)
+		return 0, fmt.Errorf("failed to count todos: %w", err)
 	}
 
-	totalPages := (total + params.PerPage - 1) / params.PerPage
+	return total, nil
+}
+
+// StreamTodos calls fn for every todo matching the filters in params
+// without loading them all into memory. Pagination is ignored. fn must not
+// call back into the service.
+func (s *todoService) StreamTodos(ctx context.Context, params models.QueryParams, fn func(models.Todo) error) error {
+	if err := validateFilters(params); err != nil {
+		return err
+	}
+	if params.Sort == "" && len(params.IDs) == 0 {
+		params.Sort = "created_at"
+	}
+	if params.Order == "" {
+		params.Order = "desc"
+	}
 
-	response := &models.PaginatedResponse{
-		Data:       todos,
-		Total:      total,
-		Page:       params.Page,
-		PerPage:    params.PerPage,
-		TotalPages: totalPages,
+	err := s.repo.GetAllStream(ctx, params, fn)
+	if err != nil && !errors.Is(err, repository.ErrInvalidSortField) && !errors.Is(err, repository.ErrInvalidSortOrder) {
+		s.log(ctx).Error("Failed to stream todos", "error", err)
 	}
+	return err
+}
 
-	s.logger.Info("Retrieved todos successfully", "count", len(todos), "total", total)
-	return response, nil
+// SearchTodos runs a ranked full-text search over titles and descriptions
+func (s *todoService) SearchTodos(ctx context.Context, q string, page, perPage int) (*models.PaginatedResponse, error) {
+	s.log(ctx).Info("Searching todos", "q", q)
+
+	if !strings.ContainsFunc(q, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+		return nil, ErrEmptySearch
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	results, total, err := s.repo.Search(ctx, q, perPage, (page-1)*perPage)
+	if err != nil {
+		s.log(ctx).Error("Failed to search todos", "error", err)
+		return nil, fmt.Errorf("failed to search todos: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Data:       results,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: (total + perPage - 1) / perPage,
+		HasMore:    page*perPage < total,
+	}, nil
 }
 
-func (s *todoService) GetTodoByID(id int) (*models.Todo, error) {
-	s.logger.Info("Getting todo by ID", "id", id)
+func (s *todoService) GetTodoByID(ctx context.Context, id int) (*models.Todo, error) {
+	s.log(ctx).Info("Getting todo by ID", "id", id)
 
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid todo ID: %d", id)
 	}
 
-	todo, err := s.repo.GetByID(id)
+	todo, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to get todo by ID", "id", id, "error", err)
+		s.log(ctx).Error("Failed to get todo by ID", "id", id, "error", err)
 		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
 
 	if todo == nil {
-		s.logger.Warn("Todo not found", "id", id)
+		s.log(ctx).Warn("Todo not found", "id", id)
 		return nil, nil
 	}
 
-	s.logger.Info("Retrieved todo successfully", "id", id, "title", todo.Title)
+	s.log(ctx).Info("Retrieved todo successfully", "id", id, "title", todo.Title)
 	return todo, nil
 }
 
-func (s *todoService) CreateTodo(req models.CreateTodoRequest) (*models.Todo, error) {
-	s.logger.Info("Creating todo", "title", req.Title)
+// CreateTodo creates a todo and reports whether it was created. When the
+// request carries a client ID that was already used, the existing todo is
+// returned instead and created is false.
+func (s *todoService) CreateTodo(ctx context.Context, req models.CreateTodoRequest) (*models.Todo, bool, error) {
+	s.log(ctx).Info("Creating todo", "title", req.Title)
 
-	// Validate request
+	todo, err := s.newTodo(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created := true
+	err = s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		stored, isNew, err := s.insertTodo(ctx, tx, todo)
+		if err != nil {
+			return err
+		}
+		todo, created = stored, isNew
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicateTitle) {
+		return nil, false, ErrTitleTaken
+	}
+	if errors.Is(err, repository.ErrDuplicateClientID) {
+		// A concurrent request with the same client ID won the race
+		todo, err = s.repo.GetByClientID(ctx, *todo.ClientID)
+		created = false
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to create todo", "error", err)
+		return nil, false, fmt.Errorf("failed to create todo: %w", err)
+	}
+
+	if !created {
+		s.log(ctx).Info("Todo with client ID already exists", "id", todo.ID, "client_id", *todo.ClientID)
+		return todo, false, nil
+	}
+
+	s.log(ctx).Info("Created todo successfully", "id", todo.ID, "title", todo.Title)
+	return todo, true, nil
+}
+
+// newTodo validates a create request and builds the todo to store
+func (s *todoService) newTodo(req models.CreateTodoRequest) (*models.Todo, error) {
 	if err := s.validateCreateRequest(req); err != nil {
 		return nil, err
 	}
 
+	status := models.StatusTodo
+	if req.Status != nil {
+		status = *req.Status
+	} else if req.Completed {
+		status = models.StatusDone
+	}
+
 	// Create todo model
 	todo := &models.Todo{
 		Title:       strings.TrimSpace(req.Title),
 		Description: req.Description,
-		Completed:   req.Completed,
+		Completed:   status == models.StatusDone,
+		Status:      status,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -128,37 +430,92 @@ func (s *todoService) CreateTodo(req models.CreateTodoRequest) (*models.Todo, er
 		}
 	}
 
-	if err := s.repo.Create(todo); err != nil {
-		s.logger.Error("Failed to create todo", "error", err)
-		return nil, fmt.Errorf("failed to create todo: %w", err)
+	if req.DueDate != nil && *req.DueDate != "" {
+		dueDate, _ := models.ParseTime(*req.DueDate)
+		todo.DueDate = &dueDate
+	}
+
+	todo.Tags = normalizeTags(req.Tags)
+
+	if req.Color != nil {
+		color, _ := models.NormalizeColor(*req.Color)
+		todo.Color = &color
+	}
+
+	if req.Icon != nil {
+		icon, _ := models.NormalizeIcon(*req.Icon)
+		todo.Icon = &icon
+	}
+
+	if req.EstimateMinutes != nil && *req.EstimateMinutes > 0 {
+		todo.EstimateMinutes = req.EstimateMinutes
+	}
+
+	todo.Location = req.Location
+	todo.Priority = req.Priority
+
+	if req.Metadata != nil && !isJSONNull(req.Metadata) {
+		object, _ := decodeMetadata(req.Metadata)
+		metadata, err := encodeMetadata(object)
+		if err != nil {
+			return nil, err
+		}
+		todo.Metadata = metadata
+	}
+
+	// Store client IDs in canonical form so that differently formatted
+	// copies of the same UUID match
+	if req.ClientID != nil {
+		clientID := uuid.MustParse(*req.ClientID).String()
+		todo.ClientID = &clientID
 	}
 
-	s.logger.Info("Created todo successfully", "id", todo.ID, "title", todo.Title)
 	return todo, nil
 }
 
-func (s *todoService) UpdateTodo(id int, req models.UpdateTodoRequest) (*models.Todo, error) {
-	s.logger.Info("Updating todo", "id", id)
+// insertTodo stores a todo built by newTodo inside tx and records its
+// created event. A todo whose client ID is already taken is not stored;
+// the existing one is returned with created false.
+func (s *todoService) insertTodo(ctx context.Context, tx repository.TxRepositories, todo *models.Todo) (*models.Todo, bool, error) {
+	if todo.ClientID != nil {
+		existing, err := tx.Todos.GetByClientID(ctx, *todo.ClientID)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			return existing, false, nil
+		}
+	}
 
-	if id <= 0 {
-		return nil, fmt.Errorf("invalid todo ID: %d", id)
+	if err := tx.Todos.Create(ctx, todo); err != nil {
+		return nil, false, err
 	}
+	return todo, true, recordEvent(tx, events.New(events.TodoCreated, todo))
+}
 
-	// Validate request
-	if err := s.validateUpdateRequest(req); err != nil {
+// QuickAddTodo creates a todo from a single line of text, see
+// parseQuickAdd
+func (s *todoService) QuickAddTodo(ctx context.Context, text string) (*models.Todo, error) {
+	req, err := parseQuickAdd(text, time.Now())
+	if err != nil {
 		return nil, err
 	}
+	s.log(ctx).Info("Parsed quick-add text", "title", req.Title, "tags", req.Tags, "due_date", req.DueDate)
 
-	// Check if todo exists
-	exists, err := s.repo.Exists(id)
-	if err != nil {
-		s.logger.Error("Failed to check todo existence", "id", id, "error", err)
-		return nil, fmt.Errorf("failed to check todo existence: %w", err)
+	todo, _, err := s.CreateTodo(ctx, req)
+	return todo, err
+}
+
+func (s *todoService) UpdateTodo(ctx context.Context, id int, req models.UpdateTodoRequest) (*models.Todo, error) {
+	s.log(ctx).Info("Updating todo", "id", id)
+
+	if id <= 0 {
+		return nil, fmt.Errorf("invalid todo ID: %d", id)
 	}
 
-	if !exists {
-		s.logger.Warn("Todo not found for update", "id", id)
-		return nil, nil
+	// Validate request
+	if err := s.validateUpdateRequest(req); err != nil {
+		return nil, err
 	}
 
 	// Build updates map
@@ -182,81 +539,564 @@ func (s *todoService) UpdateTodo(id int, req models.UpdateTodoRequest) (*models.
 		updates["completed"] = *req.Completed
 	}
 
+	if req.DueDate != nil {
+		if *req.DueDate == "" {
+			updates["due_date"] = nil
+		} else {
+			dueDate, _ := models.ParseTime(*req.DueDate)
+			updates["due_date"] = dueDate
+		}
+	}
+
+	if req.Tags != nil {
+		updates["tags"] = normalizeTags(*req.Tags)
+	}
+
+	if req.Color != nil {
+		if *req.Color == "" {
+			updates["color"] = nil
+		} else {
+			color, _ := models.NormalizeColor(*req.Color)
+			updates["color"] = color
+		}
+	}
+
+	if req.Icon != nil {
+		if *req.Icon == "" {
+			updates["icon"] = nil
+		} else {
+			icon, _ := models.NormalizeIcon(*req.Icon)
+			updates["icon"] = icon
+		}
+	}
+
+	if req.EstimateMinutes != nil {
+		if *req.EstimateMinutes == 0 {
+			updates["estimate_minutes"] = nil
+		} else {
+			updates["estimate_minutes"] = *req.EstimateMinutes
+		}
+	}
+
+	if req.Priority != nil {
+		if *req.Priority == "" {
+			updates["priority"] = nil
+		} else {
+			updates["priority"] = *req.Priority
+		}
+	}
+
+	if req.Location != nil {
+		if location, _ := decodeLocation(req.Location); location == nil {
+			updates["latitude"], updates["longitude"] = nil, nil
+		} else {
+			updates["latitude"], updates["longitude"] = location.Lat, location.Lng
+		}
+	}
+
 	// Perform update
-	todo, err := s.repo.Update(id, updates)
+	var todo *models.Todo
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		// Load the current state so that transitions can be detected
+		existing, err := tx.Todos.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to check todo existence: %w", err)
+		}
+		if existing == nil {
+			return nil
+		}
+
+		// Completing and reopening move the status too
+		status := existing.Status
+		if req.Status != nil {
+			status = *req.Status
+		} else if req.Completed != nil && *req.Completed {
+			status = models.StatusDone
+		} else if req.Completed != nil && existing.Status == models.StatusDone {
+			status = models.StatusTodo
+		}
+		if err := checkTransition(existing.Status, status); err != nil {
+			return err
+		}
+		if status != existing.Status {
+			updates["status"] = status
+			updates["completed"] = status == models.StatusDone
+		}
+
+		if req.Metadata != nil {
+			metadata, err := mergeMetadata(existing.Metadata, req.Metadata)
+			if err != nil {
+				return err
+			}
+			if metadata == nil {
+				updates["metadata"] = nil
+			} else {
+				updates["metadata"] = string(metadata)
+			}
+		}
+
+		if req.Version == nil {
+			todo, err = tx.Todos.Update(ctx, id, updates)
+		} else {
+			todo, err = tx.Todos.UpdateIfVersion(ctx, id, *req.Version, updates)
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			current, err := tx.Todos.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			return &ConflictError{Current: current}
+		}
+		if err != nil {
+			return err
+		}
+		return s.recordUpdateEvents(tx, existing, todo)
+	})
+	if errors.Is(err, repository.ErrDuplicateTitle) {
+		return nil, ErrTitleTaken
+	}
+	if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrMetadataTooLarge) {
+		return nil, err
+	}
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		s.log(ctx).Warn("Todo update conflicts with a newer version", "id", id, "version", *req.Version, "current_version", conflict.Current.Version)
+		return nil, err
+	}
 	if err != nil {
-		s.logger.Error("Failed to update todo", "id", id, "error", err)
+		s.log(ctx).Error("Failed to update todo", "id", id, "error", err)
 		return nil, fmt.Errorf("failed to update todo: %w", err)
 	}
 
-	s.logger.Info("Updated todo successfully", "id", id)
+	if todo == nil {
+		s.log(ctx).Warn("Todo not found for update", "id", id)
+		return nil, nil
+	}
+
+	s.log(ctx).Info("Updated todo successfully", "id", id)
 	return todo, nil
 }
 
-func (s *todoService) DeleteTodo(id int) error {
-	s.logger.Info("Deleting todo", "id", id)
+func (s *todoService) DeleteTodo(ctx context.Context, id int) error {
+	s.log(ctx).Info("Deleting todo", "id", id)
 
 	if id <= 0 {
 		return fmt.Errorf("invalid todo ID: %d", id)
 	}
 
 	// Check if todo exists
-	exists, err := s.repo.Exists(id)
+	exists, err := s.repo.Exists(ctx, id)
 	if err != nil {
-		s.logger.Error("Failed to check todo existence", "id", id, "error", err)
+		s.log(ctx).Error("Failed to check todo existence", "id", id, "error", err)
 		return fmt.Errorf("failed to check todo existence: %w", err)
 	}
 
 	if !exists {
-		s.logger.Warn("Todo not found for deletion", "id", id)
+		s.log(ctx).Warn("Todo not found for deletion", "id", id)
 		return fmt.Errorf("todo with id %d not found", id)
 	}
 
-	if err := s.repo.Delete(id); err != nil {
-		s.logger.Error("Failed to delete todo", "id", id, "error", err)
+	err = s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		if err := tx.Todos.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(tx, events.Event{
+			Type:       events.TodoDeleted,
+			TodoID:     id,
+			OccurredAt: time.Now().UTC(),
+		})
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to delete todo", "id", id, "error", err)
 		return fmt.Errorf("failed to delete todo: %w", err)
 	}
 
-	s.logger.Info("Deleted todo successfully", "id", id)
+	s.log(ctx).Info("Deleted todo successfully", "id", id)
 	return nil
 }
 
-func (s *todoService) GetTodoStats() (map[string]interface{}, error) {
-	s.logger.Info("Getting todo statistics")
+// RestoreTodo takes a todo out of the trash. The restored todo must still
+// satisfy the title rules, since another todo may have taken its title in
+// the meantime.
+func (s *todoService) RestoreTodo(ctx context.Context, id int) (*models.Todo, error) {
+	s.log(ctx).Info("Restoring todo", "id", id)
+
+	var todo *models.Todo
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		var err error
+		todo, err = tx.Todos.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		if todo == nil {
+			return ErrNotInTrash
+		}
+		return recordEvent(tx, events.New(events.TodoRestored, todo))
+	})
+	if errors.Is(err, repository.ErrDuplicateTitle) {
+		return nil, ErrTitleTaken
+	}
+	if errors.Is(err, ErrNotInTrash) {
+		return nil, err
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to restore todo", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to restore todo: %w", err)
+	}
+
+	s.log(ctx).Info("Restored todo successfully", "id", id)
+	return todo, nil
+}
+
+// PurgeTodo permanently deletes a todo from the trash, together with
+// everything stored for it. Todos outside the trash are refused so that a
+// single request can never destroy a live todo.
+func (s *todoService) PurgeTodo(ctx context.Context, id int) error {
+	s.log(ctx).Info("Purging todo", "id", id)
 
-	// Get all todos to calculate stats
-	params := models.QueryParams{
-		Page:    1,
-		PerPage: 1000, // Get a large number to get all todos
-		Sort:    "created_at",
-		Order:   "desc",
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		purged, err := tx.Todos.Purge(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !purged {
+			exists, err := tx.Todos.Exists(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to check todo existence: %w", err)
+			}
+			if exists {
+				return ErrNotDeleted
+			}
+			return ErrNotInTrash
+		}
+		return recordEvent(tx, events.Event{
+			Type:       events.TodoPurged,
+			TodoID:     id,
+			OccurredAt: time.Now().UTC(),
+		})
+	})
+	if errors.Is(err, ErrNotInTrash) || errors.Is(err, ErrNotDeleted) {
+		return err
 	}
+	if err != nil {
+		s.log(ctx).Error("Failed to purge todo", "id", id, "error", err)
+		return fmt.Errorf("failed to purge todo: %w", err)
+	}
+
+	s.log(ctx).Info("Purged todo successfully", "id", id)
+	return nil
+}
+
+// ListTodoRevisions returns the edit history of a todo, newest first
+func (s *todoService) ListTodoRevisions(ctx context.Context, id int) ([]models.TodoRevision, error) {
+	s.log(ctx).Info("Listing todo revisions", "id", id)
 
-	response, err := s.GetTodos(params)
+	revisions, err := s.repo.ListRevisions(ctx, id)
 	if err != nil {
+		s.log(ctx).Error("Failed to list todo revisions", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to list todo revisions: %w", err)
+	}
+
+	// Every existing todo has at least its initial revision
+	if len(revisions) == 0 {
+		return nil, ErrTodoNotFound
+	}
+
+	return revisions, nil
+}
+
+// RevertTodo restores the title, description and completion of an earlier
+// revision. The revert is an ordinary update: it emits the usual events
+// and is itself recorded as a new revision.
+func (s *todoService) RevertTodo(ctx context.Context, id, revision int) (*models.Todo, error) {
+	s.log(ctx).Info("Reverting todo", "id", id, "revision", revision)
+
+	var todo *models.Todo
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		existing, err := tx.Todos.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to check todo existence: %w", err)
+		}
+		if existing == nil {
+			return ErrTodoNotFound
+		}
+
+		rev, err := tx.Todos.GetRevision(ctx, id, revision)
+		if err != nil {
+			return err
+		}
+		if rev == nil {
+			return ErrRevisionNotFound
+		}
+
+		updates := map[string]interface{}{
+			"title":       rev.Title,
+			"description": rev.Description,
+		}
+		// Restoring the completion moves the status as UpdateTodo would,
+		// within the same workflow
+		if rev.Completed != existing.Completed {
+			status := models.StatusTodo
+			if rev.Completed {
+				status = models.StatusDone
+			}
+			if err := checkTransition(existing.Status, status); err != nil {
+				return err
+			}
+			updates["status"] = status
+			updates["completed"] = rev.Completed
+		}
+
+		todo, err = tx.Todos.Update(ctx, id, updates)
+		if err != nil {
+			return err
+		}
+		return s.recordUpdateEvents(tx, existing, todo)
+	})
+	if errors.Is(err, repository.ErrDuplicateTitle) {
+		return nil, ErrTitleTaken
+	}
+	if errors.Is(err, ErrTodoNotFound) || errors.Is(err, ErrRevisionNotFound) || errors.Is(err, ErrInvalidTransition) {
 		return nil, err
 	}
+	if err != nil {
+		s.log(ctx).Error("Failed to revert todo", "id", id, "revision", revision, "error", err)
+		return nil, fmt.Errorf("failed to revert todo: %w", err)
+	}
+
+	s.log(ctx).Info("Reverted todo successfully", "id", id, "revision", revision)
+	return todo, nil
+}
+
+func (s *todoService) GetTodoStats(ctx context.Context) (map[string]interface{}, error) {
+	s.log(ctx).Info("Getting todo statistics")
+
+	total, err := s.repo.Count(ctx, models.QueryParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo statistics: %w", err)
+	}
+	done := true
+	completed, err := s.repo.Count(ctx, models.QueryParams{Completed: &done})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo statistics: %w", err)
+	}
 
-	todos := response.Data.([]models.Todo)
-	
 	stats := map[string]interface{}{
-		"total_todos":     response.Total,
-		"completed_todos": 0,
-		"pending_todos":   0,
+		"total_todos":     total,
+		"completed_todos": completed,
+		"pending_todos":   total - completed,
 	}
 
-	for _, todo := range todos {
-		if todo.Completed {
-			stats["completed_todos"] = stats["completed_todos"].(int) + 1
-		} else {
-			stats["pending_todos"] = stats["pending_todos"].(int) + 1
+	s.log(ctx).Info("Retrieved todo statistics", "stats", stats)
+	return stats, nil
+}
+
+// GetTagStats returns the open and completed todo counts of every tag in
+// use
+func (s *todoService) GetTagStats(ctx context.Context) ([]models.TagStats, error) {
+	s.log(ctx).Info("Getting tag statistics")
+
+	stats, err := s.repo.TagStats(ctx)
+	if err != nil {
+		s.log(ctx).Error("Failed to get tag statistics", "error", err)
+		return nil, fmt.Errorf("failed to get tag statistics: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetEstimateStats reports the estimates of the todos created and
+// completed in each of the last weeks, the current week included, and of
+// the work still open. Weeks without activity are reported with zeros.
+func (s *todoService) GetEstimateStats(ctx context.Context, weeks int) (*models.EstimateStats, error) {
+	s.log(ctx).Info("Getting estimate statistics", "weeks", weeks)
+
+	if weeks < 1 || weeks > maxEstimateWeeks {
+		return nil, fmt.Errorf("%w: weeks must be between 1 and %d", ErrInvalidFilter, maxEstimateWeeks)
+	}
+
+	// Weeks start on Monday, in UTC like the stored timestamps
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	since := monday.AddDate(0, 0, -7*(weeks-1))
+
+	stats, err := s.repo.EstimateStats(ctx, since)
+	if err != nil {
+		s.log(ctx).Error("Failed to get estimate statistics", "error", err)
+		return nil, fmt.Errorf("failed to get estimate statistics: %w", err)
+	}
+
+	byWeek := make(map[string]models.WeeklyEstimate, len(stats.Weeks))
+	for _, week := range stats.Weeks {
+		byWeek[week.WeekStart] = week
+	}
+	stats.Weeks = make([]models.WeeklyEstimate, weeks)
+	for i := range stats.Weeks {
+		start := since.AddDate(0, 0, 7*i).Format("2006-01-02")
+		week, ok := byWeek[start]
+		if !ok {
+			week = models.WeeklyEstimate{WeekStart: start}
 		}
+		stats.Weeks[i] = week
 	}
 
-	s.logger.Info("Retrieved todo statistics", "stats", stats)
 	return stats, nil
 }
 
+// GetNearbyTodos returns the open todos within radius meters of center,
+// nearest first. A bounding box narrows the candidates in the database;
+// the exact distance is computed here.
+func (s *todoService) GetNearbyTodos(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyTodo, error) {
+	s.log(ctx).Info("Getting nearby todos", "lat", center.Lat, "lng", center.Lng, "radius", radius)
+
+	if err := center.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if !(radius > 0 && radius <= maxNearbyRadius) {
+		return nil, fmt.Errorf("%w: radius must be between 1 and %d meters", ErrInvalidFilter, maxNearbyRadius)
+	}
+	if limit < 1 || limit > maxNearbyTodos {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxNearbyTodos)
+	}
+
+	candidates, err := s.repo.Nearby(ctx, center.BoundingBox(radius))
+	if err != nil {
+		s.log(ctx).Error("Failed to get nearby todos", "error", err)
+		return nil, fmt.Errorf("failed to get nearby todos: %w", err)
+	}
+
+	nearby := make([]models.NearbyTodo, 0, len(candidates))
+	for _, todo := range candidates {
+		distance := center.DistanceTo(*todo.Location)
+		if distance <= radius {
+			nearby = append(nearby, models.NearbyTodo{Todo: todo, DistanceMeters: math.Round(distance*10) / 10})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].DistanceMeters < nearby[j].DistanceMeters
+	})
+	if len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+
+	return nearby, nil
+}
+
+func (s *todoService) PurgeCompletedTodos(ctx context.Context, olderThan time.Duration) (int64, error) {
+	s.log(ctx).Info("Purging completed todos", "older_than", olderThan.String())
+
+	if olderThan <= 0 {
+		return 0, fmt.Errorf("invalid purge retention: %s", olderThan)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var purged int64
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		ids, err := tx.Todos.DeleteCompletedBefore(ctx, cutoff)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		purged = int64(len(ids))
+		for _, id := range ids {
+			if err := recordEvent(tx, events.Event{
+				Type:       events.TodoPurged,
+				TodoID:     id,
+				OccurredAt: time.Now().UTC(),
+			}); err != nil {
+				return err
+			}
+		}
+		return recordEvent(tx, events.Event{
+			Type:       events.TodosPurged,
+			Data:       map[string]interface{}{"count": purged, "cutoff": cutoff.UTC()},
+			OccurredAt: time.Now().UTC(),
+		})
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to purge completed todos", "error", err)
+		return 0, fmt.Errorf("failed to purge completed todos: %w", err)
+	}
+
+	s.log(ctx).Info("Purged completed todos successfully", "count", purged, "cutoff", cutoff)
+	return purged, nil
+}
+
+// PurgeTrash permanently deletes the todos that have been in the trash
+// for longer than olderThan
+func (s *todoService) PurgeTrash(ctx context.Context, olderThan time.Duration) (int64, error) {
+	s.log(ctx).Info("Purging trash", "older_than", olderThan.String())
+
+	if olderThan <= 0 {
+		return 0, fmt.Errorf("invalid trash retention: %s", olderThan)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var purged int64
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		var err error
+		purged, err = tx.Todos.PurgeDeletedBefore(ctx, cutoff)
+		if err != nil || purged == 0 {
+			return err
+		}
+		return recordEvent(tx, events.Event{
+			Type:       events.TrashPurged,
+			Data:       map[string]interface{}{"count": purged, "cutoff": cutoff.UTC()},
+			OccurredAt: time.Now().UTC(),
+		})
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to purge trash", "error", err)
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+
+	s.log(ctx).Info("Purged trash successfully", "count", purged, "cutoff", cutoff)
+	return purged, nil
+}
+
+// recordEvent stores a domain event in the outbox as part of the current
+// transaction
+func recordEvent(tx repository.TxRepositories, evt events.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", evt.Type, err)
+	}
+
+	return tx.Outbox.Add(&models.OutboxMessage{
+		EventType: evt.Type,
+		Payload:   payload,
+	})
+}
+
+// recordUpdateEvents stores todo.updated plus a completion transition event
+// when the completed flag changed
+func (s *todoService) recordUpdateEvents(tx repository.TxRepositories, before, after *models.Todo) error {
+	if after == nil {
+		return nil
+	}
+
+	if err := recordEvent(tx, events.New(events.TodoUpdated, after)); err != nil {
+		return err
+	}
+
+	if !before.Completed && after.Completed {
+		return recordEvent(tx, events.New(events.TodoCompleted, after))
+	}
+	if before.Completed && !after.Completed {
+		return recordEvent(tx, events.New(events.TodoReopened, after))
+	}
+
+	// Moves between open statuses; completing and reopening are covered
+	// above
+	if before.Status != after.Status {
+		evt := events.New(events.TodoStatusChanged, after)
+		evt.Data = map[string]interface{}{"from": before.Status}
+		return recordEvent(tx, evt)
+	}
+
+	return nil
+}
+
 func (s *todoService) validateCreateRequest(req models.CreateTodoRequest) error {
 	if strings.TrimSpace(req.Title) == "" {
 		return fmt.Errorf("title is required")
@@ -270,7 +1110,58 @@ func (s *todoService) validateCreateRequest(req models.CreateTodoRequest) error
 		return fmt.Errorf("description cannot exceed 1000 characters")
 	}
 
-	return nil
+	if req.ClientID != nil {
+		if _, err := uuid.Parse(*req.ClientID); err != nil {
+			return fmt.Errorf("client_id must be a UUID")
+		}
+	}
+
+	if err := validateTags(req.Tags); err != nil {
+		return err
+	}
+
+	if err := validateMetadata(req.Metadata); err != nil {
+		return err
+	}
+
+	if req.Color != nil {
+		if _, err := models.NormalizeColor(*req.Color); err != nil {
+			return err
+		}
+	}
+
+	if req.Icon != nil {
+		if _, err := models.NormalizeIcon(*req.Icon); err != nil {
+			return err
+		}
+	}
+
+	if err := validateEstimate(req.EstimateMinutes); err != nil {
+		return err
+	}
+
+	if req.Location != nil {
+		if err := req.Location.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if req.Priority != nil {
+		if err := validatePriority(*req.Priority); err != nil {
+			return err
+		}
+	}
+
+	// An omitted completed is false, so only a true one can disagree
+	var completed *bool
+	if req.Completed {
+		completed = &req.Completed
+	}
+	if err := validateStatus(req.Status, completed); err != nil {
+		return err
+	}
+
+	return validateDueDate(req.DueDate)
 }
 
 func (s *todoService) validateUpdateRequest(req models.UpdateTodoRequest) error {
@@ -287,14 +1178,149 @@ func (s *todoService) validateUpdateRequest(req models.UpdateTodoRequest) error
 		return fmt.Errorf("description cannot exceed 1000 characters")
 	}
 
+	if req.Tags != nil {
+		if err := validateTags(*req.Tags); err != nil {
+			return err
+		}
+	}
+
+	if err := validateStatus(req.Status, req.Completed); err != nil {
+		return err
+	}
+
+	if err := validateMetadata(req.Metadata); err != nil {
+		return err
+	}
+
+	if req.Color != nil && *req.Color != "" {
+		if _, err := models.NormalizeColor(*req.Color); err != nil {
+			return err
+		}
+	}
+
+	if req.Icon != nil && *req.Icon != "" {
+		if _, err := models.NormalizeIcon(*req.Icon); err != nil {
+			return err
+		}
+	}
+
+	if err := validateEstimate(req.EstimateMinutes); err != nil {
+		return err
+	}
+
+	if req.Location != nil {
+		if _, err := decodeLocation(req.Location); err != nil {
+			return err
+		}
+	}
+
+	if req.Priority != nil && *req.Priority != "" {
+		if err := validatePriority(*req.Priority); err != nil {
+			return err
+		}
+	}
+
+	return validateDueDate(req.DueDate)
+}
+
+func validatePriority(priority string) error {
+	if !models.IsValidPriority(priority) {
+		return fmt.Errorf("priority must be one of %s", strings.Join(models.TodoPriorities, ", "))
+	}
 	return nil
 }
 
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
+// decodeLocation parses a location given in an update. null decodes to
+// nil, meaning no location.
+func decodeLocation(raw json.RawMessage) (*models.Location, error) {
+	if isJSONNull(raw) {
+		return nil, nil
+	}
+
+	var location struct {
+		Lat *float64 `json:"lat"`
+		Lng *float64 `json:"lng"`
+	}
+	if err := json.Unmarshal(raw, &location); err != nil || location.Lat == nil || location.Lng == nil {
+		return nil, fmt.Errorf("location must be an object with lat and lng")
+	}
+
+	decoded := &models.Location{Lat: *location.Lat, Lng: *location.Lng}
+	if err := decoded.Validate(); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// validateEstimate checks that an estimate, when given, is within range.
+// Zero is accepted and means no estimate.
+func validateEstimate(minutes *int) error {
+	if minutes == nil {
+		return nil
+	}
+	if *minutes < 0 || *minutes > maxEstimateMinutes {
+		return fmt.Errorf("estimate_minutes must be between 0 and %d", maxEstimateMinutes)
+	}
+	return nil
+}
+
+// validateStatus checks that status, when given, is valid and agrees
+// with completed
+func validateStatus(status *string, completed *bool) error {
+	if status == nil {
+		return nil
+	}
+	if !models.IsValidStatus(*status) {
+		return fmt.Errorf("status must be one of %s", strings.Join(models.TodoStatuses, ", "))
+	}
+	if completed != nil && *completed != (*status == models.StatusDone) {
+		return fmt.Errorf("completed must be true exactly when status is %s", models.StatusDone)
+	}
+	return nil
+}
+
+// validateTags checks tags in their normalized form. Commas are rejected
+// because the repository joins tags with them.
+func validateTags(tags []string) error {
+	if len(normalizeTags(tags)) > maxTags {
+		return fmt.Errorf("a todo cannot have more than %d tags", maxTags)
+	}
+	for _, tag := range tags {
+		tag = models.NormalizeTag(tag)
+		if tag == "" {
+			return fmt.Errorf("tags cannot be empty")
+		}
+		if len(tag) > maxTagLength {
+			return fmt.Errorf("tags cannot exceed %d characters", maxTagLength)
+		}
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("tags cannot contain commas")
 		}
 	}
-	return false
-}
\ No newline at end of file
+	return nil
+}
+
+// normalizeTags returns the tags normalized, sorted and without duplicates
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = models.NormalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func validateDueDate(dueDate *string) error {
+	if dueDate == nil || *dueDate == "" {
+		return nil
+	}
+	if _, err := models.ParseTime(*dueDate); err != nil {
+		return fmt.Errorf("due_date %w", err)
+	}
+	return nil
+}
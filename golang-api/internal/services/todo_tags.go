@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/centroidsol/todo-api/internal/events"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// maxBulkTodos caps the todos changed by a single bulk request
+const maxBulkTodos = 500
+
+// ErrInvalidBulkRequest is returned for bulk requests that cannot be
+// applied, such as one without IDs
+var ErrInvalidBulkRequest = errors.New("invalid bulk request")
+
+// BulkUpdateTags adds and removes tags on many todos in one transaction and
+// returns the todos whose tags changed. Every ID must be a live todo. A
+// tag cannot be both added and removed.
+func (s *todoService) BulkUpdateTags(ctx context.Context, req models.BulkTagRequest) ([]models.Todo, error) {
+	s.log(ctx).Info("Bulk updating tags", "todos", len(req.IDs), "add", req.Add, "remove", req.Remove)
+
+	if err := ValidateBulkTags(req); err != nil {
+		return nil, err
+	}
+	add, remove := normalizeTags(req.Add), normalizeTags(req.Remove)
+
+	var todos []models.Todo
+	err := s.uow.Do(ctx, func(tx repository.TxRepositories) error {
+		missing, err := tx.Todos.MissingIDs(ctx, req.IDs)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: %v", ErrTodoNotFound, missing)
+		}
+
+		changed, err := tx.Todos.UpdateTags(ctx, req.IDs, add, remove)
+		if err != nil {
+			return err
+		}
+
+		todos = make([]models.Todo, 0, len(changed))
+		for _, id := range changed {
+			todo, err := tx.Todos.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if len(todo.Tags) > maxTags {
+				return fmt.Errorf("%w: todo %d would have more than %d tags", ErrInvalidBulkRequest, id, maxTags)
+			}
+			if err := recordEvent(tx, events.New(events.TodoUpdated, todo)); err != nil {
+				return err
+			}
+			todos = append(todos, *todo)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrTodoNotFound) || errors.Is(err, ErrInvalidBulkRequest) {
+		return nil, err
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to bulk update tags", "error", err)
+		return nil, fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	s.log(ctx).Info("Bulk updated tags successfully", "changed", len(todos))
+	return todos, nil
+}
+
+// ValidateBulkTags checks a bulk tag request without looking at the todos
+// it names
+func ValidateBulkTags(req models.BulkTagRequest) error {
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkTodos {
+		return fmt.Errorf("%w: ids must list between 1 and %d todos", ErrInvalidBulkRequest, maxBulkTodos)
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		return fmt.Errorf("%w: add or remove at least one tag", ErrInvalidBulkRequest)
+	}
+	for _, tags := range [][]string{req.Add, req.Remove} {
+		if err := validateTags(tags); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBulkRequest, err)
+		}
+	}
+
+	removing := make(map[string]bool, len(req.Remove))
+	for _, tag := range normalizeTags(req.Remove) {
+		removing[tag] = true
+	}
+	for _, tag := range normalizeTags(req.Add) {
+		if removing[tag] {
+			return fmt.Errorf("%w: tag %q is both added and removed", ErrInvalidBulkRequest, tag)
+		}
+	}
+	return nil
+}
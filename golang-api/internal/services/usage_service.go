@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+)
+
+// ErrInvalidUsageRange is returned when a usage query ends before it
+// starts
+var ErrInvalidUsageRange = errors.New("from must not be after to")
+
+const (
+	// usageDay formats the UTC days usage is counted by
+	usageDay = "2006-01-02"
+	// maxUsageReportUsers is the most users a usage report lists
+	maxUsageReportUsers = 1000
+)
+
+// UsageService meters API calls per user and API key, for billing and
+// capacity planning
+type UsageService interface {
+	// Record counts a call by the user, with the API key or, for nil, an
+	// access token. A failure is logged rather than returned, so metering
+	// never fails the call it counts.
+	Record(ctx context.Context, userID int, apiKeyID *int)
+	// ForUser returns the user's calls in the query's days and what they
+	// store now
+	ForUser(ctx context.Context, userID int, query models.UsageQuery) (*models.Usage, error)
+	// Report totals every user's calls in the query's days and lists the
+	// limit users with the most calls
+	Report(ctx context.Context, query models.UsageQuery, limit int) (*models.UsageReport, error)
+}
+
+type usageService struct {
+	repo   repository.UsageRepository
+	logger *slog.Logger
+}
+
+func NewUsageService(repo repository.UsageRepository, logger *slog.Logger) UsageService {
+	return &usageService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *usageService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *usageService) Record(ctx context.Context, userID int, apiKeyID *int) {
+	if err := s.repo.Increment(ctx, userID, apiKeyID, time.Now().UTC().Format(usageDay)); err != nil {
+		s.log(ctx).Error("Failed to record usage", "user_id", userID, "error", err)
+	}
+}
+
+// usageDays returns the first and last day of query, defaulting to the
+// current calendar month so far
+func usageDays(query models.UsageQuery) (string, string, error) {
+	now := time.Now().UTC()
+	from, to := query.From, query.To
+	if from.IsZero() {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if to.IsZero() {
+		to = now
+	}
+
+	first, last := from.UTC().Format(usageDay), to.UTC().Format(usageDay)
+	if first > last {
+		return "", "", ErrInvalidUsageRange
+	}
+	return first, last, nil
+}
+
+func (s *usageService) ForUser(ctx context.Context, userID int, query models.UsageQuery) (*models.Usage, error) {
+	from, to, err := usageDays(query)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.Stored(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, ErrUserNotFound
+	}
+
+	daily, err := s.repo.ListByUser(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.Usage{From: from, To: to, Daily: daily, Stored: *stored}
+	for _, record := range daily {
+		usage.Calls += record.Calls
+	}
+	return usage, nil
+}
+
+func (s *usageService) Report(ctx context.Context, query models.UsageQuery, limit int) (*models.UsageReport, error) {
+	from, to, err := usageDays(query)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxUsageReportUsers {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxUsageReportUsers)
+	}
+
+	return s.repo.Report(ctx, from, to, limit)
+}
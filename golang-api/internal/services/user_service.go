@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	"github.com/centroidsol/todo-api/internal/auth"
+	"github.com/centroidsol/todo-api/internal/logging"
+	"github.com/centroidsol/todo-api/internal/models"
+	"github.com/centroidsol/todo-api/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrEmailTaken is returned when registering an email that already exists
+	ErrEmailTaken = errors.New("email is already registered")
+	// ErrInvalidCredentials is returned for unknown emails and wrong passwords alike
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrPasswordAccount is returned when an identity provider login
+	// matches the email of an account that logs in with a password
+	ErrPasswordAccount = errors.New("an account with this email logs in with a password")
+)
+
+type UserService interface {
+	Register(ctx context.Context, req models.RegisterRequest) (*models.AuthResponse, error)
+	// Login checks a password. ip is the client address, which failed
+	// attempts are counted against along with the account.
+	Login(ctx context.Context, req models.LoginRequest, ip string) (*models.AuthResponse, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	LoginWithIdentity(ctx context.Context, identity *auth.Identity) (*models.AuthResponse, error)
+	IssueScopedToken(ctx context.Context, userID int, req models.TokenRequest) (*models.TokenResponse, error)
+}
+
+type userService struct {
+	repo   repository.UserRepository
+	guard  LoginGuard
+	tokens *auth.TokenManager
+	// cost is the bcrypt cost passwords are hashed at
+	cost   int
+	logger *slog.Logger
+}
+
+// NewUserService returns a UserService that hashes passwords at the
+// bcrypt cost given, or bcrypt.DefaultCost when it is below bcrypt.MinCost
+func NewUserService(repo repository.UserRepository, guard LoginGuard, tokens *auth.TokenManager, cost int, logger *slog.Logger) UserService {
+	return &userService{
+		repo:   repo,
+		guard:  guard,
+		tokens: tokens,
+		cost:   cost,
+		logger: logger,
+	}
+}
+
+func (s *userService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *userService) Register(ctx context.Context, req models.RegisterRequest) (*models.AuthResponse, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	s.log(ctx).Info("Registering user", "email", email)
+
+	if err := s.validateRegisterRequest(email, req); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.EmailExists(ctx, email)
+	if err != nil {
+		s.log(ctx).Error("Failed to check email uniqueness", "error", err)
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+	if exists {
+		s.log(ctx).Warn("Registration with existing email", "email", email)
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.cost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Email:        email,
+		PasswordHash: string(hash),
+	}
+
+	if req.Name != nil {
+		if trimmed := strings.TrimSpace(*req.Name); trimmed != "" {
+			user.Name = &trimmed
+		}
+	}
+
+	err = s.repo.Create(ctx, user)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		// Registered concurrently since the check above
+		s.log(ctx).Warn("Registration with existing email", "email", email)
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to create user", "error", err)
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+
+	s.log(ctx).Info("Registered user successfully", "id", user.ID)
+	return s.issue(user)
+}
+
+func (s *userService) Login(ctx context.Context, req models.LoginRequest, ip string) (*models.AuthResponse, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Refuse before checking the password, so guesses are not even tried
+	if err := s.guard.Check(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		s.log(ctx).Error("Failed to load user for login", "error", err)
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	if user == nil {
+		s.log(ctx).Warn("Login for unknown email", "email", email)
+		s.guard.Failed(ctx, email, ip)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.log(ctx).Warn("Login with wrong password", "user_id", user.ID)
+		s.guard.Failed(ctx, email, ip)
+		return nil, ErrInvalidCredentials
+	}
+
+	s.guard.Succeeded(ctx, user.ID, email, ip)
+
+	s.log(ctx).Info("User logged in", "id", user.ID)
+	return s.issue(user)
+}
+
+// LoginWithIdentity signs in a user authenticated by an external identity
+// provider. The provider subject is mapped to a local user, linking an
+// existing account without a password with the same verified email or
+// creating a new one.
+func (s *userService) LoginWithIdentity(ctx context.Context, identity *auth.Identity) (*models.AuthResponse, error) {
+	user, err := s.repo.GetByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		s.log(ctx).Error("Failed to load user by identity", "issuer", identity.Issuer, "error", err)
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	if user == nil {
+		user, err = s.linkIdentity(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.log(ctx).Info("User logged in with identity provider", "id", user.ID, "issuer", identity.Issuer)
+	return s.issue(user)
+}
+
+func (s *userService) linkIdentity(ctx context.Context, identity *auth.Identity) (*models.User, error) {
+	if identity.Email == "" {
+		return nil, fmt.Errorf("identity provider did not return an email address")
+	}
+
+	user, err := s.repo.GetByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	if user != nil && !identity.EmailVerified {
+		// Linking on an unverified email would let anyone take over the account
+		s.log(ctx).Warn("Refusing to link identity with unverified email", "email", identity.Email)
+		return nil, ErrEmailTaken
+	}
+	if user != nil && user.PasswordHash != "" {
+		// Registering does not verify the email, so whoever registered it
+		// with a password may not be its owner; linking would let them in
+		// to the account the provider's user then fills
+		s.log(ctx).Warn("Refusing to link identity to a password account", "user_id", user.ID)
+		return nil, ErrPasswordAccount
+	}
+
+	if user == nil {
+		// Accounts created through the identity provider have no usable password
+		user = &models.User{Email: identity.Email}
+		if identity.Name != "" {
+			user.Name = &identity.Name
+		}
+		if err := s.repo.Create(ctx, user); err != nil {
+			s.log(ctx).Error("Failed to create user from identity", "error", err)
+			return nil, fmt.Errorf("failed to login: %w", err)
+		}
+		s.log(ctx).Info("Created user from identity provider", "id", user.ID)
+	}
+
+	if err := s.repo.LinkIdentity(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+		s.log(ctx).Error("Failed to link identity", "user_id", user.ID, "error", err)
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *userService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// IssueScopedToken issues the user an access token limited to the
+// requested scopes, for clients that should not get full permissions
+func (s *userService) IssueScopedToken(ctx context.Context, userID int, req models.TokenRequest) (*models.TokenResponse, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, expiresAt, err := s.tokens.Issue(user.ID, user.Email, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx).Info("Issued scoped access token", "user_id", user.ID, "scopes", scopes)
+	return &models.TokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Scopes:    scopes,
+	}, nil
+}
+
+func (s *userService) issue(user *models.User) (*models.AuthResponse, error) {
+	token, expiresAt, err := s.tokens.Issue(user.ID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	}, nil
+}
+
+func (s *userService) validateRegisterRequest(email string, req models.RegisterRequest) error {
+	if email == "" {
+		return fmt.Errorf("email is required")
+	}
+
+	if len(email) > 255 {
+		return fmt.Errorf("email cannot exceed 255 characters")
+	}
+
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return fmt.Errorf("email is invalid")
+	}
+
+	if len(req.Password) < 8 {
+		return fmt.Errorf("password must be at least 8 characters")
+	}
+
+	// bcrypt ignores everything after 72 bytes
+	if len(req.Password) > 72 {
+		return fmt.Errorf("password cannot exceed 72 characters")
+	}
+
+	if req.Name != nil && len(*req.Name) > 255 {
+		return fmt.Errorf("name cannot exceed 255 characters")
+	}
+
+	return nil
+}